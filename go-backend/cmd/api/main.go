@@ -2,7 +2,7 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,17 +13,31 @@ import (
 	"go-backend/internal/config"
 	"go-backend/internal/database"
 	httpapi "go-backend/internal/http"
+	"go-backend/internal/ical"
+	"go-backend/internal/idempotency"
+	"go-backend/internal/logging"
+	"go-backend/internal/mail"
+	"go-backend/internal/metrics"
 	"go-backend/internal/migrations"
+	"go-backend/internal/moderation"
+	"go-backend/internal/payments"
+	"go-backend/internal/realtime"
+	"go-backend/internal/storage"
 	"go-backend/internal/worker"
 )
 
 func main() {
 	cfg := config.Load()
+	slog.SetDefault(logging.New(os.Stdout, logging.Config{
+		Level:     cfg.Log.Level,
+		Format:    cfg.Log.Format,
+		RedactPII: cfg.Log.RedactPII,
+	}))
 
 	// Run database migrations
-	log.Println("Running database migrations...")
+	slog.Info("running database migrations")
 	if err := migrations.Run(cfg.Database.DSN()); err != nil {
-		log.Fatalf("failed to run migrations: %v", err)
+		fatal("failed to run migrations", err)
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -31,14 +45,43 @@ func main() {
 
 	db, err := database.Connect(ctx, cfg.Database.DSN())
 	if err != nil {
-		log.Fatalf("failed to connect to database: %v", err)
+		fatal("failed to connect to database", err)
 	}
 	defer db.Close()
-	log.Println("Connected to PostgreSQL")
+	slog.Info("connected to PostgreSQL")
+
+	// Export connection pool statistics on /metrics
+	if err := metrics.RegisterPool(db.Pool); err != nil {
+		fatal("failed to register database metrics", err)
+	}
 
 	// Initialize auth service
 	authService := auth.NewService(cfg.JWT.SecretKey, cfg.JWT.TokenDurationHours)
-	log.Printf("JWT auth service initialized (token duration: %d hours)", cfg.JWT.TokenDurationHours)
+	slog.Info("JWT auth service initialized", "token_duration_hours", cfg.JWT.TokenDurationHours)
+
+	// Initialize attachment storage
+	store, err := storage.NewLocal(cfg.Attachments.Dir)
+	if err != nil {
+		fatal("failed to initialize attachment storage", err)
+	}
+	slog.Info("attachment storage initialized", "dir", cfg.Attachments.Dir)
+
+	// Initialize email delivery
+	mailer, err := mail.NewSMTP(mail.SMTPConfig{
+		Host:     cfg.Mail.SMTPHost,
+		Port:     cfg.Mail.SMTPPort,
+		Username: cfg.Mail.SMTPUsername,
+		Password: cfg.Mail.SMTPPassword,
+		From:     cfg.Mail.From,
+	})
+	if err != nil {
+		fatal("failed to initialize mailer", err)
+	}
+	mailRenderer, err := mail.NewRenderer(cfg.Mail.AppURL)
+	if err != nil {
+		fatal("failed to load mail templates", err)
+	}
+	slog.Info("mailer initialized", "smtp_host", cfg.Mail.SMTPHost, "smtp_port", cfg.Mail.SMTPPort)
 
 	// Initialize worker manager
 	workerManager := worker.NewManager(db)
@@ -47,11 +90,77 @@ func main() {
 	bookingChecker := worker.NewBookingChecker(db, 1*time.Hour) // Check every hour
 	workerManager.Register(bookingChecker)
 
+	bookingLifecycle := worker.NewBookingLifecycle(db, 15*time.Minute)
+	workerManager.Register(bookingLifecycle)
+
+	reviewPublisher := worker.NewReviewPublisher(db, 1*time.Hour)
+	workerManager.Register(reviewPublisher)
+
+	holdReleaser := worker.NewHoldReleaser(db, 1*time.Minute)
+	workerManager.Register(holdReleaser)
+
+	icalSync := worker.NewICalSync(db, ical.NewHTTPFetcher(30*time.Second), 30*time.Minute)
+	workerManager.Register(icalSync)
+
+	payoutWorker := worker.NewPayoutWorker(db, cfg.Payments.PayoutDelayDays, cfg.Payments.Currency, 1*time.Hour)
+	workerManager.Register(payoutWorker)
+
+	idempotencyCleaner := worker.NewIdempotencyCleaner(db, idempotency.DefaultTTL, 1*time.Hour)
+	workerManager.Register(idempotencyCleaner)
+
+	// Realtime hub forwards events from other instances to connected clients
+	hub := realtime.NewHub(db)
+	workerManager.Register(hub)
+
+	realtimePruner := worker.NewRealtimePruner(db, 10*time.Minute)
+	workerManager.Register(realtimePruner)
+
+	attachmentCleaner := worker.NewAttachmentCleaner(db, store, 24*time.Hour, 1*time.Hour)
+	workerManager.Register(attachmentCleaner)
+
+	mailSender := worker.NewMailSender(db, mailer, mailRenderer, 10*time.Second)
+	workerManager.Register(mailSender)
+
+	webhookDispatcher := worker.NewWebhookDispatcher(db, 10*time.Second)
+	workerManager.Register(webhookDispatcher)
+
+	supportSLA := worker.NewSupportSLA(db, 5*time.Minute)
+	workerManager.Register(supportSLA)
+
 	// Start all workers
 	workerManager.Start()
 
+	// Initialize payment provider
+	var paymentProvider payments.Provider
+	switch cfg.Payments.Provider {
+	case "http":
+		paymentProvider = payments.NewHTTPProvider(cfg.Payments.GatewayURL, cfg.Payments.WebhookSecret)
+	default:
+		paymentProvider = payments.NewFakeProvider(cfg.Payments.WebhookSecret)
+	}
+	slog.Info("payment provider initialized", "provider", paymentProvider.Name())
+
+	// Load moderation word lists
+	words, err := moderation.LoadWordLists(cfg.Moderation.WordLists...)
+	if err != nil {
+		fatal("failed to load moderation word lists", err)
+	}
+	screener := moderation.NewScreener(words)
+	slog.Info("moderation pre-screen initialized", "words", len(words))
+
+	// Contact masking in messages sent before a booking is confirmed
+	maskPatterns, err := moderation.LoadPatterns(cfg.Moderation.MaskPatterns...)
+	if err != nil {
+		fatal("failed to load contact masking patterns", err)
+	}
+	masker, err := moderation.NewMasker(cfg.Moderation.MaskRules, maskPatterns, cfg.Moderation.MaskReplacement)
+	if err != nil {
+		fatal("failed to initialize contact masking", err)
+	}
+	slog.Info("contact masking initialized", "rules", cfg.Moderation.MaskRules, "extra_patterns", len(maskPatterns))
+
 	// Create HTTP server
-	srv := httpapi.NewServer(db, authService)
+	srv := httpapi.NewServer(db, authService, paymentProvider, screener, masker, hub, store, cfg)
 
 	server := &http.Server{
 		Addr:         ":" + cfg.Port,
@@ -60,12 +169,30 @@ func main() {
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	// End open event streams so Shutdown does not wait for them
+	server.RegisterOnShutdown(hub.Close)
+
+	// Metrics are served on their own port so they are not exposed with the API
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", metrics.Handler())
+	metricsServer := &http.Server{
+		Addr:         ":" + cfg.MetricsPort,
+		Handler:      metricsMux,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+	}
 
-	// Start HTTP server in goroutine
+	// Start HTTP servers in goroutines
 	go func() {
-		log.Printf("HTTP server listening on :%s", cfg.Port)
+		slog.Info("HTTP server listening", "port", cfg.Port)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("server error: %v", err)
+			fatal("server error", err)
+		}
+	}()
+	go func() {
+		slog.Info("metrics server listening", "port", cfg.MetricsPort)
+		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("metrics server error", err)
 		}
 	}()
 
@@ -74,7 +201,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
+	slog.Info("shutting down server")
 
 	// Create shutdown context with timeout
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -82,14 +209,23 @@ func main() {
 
 	// Shutdown HTTP server first (stop accepting new requests)
 	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Printf("HTTP server forced to shutdown: %v", err)
+		slog.Error("HTTP server forced to shutdown", "error", err)
+	}
+	if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+		slog.Error("metrics server forced to shutdown", "error", err)
 	}
-	log.Println("HTTP server stopped")
+	slog.Info("HTTP server stopped")
 
 	// Shutdown workers (allow them to finish current work)
 	if err := workerManager.Shutdown(15 * time.Second); err != nil {
-		log.Printf("Worker manager shutdown error: %v", err)
+		slog.Error("worker manager shutdown error", "error", err)
 	}
 
-	log.Println("Server stopped gracefully")
+	slog.Info("server stopped gracefully")
+}
+
+// fatal logs an error that prevents the server from running and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,32 @@
+// Command fakepay runs a local payment gateway stand-in that sends signed
+// webhooks to the API. Start the API with PAYMENT_PROVIDER=http to use it.
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+
+	"go-backend/internal/payments"
+)
+
+func main() {
+	addr := getEnv("FAKEPAY_ADDR", ":8090")
+	webhookURL := getEnv("FAKEPAY_WEBHOOK_URL", "http://localhost:8080/api/payments/webhook")
+	secret := getEnv("PAYMENT_WEBHOOK_SECRET", "whsec_local_development")
+
+	server := payments.NewFakeServer(payments.NewFakeProvider(secret), webhookURL)
+
+	slog.Info("fake payment gateway listening", "addr", addr, "webhook_url", webhookURL)
+	if err := http.ListenAndServe(addr, server); err != nil {
+		slog.Error("server error", "error", err)
+		os.Exit(1)
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
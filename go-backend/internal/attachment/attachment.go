@@ -0,0 +1,164 @@
+// Package attachment validates uploaded message attachments and renders
+// thumbnails for images.
+package attachment
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	_ "image/gif" // register decoders
+	_ "image/png"
+)
+
+const (
+	// ThumbnailSize is the longest side of a thumbnail in pixels
+	ThumbnailSize = 320
+	// MaxPerMessage is how many attachments one message can carry
+	MaxPerMessage = 5
+	// maxPixels rejects images that would take too much memory to decode
+	maxPixels = 40_000_000
+	// maxFilenameLength is the longest stored filename in bytes
+	maxFilenameLength = 200
+)
+
+// Errors
+var (
+	ErrUnsupportedType = errors.New("unsupported file type")
+	ErrInvalidImage    = errors.New("invalid or oversized image")
+)
+
+// allowedTypes are the content types accepted as attachments
+var allowedTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"application/pdf": true,
+}
+
+// DetectType sniffs the content type of an upload from its first bytes.
+// The type declared by the client is ignored.
+func DetectType(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	if !allowedTypes[contentType] {
+		return "", ErrUnsupportedType
+	}
+	return contentType, nil
+}
+
+// IsImage reports whether a content type gets a thumbnail
+func IsImage(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/")
+}
+
+// CleanFilename strips directories and control characters from a client
+// filename, falling back to "attachment" if nothing is left
+func CleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+
+	for len(name) > maxFilenameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	return name
+}
+
+// Image holds an uploaded image's dimensions and JPEG thumbnail
+type Image struct {
+	Width     int
+	Height    int
+	Thumbnail []byte
+}
+
+// ProcessImage decodes an image and renders a thumbnail no larger than
+// ThumbnailSize on either side. Images that do not decode are rejected so a
+// mislabelled file is never served as an image.
+func ProcessImage(data []byte) (*Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrInvalidImage
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scale(src, ThumbnailSize), &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return &Image{Width: cfg.Width, Height: cfg.Height, Thumbnail: buf.Bytes()}, nil
+}
+
+// scale shrinks src to fit within max×max by averaging the source pixels
+// covered by each destination pixel. Transparent areas are flattened onto
+// white, since JPEG has no alpha channel. Smaller images keep their size.
+func scale(src image.Image, max int) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dw, dh := sw, sh
+	if sw > max || sh > max {
+		if sw >= sh {
+			dw, dh = max, sh*max/sw
+		} else {
+			dw, dh = sw*max/sh, max
+		}
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*sh/dh, (y+1)*sh/dh
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*sw/dw, (x+1)*sw/dw
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, bl, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(b.Min.X+sx, b.Min.Y+sy).RGBA()
+					// Premultiplied, so adding the missing alpha as white flattens it
+					white := 0xffff - ca
+					r += uint64(cr + white)
+					g += uint64(cg + white)
+					bl += uint64(cb + white)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(bl / n >> 8),
+				A: 0xff,
+			})
+		}
+	}
+	return dst
+}
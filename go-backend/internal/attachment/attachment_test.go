@@ -0,0 +1,119 @@
+package attachment
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetectType(t *testing.T) {
+	pngData := encodePNG(t, image.NewRGBA(image.Rect(0, 0, 2, 2)))
+	tests := []struct {
+		name string
+		data []byte
+		want string
+		err  error
+	}{
+		{"png", pngData, "image/png", nil},
+		{"pdf", []byte("%PDF-1.7\n..."), "application/pdf", nil},
+		{"jpeg", []byte("\xFF\xD8\xFF\xE0rest"), "image/jpeg", nil},
+		{"html", []byte("<html><script>alert(1)</script>"), "", ErrUnsupportedType},
+		{"text", []byte("just text"), "", ErrUnsupportedType},
+		{"zip", []byte("PK\x03\x04"), "", ErrUnsupportedType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectType(tt.data)
+			if got != tt.want || !errors.Is(err, tt.err) {
+				t.Errorf("DetectType = %q, %v; want %q, %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestCleanFilename(t *testing.T) {
+	tests := map[string]string{
+		"photo.jpg":              "photo.jpg",
+		"../../etc/passwd":       "passwd",
+		`C:\Users\me\scan.pdf`:   "scan.pdf",
+		"a\"b\r\n.png":           "ab.png",
+		"":                       "attachment",
+		"   ":                    "attachment",
+		"dir/":                   "dir",
+		strings.Repeat("é", 150): strings.Repeat("é", 100),
+	}
+	for in, want := range tests {
+		if got := CleanFilename(in); got != want {
+			t.Errorf("CleanFilename(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestProcessImageScalesDown(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 1000, 500))
+	for y := 0; y < 500; y++ {
+		for x := 0; x < 1000; x++ {
+			src.Set(x, y, color.RGBA{R: 200, G: 10, B: 10, A: 255})
+		}
+	}
+
+	img, err := ProcessImage(encodePNG(t, src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Width != 1000 || img.Height != 500 {
+		t.Errorf("dimensions %dx%d, want 1000x500", img.Width, img.Height)
+	}
+
+	thumb, err := jpeg.Decode(bytes.NewReader(img.Thumbnail))
+	if err != nil {
+		t.Fatalf("thumbnail is not a JPEG: %v", err)
+	}
+	if b := thumb.Bounds(); b.Dx() != ThumbnailSize || b.Dy() != ThumbnailSize/2 {
+		t.Errorf("thumbnail %dx%d, want %dx%d", b.Dx(), b.Dy(), ThumbnailSize, ThumbnailSize/2)
+	}
+	r, g, _, _ := thumb.At(10, 10).RGBA()
+	if r>>8 < 180 || g>>8 > 40 {
+		t.Errorf("thumbnail colour %d,%d not preserved", r>>8, g>>8)
+	}
+}
+
+func TestProcessImageKeepsSmallImagesAndFlattensAlpha(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 20, 40)) // fully transparent
+
+	img, err := ProcessImage(encodePNG(t, src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumb, _ := jpeg.Decode(bytes.NewReader(img.Thumbnail))
+	if b := thumb.Bounds(); b.Dx() != 20 || b.Dy() != 40 {
+		t.Errorf("thumbnail %dx%d, want 20x40", b.Dx(), b.Dy())
+	}
+	if r, _, _, _ := thumb.At(5, 5).RGBA(); r>>8 < 240 {
+		t.Errorf("transparent pixel rendered as %d, want white", r>>8)
+	}
+}
+
+func TestProcessImageRejectsInvalidData(t *testing.T) {
+	data := encodePNG(t, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+	if _, err := ProcessImage(data[:len(data)/2]); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("truncated image: err = %v", err)
+	}
+	if _, err := ProcessImage([]byte("%PDF-1.4")); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("pdf: err = %v", err)
+	}
+}
@@ -9,10 +9,14 @@ import (
 
 // Role constants
 const (
-	RoleUser  = "user"
-	RoleAdmin = "admin"
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
 )
 
+// Roles lists the roles a user can have
+var Roles = []string{RoleUser, RoleSupport, RoleAdmin}
+
 // Errors
 var (
 	ErrInvalidToken = errors.New("invalid token")
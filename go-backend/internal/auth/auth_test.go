@@ -104,6 +104,9 @@ func TestRoleConstants(t *testing.T) {
 	if RoleUser != "user" {
 		t.Errorf("Expected RoleUser to be 'user', got %s", RoleUser)
 	}
+	if RoleSupport != "support" {
+		t.Errorf("Expected RoleSupport to be 'support', got %s", RoleSupport)
+	}
 	if RoleAdmin != "admin" {
 		t.Errorf("Expected RoleAdmin to be 'admin', got %s", RoleAdmin)
 	}
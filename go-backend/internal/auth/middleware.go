@@ -1,15 +1,27 @@
 package auth
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
+
+	"go-backend/internal/logging"
 )
 
+// ErrAccountSuspended is returned by a UserCheck for suspended accounts
+var ErrAccountSuspended = errors.New("account suspended")
+
+// UserCheck verifies that an authenticated user may still use the API, e.g.
+// that the account was not suspended after the token was issued
+type UserCheck func(ctx context.Context, userID int) error
+
 // Middleware provides JWT authentication middleware
 type Middleware struct {
 	authService *Service
+	checkUser   UserCheck
 }
 
 // NewMiddleware creates a new auth middleware
@@ -17,6 +29,11 @@ func NewMiddleware(authService *Service) *Middleware {
 	return &Middleware{authService: authService}
 }
 
+// SetUserCheck installs a check that runs for every authenticated request
+func (m *Middleware) SetUserCheck(check UserCheck) {
+	m.checkUser = check
+}
+
 // writeJSONError writes a JSON error response
 func writeJSONError(w http.ResponseWriter, status int, message string) {
 	w.Header().Set("Content-Type", "application/json")
@@ -51,6 +68,18 @@ func (m *Middleware) Authenticate(next http.Handler) http.Handler {
 			return
 		}
 
+		if m.checkUser != nil {
+			if err := m.checkUser(r.Context(), claims.UserID); err != nil {
+				if errors.Is(err, ErrAccountSuspended) {
+					writeJSONError(w, http.StatusForbidden, "account suspended")
+					return
+				}
+				slog.ErrorContext(r.Context(), "error checking user", "component", "auth", "user_id", claims.UserID, "error", err)
+				writeJSONError(w, http.StatusInternalServerError, "could not verify account")
+				return
+			}
+		}
+
 		// Add user info to context
 		userCtx := UserContext{
 			UserID: claims.UserID,
@@ -58,8 +87,9 @@ func (m *Middleware) Authenticate(next http.Handler) http.Handler {
 			Role:   claims.Role,
 		}
 		ctx := ContextWithUser(r.Context(), userCtx)
+		logging.SetUserID(ctx, userCtx.UserID)
 
-		log.Printf("[Auth] Authenticated user: id=%d, email=%s, role=%s", userCtx.UserID, userCtx.Email, userCtx.Role)
+		slog.DebugContext(ctx, "authenticated request", "component", "auth", "role", userCtx.Role)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -111,6 +141,10 @@ func (m *Middleware) Optional(next http.Handler) http.Handler {
 			next.ServeHTTP(w, r)
 			return
 		}
+		if m.checkUser != nil && m.checkUser(r.Context(), claims.UserID) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
 
 		userCtx := UserContext{
 			UserID: claims.UserID,
@@ -118,6 +152,7 @@ func (m *Middleware) Optional(next http.Handler) http.Handler {
 			Role:   claims.Role,
 		}
 		ctx := ContextWithUser(r.Context(), userCtx)
+		logging.SetUserID(ctx, userCtx.UserID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
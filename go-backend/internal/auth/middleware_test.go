@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthenticate_UserCheck(t *testing.T) {
+	service := NewService("test-secret-key-256-bits-long!!", 24)
+	token, err := service.GenerateToken(7, "guest@example.com", RoleUser)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		checkErr   error
+		wantStatus int
+	}{
+		{"active user", nil, http.StatusOK},
+		{"suspended user", ErrAccountSuspended, http.StatusForbidden},
+		{"check fails", errors.New("database down"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMiddleware(service)
+			m.SetUserCheck(func(ctx context.Context, userID int) error {
+				if userID != 7 {
+					t.Errorf("Expected user 7, got %d", userID)
+				}
+				return tt.checkErr
+			})
+			handler := m.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func TestOptional_SuspendedUserIsAnonymous(t *testing.T) {
+	service := NewService("test-secret-key-256-bits-long!!", 24)
+	token, _ := service.GenerateToken(7, "guest@example.com", RoleUser)
+
+	m := NewMiddleware(service)
+	m.SetUserCheck(func(ctx context.Context, userID int) error { return ErrAccountSuspended })
+
+	var authenticated bool
+	handler := m.Optional(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := UserFromContext(r.Context())
+		authenticated = err == nil
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if authenticated {
+		t.Error("Expected suspended user to be treated as anonymous")
+	}
+}
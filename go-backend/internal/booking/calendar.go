@@ -0,0 +1,66 @@
+package booking
+
+import (
+	"time"
+
+	"go-backend/internal/models"
+)
+
+// MaxCalendarDays limits how many days a single calendar request may return
+const MaxCalendarDays = 366
+
+// Range is a half-open date range [Start, End)
+type Range struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Contains reports whether the night starting on day falls inside the range
+func (r Range) Contains(day time.Time) bool {
+	return !day.Before(r.Start) && day.Before(r.End)
+}
+
+// Overlaps reports whether two ranges share at least one night
+func (r Range) Overlaps(other Range) bool {
+	return r.Start.Before(other.End) && other.Start.Before(r.End)
+}
+
+// BuildCalendar returns one entry per night in [from, to). Booked ranges take
+// precedence over blocked dates; blocked maps a date (DateLayout) to the host's note.
+func BuildCalendar(from, to time.Time, price float64, booked []Range, blocked map[string]string) []models.CalendarDay {
+	days := []models.CalendarDay{}
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		key := day.Format(DateLayout)
+		entry := models.CalendarDay{Date: key, Status: models.DayAvailable, Price: price}
+
+		if note, ok := blocked[key]; ok {
+			entry.Status = models.DayBlocked
+			entry.Note = note
+		}
+		for _, b := range booked {
+			if b.Contains(day) {
+				entry.Status = models.DayBooked
+				entry.Note = ""
+				break
+			}
+		}
+		days = append(days, entry)
+	}
+	return days
+}
+
+// MergeNights groups sorted nights into contiguous ranges
+func MergeNights(nights []time.Time) []Range {
+	ranges := []Range{}
+	for _, night := range nights {
+		next := night.AddDate(0, 0, 1)
+		if n := len(ranges); n > 0 && !night.After(ranges[n-1].End) {
+			if next.After(ranges[n-1].End) {
+				ranges[n-1].End = next
+			}
+			continue
+		}
+		ranges = append(ranges, Range{Start: night, End: next})
+	}
+	return ranges
+}
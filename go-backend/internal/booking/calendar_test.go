@@ -0,0 +1,96 @@
+package booking
+
+import (
+	"testing"
+	"time"
+
+	"go-backend/internal/models"
+)
+
+func TestRangeOverlaps(t *testing.T) {
+	base := Range{Start: date("2025-03-05"), End: date("2025-03-10")}
+
+	tests := []struct {
+		name     string
+		other    Range
+		expected bool
+	}{
+		{"inside", Range{Start: date("2025-03-06"), End: date("2025-03-08")}, true},
+		{"overlaps start", Range{Start: date("2025-03-01"), End: date("2025-03-06")}, true},
+		{"ends on check-in", Range{Start: date("2025-03-01"), End: date("2025-03-05")}, false},
+		{"starts on check-out", Range{Start: date("2025-03-10"), End: date("2025-03-12")}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := base.Overlaps(tt.other); got != tt.expected {
+				t.Errorf("Overlaps() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuildCalendar(t *testing.T) {
+	booked := []Range{{Start: date("2025-03-02"), End: date("2025-03-04")}}
+	blocked := map[string]string{
+		"2025-03-03": "overlaps booking",
+		"2025-03-05": "maintenance",
+	}
+
+	days := BuildCalendar(date("2025-03-01"), date("2025-03-06"), 120, booked, blocked)
+	if len(days) != 5 {
+		t.Fatalf("Expected 5 days, got %d", len(days))
+	}
+
+	expected := []string{
+		models.DayAvailable,
+		models.DayBooked,
+		models.DayBooked,
+		models.DayAvailable,
+		models.DayBlocked,
+	}
+	for i, status := range expected {
+		if days[i].Status != status {
+			t.Errorf("Day %s: expected %s, got %s", days[i].Date, status, days[i].Status)
+		}
+		if days[i].Price != 120 {
+			t.Errorf("Day %s: expected price 120, got %v", days[i].Date, days[i].Price)
+		}
+	}
+	if days[2].Note != "" {
+		t.Errorf("Expected booked day to hide block note, got %q", days[2].Note)
+	}
+	if days[4].Note != "maintenance" {
+		t.Errorf("Expected note 'maintenance', got %q", days[4].Note)
+	}
+}
+
+func TestMergeNights(t *testing.T) {
+	nights := []time.Time{
+		date("2025-03-01"),
+		date("2025-03-02"),
+		date("2025-03-03"),
+		date("2025-03-07"),
+		date("2025-03-09"),
+		date("2025-03-10"),
+	}
+
+	ranges := MergeNights(nights)
+	expected := []Range{
+		{Start: date("2025-03-01"), End: date("2025-03-04")},
+		{Start: date("2025-03-07"), End: date("2025-03-08")},
+		{Start: date("2025-03-09"), End: date("2025-03-11")},
+	}
+	if len(ranges) != len(expected) {
+		t.Fatalf("Expected %d ranges, got %d", len(expected), len(ranges))
+	}
+	for i := range expected {
+		if !ranges[i].Start.Equal(expected[i].Start) || !ranges[i].End.Equal(expected[i].End) {
+			t.Errorf("Range %d: expected %v, got %v", i, expected[i], ranges[i])
+		}
+	}
+
+	if got := MergeNights(nil); len(got) != 0 {
+		t.Errorf("Expected no ranges, got %d", len(got))
+	}
+}
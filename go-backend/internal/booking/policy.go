@@ -0,0 +1,101 @@
+package booking
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Cancellation policy names
+const (
+	PolicyFlexible      = "flexible"
+	PolicyModerate      = "moderate"
+	PolicyStrict        = "strict"
+	PolicyNonRefundable = "non_refundable"
+	PolicyCustom        = "custom"
+)
+
+// RefundTier refunds RefundPercent of the total when the booking is cancelled
+// at least DaysBefore days before check-in
+type RefundTier struct {
+	DaysBefore    int `json:"days_before"`
+	RefundPercent int `json:"refund_percent"`
+}
+
+// CancellationPolicy determines how much of a booking is refunded on cancellation
+type CancellationPolicy struct {
+	Name  string       `json:"name"`
+	Tiers []RefundTier `json:"tiers"`
+}
+
+var standardPolicies = map[string][]RefundTier{
+	PolicyFlexible:      {{DaysBefore: 1, RefundPercent: 100}},
+	PolicyModerate:      {{DaysBefore: 5, RefundPercent: 100}, {DaysBefore: 1, RefundPercent: 50}},
+	PolicyStrict:        {{DaysBefore: 14, RefundPercent: 100}, {DaysBefore: 7, RefundPercent: 50}},
+	PolicyNonRefundable: {},
+}
+
+// NewCancellationPolicy returns a standard policy by name, or a custom policy
+// built from tiers when name is PolicyCustom
+func NewCancellationPolicy(name string, tiers []RefundTier) (CancellationPolicy, error) {
+	if name == PolicyCustom {
+		p := CancellationPolicy{Name: PolicyCustom, Tiers: append([]RefundTier{}, tiers...)}
+		sort.Slice(p.Tiers, func(i, j int) bool { return p.Tiers[i].DaysBefore > p.Tiers[j].DaysBefore })
+		return p, p.Validate()
+	}
+
+	standard, ok := standardPolicies[name]
+	if !ok {
+		return CancellationPolicy{}, fmt.Errorf("unknown cancellation policy %q", name)
+	}
+	return CancellationPolicy{Name: name, Tiers: append([]RefundTier{}, standard...)}, nil
+}
+
+// DefaultCancellationPolicy is used for properties that have not chosen a policy
+func DefaultCancellationPolicy() CancellationPolicy {
+	p, _ := NewCancellationPolicy(PolicyFlexible, nil)
+	return p
+}
+
+// Validate checks that tiers are sorted by DaysBefore (descending) and that
+// refunds do not increase closer to check-in
+func (p CancellationPolicy) Validate() error {
+	if len(p.Tiers) > 10 {
+		return fmt.Errorf("at most 10 refund tiers are allowed")
+	}
+	for i, t := range p.Tiers {
+		if t.DaysBefore < 0 {
+			return fmt.Errorf("days_before must not be negative")
+		}
+		if t.RefundPercent < 0 || t.RefundPercent > 100 {
+			return fmt.Errorf("refund_percent must be between 0 and 100")
+		}
+		if i > 0 {
+			prev := p.Tiers[i-1]
+			if t.DaysBefore >= prev.DaysBefore {
+				return fmt.Errorf("tiers must have distinct days_before values")
+			}
+			if t.RefundPercent > prev.RefundPercent {
+				return fmt.Errorf("refund_percent must not increase closer to check-in")
+			}
+		}
+	}
+	return nil
+}
+
+// RefundPercent returns the refund percentage for a cancellation daysBefore days before check-in
+func (p CancellationPolicy) RefundPercent(daysBefore int) int {
+	for _, t := range p.Tiers {
+		if daysBefore >= t.DaysBefore {
+			return t.RefundPercent
+		}
+	}
+	return 0
+}
+
+// Refund computes the amount refunded when a booking of total is cancelled on
+// the given day (a date as returned by Today) for a stay starting on checkIn
+func (p CancellationPolicy) Refund(total float64, checkIn, cancelledOn time.Time) float64 {
+	daysBefore := int(checkIn.Sub(cancelledOn).Hours() / 24)
+	return RoundMoney(total * float64(p.RefundPercent(daysBefore)) / 100)
+}
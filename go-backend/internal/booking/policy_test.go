@@ -0,0 +1,73 @@
+package booking
+
+import "testing"
+
+func TestNewCancellationPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		tiers   []RefundTier
+		wantErr bool
+	}{
+		{"flexible", PolicyFlexible, nil, false},
+		{"non refundable", PolicyNonRefundable, nil, false},
+		{"unknown", "lenient", nil, true},
+		{"custom unsorted", PolicyCustom, []RefundTier{{DaysBefore: 3, RefundPercent: 25}, {DaysBefore: 30, RefundPercent: 100}}, false},
+		{"custom increasing refund", PolicyCustom, []RefundTier{{DaysBefore: 30, RefundPercent: 50}, {DaysBefore: 3, RefundPercent: 100}}, true},
+		{"custom duplicate days", PolicyCustom, []RefundTier{{DaysBefore: 7, RefundPercent: 100}, {DaysBefore: 7, RefundPercent: 50}}, true},
+		{"custom invalid percent", PolicyCustom, []RefundTier{{DaysBefore: 7, RefundPercent: 120}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewCancellationPolicy(tt.policy, tt.tiers)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCancellationPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && p.Name != tt.policy {
+				t.Errorf("Expected name %s, got %s", tt.policy, p.Name)
+			}
+		})
+	}
+}
+
+func TestRefund(t *testing.T) {
+	checkIn := date("2025-03-20")
+	custom, err := NewCancellationPolicy(PolicyCustom, []RefundTier{
+		{DaysBefore: 0, RefundPercent: 10},
+		{DaysBefore: 30, RefundPercent: 100},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create custom policy: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		policy      string
+		custom      *CancellationPolicy
+		cancelledOn string
+		expected    float64
+	}{
+		{"flexible day before", PolicyFlexible, nil, "2025-03-19", 330},
+		{"flexible same day", PolicyFlexible, nil, "2025-03-20", 0},
+		{"moderate five days", PolicyModerate, nil, "2025-03-15", 330},
+		{"moderate three days", PolicyModerate, nil, "2025-03-17", 165},
+		{"strict ten days", PolicyStrict, nil, "2025-03-10", 165},
+		{"strict three days", PolicyStrict, nil, "2025-03-17", 0},
+		{"non refundable", PolicyNonRefundable, nil, "2025-01-01", 0},
+		{"custom on check-in day", "", &custom, "2025-03-20", 33},
+		{"custom after check-in", "", &custom, "2025-03-21", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := custom
+			if tt.custom == nil {
+				p, _ = NewCancellationPolicy(tt.policy, nil)
+			}
+			if got := p.Refund(330, checkIn, date(tt.cancelledOn)); got != tt.expected {
+				t.Errorf("Refund() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package booking
+
+import "math"
+
+// ServiceFeeRate is the guest service fee charged on top of the accommodation price
+const ServiceFeeRate = 0.10
+
+// PriceBreakdown is the price of a stay, stored on the booking when it is created
+type PriceBreakdown struct {
+	NightlyRate   float64 `json:"nightly_rate"`
+	Nights        int     `json:"nights"`
+	Accommodation float64 `json:"accommodation"`
+	ServiceFee    float64 `json:"service_fee"`
+	Total         float64 `json:"total"`
+}
+
+// Quote prices a stay of the given number of nights
+func Quote(nightlyRate float64, nights int) PriceBreakdown {
+	accommodation := RoundMoney(nightlyRate * float64(nights))
+	fee := RoundMoney(accommodation * ServiceFeeRate)
+	return PriceBreakdown{
+		NightlyRate:   nightlyRate,
+		Nights:        nights,
+		Accommodation: accommodation,
+		ServiceFee:    fee,
+		Total:         RoundMoney(accommodation + fee),
+	}
+}
+
+// RoundMoney rounds an amount to cents
+func RoundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,37 @@
+package booking
+
+import "testing"
+
+func TestQuote(t *testing.T) {
+	q := Quote(149.99, 3)
+
+	if q.Nights != 3 {
+		t.Errorf("Expected 3 nights, got %d", q.Nights)
+	}
+	if q.Accommodation != 449.97 {
+		t.Errorf("Expected accommodation 449.97, got %v", q.Accommodation)
+	}
+	if q.ServiceFee != 45 {
+		t.Errorf("Expected service fee 45, got %v", q.ServiceFee)
+	}
+	if q.Total != 494.97 {
+		t.Errorf("Expected total 494.97, got %v", q.Total)
+	}
+}
+
+func TestRoundMoney(t *testing.T) {
+	tests := []struct {
+		in       float64
+		expected float64
+	}{
+		{10.004, 10},
+		{10.005, 10.01},
+		{0.1 + 0.2, 0.3},
+	}
+
+	for _, tt := range tests {
+		if got := RoundMoney(tt.in); got != tt.expected {
+			t.Errorf("RoundMoney(%v) = %v, expected %v", tt.in, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package booking
+
+import (
+	"fmt"
+	"time"
+)
+
+// DateLayout is the format used for booking dates in requests and responses
+const DateLayout = "2006-01-02"
+
+// Violation codes returned to clients so the frontend can translate them
+const (
+	CodeInvalidDates      = "invalid_dates"
+	CodeDatesInPast       = "dates_in_past"
+	CodeTooManyGuests     = "too_many_guests"
+	CodeMinNights         = "min_nights"
+	CodeMaxNights         = "max_nights"
+	CodeCheckInDay        = "check_in_day_not_allowed"
+	CodeCheckOutDay       = "check_out_day_not_allowed"
+	CodeAdvanceNotice     = "advance_notice"
+	CodeBookingWindow     = "outside_booking_window"
+	CodeDatesNotAvailable = "dates_not_available"
+	CodePaymentDeclined   = "payment_declined"
+	CodeTooManyHolds      = "too_many_holds"
+	CodeHoldNotActive     = "hold_not_active"
+)
+
+// Violation describes why a stay cannot be booked
+type Violation struct {
+	Code    string         `json:"code"`
+	Message string         `json:"error"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+func (v *Violation) Error() string {
+	return v.Message
+}
+
+// Rules are the per-property stay rules enforced when a booking is created
+type Rules struct {
+	MinNights         int   `json:"min_nights"`
+	MaxNights         int   `json:"max_nights"`          // 0 means unlimited
+	CheckInDays       []int `json:"check_in_days"`       // weekdays (0 = Sunday), empty means any day
+	CheckOutDays      []int `json:"check_out_days"`      // weekdays (0 = Sunday), empty means any day
+	AdvanceNoticeDays int   `json:"advance_notice_days"` // minimum days between today and check-in
+	BookingWindowDays int   `json:"booking_window_days"` // how far ahead the calendar is open, 0 means unlimited
+	PreparationDays   int   `json:"preparation_days"`    // days blocked before and after each stay
+}
+
+// DefaultRules returns the rules applied to properties without explicit rules
+func DefaultRules() Rules {
+	return Rules{
+		MinNights:    1,
+		CheckInDays:  []int{},
+		CheckOutDays: []int{},
+	}
+}
+
+// Validate checks that the rules themselves are consistent
+func (r Rules) Validate() error {
+	if r.MinNights < 1 {
+		return fmt.Errorf("min_nights must be at least 1")
+	}
+	if r.MaxNights < 0 || (r.MaxNights > 0 && r.MaxNights < r.MinNights) {
+		return fmt.Errorf("max_nights must be 0 or at least min_nights")
+	}
+	if r.AdvanceNoticeDays < 0 || r.BookingWindowDays < 0 || r.PreparationDays < 0 {
+		return fmt.Errorf("advance_notice_days, booking_window_days and preparation_days must not be negative")
+	}
+	if r.BookingWindowDays > 0 && r.BookingWindowDays <= r.AdvanceNoticeDays {
+		return fmt.Errorf("booking_window_days must be greater than advance_notice_days")
+	}
+	for _, days := range [][]int{r.CheckInDays, r.CheckOutDays} {
+		for _, d := range days {
+			if d < 0 || d > 6 {
+				return fmt.Errorf("weekdays must be between 0 (Sunday) and 6 (Saturday)")
+			}
+		}
+	}
+	return nil
+}
+
+// Stay is a requested booking period
+type Stay struct {
+	Start    time.Time
+	End      time.Time
+	Guests   int
+	Capacity int // maximum number of guests the property accepts
+}
+
+// Nights returns the number of nights in the stay
+func (s Stay) Nights() int {
+	return int(s.End.Sub(s.Start).Hours() / 24)
+}
+
+// ParseDates parses start and end dates in DateLayout format
+func ParseDates(start, end string) (time.Time, time.Time, error) {
+	s, err := time.Parse(DateLayout, start)
+	if err != nil {
+		return time.Time{}, time.Time{}, &Violation{Code: CodeInvalidDates, Message: "start_date must be in YYYY-MM-DD format"}
+	}
+	e, err := time.Parse(DateLayout, end)
+	if err != nil {
+		return time.Time{}, time.Time{}, &Violation{Code: CodeInvalidDates, Message: "end_date must be in YYYY-MM-DD format"}
+	}
+	if !e.After(s) {
+		return time.Time{}, time.Time{}, &Violation{Code: CodeInvalidDates, Message: "end_date must be after start_date"}
+	}
+	return s, e, nil
+}
+
+// Today truncates t to a UTC date so it can be compared with parsed booking dates
+func Today(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// CheckStay validates a stay against the rules. today must be a date as returned by Today.
+// It returns a *Violation describing the first rule that is broken.
+func (r Rules) CheckStay(stay Stay, today time.Time) error {
+	if !stay.End.After(stay.Start) {
+		return &Violation{Code: CodeInvalidDates, Message: "end_date must be after start_date"}
+	}
+	if stay.Start.Before(today) {
+		return &Violation{Code: CodeDatesInPast, Message: "start_date must not be in the past"}
+	}
+	if stay.Capacity > 0 && stay.Guests > stay.Capacity {
+		return &Violation{
+			Code:    CodeTooManyGuests,
+			Message: fmt.Sprintf("property accepts at most %d guests", stay.Capacity),
+			Details: map[string]any{"max_guests": stay.Capacity},
+		}
+	}
+
+	nights := stay.Nights()
+	if nights < r.MinNights {
+		return &Violation{
+			Code:    CodeMinNights,
+			Message: fmt.Sprintf("minimum stay is %d nights", r.MinNights),
+			Details: map[string]any{"min_nights": r.MinNights},
+		}
+	}
+	if r.MaxNights > 0 && nights > r.MaxNights {
+		return &Violation{
+			Code:    CodeMaxNights,
+			Message: fmt.Sprintf("maximum stay is %d nights", r.MaxNights),
+			Details: map[string]any{"max_nights": r.MaxNights},
+		}
+	}
+
+	if !weekdayAllowed(r.CheckInDays, stay.Start.Weekday()) {
+		return &Violation{
+			Code:    CodeCheckInDay,
+			Message: fmt.Sprintf("check-in is not allowed on %s", stay.Start.Weekday()),
+			Details: map[string]any{"allowed_days": r.CheckInDays},
+		}
+	}
+	if !weekdayAllowed(r.CheckOutDays, stay.End.Weekday()) {
+		return &Violation{
+			Code:    CodeCheckOutDay,
+			Message: fmt.Sprintf("check-out is not allowed on %s", stay.End.Weekday()),
+			Details: map[string]any{"allowed_days": r.CheckOutDays},
+		}
+	}
+
+	if stay.Start.Before(today.AddDate(0, 0, r.AdvanceNoticeDays)) {
+		return &Violation{
+			Code:    CodeAdvanceNotice,
+			Message: fmt.Sprintf("bookings require %d days advance notice", r.AdvanceNoticeDays),
+			Details: map[string]any{"advance_notice_days": r.AdvanceNoticeDays},
+		}
+	}
+	if r.BookingWindowDays > 0 && stay.End.After(today.AddDate(0, 0, r.BookingWindowDays)) {
+		return &Violation{
+			Code:    CodeBookingWindow,
+			Message: fmt.Sprintf("calendar is only open %d days ahead", r.BookingWindowDays),
+			Details: map[string]any{"booking_window_days": r.BookingWindowDays},
+		}
+	}
+
+	return nil
+}
+
+func weekdayAllowed(allowed []int, day time.Weekday) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, d := range allowed {
+		if time.Weekday(d) == day {
+			return true
+		}
+	}
+	return false
+}
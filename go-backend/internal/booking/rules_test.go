@@ -0,0 +1,127 @@
+package booking
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseDates(t *testing.T) {
+	tests := []struct {
+		name    string
+		start   string
+		end     string
+		wantErr bool
+	}{
+		{"valid range", "2025-03-01", "2025-03-05", false},
+		{"invalid start", "03/01/2025", "2025-03-05", true},
+		{"invalid end", "2025-03-01", "tomorrow", true},
+		{"end before start", "2025-03-05", "2025-03-01", true},
+		{"same day", "2025-03-01", "2025-03-01", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParseDates(tt.start, tt.end)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDates() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				var v *Violation
+				if !errors.As(err, &v) || v.Code != CodeInvalidDates {
+					t.Errorf("Expected %s violation, got %v", CodeInvalidDates, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRulesValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   Rules
+		wantErr bool
+	}{
+		{"defaults", DefaultRules(), false},
+		{"zero min nights", Rules{MinNights: 0}, true},
+		{"max below min", Rules{MinNights: 3, MaxNights: 2}, true},
+		{"negative preparation", Rules{MinNights: 1, PreparationDays: -1}, true},
+		{"window shorter than notice", Rules{MinNights: 1, AdvanceNoticeDays: 10, BookingWindowDays: 5}, true},
+		{"invalid weekday", Rules{MinNights: 1, CheckInDays: []int{7}}, true},
+		{"full rules", Rules{MinNights: 2, MaxNights: 14, CheckInDays: []int{5, 6}, BookingWindowDays: 365}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rules.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckStay(t *testing.T) {
+	today := date("2025-03-03") // Monday
+
+	rules := Rules{
+		MinNights:         2,
+		MaxNights:         7,
+		CheckInDays:       []int{int(time.Friday), int(time.Saturday)},
+		AdvanceNoticeDays: 1,
+		BookingWindowDays: 90,
+	}
+
+	tests := []struct {
+		name     string
+		rules    Rules
+		stay     Stay
+		wantCode string
+	}{
+		{"valid stay", rules, Stay{Start: date("2025-03-07"), End: date("2025-03-10"), Guests: 2, Capacity: 4}, ""},
+		{"in the past", rules, Stay{Start: date("2025-03-01"), End: date("2025-03-04"), Guests: 2}, CodeDatesInPast},
+		{"too many guests", rules, Stay{Start: date("2025-03-07"), End: date("2025-03-10"), Guests: 5, Capacity: 4}, CodeTooManyGuests},
+		{"too short", rules, Stay{Start: date("2025-03-07"), End: date("2025-03-08"), Guests: 1}, CodeMinNights},
+		{"too long", rules, Stay{Start: date("2025-03-07"), End: date("2025-03-20"), Guests: 1}, CodeMaxNights},
+		{"wrong check-in day", rules, Stay{Start: date("2025-03-05"), End: date("2025-03-08"), Guests: 1}, CodeCheckInDay},
+		{"wrong check-out day", Rules{MinNights: 1, CheckOutDays: []int{int(time.Sunday)}}, Stay{Start: date("2025-03-07"), End: date("2025-03-08"), Guests: 1}, CodeCheckOutDay},
+		{"no advance notice", Rules{MinNights: 1, AdvanceNoticeDays: 2}, Stay{Start: date("2025-03-04"), End: date("2025-03-06"), Guests: 1}, CodeAdvanceNotice},
+		{"beyond window", rules, Stay{Start: date("2025-06-06"), End: date("2025-06-09"), Guests: 1}, CodeBookingWindow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rules.CheckStay(tt.stay, today)
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				return
+			}
+
+			var v *Violation
+			if !errors.As(err, &v) {
+				t.Fatalf("Expected *Violation, got %v", err)
+			}
+			if v.Code != tt.wantCode {
+				t.Errorf("Expected code %s, got %s", tt.wantCode, v.Code)
+			}
+		})
+	}
+}
+
+func TestToday(t *testing.T) {
+	now := time.Date(2025, 3, 3, 23, 59, 0, 0, time.FixedZone("UTC+5", 5*3600))
+	got := Today(now)
+	if !got.Equal(date("2025-03-03")) {
+		t.Errorf("Expected 2025-03-03, got %s", got.Format(DateLayout))
+	}
+}
@@ -4,12 +4,21 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
-	Port     string
-	Database DatabaseConfig
-	JWT      JWTConfig
+	Port string
+	// MetricsPort serves /metrics, apart from the public API
+	MetricsPort string
+	Database    DatabaseConfig
+	JWT         JWTConfig
+	Payments    PaymentsConfig
+	Holds       HoldsConfig
+	Moderation  ModerationConfig
+	Attachments AttachmentsConfig
+	Mail        MailConfig
+	Log         LogConfig
 }
 
 type DatabaseConfig struct {
@@ -25,6 +34,62 @@ type JWTConfig struct {
 	TokenDurationHours int
 }
 
+type PaymentsConfig struct {
+	Provider      string // fake | http
+	GatewayURL    string
+	WebhookSecret string
+	Currency      string
+
+	// PayoutDelayDays is how many days after check-in host earnings are released
+	PayoutDelayDays int
+}
+
+type HoldsConfig struct {
+	// TTLMinutes is how long a hold reserves dates during checkout
+	TTLMinutes int
+	// MaxPerUser is how many active holds a user can have at once
+	MaxPerUser int
+}
+
+type ModerationConfig struct {
+	// WordLists are files of words and phrases the pre-screen flags as profanity
+	WordLists []string
+	// MaskRules are the built-in rules masking contact details in messages
+	// sent before a booking is confirmed: email, phone, url, or none
+	MaskRules []string
+	// MaskPatterns are files of extra regular expressions to mask, one per line
+	MaskPatterns []string
+	// MaskReplacement is shown in place of masked contact details
+	MaskReplacement string
+}
+
+type AttachmentsConfig struct {
+	// Dir is where the local storage backend keeps uploaded files
+	Dir string
+	// MaxSizeMB is the largest file accepted as a message attachment
+	MaxSizeMB int
+}
+
+type MailConfig struct {
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	// From is the sender of outgoing email, e.g. "GoRent <no-reply@gorent.com>"
+	From string
+	// AppURL is the address of the web app, used for links in emails
+	AppURL string
+}
+
+type LogConfig struct {
+	// Level is the lowest level logged: debug, info, warn or error
+	Level string
+	// Format is json or text
+	Format string
+	// RedactPII hides emails, names and other personal data in log lines
+	RedactPII bool
+}
+
 func (d DatabaseConfig) DSN() string {
 	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
 		d.User, d.Password, d.Host, d.Port, d.DBName)
@@ -32,7 +97,8 @@ func (d DatabaseConfig) DSN() string {
 
 func Load() *Config {
 	return &Config{
-		Port: getEnv("PORT", "8080"),
+		Port:        getEnv("PORT", "8080"),
+		MetricsPort: getEnv("METRICS_PORT", "9090"),
 		Database: DatabaseConfig{
 			Host:     getEnv("POSTGRES_HOST", "localhost"),
 			Port:     getEnv("POSTGRES_PORT", "5432"),
@@ -44,6 +110,41 @@ func Load() *Config {
 			SecretKey:          getEnv("JWT_SECRET", "your-256-bit-secret-key-change-in-production"),
 			TokenDurationHours: getEnvInt("JWT_DURATION_HOURS", 24),
 		},
+		Payments: PaymentsConfig{
+			Provider:      getEnv("PAYMENT_PROVIDER", "fake"),
+			GatewayURL:    getEnv("PAYMENT_GATEWAY_URL", "http://localhost:8090"),
+			WebhookSecret: getEnv("PAYMENT_WEBHOOK_SECRET", "whsec_local_development"),
+			Currency:      getEnv("PAYMENT_CURRENCY", "usd"),
+
+			PayoutDelayDays: getEnvInt("PAYOUT_DELAY_DAYS", 1),
+		},
+		Holds: HoldsConfig{
+			TTLMinutes: getEnvInt("HOLD_TTL_MINUTES", 10),
+			MaxPerUser: getEnvInt("MAX_HOLDS_PER_USER", 3),
+		},
+		Moderation: ModerationConfig{
+			WordLists:       getEnvList("MODERATION_WORD_LISTS"),
+			MaskRules:       splitList(getEnv("CONTACT_MASKING_RULES", "email,phone,url")),
+			MaskPatterns:    getEnvList("CONTACT_MASKING_PATTERNS"),
+			MaskReplacement: getEnv("CONTACT_MASKING_REPLACEMENT", "[contact info hidden]"),
+		},
+		Attachments: AttachmentsConfig{
+			Dir:       getEnv("ATTACHMENTS_DIR", "./data/attachments"),
+			MaxSizeMB: getEnvInt("ATTACHMENT_MAX_SIZE_MB", 10),
+		},
+		Mail: MailConfig{
+			SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:     getEnvInt("SMTP_PORT", 1025),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			From:         getEnv("MAIL_FROM", "GoRent <no-reply@gorent.local>"),
+			AppURL:       getEnv("APP_URL", "http://localhost:3000"),
+		},
+		Log: LogConfig{
+			Level:     getEnv("LOG_LEVEL", "info"),
+			Format:    getEnv("LOG_FORMAT", "json"),
+			RedactPII: getEnvBool("LOG_REDACT_PII", true),
+		},
 	}
 }
 
@@ -62,3 +163,28 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList reads a comma-separated list, ignoring empty items
+func getEnvList(key string) []string {
+	return splitList(os.Getenv(key))
+}
+
+// splitList splits a comma-separated list, ignoring empty items
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,381 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"go-backend/internal/attachment"
+	"go-backend/internal/auth"
+	"go-backend/internal/models"
+	"go-backend/internal/storage"
+)
+
+const (
+	// transferTimeout replaces the server's read and write timeouts while
+	// an attachment is uploaded or downloaded
+	transferTimeout = 2 * time.Minute
+	// multipartOverhead allows for the form fields and boundaries around a file
+	multipartOverhead = 64 << 10
+	// multipartMemory is how much of an upload is buffered in memory before
+	// spilling to a temporary file
+	multipartMemory = 1 << 20
+)
+
+// errInvalidAttachments is returned when a message refers to attachments the
+// sender did not upload to the conversation or that were already sent
+var errInvalidAttachments = errors.New("attachments not found or already sent")
+
+// attachmentColumns is the column list read by scanAttachment, selected FROM
+// message_attachments a
+const attachmentColumns = `a.id, COALESCE(a.conversation_id, 0), a.message_id, a.filename, a.content_type,
+	a.size_bytes, a.width, a.height, a.thumbnail_key IS NOT NULL, a.created_at`
+
+// scanAttachment scans a row selected with attachmentColumns
+func scanAttachment(row pgx.Row, a *models.Attachment) error {
+	var thumbnail bool
+	err := row.Scan(&a.ID, &a.ConversationID, &a.MessageID, &a.Filename, &a.ContentType,
+		&a.SizeBytes, &a.Width, &a.Height, &thumbnail, &a.CreatedAt)
+	if err != nil {
+		return err
+	}
+	a.URL = fmt.Sprintf("/api/attachments/%d", a.ID)
+	if thumbnail {
+		a.ThumbnailURL = a.URL + "/thumbnail"
+	}
+	return nil
+}
+
+// handleAttachments uploads a file to a conversation as multipart/form-data
+// with the fields conversation_id and file. The upload stays pending until a
+// message is sent with its ID in attachment_ids.
+func (s *Server) handleAttachments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := auth.UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	http.NewResponseController(w).SetReadDeadline(time.Now().Add(transferTimeout))
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxAttachmentSize+multipartOverhead)
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("file exceeds %d bytes", s.maxAttachmentSize))
+			return
+		}
+		writeError(w, http.StatusBadRequest, "invalid multipart form")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	cid, err := strconv.Atoi(r.FormValue("conversation_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid conversation_id")
+		return
+	}
+	if _, ok := s.conversationParticipants(w, r, cid, user.UserID); !ok {
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, s.maxAttachmentSize+1))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error reading upload", "component", "attachments", "error", err)
+		writeError(w, http.StatusBadRequest, "invalid file")
+		return
+	}
+	if len(data) == 0 {
+		writeError(w, http.StatusBadRequest, "file is empty")
+		return
+	}
+	if int64(len(data)) > s.maxAttachmentSize {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("file exceeds %d bytes", s.maxAttachmentSize))
+		return
+	}
+
+	contentType, err := attachment.DetectType(data)
+	if err != nil {
+		writeError(w, http.StatusUnsupportedMediaType, "unsupported file type, allowed types are JPEG, PNG, GIF and PDF")
+		return
+	}
+	var img *attachment.Image
+	if attachment.IsImage(contentType) {
+		img, err = attachment.ProcessImage(data)
+		if errors.Is(err, attachment.ErrInvalidImage) {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "error rendering thumbnail", "component", "attachments", "error", err)
+			writeError(w, http.StatusInternalServerError, "error processing image")
+			return
+		}
+	}
+
+	ctx := r.Context()
+	a, err := s.storeAttachment(ctx, cid, user.UserID, attachment.CleanFilename(header.Filename), contentType, data, img)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error storing attachment", "component", "attachments", "error", err)
+		writeError(w, http.StatusInternalServerError, "error storing attachment")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "attachment uploaded",
+		"component", "attachments", "attachment_id", a.ID, "conversation_id", cid, "type", contentType, "size", a.SizeBytes)
+	writeJSON(w, http.StatusCreated, a)
+}
+
+// storeAttachment writes a file and its thumbnail to storage and records them.
+// Files are removed again if the row cannot be inserted.
+func (s *Server) storeAttachment(ctx context.Context, cid, uploaderID int, filename, contentType string, data []byte, img *attachment.Image) (a models.Attachment, err error) {
+	key, err := storage.NewKey("attachments")
+	if err != nil {
+		return a, err
+	}
+	if err = s.storage.Put(ctx, key, bytes.NewReader(data)); err != nil {
+		return a, err
+	}
+	stored := []string{key}
+	defer func() {
+		if err != nil {
+			for _, k := range stored {
+				if derr := s.storage.Delete(context.WithoutCancel(ctx), k); derr != nil {
+					slog.ErrorContext(ctx, "error removing file", "component", "attachments", "key", k, "error", derr)
+				}
+			}
+		}
+	}()
+
+	var thumbnailKey *string
+	var width, height *int
+	if img != nil {
+		var tkey string
+		if tkey, err = storage.NewKey("thumbnails"); err != nil {
+			return a, err
+		}
+		if err = s.storage.Put(ctx, tkey, bytes.NewReader(img.Thumbnail)); err != nil {
+			return a, err
+		}
+		stored = append(stored, tkey)
+		thumbnailKey, width, height = &tkey, &img.Width, &img.Height
+	}
+
+	err = scanAttachment(s.db.Pool.QueryRow(ctx,
+		`INSERT INTO message_attachments AS a
+		 (conversation_id, uploader_id, filename, content_type, size_bytes, storage_key, thumbnail_key, width, height)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 RETURNING `+attachmentColumns,
+		cid, uploaderID, filename, contentType, len(data), key, thumbnailKey, width, height,
+	), &a)
+	return a, err
+}
+
+// attachToMessage links pending uploads of the sender to a new message
+func attachToMessage(ctx context.Context, tx pgx.Tx, cid, messageID, senderID int, ids []int) ([]models.Attachment, error) {
+	rows, err := tx.Query(ctx,
+		`UPDATE message_attachments AS a SET message_id = $1
+		 WHERE a.id = ANY($2) AND a.conversation_id = $3 AND a.uploader_id = $4 AND a.message_id IS NULL
+		 RETURNING `+attachmentColumns,
+		messageID, ids, cid, senderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []models.Attachment{}
+	for rows.Next() {
+		var a models.Attachment
+		if err := scanAttachment(rows, &a); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(attachments) != len(ids) {
+		return nil, errInvalidAttachments
+	}
+	return attachments, nil
+}
+
+// loadAttachments fills in the attachments of messages
+func loadAttachments(ctx context.Context, q querier, messages []models.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	ids := make([]int, len(messages))
+	index := make(map[int]int, len(messages))
+	for i := range messages {
+		ids[i] = messages[i].ID
+		index[messages[i].ID] = i
+		messages[i].Attachments = []models.Attachment{}
+	}
+
+	rows, err := q.Query(ctx,
+		`SELECT `+attachmentColumns+` FROM message_attachments a
+		 WHERE a.message_id = ANY($1) ORDER BY a.id`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a models.Attachment
+		if err := scanAttachment(rows, &a); err != nil {
+			return err
+		}
+		i := index[*a.MessageID]
+		messages[i].Attachments = append(messages[i].Attachments, a)
+	}
+	return rows.Err()
+}
+
+// handleAttachmentByID serves /api/attachments/{id} and
+// /api/attachments/{id}/thumbnail to the participants of the conversation.
+// Pending uploads are only visible to the uploader, and attachments of hidden
+// messages only to admins.
+func (s *Server) handleAttachmentByID(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := auth.UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	id, ok := parseIDFromPath(r.URL.Path)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	thumbnail := len(parts) == 4 && parts[3] == "thumbnail"
+	if len(parts) > 4 || (len(parts) == 4 && !thumbnail) {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	var (
+		storageKey, filename, contentType string
+		thumbnailKey                      *string
+		sizeBytes                         int64
+		uploaderID, messageID             *int
+		guestID, hostID                   *int
+		hidden                            bool
+	)
+	err = s.db.Pool.QueryRow(r.Context(),
+		`SELECT a.storage_key, a.thumbnail_key, a.filename, a.content_type, a.size_bytes,
+		        a.uploader_id, a.message_id, c.guest_id, c.host_id, m.hidden_at IS NOT NULL
+		 FROM message_attachments a
+		 JOIN conversations c ON c.id = a.conversation_id
+		 LEFT JOIN messages m ON m.id = a.message_id
+		 WHERE a.id = $1`, id,
+	).Scan(&storageKey, &thumbnailKey, &filename, &contentType, &sizeBytes,
+		&uploaderID, &messageID, &guestID, &hostID, &hidden)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "attachment not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "attachments", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	isUser := func(id *int) bool { return id != nil && *id == user.UserID }
+	admin := user.Role == auth.RoleAdmin
+	switch {
+	case messageID == nil:
+		if !isUser(uploaderID) {
+			writeError(w, http.StatusNotFound, "attachment not found")
+			return
+		}
+	case hidden && !admin:
+		writeError(w, http.StatusNotFound, "attachment not found")
+		return
+	case !isUser(guestID) && !isUser(hostID) && !admin:
+		writeError(w, http.StatusForbidden, "access denied")
+		return
+	}
+
+	key := storageKey
+	if thumbnail {
+		if thumbnailKey == nil {
+			writeError(w, http.StatusNotFound, "attachment has no thumbnail")
+			return
+		}
+		key, contentType, sizeBytes = *thumbnailKey, "image/jpeg", 0
+	}
+
+	file, err := s.storage.Open(r.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		slog.WarnContext(r.Context(), "file of attachment is missing", "component", "attachments", "key", key, "attachment_id", id)
+		writeError(w, http.StatusNotFound, "attachment not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "storage error opening", "component", "attachments", "key", key, "error", err)
+		writeError(w, http.StatusInternalServerError, "storage error")
+		return
+	}
+	defer file.Close()
+
+	// Uploads are served as untrusted content: the sniffed type is fixed,
+	// PDFs are downloaded rather than rendered and nothing may run
+	disposition := "inline"
+	if !attachment.IsImage(contentType) {
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	if sizeBytes > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(sizeBytes, 10))
+	}
+
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(transferTimeout))
+	if _, err := io.Copy(w, file); err != nil {
+		slog.ErrorContext(r.Context(), "error sending attachment", "component", "attachments", "attachment_id", id, "error", err)
+	}
+}
+
+// uniqueIDs drops duplicate IDs, keeping the first occurrence
+func uniqueIDs(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	unique := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,38 @@
+package httpapi
+
+import (
+	"context"
+	"time"
+)
+
+// isAvailable reports whether a property is free for the nights in [start, end).
+// Existing bookings and unexpired holds are padded with the property's
+// preparation days, and nights blocked on the host calendar are never available. excludeBookingID skips a
+// booking (e.g. the one being modified); pass 0 to check against all bookings.
+func isAvailable(ctx context.Context, q querier, propertyID int, start, end time.Time, preparationDays, excludeBookingID int) (bool, error) {
+	var available bool
+	err := q.QueryRow(ctx,
+		`SELECT NOT EXISTS(
+		   SELECT 1 FROM bookings
+		   WHERE property_id = $1
+		     AND id != $5
+		     AND status NOT IN ('cancelled', 'expired')
+		     AND start_date < $3::date + $4::int
+		     AND end_date + $4::int > $2::date
+		 ) AND NOT EXISTS(
+		   SELECT 1 FROM booking_holds
+		   WHERE property_id = $1
+		     AND status = 'active'
+		     AND expires_at > CURRENT_TIMESTAMP
+		     AND start_date < $3::date + $4::int
+		     AND end_date + $4::int > $2::date
+		 ) AND NOT EXISTS(
+		   SELECT 1 FROM property_calendar
+		   WHERE property_id = $1
+		     AND date >= $2::date
+		     AND date < $3::date
+		 )`,
+		propertyID, start, end, preparationDays, excludeBookingID,
+	).Scan(&available)
+	return available, err
+}
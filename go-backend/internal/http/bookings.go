@@ -0,0 +1,407 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go-backend/internal/auth"
+	"go-backend/internal/booking"
+	"go-backend/internal/metrics"
+	"go-backend/internal/models"
+	"go-backend/internal/webhook"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// bookingColumns is the column list read by scanBooking
+const bookingColumns = `id, user_id, property_id, start_date::text, end_date::text, guests, status,
+	nightly_rate, nights, accommodation_total, service_fee, total_price, cancellation_policy,
+	cancelled_at, cancellation_reason, refund_amount, checked_in_at, no_show, created_at`
+
+// scanBooking scans a row selected with bookingColumns
+func scanBooking(row pgx.Row, b *models.Booking) error {
+	return row.Scan(&b.ID, &b.UserID, &b.PropertyID, &b.StartDate, &b.EndDate, &b.Guests, &b.Status,
+		&b.NightlyRate, &b.Nights, &b.AccommodationTotal, &b.ServiceFee, &b.TotalPrice, &b.CancellationPolicy,
+		&b.CancelledAt, &b.CancellationReason, &b.RefundAmount, &b.CheckedInAt, &b.NoShow, &b.CreatedAt)
+}
+
+// violationStatus maps a violation code to its HTTP status
+func violationStatus(v *booking.Violation) int {
+	switch v.Code {
+	case booking.CodeInvalidDates:
+		return http.StatusBadRequest
+	case booking.CodeDatesNotAvailable, booking.CodeHoldNotActive:
+		return http.StatusConflict
+	case booking.CodePaymentDeclined:
+		return http.StatusPaymentRequired
+	default:
+		return http.StatusUnprocessableEntity
+	}
+}
+
+// reservation is a requested stay to be reserved for a guest
+type reservation struct {
+	UserID     int
+	PropertyID int
+	Start      time.Time
+	End        time.Time
+	Guests     int
+	HoldID     int // hold being converted, 0 if none
+}
+
+// createBooking reserves the dates as a pending booking, takes payment and
+// confirms the booking once the payment is captured
+func (s *Server) createBooking(w http.ResponseWriter, r *http.Request, userID int) {
+	type req struct {
+		PropertyID    int    `json:"property_id"`
+		StartDate     string `json:"start_date"`
+		EndDate       string `json:"end_date"`
+		Guests        int    `json:"guests"`
+		PaymentMethod string `json:"payment_method"`
+	}
+	var body req
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	// Validate required fields
+	if body.PropertyID <= 0 || body.StartDate == "" || body.EndDate == "" || body.Guests <= 0 {
+		writeError(w, http.StatusBadRequest, "property_id, start_date, end_date, and guests are required")
+		return
+	}
+	if body.PaymentMethod == "" {
+		writeError(w, http.StatusBadRequest, "payment_method is required")
+		return
+	}
+
+	start, end, err := booking.ParseDates(body.StartDate, body.EndDate)
+	if err != nil {
+		writeViolation(w, http.StatusBadRequest, err.(*booking.Violation))
+		return
+	}
+
+	b, err := s.reserveBooking(r.Context(), reservation{
+		UserID:     userID,
+		PropertyID: body.PropertyID,
+		Start:      start,
+		End:        end,
+		Guests:     body.Guests,
+	})
+	if !s.writeBookingError(w, r, err) {
+		return
+	}
+
+	b, err = s.payForBooking(r.Context(), b, body.PaymentMethod)
+	if !s.writeBookingError(w, r, err) {
+		return
+	}
+
+	slog.InfoContext(r.Context(), "booking created", "component", "bookings", "booking_id", b.ID, "status", b.Status)
+	metrics.BookingsCreated.WithLabelValues(b.Status).Inc()
+	writeJSON(w, http.StatusCreated, b)
+}
+
+// writeBookingError writes the response for an error returned while creating a
+// booking. It returns true if err is nil and the caller should continue.
+func (s *Server) writeBookingError(w http.ResponseWriter, r *http.Request, err error) bool {
+	var v *booking.Violation
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &v):
+		writeViolation(w, violationStatus(v), v)
+	case errors.Is(err, pgx.ErrNoRows):
+		writeError(w, http.StatusNotFound, "property not found")
+	case errors.Is(err, errPaymentProvider):
+		writeError(w, http.StatusBadGateway, "payment provider error")
+	default:
+		slog.ErrorContext(r.Context(), "database error creating booking", "component", "bookings", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+	}
+	return false
+}
+
+// reserveBooking validates the stay and inserts a pending booking, which holds
+// the dates while payment is taken. The property row is locked so concurrent
+// reservations for the same property are serialized. If the reservation
+// converts a hold, the hold is claimed first so it no longer blocks its dates.
+func (s *Server) reserveBooking(ctx context.Context, res reservation) (models.Booking, error) {
+	var b models.Booking
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return b, err
+	}
+	defer tx.Rollback(ctx)
+
+	var capacity int
+	var nightlyRate float64
+	err = tx.QueryRow(ctx,
+		`SELECT guests, price_per_night FROM properties WHERE id = $1 AND hidden_at IS NULL FOR UPDATE`, res.PropertyID,
+	).Scan(&capacity, &nightlyRate)
+	if err != nil {
+		return b, err
+	}
+
+	if res.HoldID != 0 {
+		if err := claimHold(ctx, tx, res.HoldID); err != nil {
+			return b, err
+		}
+	}
+
+	stay, err := checkStay(ctx, tx, res, capacity)
+	if err != nil {
+		return b, err
+	}
+
+	// Price and cancellation terms are fixed when the booking is made
+	policy, err := loadCancellationPolicy(ctx, tx, res.PropertyID)
+	if err != nil {
+		return b, err
+	}
+	price := booking.Quote(nightlyRate, stay.Nights())
+
+	err = scanBooking(tx.QueryRow(ctx,
+		`INSERT INTO bookings (user_id, property_id, start_date, end_date, guests, status,
+		 nightly_rate, nights, accommodation_total, service_fee, total_price,
+		 cancellation_policy, cancellation_tiers)
+		 VALUES ($1, $2, $3, $4, $5, 'pending', $6, $7, $8, $9, $10, $11, $12)
+		 RETURNING `+bookingColumns,
+		res.UserID, res.PropertyID, res.Start, res.End, res.Guests,
+		price.NightlyRate, price.Nights, price.Accommodation, price.ServiceFee, price.Total,
+		policy.Name, policy.Tiers,
+	), &b)
+	if err != nil {
+		return b, err
+	}
+
+	if res.HoldID != 0 {
+		_, err = tx.Exec(ctx, `UPDATE booking_holds SET booking_id = $2 WHERE id = $1`, res.HoldID, b.ID)
+		if err != nil {
+			return b, err
+		}
+	}
+
+	if err := webhook.Enqueue(ctx, tx, webhook.EventBookingCreated, b); err != nil {
+		return b, err
+	}
+	return b, tx.Commit(ctx)
+}
+
+// checkStay checks a stay against the property capacity, stay rules and
+// availability. The caller must hold the lock on the property row so the
+// result stays valid until it commits.
+func checkStay(ctx context.Context, q querier, res reservation, capacity int) (booking.Stay, error) {
+	rules, err := loadStayRules(ctx, q, res.PropertyID)
+	if err != nil {
+		return booking.Stay{}, err
+	}
+	stay := booking.Stay{Start: res.Start, End: res.End, Guests: res.Guests, Capacity: capacity}
+	if err := rules.CheckStay(stay, booking.Today(time.Now())); err != nil {
+		return stay, err
+	}
+
+	// Check for overlapping bookings, holds and blocked dates
+	available, err := isAvailable(ctx, q, res.PropertyID, res.Start, res.End, rules.PreparationDays, 0)
+	if err != nil {
+		return stay, err
+	}
+	if !available {
+		return stay, &booking.Violation{
+			Code:    booking.CodeDatesNotAvailable,
+			Message: "property is not available for selected dates",
+		}
+	}
+	return stay, nil
+}
+
+// loadCancellationPolicy returns the cancellation policy currently selected for a property
+func loadCancellationPolicy(ctx context.Context, q querier, propertyID int) (booking.CancellationPolicy, error) {
+	var p booking.CancellationPolicy
+	err := q.QueryRow(ctx,
+		`SELECT cancellation_policy, cancellation_tiers FROM properties WHERE id = $1`, propertyID,
+	).Scan(&p.Name, &p.Tiers)
+	if err != nil {
+		return p, err
+	}
+	if p.Name != booking.PolicyCustom {
+		return booking.NewCancellationPolicy(p.Name, nil)
+	}
+	return p, nil
+}
+
+func (s *Server) handlePropertyCancellationPolicy(w http.ResponseWriter, r *http.Request, id int) {
+	switch r.Method {
+	case http.MethodGet:
+		policy, err := loadCancellationPolicy(r.Context(), s.db.Pool, id)
+		if err == pgx.ErrNoRows {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error", "component", "cancellation_policy", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		writeJSON(w, http.StatusOK, policy)
+
+	case http.MethodPut:
+		s.updateCancellationPolicy(w, r, id)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) updateCancellationPolicy(w http.ResponseWriter, r *http.Request, id int) {
+	if _, ok := s.requirePropertyOwner(w, r, id); !ok {
+		return
+	}
+
+	var body booking.CancellationPolicy
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	policy, err := booking.NewCancellationPolicy(body.Name, body.Tiers)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tx, err := s.db.Pool.Begin(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error starting transaction", "component", "cancellation_policy", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	_, err = tx.Exec(r.Context(),
+		`UPDATE properties SET cancellation_policy = $1, cancellation_tiers = $2 WHERE id = $3`,
+		policy.Name, policy.Tiers, id)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error updating policy", "component", "cancellation_policy", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := enqueuePropertyWebhook(r.Context(), tx, id); err != nil {
+		slog.ErrorContext(r.Context(), "database error queueing webhook", "component", "cancellation_policy", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := tx.Commit(r.Context()); err != nil {
+		slog.ErrorContext(r.Context(), "database error committing", "component", "cancellation_policy", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "cancellation policy updated", "component", "bookings", "property_id", id, "policy", policy.Name)
+	writeJSON(w, http.StatusOK, policy)
+}
+
+// cancelBooking cancels a booking and records the refund. Guests are refunded
+// according to the policy stored on the booking; cancellations by the host or an
+// admin are refunded in full. Cancelled bookings are kept for history.
+func (s *Server) cancelBooking(w http.ResponseWriter, r *http.Request, user auth.UserContext, id int, reason string) {
+	tx, err := s.db.Pool.Begin(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error starting transaction", "component", "bookings", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	var (
+		guestID   int
+		ownerID   *int
+		status    string
+		startDate time.Time
+		total     float64
+		policy    booking.CancellationPolicy
+	)
+	err = tx.QueryRow(r.Context(),
+		`SELECT b.user_id, p.owner_id, b.status, b.start_date, b.total_price,
+		 b.cancellation_policy, b.cancellation_tiers
+		 FROM bookings b JOIN properties p ON p.id = b.property_id
+		 WHERE b.id = $1 FOR UPDATE OF b`, id,
+	).Scan(&guestID, &ownerID, &status, &startDate, &total, &policy.Name, &policy.Tiers)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading booking", "component", "bookings", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	isGuest := guestID == user.UserID
+	isHost := ownerID != nil && *ownerID == user.UserID
+	if !isGuest && !isHost && user.Role != auth.RoleAdmin {
+		writeError(w, http.StatusForbidden, "you can only cancel your own bookings")
+		return
+	}
+	if status != "pending" && status != "confirmed" {
+		writeError(w, http.StatusConflict, "booking cannot be cancelled in status "+status)
+		return
+	}
+
+	refund := total
+	if isGuest && !isHost && user.Role != auth.RoleAdmin {
+		refund = policy.Refund(total, startDate, booking.Today(time.Now()))
+	}
+
+	// Refund through the provider before committing so a failed refund leaves
+	// the booking active
+	if err := s.refundBooking(r.Context(), tx, id, refund); err != nil {
+		slog.ErrorContext(r.Context(), "error refunding booking", "component", "bookings", "booking_id", id, "error", err)
+		writeError(w, http.StatusBadGateway, "payment provider error")
+		return
+	}
+
+	var reasonArg *string
+	if reason != "" {
+		reasonArg = &reason
+	}
+
+	var b models.Booking
+	err = scanBooking(tx.QueryRow(r.Context(),
+		`UPDATE bookings
+		 SET status = 'cancelled', cancelled_at = CURRENT_TIMESTAMP, cancelled_by = $2,
+		     cancellation_reason = $3, refund_amount = $4
+		 WHERE id = $1
+		 RETURNING `+bookingColumns,
+		id, user.UserID, reasonArg, refund), &b)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error cancelling booking", "component", "bookings", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := webhook.Enqueue(r.Context(), tx, webhook.EventBookingCancelled, b); err != nil {
+		slog.ErrorContext(r.Context(), "database error queueing webhook", "component", "bookings", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := notifyBooking(r.Context(), tx, id, bookingCancelled, user.UserID); err != nil {
+		slog.ErrorContext(r.Context(), "database error queueing notifications", "component", "bookings", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		slog.ErrorContext(r.Context(), "database error committing cancellation", "component", "bookings", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "booking cancelled", "component", "bookings", "booking_id", b.ID, "refund", refund)
+	metrics.BookingsCancelled.Inc()
+	writeJSON(w, http.StatusOK, b)
+}
@@ -0,0 +1,260 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go-backend/internal/auth"
+	"go-backend/internal/booking"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// handlePropertyCalendar routes /api/properties/{id}/calendar[/block|/unblock]
+func (s *Server) handlePropertyCalendar(w http.ResponseWriter, r *http.Request, id int, rest []string) {
+	switch {
+	case len(rest) == 0:
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		s.getPropertyCalendar(w, r, id)
+
+	case len(rest) == 1 && (rest[0] == "block" || rest[0] == "unblock"):
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		s.updateBlockedDates(w, r, id, rest[0] == "block")
+
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// getPropertyCalendar returns each night's state for ?from=&to= (defaults to the next 30 days).
+// Block notes are only shown to the owner and admins.
+func (s *Server) getPropertyCalendar(w http.ResponseWriter, r *http.Request, id int) {
+	today := booking.Today(time.Now())
+	from, to := today, today.AddDate(0, 0, 30)
+
+	if fromStr, toStr := r.URL.Query().Get("from"), r.URL.Query().Get("to"); fromStr != "" || toStr != "" {
+		var err error
+		from, to, err = booking.ParseDates(fromStr, toStr)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "from and to must be valid dates with to after from")
+			return
+		}
+	}
+	if to.Sub(from) > booking.MaxCalendarDays*24*time.Hour {
+		writeError(w, http.StatusBadRequest, "calendar range is limited to 366 days")
+		return
+	}
+
+	var price float64
+	var ownerID *int
+	err := s.db.Pool.QueryRow(r.Context(),
+		`SELECT price_per_night, owner_id FROM properties WHERE id = $1`, id,
+	).Scan(&price, &ownerID)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "calendar", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	rows, err := s.db.Pool.Query(r.Context(),
+		`SELECT start_date, end_date FROM bookings
+		 WHERE property_id = $1
+		   AND status NOT IN ('cancelled', 'expired')
+		   AND start_date < $3 AND end_date > $2
+		 UNION ALL
+		 SELECT start_date, end_date FROM booking_holds
+		 WHERE property_id = $1
+		   AND status = 'active' AND expires_at > CURRENT_TIMESTAMP
+		   AND start_date < $3 AND end_date > $2`,
+		id, from, to)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "calendar", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	booked := []booking.Range{}
+	for rows.Next() {
+		var b booking.Range
+		if err := rows.Scan(&b.Start, &b.End); err != nil {
+			rows.Close()
+			slog.ErrorContext(r.Context(), "scan error", "component", "calendar", "error", err)
+			writeError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		booked = append(booked, b)
+	}
+	rows.Close()
+
+	rows, err = s.db.Pool.Query(r.Context(),
+		`SELECT date, note FROM property_calendar
+		 WHERE property_id = $1 AND date >= $2 AND date < $3`,
+		id, from, to)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "calendar", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+
+	user, authErr := auth.UserFromContext(r.Context())
+	showNotes := authErr == nil && (user.Role == auth.RoleAdmin || (ownerID != nil && *ownerID == user.UserID))
+
+	blocked := map[string]string{}
+	for rows.Next() {
+		var day time.Time
+		var note string
+		if err := rows.Scan(&day, &note); err != nil {
+			slog.ErrorContext(r.Context(), "scan error", "component", "calendar", "error", err)
+			writeError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		if !showNotes {
+			note = ""
+		}
+		blocked[day.Format(booking.DateLayout)] = note
+	}
+
+	writeJSON(w, http.StatusOK, booking.BuildCalendar(from, to, price, booked, blocked))
+}
+
+// updateBlockedDates blocks or unblocks the nights in [start_date, end_date)
+func (s *Server) updateBlockedDates(w http.ResponseWriter, r *http.Request, id int, block bool) {
+	user, ok := s.requirePropertyOwner(w, r, id)
+	if !ok {
+		return
+	}
+
+	type req struct {
+		StartDate string `json:"start_date"`
+		EndDate   string `json:"end_date"`
+		Note      string `json:"note"`
+	}
+	var body req
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	start, end, err := booking.ParseDates(body.StartDate, body.EndDate)
+	if err != nil {
+		writeViolation(w, http.StatusBadRequest, err.(*booking.Violation))
+		return
+	}
+	if end.Sub(start) > booking.MaxCalendarDays*24*time.Hour {
+		writeError(w, http.StatusBadRequest, "calendar range is limited to 366 days")
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "database error", "component", "calendar", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if !block {
+		tag, err := tx.Exec(ctx,
+			`DELETE FROM property_calendar
+			 WHERE property_id = $1 AND date >= $2 AND date < $3 AND feed_id IS NULL`,
+			id, start, end)
+		if err != nil {
+			slog.ErrorContext(ctx, "database error unblocking dates", "component", "calendar", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		if tag.RowsAffected() > 0 {
+			if err := enqueuePropertyWebhook(ctx, tx, id); err != nil {
+				slog.ErrorContext(ctx, "database error queueing webhook", "component", "calendar", "error", err)
+				writeError(w, http.StatusInternalServerError, "database error")
+				return
+			}
+		}
+		if err := tx.Commit(ctx); err != nil {
+			slog.ErrorContext(ctx, "database error unblocking dates", "component", "calendar", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		slog.InfoContext(ctx, "nights unblocked", "component", "calendar", "nights", tag.RowsAffected(), "property_id", id)
+		writeJSON(w, http.StatusOK, map[string]int64{"unblocked": tag.RowsAffected()})
+		return
+	}
+
+	// The property row is locked as in reserveBooking and placeHold, so a
+	// booking or hold cannot take the nights while they are being blocked
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM properties WHERE id = $1 FOR UPDATE`, id); err != nil {
+		slog.ErrorContext(ctx, "database error locking property", "component", "calendar", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	// Nights that are already booked or held during checkout cannot be blocked
+	var booked, held bool
+	err = tx.QueryRow(ctx,
+		`SELECT EXISTS(
+		   SELECT 1 FROM bookings
+		   WHERE property_id = $1
+		     AND status NOT IN ('cancelled', 'expired')
+		     AND start_date < $3 AND end_date > $2
+		 ), EXISTS(
+		   SELECT 1 FROM booking_holds
+		   WHERE property_id = $1
+		     AND status = 'active' AND expires_at > CURRENT_TIMESTAMP
+		     AND start_date < $3 AND end_date > $2
+		 )`, id, start, end,
+	).Scan(&booked, &held)
+	if err != nil {
+		slog.ErrorContext(ctx, "database error checking bookings", "component", "calendar", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if booked || held {
+		message := "selected dates include existing bookings"
+		if !booked {
+			message = "selected dates are held by a guest checking out"
+		}
+		writeViolation(w, http.StatusConflict, &booking.Violation{
+			Code:    booking.CodeDatesNotAvailable,
+			Message: message,
+		})
+		return
+	}
+
+	tag, err := tx.Exec(ctx,
+		`INSERT INTO property_calendar (property_id, date, note, created_by)
+		 SELECT $1, d::date, $4, $5
+		 FROM generate_series($2::date, $3::date - 1, interval '1 day') AS d
+		 ON CONFLICT (property_id, date) DO UPDATE SET note = EXCLUDED.note, feed_id = NULL`,
+		id, start, end, body.Note, user.UserID)
+	if err != nil {
+		slog.ErrorContext(ctx, "database error blocking dates", "component", "calendar", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := enqueuePropertyWebhook(ctx, tx, id); err != nil {
+		slog.ErrorContext(ctx, "database error queueing webhook", "component", "calendar", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(ctx, "database error blocking dates", "component", "calendar", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	slog.InfoContext(ctx, "nights blocked", "component", "calendar", "nights", tag.RowsAffected(), "property_id", id)
+	writeJSON(w, http.StatusOK, map[string]int64{"blocked": tag.RowsAffected()})
+}
@@ -0,0 +1,823 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"go-backend/internal/attachment"
+	"go-backend/internal/auth"
+	"go-backend/internal/models"
+	"go-backend/internal/moderation"
+	"go-backend/internal/notification"
+	"go-backend/internal/realtime"
+)
+
+// conversationColumns is the column list read by scanConversation, selected
+// FROM conversationTables with the viewing user as $1
+const conversationColumns = `c.id, c.guest_id, c.host_id, c.property_id, COALESCE(p.title, ''), c.booking_id,
+	CASE WHEN c.guest_id = $1 THEN c.host_id ELSE c.guest_id END, COALESCE(u.name, ''),
+	CASE WHEN c.guest_id = $1 THEN 'host' ELSE 'guest' END,
+	COALESCE((SELECT text FROM messages m WHERE m.conversation_id = c.id AND m.hidden_at IS NULL
+	          ORDER BY m.id DESC LIMIT 1), ''),
+	(SELECT MAX(m.created_at) FROM messages m WHERE m.conversation_id = c.id AND m.hidden_at IS NULL),
+	(SELECT COUNT(*) ` + unreadMessages + `),
+	cp.archived_at IS NOT NULL, cp.muted_at IS NOT NULL,
+	c.created_at`
+
+// unreadMessages selects the viewer's ($1) unread messages in conversation c
+const unreadMessages = `FROM messages m WHERE m.conversation_id = c.id AND m.hidden_at IS NULL
+	AND m.sender_id IS DISTINCT FROM $1 AND m.id > COALESCE(cp.last_read_message_id, 0)`
+
+const conversationTables = `conversations c
+	LEFT JOIN properties p ON p.id = c.property_id
+	LEFT JOIN users u ON u.id = CASE WHEN c.guest_id = $1 THEN c.host_id ELSE c.guest_id END
+	LEFT JOIN conversation_participants cp ON cp.conversation_id = c.id AND cp.user_id = $1`
+
+// scanConversation scans a row selected with conversationColumns
+func scanConversation(row pgx.Row, c *models.Conversation) error {
+	return row.Scan(&c.ID, &c.GuestID, &c.HostID, &c.PropertyID, &c.Property, &c.BookingID,
+		&c.ContactID, &c.ContactName, &c.ContactType, &c.LastMessage, &c.LastMessageAt, &c.UnreadCount,
+		&c.Archived, &c.Muted, &c.CreatedAt)
+}
+
+// messageColumns is the column list read by scanMessage, selected FROM
+// messages m. A message is read once the participant who did not send it has
+// read up to it.
+const messageColumns = `m.id, m.conversation_id, m.sender_id, m.text, m.created_at,
+	m.id <= COALESCE((SELECT MAX(cp.last_read_message_id) FROM conversation_participants cp
+	                  WHERE cp.conversation_id = m.conversation_id AND cp.user_id IS DISTINCT FROM m.sender_id), 0)`
+
+// scanMessage scans a row selected with messageColumns, followed by any
+// extra columns. Messages whose sender was deleted have sender_id 0.
+func scanMessage(row pgx.Row, m *models.Message, extra ...any) error {
+	var senderID *int
+	dest := append([]any{&m.ID, &m.ConversationID, &senderID, &m.Text, &m.CreatedAt, &m.Read}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	if senderID != nil {
+		m.SenderID = *senderID
+	}
+	return nil
+}
+
+// handleConversations lists the conversations the user takes part in, as
+// guest or host, and starts new ones. The list leaves out archived
+// conversations unless ?archived=true or ?archived=all, and can be limited to
+// ?unread=true and ?property_id=.
+func (s *Server) handleConversations(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		query := `SELECT ` + conversationColumns + ` FROM ` + conversationTables + `
+			WHERE $1 IN (c.guest_id, c.host_id)`
+		args := []any{user.UserID}
+
+		q := r.URL.Query()
+		switch q.Get("archived") {
+		case "", "false":
+			query += ` AND cp.archived_at IS NULL`
+		case "true":
+			query += ` AND cp.archived_at IS NOT NULL`
+		case "all":
+		default:
+			writeError(w, http.StatusBadRequest, "archived must be true, false or all")
+			return
+		}
+		switch q.Get("unread") {
+		case "", "false":
+		case "true":
+			query += ` AND EXISTS (SELECT 1 ` + unreadMessages + `)`
+		default:
+			writeError(w, http.StatusBadRequest, "unread must be true or false")
+			return
+		}
+		if v := q.Get("property_id"); v != "" {
+			propertyID, err := strconv.Atoi(v)
+			if err != nil || propertyID <= 0 {
+				writeError(w, http.StatusBadRequest, "invalid property_id")
+				return
+			}
+			args = append(args, propertyID)
+			query += ` AND c.property_id = $` + strconv.Itoa(len(args))
+		}
+		query += ` ORDER BY COALESCE((SELECT MAX(m.created_at) FROM messages m WHERE m.conversation_id = c.id), c.created_at) DESC,
+			c.id DESC`
+
+		rows, err := s.db.Pool.Query(r.Context(), query, args...)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error", "component", "conversations", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		defer rows.Close()
+
+		conversations := []models.Conversation{}
+		for rows.Next() {
+			var c models.Conversation
+			if err := scanConversation(rows, &c); err != nil {
+				slog.ErrorContext(r.Context(), "scan error", "component", "conversations", "error", err)
+				writeError(w, http.StatusInternalServerError, "scan error")
+				return
+			}
+			conversations = append(conversations, c)
+		}
+		writeJSON(w, http.StatusOK, conversations)
+
+	case http.MethodPost:
+		s.startConversation(w, r, user)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// startConversation sends a first message to the host of a listing, or to the
+// other party of a booking. If the participants already have a conversation
+// about the same property and booking the message is added to it.
+func (s *Server) startConversation(w http.ResponseWriter, r *http.Request, user auth.UserContext) {
+	type req struct {
+		PropertyID int    `json:"property_id"`
+		BookingID  int    `json:"booking_id"`
+		Text       string `json:"text"`
+	}
+	var body req
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	text := strings.TrimSpace(body.Text)
+	if text == "" {
+		writeError(w, http.StatusBadRequest, "text is required")
+		return
+	}
+
+	ctx := r.Context()
+	var (
+		guestID    int
+		hostID     *int
+		propertyID int
+		bookingID  *int
+	)
+	switch {
+	case body.BookingID > 0:
+		err := s.db.Pool.QueryRow(ctx,
+			`SELECT b.user_id, p.owner_id, b.property_id FROM bookings b
+			 JOIN properties p ON p.id = b.property_id WHERE b.id = $1`, body.BookingID,
+		).Scan(&guestID, &hostID, &propertyID)
+		if err == pgx.ErrNoRows {
+			writeError(w, http.StatusNotFound, "booking not found")
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error loading booking", "component", "conversations", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		if guestID != user.UserID && (hostID == nil || *hostID != user.UserID) {
+			writeError(w, http.StatusForbidden, "access denied")
+			return
+		}
+		if body.PropertyID != 0 && body.PropertyID != propertyID {
+			writeError(w, http.StatusBadRequest, "booking is not for this property")
+			return
+		}
+		bookingID = &body.BookingID
+
+	case body.PropertyID > 0:
+		err := s.db.Pool.QueryRow(ctx,
+			`SELECT owner_id FROM properties WHERE id = $1 AND hidden_at IS NULL`, body.PropertyID,
+		).Scan(&hostID)
+		if err == pgx.ErrNoRows {
+			writeError(w, http.StatusNotFound, "property not found")
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error loading property", "component", "conversations", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		guestID, propertyID = user.UserID, body.PropertyID
+
+	default:
+		writeError(w, http.StatusBadRequest, "property_id or booking_id is required")
+		return
+	}
+	if hostID == nil {
+		writeError(w, http.StatusUnprocessableEntity, "property has no host to contact")
+		return
+	}
+	if *hostID == guestID {
+		writeError(w, http.StatusBadRequest, "you cannot start a conversation with yourself")
+		return
+	}
+
+	text, masked, err := s.maskContactInfo(ctx, text, []int{guestID, *hostID})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error checking bookings", "component", "conversations", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error starting transaction", "component", "conversations", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	created := true
+	var cid int
+	err = tx.QueryRow(ctx,
+		`INSERT INTO conversations (guest_id, host_id, property_id, booking_id)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (guest_id, host_id, property_id, COALESCE(booking_id, 0)) DO NOTHING
+		 RETURNING id`, guestID, *hostID, propertyID, bookingID,
+	).Scan(&cid)
+	if err == pgx.ErrNoRows {
+		created = false
+		err = tx.QueryRow(ctx,
+			`SELECT id FROM conversations
+			 WHERE guest_id = $1 AND host_id = $2 AND property_id = $3 AND COALESCE(booking_id, 0) = COALESCE($4, 0)`,
+			guestID, *hostID, propertyID, bookingID,
+		).Scan(&cid)
+	}
+	if err == nil && created {
+		_, err = tx.Exec(ctx,
+			`INSERT INTO conversation_participants (conversation_id, user_id) VALUES ($1, $2), ($1, $3)`,
+			cid, guestID, *hostID)
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error creating conversation", "component", "conversations", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	m, err := createMessage(ctx, tx, cid, user.UserID, text, nil, []int{guestID, *hostID})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error creating message", "component", "conversations", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	var c models.Conversation
+	err = scanConversation(tx.QueryRow(ctx,
+		`SELECT `+conversationColumns+` FROM `+conversationTables+` WHERE c.id = $2`, user.UserID, cid), &c)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading conversation", "component", "conversations", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(r.Context(), "database error committing conversation", "component", "conversations", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	s.screenMessage(ctx, m, masked)
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+		slog.InfoContext(r.Context(), "conversation started",
+			"component", "conversations", "conversation_id", cid, "property_id", propertyID, "guest_id", guestID, "host_id", *hostID)
+	}
+	writeJSON(w, status, map[string]any{
+		"conversation": c,
+		"message":      m,
+	})
+}
+
+// maskContactInfo masks contact details in a message unless the participants
+// have a confirmed booking together, so guests and hosts cannot move off the
+// platform before booking. It returns the text to store and what was masked.
+func (s *Server) maskContactInfo(ctx context.Context, text string, participants []int) (string, []moderation.Flag, error) {
+	if !s.masker.Enabled() || text == "" {
+		return text, nil, nil
+	}
+	masked, flags := s.masker.Mask(text)
+	if len(flags) == 0 {
+		return text, nil, nil
+	}
+
+	var booked bool
+	err := s.db.Pool.QueryRow(ctx,
+		`SELECT EXISTS (
+		   SELECT 1 FROM bookings b JOIN properties p ON p.id = b.property_id
+		   WHERE b.user_id = ANY($1) AND p.owner_id = ANY($1) AND b.user_id <> p.owner_id
+		   AND b.status IN ('confirmed', 'in_progress', 'completed')
+		 )`, participants,
+	).Scan(&booked)
+	if err != nil {
+		return text, nil, err
+	}
+	if booked {
+		return text, nil, nil
+	}
+	return masked, flags, nil
+}
+
+// screenMessage pre-screens a new message. If contact details were masked
+// the attempt is reported with the original matches, which are not stored
+// in the message itself.
+func (s *Server) screenMessage(ctx context.Context, m models.Message, masked []moderation.Flag) {
+	if len(masked) == 0 {
+		s.prescreen(ctx, moderation.TargetMessage, m.ID, m.Text)
+		return
+	}
+	flags := append(masked, s.screener.Screen(m.Text)...)
+	s.fileAutomaticReport(ctx, moderation.TargetMessage, m.ID, moderation.ActionMask, flags)
+	slog.InfoContext(ctx, "masked contact details in message", "component", "messages", "message_id", m.ID, "sender_id", m.SenderID)
+}
+
+// conversationParticipants returns the users taking part in a conversation
+// after verifying the user is one of them, writing the error response if not
+func (s *Server) conversationParticipants(w http.ResponseWriter, r *http.Request, cid, userID int) ([]int, bool) {
+	var guestID, hostID *int
+	err := s.db.Pool.QueryRow(r.Context(),
+		`SELECT guest_id, host_id FROM conversations WHERE id = $1`, cid,
+	).Scan(&guestID, &hostID)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "conversation not found")
+		return nil, false
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "messages", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return nil, false
+	}
+
+	var participants []int
+	member := false
+	for _, id := range []*int{guestID, hostID} {
+		if id != nil {
+			participants = append(participants, *id)
+			member = member || *id == userID
+		}
+	}
+	if !member {
+		writeError(w, http.StatusForbidden, "access denied")
+		return nil, false
+	}
+	return participants, true
+}
+
+// createMessage adds a message with the sender's pending attachments to a
+// conversation and pushes it to the participants once the transaction
+// commits. The sender has read the conversation up to their own message;
+// recipients who have not muted the conversation are notified.
+func createMessage(ctx context.Context, tx pgx.Tx, cid, senderID int, text string, attachmentIDs, participants []int) (models.Message, error) {
+	var m models.Message
+	err := scanMessage(tx.QueryRow(ctx,
+		`INSERT INTO messages AS m (conversation_id, sender_id, text)
+		 VALUES ($1, $2, $3)
+		 RETURNING `+messageColumns,
+		cid, senderID, text,
+	), &m)
+	if err != nil {
+		return m, err
+	}
+	m.Attachments = []models.Attachment{}
+	if len(attachmentIDs) > 0 {
+		if m.Attachments, err = attachToMessage(ctx, tx, cid, m.ID, senderID, attachmentIDs); err != nil {
+			return m, err
+		}
+	}
+	if _, err := markRead(ctx, tx, cid, senderID, m.ID); err != nil {
+		return m, err
+	}
+	// A new message brings an archived conversation back to the recipient's
+	// inbox, unless they muted it
+	_, err = tx.Exec(ctx,
+		`UPDATE conversation_participants SET archived_at = NULL
+		 WHERE conversation_id = $1 AND user_id <> $2 AND archived_at IS NOT NULL AND muted_at IS NULL`,
+		cid, senderID)
+	if err != nil {
+		return m, err
+	}
+	if err := notifyMessage(ctx, tx, m, participants); err != nil {
+		return m, err
+	}
+	_, err = realtime.Publish(ctx, tx, realtime.EventMessageCreated, participants, m)
+	return m, err
+}
+
+// markRead moves a participant's read position forward to messageID and
+// reports whether it moved
+func markRead(ctx context.Context, q querier, cid, userID, messageID int) (bool, error) {
+	tag, err := q.Exec(ctx,
+		`INSERT INTO conversation_participants (conversation_id, user_id, last_read_message_id, last_read_at)
+		 VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		 ON CONFLICT (conversation_id, user_id) DO UPDATE
+		 SET last_read_message_id = EXCLUDED.last_read_message_id, last_read_at = EXCLUDED.last_read_at
+		 WHERE conversation_participants.last_read_message_id < EXCLUDED.last_read_message_id`,
+		cid, userID, messageID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// participantStateUpdates are the SET clauses of the per-participant
+// conversation actions
+var participantStateUpdates = map[string]string{
+	"archive":   `archived_at = COALESCE(archived_at, CURRENT_TIMESTAMP)`,
+	"unarchive": `archived_at = NULL`,
+	"mute":      `muted_at = COALESCE(muted_at, CURRENT_TIMESTAMP)`,
+	"unmute":    `muted_at = NULL`,
+}
+
+// handleConversationByID serves /api/conversations/{id}/typing,
+// /api/conversations/{id}/read and the archive, unarchive, mute and unmute
+// actions
+func (s *Server) handleConversationByID(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	id, ok := parseIDFromPath(r.URL.Path)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 4 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	_, stateAction := participantStateUpdates[parts[3]]
+	if parts[3] != "typing" && parts[3] != "read" && !stateAction {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	switch parts[3] {
+	case "read":
+		s.markConversationRead(w, r, user, id)
+	case "typing":
+		s.signalTyping(w, r, user, id)
+	default:
+		s.updateParticipantState(w, r, user, id, parts[3])
+	}
+}
+
+// updateParticipantState archives, unarchives, mutes or unmutes a
+// conversation for the user only. Archived conversations leave the default
+// list; muted ones do not count towards the unread badge and stay archived
+// when new messages arrive.
+func (s *Server) updateParticipantState(w http.ResponseWriter, r *http.Request, user auth.UserContext, cid int, action string) {
+	if _, ok := s.conversationParticipants(w, r, cid, user.UserID); !ok {
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error starting transaction", "component", "conversations", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO conversation_participants (conversation_id, user_id) VALUES ($1, $2)
+		 ON CONFLICT (conversation_id, user_id) DO NOTHING`, cid, user.UserID)
+	if err == nil {
+		_, err = tx.Exec(ctx,
+			`UPDATE conversation_participants SET `+participantStateUpdates[action]+`
+			 WHERE conversation_id = $1 AND user_id = $2`, cid, user.UserID)
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error updating conversation",
+			"component", "conversations", "conversation_id", cid, "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	var c models.Conversation
+	err = scanConversation(tx.QueryRow(ctx,
+		`SELECT `+conversationColumns+` FROM `+conversationTables+` WHERE c.id = $2`, user.UserID, cid), &c)
+	if err == nil {
+		_, err = realtime.Publish(ctx, tx, realtime.EventConversationUpdated, []int{user.UserID}, c)
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading conversation", "component", "conversations", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(r.Context(), "database error committing conversation state", "component", "conversations", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, c)
+}
+
+// markConversationRead marks the conversation read up to a message, or up to
+// the latest message if none is given, and sends a read receipt to the
+// participants. Read positions never move backwards.
+func (s *Server) markConversationRead(w http.ResponseWriter, r *http.Request, user auth.UserContext, cid int) {
+	type req struct {
+		MessageID int `json:"message_id"`
+	}
+	var body req
+	// The body is optional
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if body.MessageID < 0 {
+		writeError(w, http.StatusBadRequest, "invalid message_id")
+		return
+	}
+
+	participants, ok := s.conversationParticipants(w, r, cid, user.UserID)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	messageID := body.MessageID
+	var err error
+	if messageID == 0 {
+		err = s.db.Pool.QueryRow(ctx,
+			`SELECT COALESCE(MAX(id), 0) FROM messages WHERE conversation_id = $1`, cid,
+		).Scan(&messageID)
+	} else {
+		var found int
+		err = s.db.Pool.QueryRow(ctx,
+			`SELECT id FROM messages WHERE id = $1 AND conversation_id = $2`, messageID, cid,
+		).Scan(&found)
+		if err == pgx.ErrNoRows {
+			writeError(w, http.StatusNotFound, "message not found in this conversation")
+			return
+		}
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "messages", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error starting transaction", "component", "messages", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	moved, err := markRead(ctx, tx, cid, user.UserID, messageID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error marking conversation read", "component", "messages", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	var lastRead, unread int
+	err = tx.QueryRow(ctx,
+		`SELECT cp.last_read_message_id,
+		 (SELECT COUNT(*) FROM messages m WHERE m.conversation_id = cp.conversation_id AND m.hidden_at IS NULL
+		  AND m.sender_id IS DISTINCT FROM cp.user_id AND m.id > cp.last_read_message_id)
+		 FROM conversation_participants cp WHERE cp.conversation_id = $1 AND cp.user_id = $2`,
+		cid, user.UserID,
+	).Scan(&lastRead, &unread)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading read state", "component", "messages", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	receipt := map[string]any{
+		"conversation_id":      cid,
+		"user_id":              user.UserID,
+		"last_read_message_id": lastRead,
+	}
+	if moved {
+		if _, err := realtime.Publish(ctx, tx, realtime.EventMessagesRead, participants, receipt); err != nil {
+			slog.ErrorContext(r.Context(), "database error publishing read receipt", "component", "messages", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+	}
+	// Reading the whole conversation also reads its new message notification
+	if unread == 0 {
+		if err := notification.MarkGroupRead(ctx, tx, user.UserID, conversationGroupKey(cid)); err != nil {
+			slog.ErrorContext(r.Context(), "database error marking message notification read", "component", "messages", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(r.Context(), "database error committing read state", "component", "messages", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	receipt["unread_count"] = unread
+	writeJSON(w, http.StatusOK, receipt)
+}
+
+// handleUnreadCount returns the number of unread messages across the user's
+// conversations, for the header badge. Muted conversations are not counted.
+func (s *Server) handleUnreadCount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := auth.UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var messages, conversations int
+	err = s.db.Pool.QueryRow(r.Context(),
+		`SELECT COALESCE(SUM(n), 0)::int, COUNT(*) FILTER (WHERE n > 0) FROM (
+		   SELECT (SELECT COUNT(*) FROM messages m WHERE m.conversation_id = c.id AND m.hidden_at IS NULL
+		           AND m.sender_id IS DISTINCT FROM $1 AND m.id > COALESCE(cp.last_read_message_id, 0)) AS n
+		   FROM conversations c
+		   LEFT JOIN conversation_participants cp ON cp.conversation_id = c.id AND cp.user_id = $1
+		   WHERE $1 IN (c.guest_id, c.host_id) AND cp.muted_at IS NULL
+		 ) unread`, user.UserID,
+	).Scan(&messages, &conversations)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error counting unread messages", "component", "messages", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{
+		"unread_count":         messages,
+		"unread_conversations": conversations,
+	})
+}
+
+// signalTyping tells the other participant the user is typing. The indicator
+// is not stored; clients should send it every few seconds while typing.
+func (s *Server) signalTyping(w http.ResponseWriter, r *http.Request, user auth.UserContext, cid int) {
+	participants, ok := s.conversationParticipants(w, r, cid, user.UserID)
+	if !ok {
+		return
+	}
+
+	var others []int
+	for _, id := range participants {
+		if id != user.UserID {
+			others = append(others, id)
+		}
+	}
+	if len(others) > 0 {
+		err := realtime.Signal(r.Context(), s.db.Pool, realtime.EventTyping, others, map[string]int{
+			"conversation_id": cid,
+			"user_id":         user.UserID,
+		})
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error signalling typing", "component", "messages", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleMessages lists and sends the messages of a conversation. Both the
+// guest and the host can read and reply.
+func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		cid, err := strconv.Atoi(r.URL.Query().Get("conversationId"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid conversationId")
+			return
+		}
+		if _, ok := s.conversationParticipants(w, r, cid, user.UserID); !ok {
+			return
+		}
+
+		rows, err := s.db.Pool.Query(r.Context(),
+			`SELECT `+messageColumns+` FROM messages m
+			 WHERE m.conversation_id = $1 AND m.hidden_at IS NULL ORDER BY m.id ASC`, cid)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error", "component", "messages", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		defer rows.Close()
+
+		messages := []models.Message{}
+		for rows.Next() {
+			var m models.Message
+			if err := scanMessage(rows, &m); err != nil {
+				slog.ErrorContext(r.Context(), "scan error", "component", "messages", "error", err)
+				writeError(w, http.StatusInternalServerError, "scan error")
+				return
+			}
+			messages = append(messages, m)
+		}
+		rows.Close()
+		if err := loadAttachments(r.Context(), s.db.Pool, messages); err != nil {
+			slog.ErrorContext(r.Context(), "database error loading attachments", "component", "messages", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		writeJSON(w, http.StatusOK, messages)
+
+	case http.MethodPost:
+		type req struct {
+			ConversationID int    `json:"conversation_id"`
+			Text           string `json:"text"`
+			AttachmentIDs  []int  `json:"attachment_ids"`
+		}
+		var body req
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+		text := strings.TrimSpace(body.Text)
+		attachmentIDs := uniqueIDs(body.AttachmentIDs)
+		if text == "" && len(attachmentIDs) == 0 {
+			writeError(w, http.StatusBadRequest, "text or attachment_ids is required")
+			return
+		}
+		if len(attachmentIDs) > attachment.MaxPerMessage {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("at most %d attachments per message", attachment.MaxPerMessage))
+			return
+		}
+		participants, ok := s.conversationParticipants(w, r, body.ConversationID, user.UserID)
+		if !ok {
+			return
+		}
+
+		ctx := r.Context()
+		text, masked, err := s.maskContactInfo(ctx, text, participants)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error checking bookings", "component", "messages", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+
+		tx, err := s.db.Pool.Begin(ctx)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error starting transaction", "component", "messages", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		defer tx.Rollback(ctx)
+
+		m, err := createMessage(ctx, tx, body.ConversationID, user.UserID, text, attachmentIDs, participants)
+		if errors.Is(err, errInvalidAttachments) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error creating message", "component", "messages", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			slog.ErrorContext(r.Context(), "database error committing message", "component", "messages", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		s.screenMessage(ctx, m, masked)
+
+		writeJSON(w, http.StatusCreated, m)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
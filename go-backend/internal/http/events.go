@@ -0,0 +1,148 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-backend/internal/auth"
+	"go-backend/internal/realtime"
+)
+
+// streamWriteTimeout bounds each write to an event stream, so a client that
+// stops reading is disconnected instead of holding the handler
+const streamWriteTimeout = 10 * time.Second
+
+// withQueryToken lets EventSource clients and image tags, which cannot set
+// headers, pass the JWT as ?access_token=
+func withQueryToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := r.URL.Query().Get("access_token"); token != "" && r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleEvents streams the user's realtime events as Server-Sent Events.
+// Clients resume with the Last-Event-ID header (sent by EventSource when it
+// reconnects) or ?since=, and receive a "reset" event if the events they
+// missed are no longer stored.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := auth.UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	cursor := r.Header.Get("Last-Event-ID")
+	if cursor == "" {
+		cursor = r.URL.Query().Get("since")
+	}
+	var since int64
+	if cursor != "" {
+		since, err = strconv.ParseInt(cursor, 10, 64)
+		if err != nil || since < 0 {
+			writeError(w, http.StatusBadRequest, "invalid event cursor")
+			return
+		}
+	}
+
+	// Subscribe before replaying so no event falls between the two
+	sub := s.hub.Subscribe(user.UserID)
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(format string, args ...any) bool {
+		rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+	if !send("retry: 3000\n\n") {
+		return
+	}
+
+	last := since
+	for since > 0 {
+		events, expired, err := s.hub.Replay(r.Context(), user.UserID, last)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error replaying events", "component", "events", "error", err)
+			return
+		}
+		if expired {
+			if !send("event: reset\ndata: {}\n\n") {
+				return
+			}
+			break
+		}
+		for _, ev := range events {
+			if !writeEvent(send, ev) {
+				return
+			}
+			last = ev.ID
+		}
+		if len(events) < realtime.ReplayLimit {
+			break
+		}
+	}
+
+	heartbeat := time.NewTicker(realtime.HeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case ev, ok := <-sub.C:
+			if !ok {
+				// Dropped for falling behind or shutting down; the client
+				// reconnects with Last-Event-ID
+				return
+			}
+			if ev.ID != 0 && ev.ID <= last {
+				continue // already replayed
+			}
+			if !writeEvent(send, ev) {
+				return
+			}
+			if ev.ID != 0 {
+				last = ev.ID
+			}
+
+		case <-heartbeat.C:
+			if !send(": ping\n\n") {
+				return
+			}
+		}
+	}
+}
+
+// writeEvent writes an event in SSE format; ephemeral events have no id so
+// they do not move the client's cursor
+func writeEvent(send func(string, ...any) bool, ev realtime.Event) bool {
+	data := ev.Data
+	if len(data) == 0 {
+		data = json.RawMessage("{}")
+	}
+	if ev.ID != 0 {
+		return send("id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+	}
+	return send("event: %s\ndata: %s\n\n", ev.Type, data)
+}
@@ -0,0 +1,241 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"go-backend/internal/auth"
+	"go-backend/internal/models"
+	"go-backend/internal/moderation"
+	"go-backend/internal/review"
+)
+
+// guestReviewColumns is the column list read by scanGuestReview, selected FROM guestReviewTables
+const guestReviewColumns = `g.id, g.booking_id, g.guest_id, g.author_id, COALESCE(u.name, ''),
+	g.rating, g.cleanliness, g.communication, g.house_rules, g.comment, g.published_at, g.hidden_at, g.created_at`
+
+const guestReviewTables = `guest_reviews g LEFT JOIN users u ON u.id = g.author_id`
+
+// scanGuestReview scans a row selected with guestReviewColumns
+func scanGuestReview(row pgx.Row, gr *models.GuestReview) error {
+	return row.Scan(&gr.ID, &gr.BookingID, &gr.GuestID, &gr.AuthorID, &gr.AuthorName,
+		&gr.Rating, &gr.Cleanliness, &gr.Communication, &gr.HouseRules, &gr.Comment,
+		&gr.PublishedAt, &gr.HiddenAt, &gr.CreatedAt)
+}
+
+// handleBookingGuestReview serves /api/bookings/{id}/guest-review, the host's
+// review of the guest
+func (s *Server) handleBookingGuestReview(w http.ResponseWriter, r *http.Request, user auth.UserContext, id int) {
+	switch r.Method {
+	case http.MethodGet:
+		guestID, ownerID, err := bookingParties(r.Context(), s.db.Pool, id)
+		if err == pgx.ErrNoRows {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error", "component", "reviews", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		isHost := ownerID != nil && *ownerID == user.UserID
+		if guestID != user.UserID && !isHost && user.Role != auth.RoleAdmin {
+			writeError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		var gr models.GuestReview
+		err = scanGuestReview(s.db.Pool.QueryRow(r.Context(),
+			`SELECT `+guestReviewColumns+` FROM `+guestReviewTables+` WHERE g.booking_id = $1`, id), &gr)
+		if err == pgx.ErrNoRows {
+			writeError(w, http.StatusNotFound, "guest has not been reviewed")
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error", "component", "reviews", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		if !canSeeReview(user, gr.AuthorID, gr.PublishedAt, gr.HiddenAt) {
+			writeHiddenReview(w, gr.PublishedAt)
+			return
+		}
+		writeJSON(w, http.StatusOK, gr)
+
+	case http.MethodPost:
+		s.createGuestReview(w, r, user, id)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// createGuestReview lets the host review the guest of a completed booking once
+// within the review window. If the guest has already reviewed the stay both
+// reviews are published in the same transaction.
+func (s *Server) createGuestReview(w http.ResponseWriter, r *http.Request, user auth.UserContext, bookingID int) {
+	type req struct {
+		review.GuestScores
+		Comment string `json:"comment"`
+	}
+	var body req
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := body.GuestScores.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	comment, err := review.NormalizeComment(body.Comment)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error starting transaction", "component", "reviews", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	st, err := lockReviewableStay(ctx, tx, bookingID)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading booking", "component", "reviews", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if st.OwnerID == nil || *st.OwnerID != user.UserID {
+		writeError(w, http.StatusForbidden, "only the host can review the guest")
+		return
+	}
+	if !checkReviewWindow(w, st) {
+		return
+	}
+
+	var reviewID int
+	err = tx.QueryRow(ctx,
+		`INSERT INTO guest_reviews (booking_id, guest_id, author_id, rating,
+		 cleanliness, communication, house_rules, comment)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 ON CONFLICT (booking_id) DO NOTHING
+		 RETURNING id`,
+		bookingID, st.GuestID, user.UserID, body.Rating,
+		body.Cleanliness, body.Communication, body.HouseRules, comment,
+	).Scan(&reviewID)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusConflict, "guest has already been reviewed")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error creating guest review", "component", "reviews", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	if !publishIfBothReviewed(ctx, w, tx, bookingID) {
+		return
+	}
+
+	var gr models.GuestReview
+	err = scanGuestReview(tx.QueryRow(ctx,
+		`SELECT `+guestReviewColumns+` FROM `+guestReviewTables+` WHERE g.id = $1`, reviewID), &gr)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading guest review", "component", "reviews", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(r.Context(), "database error committing guest review", "component", "reviews", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "guest review created",
+		"component", "reviews", "guest_review_id", gr.ID, "booking_id", bookingID,
+		"guest_id", st.GuestID, "rating", gr.Rating, "published", gr.PublishedAt != nil)
+	s.prescreen(r.Context(), moderation.TargetGuestReview, gr.ID, comment)
+	writeJSON(w, http.StatusCreated, gr)
+}
+
+// handleUserByID serves /api/users/{id}/guest-profile
+func (s *Server) handleUserByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDFromPath(r.URL.Path)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 4 || parts[3] != "guest-profile" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	s.getGuestProfile(w, r, id)
+}
+
+// getGuestProfile returns a guest's rating and a page of the published reviews
+// hosts wrote about them, so hosts can judge booking and modification requests
+func (s *Server) getGuestProfile(w http.ResponseWriter, r *http.Request, id int) {
+	limit, offset, ok := parsePagination(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "limit must be positive and offset must not be negative")
+		return
+	}
+
+	p := models.GuestProfile{Reviews: []models.GuestReview{}, Limit: limit, Offset: offset}
+	err := s.db.Pool.QueryRow(r.Context(),
+		`SELECT id, name, created_at, guest_rating::float8, guest_reviews,
+		 (SELECT COUNT(*) FROM bookings WHERE user_id = users.id AND status = 'completed')
+		 FROM users WHERE id = $1`, id,
+	).Scan(&p.ID, &p.Name, &p.MemberSince, &p.GuestRating, &p.GuestReviews, &p.CompletedStays)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading guest profile", "component", "reviews", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	rows, err := s.db.Pool.Query(r.Context(),
+		`SELECT `+guestReviewColumns+` FROM `+guestReviewTables+`
+		 WHERE g.guest_id = $1 AND g.published_at IS NOT NULL AND g.hidden_at IS NULL
+		 ORDER BY g.published_at DESC, g.id DESC
+		 LIMIT $2 OFFSET $3`, id, limit, offset)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "reviews", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var gr models.GuestReview
+		if err := scanGuestReview(rows, &gr); err != nil {
+			slog.ErrorContext(r.Context(), "scan error", "component", "reviews", "error", err)
+			writeError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		p.Reviews = append(p.Reviews, gr)
+	}
+
+	writeJSON(w, http.StatusOK, p)
+}
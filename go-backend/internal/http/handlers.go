@@ -2,39 +2,78 @@ package httpapi
 
 import (
 	"encoding/json"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"go-backend/internal/auth"
+	"go-backend/internal/booking"
+	"go-backend/internal/config"
 	"go-backend/internal/database"
+	"go-backend/internal/idempotency"
+	"go-backend/internal/mail"
+	"go-backend/internal/metrics"
 	"go-backend/internal/models"
+	"go-backend/internal/moderation"
+	"go-backend/internal/payments"
+	"go-backend/internal/realtime"
+	"go-backend/internal/storage"
+	"go-backend/internal/webhook"
 
 	"github.com/jackc/pgx/v5"
 )
 
 type Server struct {
-	mux            *http.ServeMux
-	db             *database.DB
-	authService    *auth.Service
-	authMiddleware *auth.Middleware
+	mux               *http.ServeMux
+	db                *database.DB
+	authService       *auth.Service
+	authMiddleware    *auth.Middleware
+	payments          payments.Provider
+	currency          string
+	payoutDelayDays   int
+	holdTTL           time.Duration
+	maxHoldsPerUser   int
+	screener          *moderation.Screener
+	masker            *moderation.Masker
+	hub               *realtime.Hub
+	storage           storage.Storage
+	maxAttachmentSize int64
+	idempotent        func(http.Handler) http.Handler
 }
 
-func NewServer(db *database.DB, authService *auth.Service) *Server {
+func NewServer(db *database.DB, authService *auth.Service, paymentProvider payments.Provider, screener *moderation.Screener, masker *moderation.Masker, hub *realtime.Hub, store storage.Storage, cfg *config.Config) *Server {
 	s := &Server{
-		mux:            http.NewServeMux(),
-		db:             db,
-		authService:    authService,
-		authMiddleware: auth.NewMiddleware(authService),
-	}
+		mux:               http.NewServeMux(),
+		db:                db,
+		authService:       authService,
+		authMiddleware:    auth.NewMiddleware(authService),
+		payments:          paymentProvider,
+		currency:          cfg.Payments.Currency,
+		payoutDelayDays:   cfg.Payments.PayoutDelayDays,
+		holdTTL:           time.Duration(cfg.Holds.TTLMinutes) * time.Minute,
+		maxHoldsPerUser:   cfg.Holds.MaxPerUser,
+		screener:          screener,
+		masker:            masker,
+		hub:               hub,
+		storage:           store,
+		maxAttachmentSize: int64(cfg.Attachments.MaxSizeMB) << 20,
+	}
+	s.authMiddleware.SetUserCheck(s.checkUserActive)
+	s.idempotent = idempotency.Middleware(idempotency.NewPostgresStore(db, idempotency.DefaultTTL),
+		func(r *http.Request) (int, bool) {
+			user, err := auth.UserFromContext(r.Context())
+			return user.UserID, err == nil
+		})
 	s.registerRoutes()
 	return s
 }
 
 func (s *Server) Router() http.Handler {
-	return withCORS(s.mux)
+	return withRequestID(withAccessLog(withMetrics(s.mux, withCORS(s.mux))))
 }
 
 func (s *Server) registerRoutes() {
@@ -42,25 +81,86 @@ func (s *Server) registerRoutes() {
 	s.mux.HandleFunc("/api/health", s.handleHealth)
 	s.mux.HandleFunc("/api/auth/register", s.handleRegister)
 	s.mux.HandleFunc("/api/auth/signin", s.handleSignIn)
-	s.mux.HandleFunc("/api/properties", s.handleProperties)
-	s.mux.HandleFunc("/api/properties/", s.handlePropertyByID)
-
-	// Protected routes (require authentication)
+	s.mux.HandleFunc("/api/auth/password-reset", s.handlePasswordResetRequest)
+	s.mux.HandleFunc("/api/auth/password-reset/confirm", s.handlePasswordResetConfirm)
+	s.mux.Handle("/api/properties", s.authenticateWrites(http.HandlerFunc(s.handleProperties)))
+	s.mux.Handle("/api/properties/", s.authenticateWrites(http.HandlerFunc(s.handlePropertyByID)))
+	s.mux.HandleFunc("/api/ical/", s.handleICalExport)
+	s.mux.HandleFunc("/api/payments/webhook", s.handlePaymentWebhook)
+
+	// Protected routes (require authentication, mutations accept Idempotency-Key)
 	s.mux.Handle("/api/favourites", s.authMiddleware.Authenticate(
-		http.HandlerFunc(s.handleFavourites)))
+		s.idempotent(http.HandlerFunc(s.handleFavourites))))
 	s.mux.Handle("/api/bookings", s.authMiddleware.Authenticate(
-		http.HandlerFunc(s.handleBookings)))
+		s.idempotent(http.HandlerFunc(s.handleBookings))))
 	s.mux.Handle("/api/bookings/", s.authMiddleware.Authenticate(
-		http.HandlerFunc(s.handleBookingByID)))
+		s.idempotent(http.HandlerFunc(s.handleBookingByID))))
+	s.mux.Handle("/api/holds", s.authMiddleware.Authenticate(
+		s.idempotent(http.HandlerFunc(s.handleHolds))))
+	s.mux.Handle("/api/holds/", s.authMiddleware.Authenticate(
+		s.idempotent(http.HandlerFunc(s.handleHoldByID))))
+	s.mux.Handle("/api/reviews/", s.authMiddleware.Authenticate(
+		s.idempotent(http.HandlerFunc(s.handleReviewByID))))
+	s.mux.Handle("/api/users/", s.authMiddleware.Authenticate(
+		s.idempotent(http.HandlerFunc(s.handleUserByID))))
 	s.mux.Handle("/api/conversations", s.authMiddleware.Authenticate(
-		http.HandlerFunc(s.handleConversations)))
+		s.idempotent(http.HandlerFunc(s.handleConversations))))
+	s.mux.Handle("/api/conversations/", s.authMiddleware.Authenticate(
+		s.idempotent(http.HandlerFunc(s.handleConversationByID))))
 	s.mux.Handle("/api/messages", s.authMiddleware.Authenticate(
-		http.HandlerFunc(s.handleMessages)))
+		s.idempotent(http.HandlerFunc(s.handleMessages))))
+	s.mux.Handle("/api/messages/unread-count", s.authMiddleware.Authenticate(
+		http.HandlerFunc(s.handleUnreadCount)))
+	s.mux.Handle("/api/messages/search", s.authMiddleware.Authenticate(
+		http.HandlerFunc(s.handleMessageSearch)))
+	// Uploads skip the idempotency middleware, which buffers request bodies;
+	// a retried upload only leaves a pending attachment for the cleaner
+	s.mux.Handle("/api/attachments", s.authMiddleware.Authenticate(
+		http.HandlerFunc(s.handleAttachments)))
+	s.mux.Handle("/api/attachments/", withQueryToken(s.authMiddleware.Authenticate(
+		http.HandlerFunc(s.handleAttachmentByID))))
+	s.mux.Handle("/api/notifications", s.authMiddleware.Authenticate(
+		s.idempotent(http.HandlerFunc(s.handleNotifications))))
+	s.mux.Handle("/api/notifications/", s.authMiddleware.Authenticate(
+		s.idempotent(http.HandlerFunc(s.handleNotificationByID))))
+	s.mux.Handle("/api/reports", s.authMiddleware.Authenticate(
+		s.idempotent(http.HandlerFunc(s.handleReports))))
+	s.mux.Handle("/api/events", withQueryToken(s.authMiddleware.Authenticate(
+		http.HandlerFunc(s.handleEvents))))
+	s.mux.Handle("/api/support/tickets", s.authMiddleware.Authenticate(
+		s.idempotent(http.HandlerFunc(s.handleSupportTickets))))
+	s.mux.Handle("/api/support/tickets/", s.authMiddleware.Authenticate(
+		s.idempotent(http.HandlerFunc(s.handleSupportTicketByID))))
+	s.mux.Handle("/api/host/earnings", s.authMiddleware.Authenticate(
+		http.HandlerFunc(s.handleHostEarnings)))
+	s.mux.Handle("/api/host/payouts", s.authMiddleware.Authenticate(
+		http.HandlerFunc(s.handleHostPayouts)))
 
 	// Admin routes
 	s.mux.Handle("/api/admin/users", s.authMiddleware.Authenticate(
 		s.authMiddleware.RequireRole(auth.RoleAdmin)(
 			http.HandlerFunc(s.handleAdminUsers))))
+	s.mux.Handle("/api/admin/users/", s.authMiddleware.Authenticate(
+		s.authMiddleware.RequireRole(auth.RoleAdmin)(
+			s.idempotent(http.HandlerFunc(s.handleAdminUserByID)))))
+	s.mux.Handle("/api/admin/reconciliation", s.authMiddleware.Authenticate(
+		s.authMiddleware.RequireRole(auth.RoleAdmin)(
+			http.HandlerFunc(s.handleAdminReconciliation))))
+	s.mux.Handle("/api/admin/moderation/queue", s.authMiddleware.Authenticate(
+		s.authMiddleware.RequireRole(auth.RoleAdmin)(
+			http.HandlerFunc(s.handleModerationQueue))))
+	s.mux.Handle("/api/admin/moderation/actions", s.authMiddleware.Authenticate(
+		s.authMiddleware.RequireRole(auth.RoleAdmin)(
+			s.idempotent(http.HandlerFunc(s.handleModerationActions)))))
+	s.mux.Handle("/api/admin/moderation/audit", s.authMiddleware.Authenticate(
+		s.authMiddleware.RequireRole(auth.RoleAdmin)(
+			http.HandlerFunc(s.handleModerationAudit))))
+	s.mux.Handle("/api/admin/webhooks", s.authMiddleware.Authenticate(
+		s.authMiddleware.RequireRole(auth.RoleAdmin)(
+			s.idempotent(http.HandlerFunc(s.handleAdminWebhooks)))))
+	s.mux.Handle("/api/admin/webhooks/", s.authMiddleware.Authenticate(
+		s.authMiddleware.RequireRole(auth.RoleAdmin)(
+			s.idempotent(http.HandlerFunc(s.handleAdminWebhookByID)))))
 }
 
 func writeJSON(w http.ResponseWriter, status int, v any) {
@@ -73,6 +173,11 @@ func writeError(w http.ResponseWriter, status int, message string) {
 	writeJSON(w, status, map[string]string{"error": message})
 }
 
+// writeViolation writes a structured error with a code the frontend can translate
+func writeViolation(w http.ResponseWriter, status int, v *booking.Violation) {
+	writeJSON(w, status, v)
+}
+
 func parseIDFromPath(path string) (int, bool) {
 	parts := strings.Split(strings.Trim(path, "/"), "/")
 	if len(parts) < 3 {
@@ -85,6 +190,43 @@ func parseIDFromPath(path string) (int, bool) {
 	return id, true
 }
 
+// Pagination limits for list endpoints
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// parsePagination reads ?limit= and ?offset=, applying the default and maximum limit
+func parsePagination(r *http.Request) (limit, offset int, ok bool) {
+	limit, offset = defaultPageLimit, 0
+	q := r.URL.Query()
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return 0, 0, false
+		}
+		limit = min(n, maxPageLimit)
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, false
+		}
+		offset = n
+	}
+	return limit, offset, true
+}
+
+// isValidTimezone reports whether name is a time zone known to both Go and
+// Postgres, which computes each property's local date
+func isValidTimezone(name string) bool {
+	if name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
 // Email validation helper
 func isValidEmail(email string) bool {
 	emailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
@@ -107,6 +249,7 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 		Email    string `json:"email"`
 		Name     string `json:"name"`
 		Password string `json:"password"`
+		Locale   string `json:"locale"` // language of emails; defaults to Accept-Language
 	}
 	var body req
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -139,7 +282,7 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 		body.Email,
 	).Scan(&exists)
 	if err != nil {
-		log.Printf("[Register] Database error checking email: %v", err)
+		slog.ErrorContext(r.Context(), "database error checking email", "component", "auth", "error", err)
 		writeError(w, http.StatusInternalServerError, "database error")
 		return
 	}
@@ -151,22 +294,27 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 	// Hash password
 	hash, err := auth.HashPassword(body.Password)
 	if err != nil {
-		log.Printf("[Register] Error hashing password: %v", err)
+		slog.ErrorContext(r.Context(), "error hashing password", "component", "auth", "error", err)
 		writeError(w, http.StatusInternalServerError, "could not hash password")
 		return
 	}
 
+	locale := body.Locale
+	if locale == "" {
+		locale = r.Header.Get("Accept-Language")
+	}
+
 	// Insert user with default role "user"
 	var user models.User
 	err = s.db.Pool.QueryRow(r.Context(),
-		`INSERT INTO users (email, name, password_hash, role)
-		 VALUES ($1, $2, $3, $4)
+		`INSERT INTO users (email, name, password_hash, role, locale)
+		 VALUES ($1, $2, $3, $4, $5)
 		 RETURNING id, email, name, role, created_at`,
-		body.Email, body.Name, hash, auth.RoleUser,
+		body.Email, body.Name, hash, auth.RoleUser, mail.MatchLocale(locale),
 	).Scan(&user.ID, &user.Email, &user.Name, &user.Role, &user.CreatedAt)
 
 	if err != nil {
-		log.Printf("[Register] Error creating user: %v", err)
+		slog.ErrorContext(r.Context(), "error creating user", "component", "auth", "error", err)
 		writeError(w, http.StatusInternalServerError, "could not create user")
 		return
 	}
@@ -174,12 +322,13 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 	// Generate token
 	token, err := s.authService.GenerateToken(user.ID, user.Email, user.Role)
 	if err != nil {
-		log.Printf("[Register] Error generating token: %v", err)
+		slog.ErrorContext(r.Context(), "error generating token", "component", "auth", "error", err)
 		writeError(w, http.StatusInternalServerError, "could not generate token")
 		return
 	}
 
-	log.Printf("[Register] User registered: id=%d, email=%s", user.ID, user.Email)
+	slog.InfoContext(r.Context(), "user registered", "component", "auth", "user_id", user.ID, "email", user.Email)
+	metrics.UsersRegistered.Inc()
 
 	writeJSON(w, http.StatusCreated, map[string]any{
 		"token": token,
@@ -204,35 +353,43 @@ func (s *Server) handleSignIn(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var user models.User
+	var suspended bool
 	err := s.db.Pool.QueryRow(r.Context(),
-		`SELECT id, email, name, password_hash, role, created_at FROM users WHERE email = $1`,
+		`SELECT id, email, name, password_hash, role, created_at, suspended_at IS NOT NULL FROM users WHERE email = $1`,
 		body.Email,
-	).Scan(&user.ID, &user.Email, &user.Name, &user.PasswordHash, &user.Role, &user.CreatedAt)
+	).Scan(&user.ID, &user.Email, &user.Name, &user.PasswordHash, &user.Role, &user.CreatedAt, &suspended)
 
 	if err == pgx.ErrNoRows {
+		metrics.SignInFailures.WithLabelValues(metrics.ReasonInvalidCredentials).Inc()
 		writeError(w, http.StatusUnauthorized, "invalid credentials")
 		return
 	}
 	if err != nil {
-		log.Printf("[SignIn] Database error: %v", err)
+		slog.ErrorContext(r.Context(), "database error", "component", "auth", "error", err)
 		writeError(w, http.StatusInternalServerError, "database error")
 		return
 	}
 
 	if !auth.CheckPassword(body.Password, user.PasswordHash) {
+		metrics.SignInFailures.WithLabelValues(metrics.ReasonInvalidCredentials).Inc()
 		writeError(w, http.StatusUnauthorized, "invalid credentials")
 		return
 	}
+	if suspended {
+		metrics.SignInFailures.WithLabelValues(metrics.ReasonSuspended).Inc()
+		writeError(w, http.StatusForbidden, "account suspended")
+		return
+	}
 
 	// Generate JWT token
 	token, err := s.authService.GenerateToken(user.ID, user.Email, user.Role)
 	if err != nil {
-		log.Printf("[SignIn] Error generating token: %v", err)
+		slog.ErrorContext(r.Context(), "error generating token", "component", "auth", "error", err)
 		writeError(w, http.StatusInternalServerError, "could not generate token")
 		return
 	}
 
-	log.Printf("[SignIn] User logged in: id=%d, email=%s", user.ID, user.Email)
+	slog.InfoContext(r.Context(), "user signed in", "component", "auth", "user_id", user.ID, "email", user.Email)
 
 	writeJSON(w, http.StatusOK, map[string]any{
 		"token": token,
@@ -254,10 +411,13 @@ func (s *Server) handleProperties(w http.ResponseWriter, r *http.Request) {
 func (s *Server) getProperties(w http.ResponseWriter, r *http.Request) {
 	location := strings.ToLower(r.URL.Query().Get("location"))
 	propType := r.URL.Query().Get("type")
+	startDate := r.URL.Query().Get("start_date")
+	endDate := r.URL.Query().Get("end_date")
+	guests, _ := strconv.Atoi(r.URL.Query().Get("guests"))
 
 	query := `SELECT id, title, location, price_per_night, rating, reviews,
-	          guests, bedrooms, bathrooms, type, amenities, COALESCE(image, ''), owner_id, created_at
-	          FROM properties WHERE 1=1`
+	          guests, bedrooms, bathrooms, type, amenities, COALESCE(image, ''), owner_id, timezone, created_at
+	          FROM properties WHERE hidden_at IS NULL`
 	args := []any{}
 	argNum := 1
 
@@ -271,12 +431,46 @@ func (s *Server) getProperties(w http.ResponseWriter, r *http.Request) {
 		args = append(args, propType)
 		argNum++
 	}
+	if guests > 0 {
+		query += ` AND guests >= $` + strconv.Itoa(argNum)
+		args = append(args, guests)
+		argNum++
+	}
+	if startDate != "" || endDate != "" {
+		start, end, err := booking.ParseDates(startDate, endDate)
+		if err != nil {
+			writeViolation(w, http.StatusBadRequest, err.(*booking.Violation))
+			return
+		}
+		// Only properties free of bookings and holds (padded by preparation days) and blocked dates
+		startArg, endArg := "$"+strconv.Itoa(argNum), "$"+strconv.Itoa(argNum+1)
+		query += ` AND NOT EXISTS(
+			SELECT 1 FROM bookings b
+			WHERE b.property_id = properties.id
+			  AND b.status NOT IN ('cancelled', 'expired')
+			  AND b.start_date < ` + endArg + `::date + COALESCE((SELECT preparation_days FROM property_stay_rules sr WHERE sr.property_id = properties.id), 0)
+			  AND b.end_date + COALESCE((SELECT preparation_days FROM property_stay_rules sr WHERE sr.property_id = properties.id), 0) > ` + startArg + `::date
+		) AND NOT EXISTS(
+			SELECT 1 FROM booking_holds h
+			WHERE h.property_id = properties.id
+			  AND h.status = 'active' AND h.expires_at > CURRENT_TIMESTAMP
+			  AND h.start_date < ` + endArg + `::date + COALESCE((SELECT preparation_days FROM property_stay_rules sr WHERE sr.property_id = properties.id), 0)
+			  AND h.end_date + COALESCE((SELECT preparation_days FROM property_stay_rules sr WHERE sr.property_id = properties.id), 0) > ` + startArg + `::date
+		) AND NOT EXISTS(
+			SELECT 1 FROM property_calendar pc
+			WHERE pc.property_id = properties.id
+			  AND pc.date >= ` + startArg + `::date
+			  AND pc.date < ` + endArg + `::date
+		)`
+		args = append(args, start, end)
+		argNum += 2
+	}
 
 	query += ` ORDER BY created_at DESC`
 
 	rows, err := s.db.Pool.Query(r.Context(), query, args...)
 	if err != nil {
-		log.Printf("[Properties] Database error: %v", err)
+		slog.ErrorContext(r.Context(), "database error", "component", "properties", "error", err)
 		writeError(w, http.StatusInternalServerError, "database error")
 		return
 	}
@@ -287,9 +481,9 @@ func (s *Server) getProperties(w http.ResponseWriter, r *http.Request) {
 		var p models.Property
 		err := rows.Scan(&p.ID, &p.Title, &p.Location, &p.PricePerNight, &p.Rating,
 			&p.Reviews, &p.Guests, &p.Bedrooms, &p.Bathrooms, &p.Type,
-			&p.Amenities, &p.Image, &p.OwnerID, &p.CreatedAt)
+			&p.Amenities, &p.Image, &p.OwnerID, &p.Timezone, &p.CreatedAt)
 		if err != nil {
-			log.Printf("[Properties] Scan error: %v", err)
+			slog.ErrorContext(r.Context(), "scan error", "component", "properties", "error", err)
 			writeError(w, http.StatusInternalServerError, "scan error")
 			return
 		}
@@ -300,7 +494,6 @@ func (s *Server) getProperties(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) createProperty(w http.ResponseWriter, r *http.Request) {
-	// Check if user is authenticated (optional - can create without auth or require it)
 	user, err := auth.UserFromContext(r.Context())
 	if err != nil {
 		writeError(w, http.StatusUnauthorized, "authentication required")
@@ -317,6 +510,7 @@ func (s *Server) createProperty(w http.ResponseWriter, r *http.Request) {
 		Type          string   `json:"type"`
 		Amenities     []string `json:"amenities"`
 		Image         string   `json:"image"`
+		Timezone      string   `json:"timezone"`
 	}
 	var body req
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -329,22 +523,30 @@ func (s *Server) createProperty(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "title, location, and price_per_night are required")
 		return
 	}
+	if body.Timezone == "" {
+		body.Timezone = "UTC"
+	}
+	if !isValidTimezone(body.Timezone) {
+		writeError(w, http.StatusBadRequest, "timezone must be an IANA time zone name such as Europe/Paris")
+		return
+	}
 
 	var p models.Property
 	err = s.db.Pool.QueryRow(r.Context(),
-		`INSERT INTO properties (title, location, price_per_night, guests, bedrooms, bathrooms, type, amenities, image, owner_id)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-		 RETURNING id, title, location, price_per_night, rating, reviews, guests, bedrooms, bathrooms, type, amenities, COALESCE(image, ''), owner_id, created_at`,
-		body.Title, body.Location, body.PricePerNight, body.Guests, body.Bedrooms, body.Bathrooms, body.Type, body.Amenities, body.Image, user.UserID,
-	).Scan(&p.ID, &p.Title, &p.Location, &p.PricePerNight, &p.Rating, &p.Reviews, &p.Guests, &p.Bedrooms, &p.Bathrooms, &p.Type, &p.Amenities, &p.Image, &p.OwnerID, &p.CreatedAt)
+		`INSERT INTO properties (title, location, price_per_night, guests, bedrooms, bathrooms, type, amenities, image, owner_id, timezone)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		 RETURNING id, title, location, price_per_night, rating, reviews, guests, bedrooms, bathrooms, type, amenities, COALESCE(image, ''), owner_id, timezone, created_at`,
+		body.Title, body.Location, body.PricePerNight, body.Guests, body.Bedrooms, body.Bathrooms, body.Type, body.Amenities, body.Image, user.UserID, body.Timezone,
+	).Scan(&p.ID, &p.Title, &p.Location, &p.PricePerNight, &p.Rating, &p.Reviews, &p.Guests, &p.Bedrooms, &p.Bathrooms, &p.Type, &p.Amenities, &p.Image, &p.OwnerID, &p.Timezone, &p.CreatedAt)
 
 	if err != nil {
-		log.Printf("[CreateProperty] Database error: %v", err)
+		slog.ErrorContext(r.Context(), "database error", "component", "properties", "error", err)
 		writeError(w, http.StatusInternalServerError, "database error")
 		return
 	}
 
-	log.Printf("[CreateProperty] Property created: id=%d by user=%d", p.ID, user.UserID)
+	slog.InfoContext(r.Context(), "property created", "component", "properties", "property_id", p.ID)
+	s.prescreen(r.Context(), moderation.TargetListing, p.ID, p.Title+"\n"+p.Location)
 	writeJSON(w, http.StatusCreated, p)
 }
 
@@ -355,6 +557,26 @@ func (s *Server) handlePropertyByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Property sub-resources: /api/properties/{id}/{resource}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) > 3 {
+		switch {
+		case len(parts) == 4 && parts[3] == "rules":
+			s.handlePropertyRules(w, r, id)
+		case len(parts) == 4 && parts[3] == "cancellation-policy":
+			s.handlePropertyCancellationPolicy(w, r, id)
+		case parts[3] == "calendar":
+			s.handlePropertyCalendar(w, r, id, parts[4:])
+		case parts[3] == "ical":
+			s.handlePropertyICal(w, r, id, parts[4:])
+		case len(parts) == 4 && parts[3] == "reviews":
+			s.listPropertyReviews(w, r, id)
+		default:
+			writeError(w, http.StatusNotFound, "not found")
+		}
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		s.getPropertyByID(w, r, id)
@@ -371,18 +593,18 @@ func (s *Server) getPropertyByID(w http.ResponseWriter, r *http.Request, id int)
 	var p models.Property
 	err := s.db.Pool.QueryRow(r.Context(),
 		`SELECT id, title, location, price_per_night, rating, reviews,
-		 guests, bedrooms, bathrooms, type, amenities, COALESCE(image, ''), owner_id, created_at
-		 FROM properties WHERE id = $1`, id,
+		 guests, bedrooms, bathrooms, type, amenities, COALESCE(image, ''), owner_id, timezone, created_at
+		 FROM properties WHERE id = $1 AND hidden_at IS NULL`, id,
 	).Scan(&p.ID, &p.Title, &p.Location, &p.PricePerNight, &p.Rating,
 		&p.Reviews, &p.Guests, &p.Bedrooms, &p.Bathrooms, &p.Type,
-		&p.Amenities, &p.Image, &p.OwnerID, &p.CreatedAt)
+		&p.Amenities, &p.Image, &p.OwnerID, &p.Timezone, &p.CreatedAt)
 
 	if err == pgx.ErrNoRows {
 		writeError(w, http.StatusNotFound, "not found")
 		return
 	}
 	if err != nil {
-		log.Printf("[GetProperty] Database error: %v", err)
+		slog.ErrorContext(r.Context(), "database error", "component", "get_property", "error", err)
 		writeError(w, http.StatusInternalServerError, "database error")
 		return
 	}
@@ -424,29 +646,54 @@ func (s *Server) updateProperty(w http.ResponseWriter, r *http.Request, id int)
 		Type          string   `json:"type"`
 		Amenities     []string `json:"amenities"`
 		Image         string   `json:"image"`
+		Timezone      string   `json:"timezone"` // optional, unchanged when empty
 	}
 	var body req
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid json")
 		return
 	}
+	if body.Timezone != "" && !isValidTimezone(body.Timezone) {
+		writeError(w, http.StatusBadRequest, "timezone must be an IANA time zone name such as Europe/Paris")
+		return
+	}
+
+	tx, err := s.db.Pool.Begin(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error starting transaction", "component", "properties", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(r.Context())
 
 	var p models.Property
-	err = s.db.Pool.QueryRow(r.Context(),
+	err = tx.QueryRow(r.Context(),
 		`UPDATE properties SET title = $1, location = $2, price_per_night = $3, guests = $4,
-		 bedrooms = $5, bathrooms = $6, type = $7, amenities = $8, image = $9
+		 bedrooms = $5, bathrooms = $6, type = $7, amenities = $8, image = $9,
+		 timezone = COALESCE(NULLIF($11, ''), timezone)
 		 WHERE id = $10
-		 RETURNING id, title, location, price_per_night, rating, reviews, guests, bedrooms, bathrooms, type, amenities, COALESCE(image, ''), owner_id, created_at`,
-		body.Title, body.Location, body.PricePerNight, body.Guests, body.Bedrooms, body.Bathrooms, body.Type, body.Amenities, body.Image, id,
-	).Scan(&p.ID, &p.Title, &p.Location, &p.PricePerNight, &p.Rating, &p.Reviews, &p.Guests, &p.Bedrooms, &p.Bathrooms, &p.Type, &p.Amenities, &p.Image, &p.OwnerID, &p.CreatedAt)
+		 RETURNING id, title, location, price_per_night, rating, reviews, guests, bedrooms, bathrooms, type, amenities, COALESCE(image, ''), owner_id, timezone, created_at`,
+		body.Title, body.Location, body.PricePerNight, body.Guests, body.Bedrooms, body.Bathrooms, body.Type, body.Amenities, body.Image, id, body.Timezone,
+	).Scan(&p.ID, &p.Title, &p.Location, &p.PricePerNight, &p.Rating, &p.Reviews, &p.Guests, &p.Bedrooms, &p.Bathrooms, &p.Type, &p.Amenities, &p.Image, &p.OwnerID, &p.Timezone, &p.CreatedAt)
 
 	if err != nil {
-		log.Printf("[UpdateProperty] Database error: %v", err)
+		slog.ErrorContext(r.Context(), "database error", "component", "properties", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := webhook.Enqueue(r.Context(), tx, webhook.EventPropertyUpdated, p); err != nil {
+		slog.ErrorContext(r.Context(), "database error queueing webhook", "component", "properties", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := tx.Commit(r.Context()); err != nil {
+		slog.ErrorContext(r.Context(), "database error committing", "component", "properties", "error", err)
 		writeError(w, http.StatusInternalServerError, "database error")
 		return
 	}
 
-	log.Printf("[UpdateProperty] Property updated: id=%d by user=%d", p.ID, user.UserID)
+	slog.InfoContext(r.Context(), "property updated", "component", "properties", "property_id", p.ID)
+	s.prescreen(r.Context(), moderation.TargetListing, p.ID, p.Title+"\n"+p.Location)
 	writeJSON(w, http.StatusOK, p)
 }
 
@@ -476,12 +723,12 @@ func (s *Server) deleteProperty(w http.ResponseWriter, r *http.Request, id int)
 
 	_, err = s.db.Pool.Exec(r.Context(), `DELETE FROM properties WHERE id = $1`, id)
 	if err != nil {
-		log.Printf("[DeleteProperty] Database error: %v", err)
+		slog.ErrorContext(r.Context(), "database error", "component", "properties", "error", err)
 		writeError(w, http.StatusInternalServerError, "database error")
 		return
 	}
 
-	log.Printf("[DeleteProperty] Property deleted: id=%d by user=%d", id, user.UserID)
+	slog.InfoContext(r.Context(), "property deleted", "component", "properties", "property_id", id)
 	writeJSON(w, http.StatusOK, map[string]string{"message": "property deleted"})
 }
 
@@ -497,13 +744,13 @@ func (s *Server) handleFavourites(w http.ResponseWriter, r *http.Request) {
 	case http.MethodGet:
 		rows, err := s.db.Pool.Query(r.Context(),
 			`SELECT p.id, p.title, p.location, p.price_per_night, p.rating, p.reviews,
-			 p.guests, p.bedrooms, p.bathrooms, p.type, p.amenities, COALESCE(p.image, ''), p.owner_id, p.created_at
+			 p.guests, p.bedrooms, p.bathrooms, p.type, p.amenities, COALESCE(p.image, ''), p.owner_id, p.timezone, p.created_at
 			 FROM properties p
 			 INNER JOIN favourites f ON f.property_id = p.id
-			 WHERE f.user_id = $1
+			 WHERE f.user_id = $1 AND p.hidden_at IS NULL
 			 ORDER BY f.created_at DESC`, userID)
 		if err != nil {
-			log.Printf("[Favourites] Database error: %v", err)
+			slog.ErrorContext(r.Context(), "database error", "component", "favourites", "error", err)
 			writeError(w, http.StatusInternalServerError, "database error")
 			return
 		}
@@ -514,9 +761,9 @@ func (s *Server) handleFavourites(w http.ResponseWriter, r *http.Request) {
 			var p models.Property
 			err := rows.Scan(&p.ID, &p.Title, &p.Location, &p.PricePerNight, &p.Rating,
 				&p.Reviews, &p.Guests, &p.Bedrooms, &p.Bathrooms, &p.Type,
-				&p.Amenities, &p.Image, &p.OwnerID, &p.CreatedAt)
+				&p.Amenities, &p.Image, &p.OwnerID, &p.Timezone, &p.CreatedAt)
 			if err != nil {
-				log.Printf("[Favourites] Scan error: %v", err)
+				slog.ErrorContext(r.Context(), "scan error", "component", "favourites", "error", err)
 				writeError(w, http.StatusInternalServerError, "scan error")
 				return
 			}
@@ -541,7 +788,7 @@ func (s *Server) handleFavourites(w http.ResponseWriter, r *http.Request) {
 			userID, body.PropertyID,
 		).Scan(&exists)
 		if err != nil {
-			log.Printf("[Favourites] Database error: %v", err)
+			slog.ErrorContext(r.Context(), "database error", "component", "favourites", "error", err)
 			writeError(w, http.StatusInternalServerError, "database error")
 			return
 		}
@@ -552,7 +799,7 @@ func (s *Server) handleFavourites(w http.ResponseWriter, r *http.Request) {
 				`DELETE FROM favourites WHERE user_id = $1 AND property_id = $2`,
 				userID, body.PropertyID)
 			if err != nil {
-				log.Printf("[Favourites] Database error: %v", err)
+				slog.ErrorContext(r.Context(), "database error", "component", "favourites", "error", err)
 				writeError(w, http.StatusInternalServerError, "database error")
 				return
 			}
@@ -563,7 +810,7 @@ func (s *Server) handleFavourites(w http.ResponseWriter, r *http.Request) {
 				`INSERT INTO favourites (user_id, property_id) VALUES ($1, $2)`,
 				userID, body.PropertyID)
 			if err != nil {
-				log.Printf("[Favourites] Database error: %v", err)
+				slog.ErrorContext(r.Context(), "database error", "component", "favourites", "error", err)
 				writeError(w, http.StatusInternalServerError, "database error")
 				return
 			}
@@ -586,10 +833,10 @@ func (s *Server) handleBookings(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
 		rows, err := s.db.Pool.Query(r.Context(),
-			`SELECT id, user_id, property_id, start_date, end_date, guests, status, created_at
+			`SELECT `+bookingColumns+`
 			 FROM bookings WHERE user_id = $1 ORDER BY created_at DESC`, userID)
 		if err != nil {
-			log.Printf("[Bookings] Database error: %v", err)
+			slog.ErrorContext(r.Context(), "database error", "component", "bookings", "error", err)
 			writeError(w, http.StatusInternalServerError, "database error")
 			return
 		}
@@ -598,10 +845,8 @@ func (s *Server) handleBookings(w http.ResponseWriter, r *http.Request) {
 		bookings := []models.Booking{}
 		for rows.Next() {
 			var b models.Booking
-			err := rows.Scan(&b.ID, &b.UserID, &b.PropertyID, &b.StartDate, &b.EndDate,
-				&b.Guests, &b.Status, &b.CreatedAt)
-			if err != nil {
-				log.Printf("[Bookings] Scan error: %v", err)
+			if err := scanBooking(rows, &b); err != nil {
+				slog.ErrorContext(r.Context(), "scan error", "component", "bookings", "error", err)
 				writeError(w, http.StatusInternalServerError, "scan error")
 				return
 			}
@@ -610,61 +855,7 @@ func (s *Server) handleBookings(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, bookings)
 
 	case http.MethodPost:
-		type req struct {
-			PropertyID int    `json:"property_id"`
-			StartDate  string `json:"start_date"`
-			EndDate    string `json:"end_date"`
-			Guests     int    `json:"guests"`
-		}
-		var body req
-		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-			writeError(w, http.StatusBadRequest, "invalid json")
-			return
-		}
-
-		// Validate required fields
-		if body.PropertyID <= 0 || body.StartDate == "" || body.EndDate == "" || body.Guests <= 0 {
-			writeError(w, http.StatusBadRequest, "property_id, start_date, end_date, and guests are required")
-			return
-		}
-
-		// Check for overlapping bookings
-		var overlapping int
-		err := s.db.Pool.QueryRow(r.Context(),
-			`SELECT COUNT(*) FROM bookings
-			 WHERE property_id = $1
-			   AND status != 'cancelled'
-			   AND start_date < $3
-			   AND end_date > $2`,
-			body.PropertyID, body.StartDate, body.EndDate,
-		).Scan(&overlapping)
-		if err != nil {
-			log.Printf("[Bookings] Database error checking overlap: %v", err)
-			writeError(w, http.StatusInternalServerError, "database error")
-			return
-		}
-		if overlapping > 0 {
-			writeError(w, http.StatusConflict, "property is not available for selected dates")
-			return
-		}
-
-		var b models.Booking
-		err = s.db.Pool.QueryRow(r.Context(),
-			`INSERT INTO bookings (user_id, property_id, start_date, end_date, guests, status)
-			 VALUES ($1, $2, $3, $4, $5, 'confirmed')
-			 RETURNING id, user_id, property_id, start_date, end_date, guests, status, created_at`,
-			userID, body.PropertyID, body.StartDate, body.EndDate, body.Guests,
-		).Scan(&b.ID, &b.UserID, &b.PropertyID, &b.StartDate, &b.EndDate,
-			&b.Guests, &b.Status, &b.CreatedAt)
-
-		if err != nil {
-			log.Printf("[Bookings] Database error creating booking: %v", err)
-			writeError(w, http.StatusInternalServerError, "database error")
-			return
-		}
-
-		log.Printf("[Bookings] Booking created: id=%d by user=%d", b.ID, userID)
-		writeJSON(w, http.StatusCreated, b)
+		s.createBooking(w, r, userID)
 
 	default:
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -684,13 +875,31 @@ func (s *Server) handleBookingByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Booking sub-resources: /api/bookings/{id}/{resource}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) > 3 {
+		switch parts[3] {
+		case "modifications":
+			s.handleBookingModifications(w, r, user, id, parts[4:])
+		case "check-in":
+			s.checkInBooking(w, r, user, id)
+		case "events":
+			s.listBookingEvents(w, r, user, id)
+		case "review":
+			s.handleBookingReview(w, r, user, id)
+		case "guest-review":
+			s.handleBookingGuestReview(w, r, user, id)
+		default:
+			writeError(w, http.StatusNotFound, "not found")
+		}
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		var b models.Booking
-		err := s.db.Pool.QueryRow(r.Context(),
-			`SELECT id, user_id, property_id, start_date, end_date, guests, status, created_at
-			 FROM bookings WHERE id = $1`, id,
-		).Scan(&b.ID, &b.UserID, &b.PropertyID, &b.StartDate, &b.EndDate, &b.Guests, &b.Status, &b.CreatedAt)
+		err := scanBooking(s.db.Pool.QueryRow(r.Context(),
+			`SELECT `+bookingColumns+` FROM bookings WHERE id = $1`, id), &b)
 
 		if err == pgx.ErrNoRows {
 			writeError(w, http.StatusNotFound, "not found")
@@ -710,27 +919,9 @@ func (s *Server) handleBookingByID(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, b)
 
 	case http.MethodPut:
-		// Get booking to check ownership
-		var bookingUserID int
-		err := s.db.Pool.QueryRow(r.Context(),
-			`SELECT user_id FROM bookings WHERE id = $1`, id,
-		).Scan(&bookingUserID)
-		if err == pgx.ErrNoRows {
-			writeError(w, http.StatusNotFound, "not found")
-			return
-		}
-		if err != nil {
-			writeError(w, http.StatusInternalServerError, "database error")
-			return
-		}
-
-		if user.Role != auth.RoleAdmin && bookingUserID != user.UserID {
-			writeError(w, http.StatusForbidden, "you can only update your own bookings")
-			return
-		}
-
 		type req struct {
 			Status string `json:"status"`
+			Reason string `json:"reason"`
 		}
 		var body req
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -738,219 +929,25 @@ func (s *Server) handleBookingByID(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// Validate status
-		validStatuses := map[string]bool{"pending": true, "confirmed": true, "cancelled": true}
-		if !validStatuses[body.Status] {
-			writeError(w, http.StatusBadRequest, "invalid status")
+		// Cancellation is the only status change a user can make: bookings
+		// are confirmed by payment and advanced by the lifecycle worker
+		if body.Status != "cancelled" {
+			writeError(w, http.StatusBadRequest, "only cancellation is allowed; bookings are confirmed by payment")
 			return
 		}
-
-		var b models.Booking
-		err = s.db.Pool.QueryRow(r.Context(),
-			`UPDATE bookings SET status = $1 WHERE id = $2
-			 RETURNING id, user_id, property_id, start_date, end_date, guests, status, created_at`,
-			body.Status, id,
-		).Scan(&b.ID, &b.UserID, &b.PropertyID, &b.StartDate, &b.EndDate, &b.Guests, &b.Status, &b.CreatedAt)
-
-		if err != nil {
-			log.Printf("[Bookings] Database error updating booking: %v", err)
-			writeError(w, http.StatusInternalServerError, "database error")
-			return
-		}
-
-		log.Printf("[Bookings] Booking updated: id=%d, status=%s by user=%d", b.ID, b.Status, user.UserID)
-		writeJSON(w, http.StatusOK, b)
+		s.cancelBooking(w, r, user, id, body.Reason)
 
 	case http.MethodDelete:
-		// Get booking to check ownership
-		var bookingUserID int
-		err := s.db.Pool.QueryRow(r.Context(),
-			`SELECT user_id FROM bookings WHERE id = $1`, id,
-		).Scan(&bookingUserID)
-		if err == pgx.ErrNoRows {
-			writeError(w, http.StatusNotFound, "not found")
-			return
-		}
-		if err != nil {
-			writeError(w, http.StatusInternalServerError, "database error")
-			return
-		}
-
-		if user.Role != auth.RoleAdmin && bookingUserID != user.UserID {
-			writeError(w, http.StatusForbidden, "you can only delete your own bookings")
-			return
-		}
-
-		_, err = s.db.Pool.Exec(r.Context(), `DELETE FROM bookings WHERE id = $1`, id)
-		if err != nil {
-			log.Printf("[Bookings] Database error deleting booking: %v", err)
-			writeError(w, http.StatusInternalServerError, "database error")
-			return
-		}
-
-		log.Printf("[Bookings] Booking deleted: id=%d by user=%d", id, user.UserID)
-		writeJSON(w, http.StatusOK, map[string]string{"message": "booking deleted"})
-
-	default:
-		w.WriteHeader(http.StatusMethodNotAllowed)
-	}
-}
-
-func (s *Server) handleConversations(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		return
-	}
-
-	user, err := auth.UserFromContext(r.Context())
-	if err != nil {
-		writeError(w, http.StatusUnauthorized, "unauthorized")
-		return
-	}
-	userID := user.UserID
-
-	type convoSummary struct {
-		ID          int    `json:"id"`
-		ContactName string `json:"contact_name"`
-		ContactType string `json:"contact_type"`
-		Property    string `json:"property_name"`
-		LastMessage string `json:"last_message"`
-	}
-
-	rows, err := s.db.Pool.Query(r.Context(),
-		`SELECT c.id, c.contact_name, c.contact_type, COALESCE(c.property_name, ''),
-		 COALESCE((SELECT text FROM messages m WHERE m.conversation_id = c.id ORDER BY m.created_at DESC LIMIT 1), '')
-		 FROM conversations c WHERE c.user_id = $1 ORDER BY c.created_at DESC`, userID)
-	if err != nil {
-		log.Printf("[Conversations] Database error: %v", err)
-		writeError(w, http.StatusInternalServerError, "database error")
-		return
-	}
-	defer rows.Close()
-
-	conversations := []convoSummary{}
-	for rows.Next() {
-		var c convoSummary
-		err := rows.Scan(&c.ID, &c.ContactName, &c.ContactType, &c.Property, &c.LastMessage)
-		if err != nil {
-			log.Printf("[Conversations] Scan error: %v", err)
-			writeError(w, http.StatusInternalServerError, "scan error")
-			return
-		}
-		conversations = append(conversations, c)
-	}
-
-	writeJSON(w, http.StatusOK, conversations)
-}
-
-func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
-	user, err := auth.UserFromContext(r.Context())
-	if err != nil {
-		writeError(w, http.StatusUnauthorized, "unauthorized")
-		return
-	}
-	userID := user.UserID
-
-	switch r.Method {
-	case http.MethodGet:
-		cidStr := r.URL.Query().Get("conversationId")
-		cid, err := strconv.Atoi(cidStr)
-		if err != nil {
-			writeError(w, http.StatusBadRequest, "invalid conversationId")
-			return
-		}
-
-		// Verify conversation belongs to user
-		var ownerID int
-		err = s.db.Pool.QueryRow(r.Context(),
-			`SELECT user_id FROM conversations WHERE id = $1`, cid,
-		).Scan(&ownerID)
-		if err == pgx.ErrNoRows {
-			writeError(w, http.StatusNotFound, "conversation not found")
-			return
-		}
-		if err != nil {
-			log.Printf("[Messages] Database error: %v", err)
-			writeError(w, http.StatusInternalServerError, "database error")
-			return
-		}
-		if ownerID != userID {
-			writeError(w, http.StatusForbidden, "access denied")
-			return
-		}
-
-		rows, err := s.db.Pool.Query(r.Context(),
-			`SELECT id, conversation_id, sender_id, text, created_at, read
-			 FROM messages WHERE conversation_id = $1 ORDER BY created_at ASC`, cid)
-		if err != nil {
-			log.Printf("[Messages] Database error: %v", err)
-			writeError(w, http.StatusInternalServerError, "database error")
-			return
-		}
-		defer rows.Close()
-
-		messages := []models.Message{}
-		for rows.Next() {
-			var m models.Message
-			var senderID *int
-			err := rows.Scan(&m.ID, &m.ConversationID, &senderID, &m.Text, &m.CreatedAt, &m.Read)
-			if err != nil {
-				log.Printf("[Messages] Scan error: %v", err)
-				writeError(w, http.StatusInternalServerError, "scan error")
-				return
-			}
-			if senderID != nil {
-				m.SenderID = *senderID
-			}
-			messages = append(messages, m)
-		}
-		writeJSON(w, http.StatusOK, messages)
-
-	case http.MethodPost:
+		// Bookings are cancelled rather than deleted so they stay in the history
 		type req struct {
-			ConversationID int    `json:"conversation_id"`
-			Text           string `json:"text"`
+			Reason string `json:"reason"`
 		}
 		var body req
-		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
 			writeError(w, http.StatusBadRequest, "invalid json")
 			return
 		}
-
-		// Verify conversation belongs to user
-		var ownerID int
-		err := s.db.Pool.QueryRow(r.Context(),
-			`SELECT user_id FROM conversations WHERE id = $1`, body.ConversationID,
-		).Scan(&ownerID)
-		if err == pgx.ErrNoRows {
-			writeError(w, http.StatusNotFound, "conversation not found")
-			return
-		}
-		if err != nil {
-			log.Printf("[Messages] Database error: %v", err)
-			writeError(w, http.StatusInternalServerError, "database error")
-			return
-		}
-		if ownerID != userID {
-			writeError(w, http.StatusForbidden, "access denied")
-			return
-		}
-
-		var m models.Message
-		err = s.db.Pool.QueryRow(r.Context(),
-			`INSERT INTO messages (conversation_id, sender_id, text, read)
-			 VALUES ($1, $2, $3, true)
-			 RETURNING id, conversation_id, sender_id, text, created_at, read`,
-			body.ConversationID, userID, body.Text,
-		).Scan(&m.ID, &m.ConversationID, &m.SenderID, &m.Text, &m.CreatedAt, &m.Read)
-
-		if err != nil {
-			log.Printf("[Messages] Database error creating message: %v", err)
-			writeError(w, http.StatusInternalServerError, "database error")
-			return
-		}
-
-		writeJSON(w, http.StatusCreated, m)
+		s.cancelBooking(w, r, user, id, body.Reason)
 
 	default:
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -966,7 +963,7 @@ func (s *Server) handleAdminUsers(w http.ResponseWriter, r *http.Request) {
 	rows, err := s.db.Pool.Query(r.Context(),
 		`SELECT id, email, name, role, created_at FROM users ORDER BY created_at DESC`)
 	if err != nil {
-		log.Printf("[AdminUsers] Database error: %v", err)
+		slog.ErrorContext(r.Context(), "database error", "component", "admin_users", "error", err)
 		writeError(w, http.StatusInternalServerError, "database error")
 		return
 	}
@@ -977,7 +974,7 @@ func (s *Server) handleAdminUsers(w http.ResponseWriter, r *http.Request) {
 		var u models.UserResponse
 		err := rows.Scan(&u.ID, &u.Email, &u.Name, &u.Role, &u.CreatedAt)
 		if err != nil {
-			log.Printf("[AdminUsers] Scan error: %v", err)
+			slog.ErrorContext(r.Context(), "scan error", "component", "admin_users", "error", err)
 			writeError(w, http.StatusInternalServerError, "scan error")
 			return
 		}
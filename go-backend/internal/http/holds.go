@@ -0,0 +1,345 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"go-backend/internal/auth"
+	"go-backend/internal/booking"
+	"go-backend/internal/metrics"
+	"go-backend/internal/models"
+)
+
+// holdColumns is the column list read by scanHold
+const holdColumns = `id, user_id, property_id, start_date::text, end_date::text, guests, status,
+	expires_at, booking_id, created_at`
+
+// scanHold scans a row selected with holdColumns
+func scanHold(row pgx.Row, h *models.BookingHold) error {
+	return row.Scan(&h.ID, &h.UserID, &h.PropertyID, &h.StartDate, &h.EndDate, &h.Guests, &h.Status,
+		&h.ExpiresAt, &h.BookingID, &h.CreatedAt)
+}
+
+// handleHolds lists the user's active holds and creates new ones
+func (s *Server) handleHolds(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := s.db.Pool.Query(r.Context(),
+			`SELECT `+holdColumns+` FROM booking_holds
+			 WHERE user_id = $1 AND status = 'active' AND expires_at > CURRENT_TIMESTAMP
+			 ORDER BY expires_at`, user.UserID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error", "component", "holds", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		defer rows.Close()
+
+		holds := []models.BookingHold{}
+		for rows.Next() {
+			var h models.BookingHold
+			if err := scanHold(rows, &h); err != nil {
+				slog.ErrorContext(r.Context(), "scan error", "component", "holds", "error", err)
+				writeError(w, http.StatusInternalServerError, "scan error")
+				return
+			}
+			holds = append(holds, h)
+		}
+		writeJSON(w, http.StatusOK, holds)
+
+	case http.MethodPost:
+		s.createHold(w, r, user.UserID)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleHoldByID serves /api/holds/{id} and /api/holds/{id}/convert
+func (s *Server) handleHoldByID(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	id, ok := parseIDFromPath(r.URL.Path)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) > 3 {
+		if parts[3] != "convert" || len(parts) > 4 {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		s.convertHold(w, r, user, id)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h, ok := s.loadHold(w, r, user, id)
+		if !ok {
+			return
+		}
+		writeJSON(w, http.StatusOK, h)
+
+	case http.MethodDelete:
+		s.releaseHold(w, r, user, id)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// loadHold loads a hold for its owner or an admin, writing the error response
+// if it cannot be accessed
+func (s *Server) loadHold(w http.ResponseWriter, r *http.Request, user auth.UserContext, id int) (models.BookingHold, bool) {
+	var h models.BookingHold
+	err := scanHold(s.db.Pool.QueryRow(r.Context(),
+		`SELECT `+holdColumns+` FROM booking_holds WHERE id = $1`, id), &h)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not found")
+		return h, false
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading hold", "component", "holds", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return h, false
+	}
+	if h.UserID != user.UserID && user.Role != auth.RoleAdmin {
+		writeError(w, http.StatusForbidden, "access denied")
+		return h, false
+	}
+	return h, true
+}
+
+// createHold reserves dates for the user for the configured hold TTL. The
+// stay is validated exactly like a booking so converting the hold only fails
+// if the hold has expired or payment is declined.
+func (s *Server) createHold(w http.ResponseWriter, r *http.Request, userID int) {
+	type req struct {
+		PropertyID int    `json:"property_id"`
+		StartDate  string `json:"start_date"`
+		EndDate    string `json:"end_date"`
+		Guests     int    `json:"guests"`
+	}
+	var body req
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if body.PropertyID <= 0 || body.StartDate == "" || body.EndDate == "" || body.Guests <= 0 {
+		writeError(w, http.StatusBadRequest, "property_id, start_date, end_date, and guests are required")
+		return
+	}
+
+	start, end, err := booking.ParseDates(body.StartDate, body.EndDate)
+	if err != nil {
+		writeViolation(w, http.StatusBadRequest, err.(*booking.Violation))
+		return
+	}
+
+	h, err := s.placeHold(r.Context(), reservation{
+		UserID:     userID,
+		PropertyID: body.PropertyID,
+		Start:      start,
+		End:        end,
+		Guests:     body.Guests,
+	})
+	if !s.writeBookingError(w, r, err) {
+		return
+	}
+
+	slog.InfoContext(r.Context(), "hold created",
+		"component", "holds", "hold_id", h.ID, "property_id", h.PropertyID, "expires_at", h.ExpiresAt)
+	writeJSON(w, http.StatusCreated, h)
+}
+
+// placeHold inserts an active hold. The property row is locked as in
+// reserveBooking, then the user row is locked while counting the user's holds
+// so concurrent requests cannot exceed the limit.
+func (s *Server) placeHold(ctx context.Context, res reservation) (models.BookingHold, error) {
+	var h models.BookingHold
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return h, err
+	}
+	defer tx.Rollback(ctx)
+
+	var capacity int
+	err = tx.QueryRow(ctx,
+		`SELECT guests FROM properties WHERE id = $1 AND hidden_at IS NULL FOR UPDATE`, res.PropertyID,
+	).Scan(&capacity)
+	if err != nil {
+		return h, err
+	}
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, res.UserID); err != nil {
+		return h, err
+	}
+	var active int
+	err = tx.QueryRow(ctx,
+		`SELECT COUNT(*) FROM booking_holds
+		 WHERE user_id = $1 AND status = 'active' AND expires_at > CURRENT_TIMESTAMP`, res.UserID,
+	).Scan(&active)
+	if err != nil {
+		return h, err
+	}
+	if active >= s.maxHoldsPerUser {
+		return h, &booking.Violation{
+			Code:    booking.CodeTooManyHolds,
+			Message: fmt.Sprintf("you can have at most %d active holds", s.maxHoldsPerUser),
+			Details: map[string]any{"max_holds": s.maxHoldsPerUser},
+		}
+	}
+
+	if _, err := checkStay(ctx, tx, res, capacity); err != nil {
+		return h, err
+	}
+
+	err = scanHold(tx.QueryRow(ctx,
+		`INSERT INTO booking_holds (user_id, property_id, start_date, end_date, guests, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP + $6::interval)
+		 RETURNING `+holdColumns,
+		res.UserID, res.PropertyID, res.Start, res.End, res.Guests,
+		fmt.Sprintf("%d seconds", int(s.holdTTL.Seconds())),
+	), &h)
+	if err != nil {
+		return h, err
+	}
+
+	return h, tx.Commit(ctx)
+}
+
+// claimHold marks an active, unexpired hold as converted. It must run in the
+// transaction creating the booking, after the property row has been locked.
+func claimHold(ctx context.Context, tx pgx.Tx, holdID int) error {
+	tag, err := tx.Exec(ctx,
+		`UPDATE booking_holds SET status = 'converted'
+		 WHERE id = $1 AND status = 'active' AND expires_at > CURRENT_TIMESTAMP`, holdID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return &booking.Violation{
+			Code:    booking.CodeHoldNotActive,
+			Message: "hold has expired or was already used",
+		}
+	}
+	return nil
+}
+
+// convertHold turns a hold into a booking and takes payment. If payment fails
+// the hold is reactivated until it expires so the guest can try again.
+func (s *Server) convertHold(w http.ResponseWriter, r *http.Request, user auth.UserContext, id int) {
+	type req struct {
+		PaymentMethod string `json:"payment_method"`
+	}
+	var body req
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if body.PaymentMethod == "" {
+		writeError(w, http.StatusBadRequest, "payment_method is required")
+		return
+	}
+
+	h, ok := s.loadHold(w, r, user, id)
+	if !ok {
+		return
+	}
+	if h.UserID != user.UserID {
+		writeError(w, http.StatusForbidden, "only the guest can convert a hold")
+		return
+	}
+
+	start, end, err := booking.ParseDates(h.StartDate, h.EndDate)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "invalid dates on hold", "component", "holds", "hold_id", h.ID, "error", err)
+		writeError(w, http.StatusInternalServerError, "invalid hold")
+		return
+	}
+
+	b, err := s.reserveBooking(r.Context(), reservation{
+		UserID:     h.UserID,
+		PropertyID: h.PropertyID,
+		Start:      start,
+		End:        end,
+		Guests:     h.Guests,
+		HoldID:     h.ID,
+	})
+	if !s.writeBookingError(w, r, err) {
+		return
+	}
+
+	b, err = s.payForBooking(r.Context(), b, body.PaymentMethod)
+	if err != nil {
+		s.reactivateHold(r.Context(), h.ID)
+		s.writeBookingError(w, r, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "hold converted", "component", "holds", "hold_id", h.ID, "booking_id", b.ID, "status", b.Status)
+	metrics.BookingsCreated.WithLabelValues(b.Status).Inc()
+	writeJSON(w, http.StatusCreated, b)
+}
+
+// reactivateHold returns a converted hold to active after its booking failed
+// to be paid. Expired holds stay converted.
+func (s *Server) reactivateHold(ctx context.Context, holdID int) {
+	ctx = context.WithoutCancel(ctx)
+	_, err := s.db.Pool.Exec(ctx,
+		`UPDATE booking_holds SET status = 'active', booking_id = NULL
+		 WHERE id = $1 AND status = 'converted' AND expires_at > CURRENT_TIMESTAMP`, holdID)
+	if err != nil {
+		slog.ErrorContext(ctx, "database error reactivating hold", "component", "holds", "hold_id", holdID, "error", err)
+	}
+}
+
+// releaseHold frees the dates of an active hold before it expires
+func (s *Server) releaseHold(w http.ResponseWriter, r *http.Request, user auth.UserContext, id int) {
+	h, ok := s.loadHold(w, r, user, id)
+	if !ok {
+		return
+	}
+
+	err := scanHold(s.db.Pool.QueryRow(r.Context(),
+		`UPDATE booking_holds SET status = 'released'
+		 WHERE id = $1 AND status = 'active'
+		 RETURNING `+holdColumns, id), &h)
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeError(w, http.StatusConflict, "hold cannot be released in status "+h.Status)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error releasing hold", "component", "holds", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "hold released", "component", "holds", "hold_id", id)
+	writeJSON(w, http.StatusOK, h)
+}
@@ -0,0 +1,362 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-backend/internal/booking"
+	"go-backend/internal/ical"
+	"go-backend/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// handleICalExport serves the secret per-property feed at /api/ical/{token}.ics
+func (s *Server) handleICalExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/ical/"), ".ics")
+	if token == "" || strings.Contains(token, "/") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	var propertyID int
+	var title string
+	err := s.db.Pool.QueryRow(r.Context(),
+		`SELECT id, title FROM properties WHERE ical_token = $1`, token,
+	).Scan(&propertyID, &title)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "ical", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	events, err := s.exportEvents(r.Context(), propertyID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error building export", "component", "ical", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := ical.Write(&buf, title, events, time.Now()); err != nil {
+		slog.ErrorContext(r.Context(), "error encoding calendar", "component", "ical", "error", err)
+		writeError(w, http.StatusInternalServerError, "could not encode calendar")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="property-%d.ics"`, propertyID))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// exportEvents lists upcoming bookings and host-blocked nights. Nights imported
+// from other platforms are left out so calendars do not echo each other.
+func (s *Server) exportEvents(ctx context.Context, propertyID int) ([]ical.Event, error) {
+	today := booking.Today(time.Now())
+
+	rows, err := s.db.Pool.Query(ctx,
+		`SELECT id, start_date, end_date FROM bookings
+		 WHERE property_id = $1
+		   AND status NOT IN ('cancelled', 'expired')
+		   AND end_date > $2`,
+		propertyID, today)
+	if err != nil {
+		return nil, err
+	}
+	events := []ical.Event{}
+	for rows.Next() {
+		var id int
+		var e ical.Event
+		if err := rows.Scan(&id, &e.Start, &e.End); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		e.UID = fmt.Sprintf("booking-%d@gorent", id)
+		e.Summary = "Reserved"
+		events = append(events, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.Pool.Query(ctx,
+		`SELECT date FROM property_calendar
+		 WHERE property_id = $1 AND feed_id IS NULL AND date >= $2
+		 ORDER BY date`,
+		propertyID, today)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	nights := []time.Time{}
+	for rows.Next() {
+		var night time.Time
+		if err := rows.Scan(&night); err != nil {
+			return nil, err
+		}
+		nights = append(nights, night)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, b := range booking.MergeNights(nights) {
+		events = append(events, ical.Event{
+			UID:     fmt.Sprintf("blocked-%d-%s@gorent", propertyID, b.Start.Format("20060102")),
+			Summary: "Not available",
+			Start:   b.Start,
+			End:     b.End,
+		})
+	}
+	return events, nil
+}
+
+// handlePropertyICal routes /api/properties/{id}/ical[/reset|/feeds[/{feedID}]] (owner only)
+func (s *Server) handlePropertyICal(w http.ResponseWriter, r *http.Request, id int, rest []string) {
+	if _, ok := s.requirePropertyOwner(w, r, id); !ok {
+		return
+	}
+
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodGet:
+		s.getPropertyICal(w, r, id)
+	case len(rest) == 1 && rest[0] == "reset" && r.Method == http.MethodPost:
+		s.resetICalToken(w, r, id)
+	case len(rest) == 1 && rest[0] == "feeds" && r.Method == http.MethodPost:
+		s.createICalFeed(w, r, id)
+	case len(rest) == 2 && rest[0] == "feeds" && r.Method == http.MethodDelete:
+		feedID, err := strconv.Atoi(rest[1])
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid feed id")
+			return
+		}
+		s.deleteICalFeed(w, r, id, feedID)
+	case len(rest) <= 2:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func newICalToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (s *Server) getPropertyICal(w http.ResponseWriter, r *http.Request, id int) {
+	var token *string
+	err := s.db.Pool.QueryRow(r.Context(),
+		`SELECT ical_token FROM properties WHERE id = $1`, id).Scan(&token)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "ical", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	// Tokens are generated the first time the host asks for the export URL
+	if token == nil {
+		newToken, err := newICalToken()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "could not generate token")
+			return
+		}
+		err = s.db.Pool.QueryRow(r.Context(),
+			`UPDATE properties SET ical_token = COALESCE(ical_token, $1) WHERE id = $2 RETURNING ical_token`,
+			newToken, id).Scan(&token)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error saving token", "component", "ical", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+	}
+
+	feeds, err := s.loadICalFeeds(r.Context(), id)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading feeds", "component", "ical", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"export_url": "/api/ical/" + *token + ".ics",
+		"feeds":      feeds,
+	})
+}
+
+func (s *Server) loadICalFeeds(ctx context.Context, propertyID int) ([]models.ICalFeed, error) {
+	rows, err := s.db.Pool.Query(ctx,
+		`SELECT id, property_id, name, url, last_synced_at, last_error, created_at
+		 FROM property_ical_feeds WHERE property_id = $1 ORDER BY id`, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	feeds := []models.ICalFeed{}
+	byID := map[int]int{}
+	for rows.Next() {
+		f := models.ICalFeed{Conflicts: []models.ICalConflict{}}
+		if err := rows.Scan(&f.ID, &f.PropertyID, &f.Name, &f.URL, &f.LastSyncedAt, &f.LastError, &f.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		byID[f.ID] = len(feeds)
+		feeds = append(feeds, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.Pool.Query(ctx,
+		`SELECT c.feed_id, c.booking_id, c.event_uid, c.summary, c.start_date::text, c.end_date::text, c.detected_at
+		 FROM property_ical_conflicts c
+		 JOIN property_ical_feeds f ON f.id = c.feed_id
+		 WHERE f.property_id = $1 ORDER BY c.start_date`, propertyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var feedID int
+		var c models.ICalConflict
+		if err := rows.Scan(&feedID, &c.BookingID, &c.EventUID, &c.Summary, &c.StartDate, &c.EndDate, &c.DetectedAt); err != nil {
+			return nil, err
+		}
+		if i, ok := byID[feedID]; ok {
+			feeds[i].Conflicts = append(feeds[i].Conflicts, c)
+		}
+	}
+	return feeds, rows.Err()
+}
+
+func (s *Server) resetICalToken(w http.ResponseWriter, r *http.Request, id int) {
+	token, err := newICalToken()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "could not generate token")
+		return
+	}
+	if _, err := s.db.Pool.Exec(r.Context(),
+		`UPDATE properties SET ical_token = $1 WHERE id = $2`, token, id); err != nil {
+		slog.ErrorContext(r.Context(), "database error resetting token", "component", "ical", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "export token reset", "component", "ical", "property_id", id)
+	writeJSON(w, http.StatusOK, map[string]string{"export_url": "/api/ical/" + token + ".ics"})
+}
+
+func (s *Server) createICalFeed(w http.ResponseWriter, r *http.Request, id int) {
+	type req struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}
+	var body req
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := ical.ValidateURL(body.URL); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if strings.TrimSpace(body.Name) == "" {
+		u, _ := url.Parse(body.URL)
+		body.Name = u.Host
+	}
+
+	tx, err := s.db.Pool.Begin(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error starting transaction", "component", "ical", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	f := models.ICalFeed{Conflicts: []models.ICalConflict{}}
+	err = tx.QueryRow(r.Context(),
+		`INSERT INTO property_ical_feeds (property_id, name, url)
+		 VALUES ($1, $2, $3)
+		 RETURNING id, property_id, name, url, last_synced_at, last_error, created_at`,
+		id, body.Name, body.URL,
+	).Scan(&f.ID, &f.PropertyID, &f.Name, &f.URL, &f.LastSyncedAt, &f.LastError, &f.CreatedAt)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error creating feed", "component", "ical", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := enqueuePropertyWebhook(r.Context(), tx, id); err != nil {
+		slog.ErrorContext(r.Context(), "database error queueing webhook", "component", "ical", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := tx.Commit(r.Context()); err != nil {
+		slog.ErrorContext(r.Context(), "database error committing", "component", "ical", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "feed registered", "component", "ical", "feed_id", f.ID, "property_id", id)
+	writeJSON(w, http.StatusCreated, f)
+}
+
+func (s *Server) deleteICalFeed(w http.ResponseWriter, r *http.Request, id, feedID int) {
+	tx, err := s.db.Pool.Begin(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error starting transaction", "component", "ical", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	// Imported nights and conflicts are removed by ON DELETE CASCADE
+	tag, err := tx.Exec(r.Context(),
+		`DELETE FROM property_ical_feeds WHERE id = $1 AND property_id = $2`, feedID, id)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error deleting feed", "component", "ical", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		writeError(w, http.StatusNotFound, "feed not found")
+		return
+	}
+	if err := enqueuePropertyWebhook(r.Context(), tx, id); err != nil {
+		slog.ErrorContext(r.Context(), "database error queueing webhook", "component", "ical", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := tx.Commit(r.Context()); err != nil {
+		slog.ErrorContext(r.Context(), "database error committing", "component", "ical", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "feed deleted", "component", "ical", "feed_id", feedID, "property_id", id)
+	writeJSON(w, http.StatusOK, map[string]string{"message": "feed deleted"})
+}
@@ -0,0 +1,330 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"go-backend/internal/auth"
+	"go-backend/internal/ledger"
+	"go-backend/internal/models"
+	"go-backend/internal/payments"
+)
+
+// paymentShares loads what is needed to split a payment between host and
+// platform. A booking may be paid by several payments (e.g. after a
+// modification), so the fee share of each is taken in proportion to the
+// booking's service fee.
+func paymentShares(ctx context.Context, tx pgx.Tx, paymentID int) (bookingID, hostID int, total, fee int64, err error) {
+	var (
+		amount     float64
+		serviceFee float64
+		totalPrice float64
+		ownerID    *int
+	)
+	err = tx.QueryRow(ctx,
+		`SELECT b.id, p.owner_id, pay.amount, b.service_fee, b.total_price
+		 FROM payments pay
+		 JOIN bookings b ON b.id = pay.booking_id
+		 JOIN properties p ON p.id = b.property_id
+		 WHERE pay.id = $1`, paymentID,
+	).Scan(&bookingID, &ownerID, &amount, &serviceFee, &totalPrice)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	if ownerID != nil {
+		hostID = *ownerID
+	}
+	total = payments.ToCents(amount)
+	if totalPrice > 0 {
+		_, fee = ledger.Split(payments.ToCents(totalPrice), payments.ToCents(serviceFee), total)
+	}
+	return bookingID, hostID, total, fee, nil
+}
+
+// postCapture records a captured payment in the ledger
+func postCapture(ctx context.Context, tx pgx.Tx, paymentID int) error {
+	bookingID, hostID, total, fee, err := paymentShares(ctx, tx, paymentID)
+	if err != nil {
+		return err
+	}
+	ref := fmt.Sprintf("capture:payment:%d", paymentID)
+	_, err = ledger.Post(ctx, tx, ledger.Capture(ref, paymentID, bookingID, hostID, total, fee))
+	return err
+}
+
+// postRefund records amount cents refunded on a payment. refundedTotal is the
+// payment's cumulative refunded amount after this refund and makes the posting
+// unique, so a refund seen both in the API response and by webhook posts once.
+func postRefund(ctx context.Context, tx pgx.Tx, paymentID int, refundedTotal, amount int64) error {
+	if amount <= 0 {
+		return nil
+	}
+	bookingID, hostID, total, fee, err := paymentShares(ctx, tx, paymentID)
+	if err != nil {
+		return err
+	}
+	ref := fmt.Sprintf("refund:payment:%d:%d", paymentID, refundedTotal)
+	_, err = ledger.Post(ctx, tx, ledger.Refund(ref, paymentID, bookingID, hostID, total, fee, amount))
+	return err
+}
+
+// handleHostEarnings returns the signed-in host's earnings per booking
+func (s *Server) handleHostEarnings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	user, err := auth.UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	rows, err := s.db.Pool.Query(r.Context(),
+		`SELECT b.id, b.property_id, COALESCE(p.title, ''), b.start_date::text,
+		 (b.start_date + $2::int)::text, b.start_date + $2::int <= CURRENT_DATE,
+		 COALESCE(SUM(e.credit) FILTER (WHERE j.kind = 'capture'), 0)::bigint,
+		 COALESCE(SUM(e.debit) FILTER (WHERE j.kind = 'refund'), 0)::bigint,
+		 COALESCE(SUM(e.debit) FILTER (WHERE j.kind = 'payout'), 0)::bigint
+		 FROM ledger_entries e
+		 JOIN ledger_journals j ON j.id = e.journal_id
+		 JOIN bookings b ON b.id = e.booking_id
+		 LEFT JOIN properties p ON p.id = b.property_id
+		 WHERE e.account = $3 AND e.host_id = $1
+		 GROUP BY b.id, p.title
+		 ORDER BY b.start_date DESC`,
+		user.UserID, s.payoutDelayDays, ledger.AccountHostPayable)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading earnings", "component", "ledger", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+
+	earnings := models.HostEarnings{
+		Currency:        s.currency,
+		PayoutDelayDays: s.payoutDelayDays,
+		Bookings:        []models.BookingEarnings{},
+	}
+	var earned, refunded, paidOut, available int64
+	for rows.Next() {
+		var (
+			b                 models.BookingEarnings
+			released          bool
+			cEarned, cRefund  int64
+			cPaidOut, balance int64
+		)
+		err := rows.Scan(&b.BookingID, &b.PropertyID, &b.PropertyTitle, &b.StartDate,
+			&b.ReleaseDate, &released, &cEarned, &cRefund, &cPaidOut)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "scan error", "component", "ledger", "error", err)
+			writeError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		balance = cEarned - cRefund - cPaidOut
+		b.Earned = payments.FromCents(cEarned)
+		b.Refunded = payments.FromCents(cRefund)
+		b.PaidOut = payments.FromCents(cPaidOut)
+		b.Balance = payments.FromCents(balance)
+		earnings.Bookings = append(earnings.Bookings, b)
+
+		earned += cEarned
+		refunded += cRefund
+		paidOut += cPaidOut
+		if released && balance > 0 {
+			available += balance
+		}
+	}
+	if err := rows.Err(); err != nil {
+		slog.ErrorContext(r.Context(), "database error loading earnings", "component", "ledger", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	earnings.Earned = payments.FromCents(earned)
+	earnings.Refunded = payments.FromCents(refunded)
+	earnings.PaidOut = payments.FromCents(paidOut)
+	earnings.Balance = payments.FromCents(earned - refunded - paidOut)
+	earnings.Available = payments.FromCents(available)
+	writeJSON(w, http.StatusOK, earnings)
+}
+
+// handleHostPayouts lists the payouts released to the signed-in host
+func (s *Server) handleHostPayouts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	user, err := auth.UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	rows, err := s.db.Pool.Query(r.Context(),
+		`SELECT id, host_id, amount, currency, status, created_at
+		 FROM host_payouts WHERE host_id = $1 ORDER BY created_at DESC`, user.UserID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading payouts", "component", "ledger", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+
+	payouts := []models.HostPayout{}
+	index := map[int]int{}
+	for rows.Next() {
+		var p models.HostPayout
+		if err := rows.Scan(&p.ID, &p.HostID, &p.Amount, &p.Currency, &p.Status, &p.CreatedAt); err != nil {
+			slog.ErrorContext(r.Context(), "scan error", "component", "ledger", "error", err)
+			writeError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		p.Items = []models.HostPayoutItem{}
+		index[p.ID] = len(payouts)
+		payouts = append(payouts, p)
+	}
+	rows.Close()
+
+	itemRows, err := s.db.Pool.Query(r.Context(),
+		`SELECT i.payout_id, i.booking_id, i.amount
+		 FROM host_payout_items i JOIN host_payouts p ON p.id = i.payout_id
+		 WHERE p.host_id = $1 ORDER BY i.booking_id`, user.UserID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading payout items", "component", "ledger", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		var payoutID int
+		var item models.HostPayoutItem
+		if err := itemRows.Scan(&payoutID, &item.BookingID, &item.Amount); err != nil {
+			slog.ErrorContext(r.Context(), "scan error", "component", "ledger", "error", err)
+			writeError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		if i, ok := index[payoutID]; ok {
+			payouts[i].Items = append(payouts[i].Items, item)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, payouts)
+}
+
+// handleAdminReconciliation reports account balances and any ledger postings
+// that disagree with the payments table
+func (s *Server) handleAdminReconciliation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := r.Context()
+	report := models.Reconciliation{
+		GeneratedAt:        time.Now(),
+		Accounts:           []models.AccountBalance{},
+		UnbalancedJournals: []int{},
+		PaymentMismatches:  []models.PaymentMismatch{},
+	}
+
+	// Trial balance
+	rows, err := s.db.Pool.Query(ctx,
+		`SELECT account, SUM(debit)::bigint, SUM(credit)::bigint FROM ledger_entries GROUP BY account ORDER BY account`)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "reconciliation", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	var totalDebits, totalCredits int64
+	for rows.Next() {
+		var account string
+		var debits, credits int64
+		if err := rows.Scan(&account, &debits, &credits); err != nil {
+			rows.Close()
+			slog.ErrorContext(r.Context(), "scan error", "component", "reconciliation", "error", err)
+			writeError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		totalDebits += debits
+		totalCredits += credits
+		report.Accounts = append(report.Accounts, models.AccountBalance{
+			Account: account,
+			Debits:  payments.FromCents(debits),
+			Credits: payments.FromCents(credits),
+			Balance: payments.FromCents(credits - debits),
+		})
+	}
+	rows.Close()
+
+	// Journals that do not balance on their own
+	rows, err = s.db.Pool.Query(ctx,
+		`SELECT journal_id FROM ledger_entries
+		 GROUP BY journal_id HAVING SUM(debit) <> SUM(credit) ORDER BY journal_id`)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "reconciliation", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			slog.ErrorContext(r.Context(), "scan error", "component", "reconciliation", "error", err)
+			writeError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		report.UnbalancedJournals = append(report.UnbalancedJournals, id)
+	}
+	rows.Close()
+
+	// Payments whose captured or refunded amounts are not reflected in the ledger
+	rows, err = s.db.Pool.Query(ctx,
+		`SELECT pay.id, pay.booking_id, pay.status,
+		 CASE WHEN pay.status IN ('captured', 'partially_refunded', 'refunded')
+		      THEN ROUND(pay.amount * 100)::bigint ELSE 0 END AS captured,
+		 ROUND(pay.refunded_amount * 100)::bigint AS refunded,
+		 COALESCE(SUM(e.debit) FILTER (WHERE j.kind = 'capture' AND e.account = $1), 0)::bigint AS ledger_captured,
+		 COALESCE(SUM(e.credit) FILTER (WHERE j.kind = 'refund' AND e.account = $2), 0)::bigint AS ledger_refunded
+		 FROM payments pay
+		 LEFT JOIN ledger_journals j ON j.payment_id = pay.id
+		 LEFT JOIN ledger_entries e ON e.journal_id = j.id
+		 GROUP BY pay.id
+		 HAVING CASE WHEN pay.status IN ('captured', 'partially_refunded', 'refunded')
+		             THEN ROUND(pay.amount * 100)::bigint ELSE 0 END
+		        <> COALESCE(SUM(e.debit) FILTER (WHERE j.kind = 'capture' AND e.account = $1), 0)::bigint
+		     OR ROUND(pay.refunded_amount * 100)::bigint
+		        <> COALESCE(SUM(e.credit) FILTER (WHERE j.kind = 'refund' AND e.account = $2), 0)::bigint
+		 ORDER BY pay.id`,
+		ledger.AccountGuestPayments, ledger.AccountRefunds)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "reconciliation", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m models.PaymentMismatch
+		var captured, refunded, ledgerCaptured, ledgerRefunded int64
+		err := rows.Scan(&m.PaymentID, &m.BookingID, &m.Status,
+			&captured, &refunded, &ledgerCaptured, &ledgerRefunded)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "scan error", "component", "reconciliation", "error", err)
+			writeError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		m.Captured = payments.FromCents(captured)
+		m.Refunded = payments.FromCents(refunded)
+		m.LedgerCaptured = payments.FromCents(ledgerCaptured)
+		m.LedgerRefunded = payments.FromCents(ledgerRefunded)
+		report.PaymentMismatches = append(report.PaymentMismatches, m)
+	}
+
+	report.Balanced = totalDebits == totalCredits && len(report.UnbalancedJournals) == 0
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,145 @@
+package httpapi
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+
+	"go-backend/internal/auth"
+	"go-backend/internal/models"
+)
+
+// checkInBooking records the guest's arrival. The guest, the host or an admin
+// may check in once the check-in day has been reached in the property's time
+// zone; this also clears a no-show flag set by the lifecycle worker.
+func (s *Server) checkInBooking(w http.ResponseWriter, r *http.Request, user auth.UserContext, id int) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error starting transaction", "component", "bookings", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		guestID int
+		ownerID *int
+		status  string
+		arrived bool
+	)
+	err = tx.QueryRow(ctx,
+		`SELECT b.user_id, p.owner_id, b.status,
+		 b.start_date <= (CURRENT_TIMESTAMP AT TIME ZONE p.timezone)::date
+		 FROM bookings b JOIN properties p ON p.id = b.property_id
+		 WHERE b.id = $1 FOR UPDATE OF b`, id,
+	).Scan(&guestID, &ownerID, &status, &arrived)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading booking", "component", "bookings", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	isHost := ownerID != nil && *ownerID == user.UserID
+	if guestID != user.UserID && !isHost && user.Role != auth.RoleAdmin {
+		writeError(w, http.StatusForbidden, "access denied")
+		return
+	}
+	if status != "confirmed" && status != "in_progress" {
+		writeError(w, http.StatusConflict, "booking cannot be checked in from status "+status)
+		return
+	}
+	if !arrived {
+		writeError(w, http.StatusConflict, "check-in day has not been reached")
+		return
+	}
+
+	var b models.Booking
+	err = scanBooking(tx.QueryRow(ctx,
+		`UPDATE bookings
+		 SET status = 'in_progress', checked_in_at = COALESCE(checked_in_at, CURRENT_TIMESTAMP), no_show = false
+		 WHERE id = $1
+		 RETURNING `+bookingColumns, id), &b)
+	var firstCheckIn bool
+	if err == nil {
+		err = tx.QueryRow(ctx,
+			`WITH recorded AS (
+			   INSERT INTO booking_events (booking_id, type) VALUES ($1, $2), ($1, $3)
+			   ON CONFLICT (booking_id, type) DO NOTHING
+			   RETURNING type
+			 )
+			 SELECT EXISTS(SELECT 1 FROM recorded WHERE type = $3)`,
+			id, models.BookingEventStarted, models.BookingEventCheckedIn).Scan(&firstCheckIn)
+	}
+	if err == nil && firstCheckIn {
+		err = notifyBooking(ctx, tx, id, bookingCheckedIn, user.UserID)
+	}
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error checking in booking", "component", "bookings", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "booking checked in", "component", "bookings", "booking_id", id)
+	writeJSON(w, http.StatusOK, b)
+}
+
+// listBookingEvents returns the lifecycle events of a booking
+func (s *Server) listBookingEvents(w http.ResponseWriter, r *http.Request, user auth.UserContext, id int) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	guestID, ownerID, err := bookingParties(r.Context(), s.db.Pool, id)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading booking", "component", "bookings", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	isHost := ownerID != nil && *ownerID == user.UserID
+	if guestID != user.UserID && !isHost && user.Role != auth.RoleAdmin {
+		writeError(w, http.StatusForbidden, "access denied")
+		return
+	}
+
+	rows, err := s.db.Pool.Query(r.Context(),
+		`SELECT id, booking_id, type, created_at FROM booking_events
+		 WHERE booking_id = $1 ORDER BY created_at, id`, id)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading events", "component", "bookings", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+
+	events := []models.BookingEvent{}
+	for rows.Next() {
+		var e models.BookingEvent
+		if err := rows.Scan(&e.ID, &e.BookingID, &e.Type, &e.CreatedAt); err != nil {
+			slog.ErrorContext(r.Context(), "scan error", "component", "bookings", "error", err)
+			writeError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		events = append(events, e)
+	}
+
+	writeJSON(w, http.StatusOK, events)
+}
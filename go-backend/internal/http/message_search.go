@@ -0,0 +1,145 @@
+package httpapi
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-backend/internal/auth"
+	"go-backend/internal/booking"
+	"go-backend/internal/models"
+)
+
+// maxSearchQueryLength limits the length of a message search query
+const maxSearchQueryLength = 200
+
+// searchSnippet highlights the matches of query q in the HTML-escaped text of
+// message m, so clients can render it without trusting the message
+const searchSnippet = `ts_headline('english',
+	replace(replace(replace(m.text, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), q,
+	'StartSel=<mark>, StopSel=</mark>, MinWords=10, MaxWords=30, MaxFragments=2')`
+
+// handleMessageSearch searches the messages of the user's conversations,
+// including archived ones. ?q= uses web search syntax ("quoted phrases",
+// -excluded, or); results can be limited to ?property_id= and a ?from= and
+// ?to= date range, and are ordered by relevance or, with ?sort=recent, newest
+// first.
+func (s *Server) handleMessageSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := auth.UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	q := r.URL.Query()
+	text := strings.TrimSpace(q.Get("q"))
+	if text == "" {
+		writeError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+	if len(text) > maxSearchQueryLength {
+		writeError(w, http.StatusBadRequest, "q is limited to 200 characters")
+		return
+	}
+	limit, offset, ok := parsePagination(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "limit must be positive and offset must not be negative")
+		return
+	}
+
+	query := `SELECT ` + messageColumns + `, c.property_id, COALESCE(p.title, ''), COALESCE(u.name, ''),
+		` + searchSnippet + `
+		FROM messages m
+		JOIN conversations c ON c.id = m.conversation_id
+		LEFT JOIN properties p ON p.id = c.property_id
+		LEFT JOIN users u ON u.id = CASE WHEN c.guest_id = $1 THEN c.host_id ELSE c.guest_id END
+		CROSS JOIN websearch_to_tsquery('english', $2) q
+		WHERE $1 IN (c.guest_id, c.host_id) AND m.hidden_at IS NULL AND m.search_vector @@ q`
+	args := []any{user.UserID, text}
+
+	if v := q.Get("property_id"); v != "" {
+		propertyID, err := strconv.Atoi(v)
+		if err != nil || propertyID <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid property_id")
+			return
+		}
+		args = append(args, propertyID)
+		query += ` AND c.property_id = $` + strconv.Itoa(len(args))
+	}
+
+	var from, to time.Time
+	if v := q.Get("from"); v != "" {
+		if from, err = time.Parse(booking.DateLayout, v); err != nil {
+			writeError(w, http.StatusBadRequest, "from must be in YYYY-MM-DD format")
+			return
+		}
+		args = append(args, from)
+		query += ` AND m.created_at >= $` + strconv.Itoa(len(args))
+	}
+	if v := q.Get("to"); v != "" {
+		if to, err = time.Parse(booking.DateLayout, v); err != nil {
+			writeError(w, http.StatusBadRequest, "to must be in YYYY-MM-DD format")
+			return
+		}
+		if !from.IsZero() && to.Before(from) {
+			writeError(w, http.StatusBadRequest, "to must not be before from")
+			return
+		}
+		// to is inclusive
+		args = append(args, to.AddDate(0, 0, 1))
+		query += ` AND m.created_at < $` + strconv.Itoa(len(args))
+	}
+
+	switch q.Get("sort") {
+	case "", "relevance":
+		query += ` ORDER BY ts_rank(m.search_vector, q) DESC, m.id DESC`
+	case "recent":
+		query += ` ORDER BY m.id DESC`
+	default:
+		writeError(w, http.StatusBadRequest, "sort must be relevance or recent")
+		return
+	}
+	args = append(args, limit, offset)
+	query += ` LIMIT $` + strconv.Itoa(len(args)-1) + ` OFFSET $` + strconv.Itoa(len(args))
+
+	rows, err := s.db.Pool.Query(r.Context(), query, args...)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error searching messages", "component", "messages", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+
+	results := []models.MessageSearchResult{}
+	var messages []models.Message
+	for rows.Next() {
+		var res models.MessageSearchResult
+		err := scanMessage(rows, &res.Message, &res.PropertyID, &res.PropertyName, &res.ContactName, &res.Snippet)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "scan error", "component", "messages", "error", err)
+			writeError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		results = append(results, res)
+		messages = append(messages, res.Message)
+	}
+	rows.Close()
+
+	if err := loadAttachments(r.Context(), s.db.Pool, messages); err != nil {
+		slog.ErrorContext(r.Context(), "database error loading attachments", "component", "messages", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	for i := range results {
+		results[i].Message.Attachments = messages[i].Attachments
+	}
+
+	writeJSON(w, http.StatusOK, results)
+}
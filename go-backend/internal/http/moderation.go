@@ -0,0 +1,662 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+
+	"go-backend/internal/auth"
+	"go-backend/internal/models"
+	"go-backend/internal/moderation"
+	"go-backend/internal/review"
+)
+
+// reportColumns is the column list read by scanReport, selected FROM reports r
+const reportColumns = `r.id, r.reporter_id, r.target_type, r.target_id, r.reason, r.details, r.flags,
+	r.status, r.resolved_by, r.resolved_at, r.created_at`
+
+// scanReport scans a row selected with reportColumns
+func scanReport(row pgx.Row, rp *models.Report) error {
+	return row.Scan(&rp.ID, &rp.ReporterID, &rp.TargetType, &rp.TargetID, &rp.Reason, &rp.Details, &rp.Flags,
+		&rp.Status, &rp.ResolvedBy, &rp.ResolvedAt, &rp.CreatedAt)
+}
+
+// actionColumns is the column list read by scanAction
+const actionColumns = `id, report_id, target_type, target_id, action, actor_id, subject_user_id, note, created_at`
+
+// scanAction scans a row selected with actionColumns
+func scanAction(row pgx.Row, a *models.ModerationAction) error {
+	return row.Scan(&a.ID, &a.ReportID, &a.TargetType, &a.TargetID, &a.Action, &a.ActorID, &a.SubjectUserID,
+		&a.Note, &a.CreatedAt)
+}
+
+// targetOwnerQueries select the user responsible for a target: the author of
+// a review, the owner of a listing, the sender of a message or the user itself
+var targetOwnerQueries = map[string]string{
+	moderation.TargetReview:      `SELECT author_id FROM reviews WHERE id = $1`,
+	moderation.TargetGuestReview: `SELECT author_id FROM guest_reviews WHERE id = $1`,
+	moderation.TargetListing:     `SELECT owner_id FROM properties WHERE id = $1`,
+	moderation.TargetMessage:     `SELECT sender_id FROM messages WHERE id = $1`,
+	moderation.TargetUser:        `SELECT id FROM users WHERE id = $1`,
+}
+
+// targetOwner returns the user responsible for a target, nil for content
+// without one (e.g. a listing whose owner was deleted)
+func targetOwner(ctx context.Context, q querier, targetType string, targetID int) (*int, error) {
+	var ownerID *int
+	err := q.QueryRow(ctx, targetOwnerQueries[targetType], targetID).Scan(&ownerID)
+	return ownerID, err
+}
+
+// queuePreview selects the reported text, or the name of a reported user
+const queuePreview = `COALESCE(CASE r.target_type
+	WHEN 'review' THEN (SELECT comment FROM reviews WHERE id = r.target_id)
+	WHEN 'guest_review' THEN (SELECT comment FROM guest_reviews WHERE id = r.target_id)
+	WHEN 'listing' THEN (SELECT title || E'\n' || location FROM properties WHERE id = r.target_id)
+	WHEN 'message' THEN (SELECT text FROM messages WHERE id = r.target_id)
+	WHEN 'user' THEN (SELECT name FROM users WHERE id = r.target_id)
+	END, '')`
+
+// queueHidden selects whether reported content is hidden or a reported user suspended
+const queueHidden = `COALESCE(CASE r.target_type
+	WHEN 'review' THEN (SELECT hidden_at IS NOT NULL FROM reviews WHERE id = r.target_id)
+	WHEN 'guest_review' THEN (SELECT hidden_at IS NOT NULL FROM guest_reviews WHERE id = r.target_id)
+	WHEN 'listing' THEN (SELECT hidden_at IS NOT NULL FROM properties WHERE id = r.target_id)
+	WHEN 'message' THEN (SELECT hidden_at IS NOT NULL FROM messages WHERE id = r.target_id)
+	WHEN 'user' THEN (SELECT suspended_at IS NOT NULL FROM users WHERE id = r.target_id)
+	END, false)`
+
+// checkUserActive is the auth middleware's user check, rejecting tokens of
+// users suspended after the token was issued
+func (s *Server) checkUserActive(ctx context.Context, userID int) error {
+	var suspended bool
+	err := s.db.Pool.QueryRow(ctx,
+		`SELECT suspended_at IS NOT NULL FROM users WHERE id = $1`, userID,
+	).Scan(&suspended)
+	if err == pgx.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if suspended {
+		return auth.ErrAccountSuspended
+	}
+	return nil
+}
+
+// handleReports lists the user's own reports and files new ones
+func (s *Server) handleReports(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		limit, offset, ok := parsePagination(r)
+		if !ok {
+			writeError(w, http.StatusBadRequest, "limit must be positive and offset must not be negative")
+			return
+		}
+		rows, err := s.db.Pool.Query(r.Context(),
+			`SELECT `+reportColumns+` FROM reports r WHERE r.reporter_id = $1
+			 ORDER BY r.created_at DESC, r.id DESC LIMIT $2 OFFSET $3`, user.UserID, limit, offset)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error", "component", "moderation", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		defer rows.Close()
+
+		reports := []models.Report{}
+		for rows.Next() {
+			var rp models.Report
+			if err := scanReport(rows, &rp); err != nil {
+				slog.ErrorContext(r.Context(), "scan error", "component", "moderation", "error", err)
+				writeError(w, http.StatusInternalServerError, "scan error")
+				return
+			}
+			reports = append(reports, rp)
+		}
+		writeJSON(w, http.StatusOK, reports)
+
+	case http.MethodPost:
+		s.createReport(w, r, user)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// createReport files a user's report of a review, listing, message or user.
+// Users can only report what they can see, cannot report themselves or their
+// own content, and can have one open report per target.
+func (s *Server) createReport(w http.ResponseWriter, r *http.Request, user auth.UserContext) {
+	type req struct {
+		TargetType string `json:"target_type"`
+		TargetID   int    `json:"target_id"`
+		Reason     string `json:"reason"`
+		Details    string `json:"details"`
+	}
+	var body req
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if !moderation.ValidTarget(body.TargetType) || body.TargetID <= 0 {
+		writeError(w, http.StatusBadRequest, "target_type must be review, guest_review, listing, message or user and target_id is required")
+		return
+	}
+	if !moderation.ValidReason(body.Reason) {
+		writeError(w, http.StatusBadRequest, "reason must be spam, offensive, harassment, fraud, inappropriate, off_platform or other")
+		return
+	}
+	details := strings.TrimSpace(body.Details)
+	if utf8.RuneCountInString(details) > moderation.MaxDetailsLength {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("details must be at most %d characters", moderation.MaxDetailsLength))
+		return
+	}
+	if body.Reason == moderation.ReasonOther && details == "" {
+		writeError(w, http.StatusBadRequest, "details are required when reason is other")
+		return
+	}
+
+	ownerID, err := s.reportableTarget(r.Context(), user.UserID, body.TargetType, body.TargetID)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading report target", "component", "moderation", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if ownerID != nil && *ownerID == user.UserID {
+		writeError(w, http.StatusBadRequest, "you cannot report yourself or your own content")
+		return
+	}
+
+	var rp models.Report
+	err = scanReport(s.db.Pool.QueryRow(r.Context(),
+		`INSERT INTO reports AS r (reporter_id, target_type, target_id, reason, details)
+		 VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		 ON CONFLICT (reporter_id, target_type, target_id) WHERE status = 'open' AND reporter_id IS NOT NULL DO NOTHING
+		 RETURNING `+reportColumns,
+		user.UserID, body.TargetType, body.TargetID, body.Reason, details,
+	), &rp)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusConflict, "you have already reported this")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error creating report", "component", "moderation", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "report filed",
+		"component", "moderation", "report_id", rp.ID, "target_type", rp.TargetType, "target_id", rp.TargetID, "reason", rp.Reason)
+	writeJSON(w, http.StatusCreated, rp)
+}
+
+// reportableTarget returns the owner of a target the user can see, or
+// pgx.ErrNoRows if it does not exist or is not visible to them: reviews must
+// be published, listings not hidden and messages in one of their conversations
+func (s *Server) reportableTarget(ctx context.Context, userID int, targetType string, targetID int) (*int, error) {
+	var (
+		ownerID *int
+		visible bool
+	)
+	var err error
+	switch targetType {
+	case moderation.TargetReview:
+		err = s.db.Pool.QueryRow(ctx,
+			`SELECT author_id, published_at IS NOT NULL FROM reviews WHERE id = $1`, targetID,
+		).Scan(&ownerID, &visible)
+	case moderation.TargetGuestReview:
+		err = s.db.Pool.QueryRow(ctx,
+			`SELECT author_id, published_at IS NOT NULL FROM guest_reviews WHERE id = $1`, targetID,
+		).Scan(&ownerID, &visible)
+	case moderation.TargetListing:
+		err = s.db.Pool.QueryRow(ctx,
+			`SELECT owner_id, hidden_at IS NULL FROM properties WHERE id = $1`, targetID,
+		).Scan(&ownerID, &visible)
+	case moderation.TargetMessage:
+		err = s.db.Pool.QueryRow(ctx,
+			`SELECT m.sender_id, $2 IN (c.guest_id, c.host_id) FROM messages m
+			 JOIN conversations c ON c.id = m.conversation_id WHERE m.id = $1`, targetID, userID,
+		).Scan(&ownerID, &visible)
+	default:
+		ownerID, err = targetOwner(ctx, s.db.Pool, targetType, targetID)
+		visible = true
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, pgx.ErrNoRows
+	}
+	return ownerID, nil
+}
+
+// prescreen runs the automatic pre-screen over newly written text. Anything
+// flagged opens an automatic report, or adds to the target's open one, and is
+// recorded in the audit trail. Errors are only logged so screening never
+// blocks the write that triggered it.
+func (s *Server) prescreen(ctx context.Context, targetType string, targetID int, text string) {
+	s.fileAutomaticReport(ctx, targetType, targetID, moderation.ActionAutoFlag, s.screener.Screen(text))
+}
+
+// auditVerbs describe automatic actions in the audit trail notes
+var auditVerbs = map[string]string{
+	moderation.ActionAutoFlag: "flagged",
+	moderation.ActionMask:     "masked",
+}
+
+// fileAutomaticReport opens or extends the automatic report of a target with
+// flags and records action in the audit trail
+func (s *Server) fileAutomaticReport(ctx context.Context, targetType string, targetID int, action string, flags []moderation.Flag) {
+	if len(flags) == 0 {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	matches := make([]string, len(flags))
+	for i, f := range flags {
+		matches[i] = f.Kind + ": " + f.Match
+	}
+	details := strings.Join(matches, "\n")
+	kinds := moderation.Kinds(flags)
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "database error starting pre-screen", "component", "moderation", "error", err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var reportID int
+	err = tx.QueryRow(ctx,
+		`INSERT INTO reports (target_type, target_id, reason, details, flags)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (target_type, target_id) WHERE status = 'open' AND reporter_id IS NULL DO UPDATE SET
+		   details = EXCLUDED.details,
+		   flags = ARRAY(SELECT DISTINCT f FROM unnest(reports.flags || EXCLUDED.flags) f ORDER BY f)
+		 RETURNING id`,
+		targetType, targetID, moderation.ReasonAutoFlagged, details, kinds,
+	).Scan(&reportID)
+	if err != nil {
+		slog.ErrorContext(ctx, "database error filing automatic report",
+			"component", "moderation", "target_type", targetType, "target_id", targetID, "error", err)
+		return
+	}
+
+	ownerID, err := targetOwner(ctx, tx, targetType, targetID)
+	if err != nil {
+		slog.ErrorContext(ctx, "database error loading owner",
+			"component", "moderation", "target_type", targetType, "target_id", targetID, "error", err)
+		return
+	}
+	_, err = tx.Exec(ctx,
+		`INSERT INTO moderation_actions (report_id, target_type, target_id, action, subject_user_id, note)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		reportID, targetType, targetID, action, ownerID, auditVerbs[action]+" "+strings.Join(kinds, ", "))
+	if err != nil {
+		slog.ErrorContext(ctx, "database error recording pre-screen",
+			"component", "moderation", "target_type", targetType, "target_id", targetID, "error", err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(ctx, "database error committing pre-screen", "component", "moderation", "error", err)
+		return
+	}
+	slog.InfoContext(ctx, "automatic report filed",
+		"component", "moderation", "target_type", targetType, "target_id", targetID, "report_id", reportID, "action", action, "flags", kinds)
+}
+
+// handleModerationQueue lists reports for moderators with a preview of what
+// was reported. Open reports are listed oldest first.
+func (s *Server) handleModerationQueue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, offset, ok := parsePagination(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "limit must be positive and offset must not be negative")
+		return
+	}
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = moderation.StatusOpen
+	case moderation.StatusOpen, moderation.StatusResolved, moderation.StatusDismissed:
+	default:
+		writeError(w, http.StatusBadRequest, "status must be open, resolved or dismissed")
+		return
+	}
+
+	query := `SELECT ` + reportColumns + `, ` + queuePreview + `, ` + queueHidden + `,
+		(SELECT COUNT(*) FROM reports o
+		 WHERE o.target_type = r.target_type AND o.target_id = r.target_id AND o.status = 'open')
+		FROM reports r WHERE r.status = $1`
+	args := []any{status}
+	if targetType := r.URL.Query().Get("target_type"); targetType != "" {
+		if !moderation.ValidTarget(targetType) {
+			writeError(w, http.StatusBadRequest, "invalid target_type")
+			return
+		}
+		args = append(args, targetType)
+		query += ` AND r.target_type = $` + strconv.Itoa(len(args))
+	}
+	if status == moderation.StatusOpen {
+		query += ` ORDER BY r.created_at, r.id`
+	} else {
+		query += ` ORDER BY r.resolved_at DESC, r.id DESC`
+	}
+	args = append(args, limit, offset)
+	query += ` LIMIT $` + strconv.Itoa(len(args)-1) + ` OFFSET $` + strconv.Itoa(len(args))
+
+	rows, err := s.db.Pool.Query(r.Context(), query, args...)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "moderation", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+
+	queue := []models.QueuedReport{}
+	for rows.Next() {
+		var q models.QueuedReport
+		err := rows.Scan(&q.ID, &q.ReporterID, &q.TargetType, &q.TargetID, &q.Reason, &q.Details, &q.Flags,
+			&q.Status, &q.ResolvedBy, &q.ResolvedAt, &q.CreatedAt, &q.Preview, &q.Hidden, &q.OpenReports)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "scan error", "component", "moderation", "error", err)
+			writeError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		queue = append(queue, q)
+	}
+
+	writeJSON(w, http.StatusOK, queue)
+}
+
+// errNoSubject is returned when warning or suspending the owner of content
+// that has none
+var errNoSubject = errors.New("target has no user to act on")
+
+// errSuspendAdmin is returned when suspending an admin
+var errSuspendAdmin = errors.New("admins cannot be suspended")
+
+// handleModerationActions applies a moderator action to a target, closes its
+// open reports and records the action in the audit trail
+func (s *Server) handleModerationActions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := auth.UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	type req struct {
+		ReportID   int    `json:"report_id"`
+		TargetType string `json:"target_type"`
+		TargetID   int    `json:"target_id"`
+		Action     string `json:"action"`
+		Note       string `json:"note"`
+	}
+	var body req
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	note := strings.TrimSpace(body.Note)
+	if utf8.RuneCountInString(note) > moderation.MaxDetailsLength {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("note must be at most %d characters", moderation.MaxDetailsLength))
+		return
+	}
+
+	ctx := r.Context()
+	var reportID *int
+	if body.ReportID > 0 {
+		var rp models.Report
+		err := scanReport(s.db.Pool.QueryRow(ctx,
+			`SELECT `+reportColumns+` FROM reports r WHERE r.id = $1`, body.ReportID), &rp)
+		if err == pgx.ErrNoRows {
+			writeError(w, http.StatusNotFound, "report not found")
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error loading report", "component", "moderation", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		if body.TargetType == "" && body.TargetID == 0 {
+			body.TargetType, body.TargetID = rp.TargetType, rp.TargetID
+		} else if body.TargetType != rp.TargetType || body.TargetID != rp.TargetID {
+			writeError(w, http.StatusBadRequest, "target does not match the report")
+			return
+		}
+		reportID = &rp.ID
+	}
+	if err := moderation.CheckAction(body.TargetType, body.Action); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error starting transaction", "component", "moderation", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	subjectID, err := applyModerationAction(ctx, tx, body.TargetType, body.TargetID, body.Action)
+	switch {
+	case err == pgx.ErrNoRows:
+		writeError(w, http.StatusNotFound, "target not found")
+		return
+	case errors.Is(err, errNoSubject), errors.Is(err, errSuspendAdmin):
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "database error applying action",
+			"component", "moderation", "action", body.Action, "target_type", body.TargetType, "target_id", body.TargetID, "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	tag, err := tx.Exec(ctx,
+		`UPDATE reports SET status = $1, resolved_by = $2, resolved_at = CURRENT_TIMESTAMP
+		 WHERE target_type = $3 AND target_id = $4 AND status = 'open'`,
+		moderation.ReportStatus(body.Action), user.UserID, body.TargetType, body.TargetID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error closing reports", "component", "moderation", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	var a models.ModerationAction
+	err = scanAction(tx.QueryRow(ctx,
+		`INSERT INTO moderation_actions (report_id, target_type, target_id, action, actor_id, subject_user_id, note)
+		 VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''))
+		 RETURNING `+actionColumns,
+		reportID, body.TargetType, body.TargetID, body.Action, user.UserID, subjectID, note,
+	), &a)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error recording action", "component", "moderation", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(r.Context(), "database error committing action", "component", "moderation", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "moderation action applied",
+		"component", "moderation", "action", a.Action, "target_type", a.TargetType, "target_id", a.TargetID, "reports_closed", tag.RowsAffected())
+	writeJSON(w, http.StatusOK, map[string]any{
+		"action":         a,
+		"reports_closed": tag.RowsAffected(),
+	})
+}
+
+// applyModerationAction changes the target for an action and returns the user
+// the action concerns. Hiding or restoring a review recomputes the ratings it
+// counts towards.
+func applyModerationAction(ctx context.Context, tx pgx.Tx, targetType string, targetID int, action string) (*int, error) {
+	subjectID, err := targetOwner(ctx, tx, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch action {
+	case moderation.ActionHide, moderation.ActionRestore:
+		if targetType == moderation.TargetUser {
+			// Restoring a user lifts their suspension
+			_, err = tx.Exec(ctx, `UPDATE users SET suspended_at = NULL WHERE id = $1`, targetID)
+			return subjectID, err
+		}
+		return subjectID, setHidden(ctx, tx, targetType, targetID, action == moderation.ActionHide)
+
+	case moderation.ActionWarn:
+		if subjectID == nil {
+			return nil, errNoSubject
+		}
+		_, err = tx.Exec(ctx, `UPDATE users SET warnings = warnings + 1 WHERE id = $1`, *subjectID)
+		return subjectID, err
+
+	case moderation.ActionSuspend:
+		if subjectID == nil {
+			return nil, errNoSubject
+		}
+		var role string
+		if err := tx.QueryRow(ctx, `SELECT role FROM users WHERE id = $1 FOR UPDATE`, *subjectID).Scan(&role); err != nil {
+			return nil, err
+		}
+		if role == auth.RoleAdmin {
+			return nil, errSuspendAdmin
+		}
+		_, err = tx.Exec(ctx,
+			`UPDATE users SET suspended_at = COALESCE(suspended_at, CURRENT_TIMESTAMP) WHERE id = $1`, *subjectID)
+		return subjectID, err
+	}
+
+	// Dismissing only closes the reports
+	return subjectID, nil
+}
+
+// hideTables maps content targets to their tables
+var hideTables = map[string]string{
+	moderation.TargetReview:      "reviews",
+	moderation.TargetGuestReview: "guest_reviews",
+	moderation.TargetListing:     "properties",
+	moderation.TargetMessage:     "messages",
+}
+
+// setHidden hides or restores content. Reviews are locked with review.Lock
+// first so the rating refresh cannot race with publishing.
+func setHidden(ctx context.Context, tx pgx.Tx, targetType string, targetID int, hidden bool) error {
+	table := hideTables[targetType]
+
+	isReview := targetType == moderation.TargetReview || targetType == moderation.TargetGuestReview
+	var bookingID int
+	if isReview {
+		err := tx.QueryRow(ctx, `SELECT booking_id FROM `+table+` WHERE id = $1`, targetID).Scan(&bookingID)
+		if err != nil {
+			return err
+		}
+		if _, err := review.Lock(ctx, tx, bookingID); err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.Exec(ctx,
+		`UPDATE `+table+` SET hidden_at = CASE WHEN $2 THEN COALESCE(hidden_at, CURRENT_TIMESTAMP) END
+		 WHERE id = $1`, targetID, hidden)
+	if err != nil {
+		return err
+	}
+
+	if isReview {
+		return review.RefreshRatings(ctx, tx, bookingID)
+	}
+	return nil
+}
+
+// handleModerationAudit returns the moderation audit trail, newest first,
+// optionally for a single target
+func (s *Server) handleModerationAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, offset, ok := parsePagination(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "limit must be positive and offset must not be negative")
+		return
+	}
+
+	query := `SELECT ` + actionColumns + ` FROM moderation_actions WHERE 1=1`
+	args := []any{}
+	if targetType := r.URL.Query().Get("target_type"); targetType != "" {
+		if !moderation.ValidTarget(targetType) {
+			writeError(w, http.StatusBadRequest, "invalid target_type")
+			return
+		}
+		args = append(args, targetType)
+		query += ` AND target_type = $` + strconv.Itoa(len(args))
+	}
+	if v := r.URL.Query().Get("target_id"); v != "" {
+		targetID, err := strconv.Atoi(v)
+		if err != nil || targetID <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid target_id")
+			return
+		}
+		args = append(args, targetID)
+		query += ` AND target_id = $` + strconv.Itoa(len(args))
+	}
+	args = append(args, limit, offset)
+	query += ` ORDER BY created_at DESC, id DESC LIMIT $` + strconv.Itoa(len(args)-1) + ` OFFSET $` + strconv.Itoa(len(args))
+
+	rows, err := s.db.Pool.Query(r.Context(), query, args...)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "moderation", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+
+	actions := []models.ModerationAction{}
+	for rows.Next() {
+		var a models.ModerationAction
+		if err := scanAction(rows, &a); err != nil {
+			slog.ErrorContext(r.Context(), "scan error", "component", "moderation", "error", err)
+			writeError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		actions = append(actions, a)
+	}
+
+	writeJSON(w, http.StatusOK, actions)
+}
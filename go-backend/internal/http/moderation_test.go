@@ -0,0 +1,113 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go-backend/internal/auth"
+	"go-backend/internal/config"
+	"go-backend/internal/database"
+	"go-backend/internal/migrations"
+	"go-backend/internal/moderation"
+	"go-backend/internal/payments"
+	"go-backend/internal/realtime"
+	"go-backend/internal/storage"
+)
+
+// newTestServer returns a server on the database at TEST_DATABASE_URL,
+// skipping the test when it is not set
+func newTestServer(t *testing.T, words ...string) (*Server, *database.DB) {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	if err := migrations.Run(dsn); err != nil {
+		t.Fatalf("migrations: %v", err)
+	}
+	db, err := database.Connect(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	store, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	masker, err := moderation.NewMasker(nil, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Load()
+	s := NewServer(db, auth.NewService("test-secret", 1), payments.NewFakeProvider("whsec_test"),
+		moderation.NewScreener(words), masker, realtime.NewHub(db), store, cfg)
+	return s, db
+}
+
+// signIn creates a user and returns a bearer token for it
+func signIn(t *testing.T, s *Server, db *database.DB) string {
+	t.Helper()
+	var id int
+	email := fmt.Sprintf("host-%d@example.com", time.Now().UnixNano())
+	err := db.Pool.QueryRow(context.Background(),
+		`INSERT INTO users (email, name, password_hash, role) VALUES ($1, 'Host', 'x', $2) RETURNING id`,
+		email, auth.RoleUser).Scan(&id)
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	token, err := s.authService.GenerateToken(id, email, auth.RoleUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + token
+}
+
+func TestFlaggedListingIsReported(t *testing.T) {
+	s, db := newTestServer(t, "scamword")
+	router := s.Router()
+	listing := `{"title": "Quiet scamword loft", "location": "Lisbon", "price_per_night": 90}`
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/properties", strings.NewReader(listing)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("create without token: status = %d, expected 401", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/properties", strings.NewReader(listing))
+	req.Header.Set("Authorization", signIn(t, s, db))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, expected 201: %s", rec.Code, rec.Body.String())
+	}
+	var p struct {
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		reason string
+		flags  []string
+	)
+	err := db.Pool.QueryRow(context.Background(),
+		`SELECT reason, flags FROM reports
+		 WHERE target_type = $1 AND target_id = $2 AND reporter_id IS NULL AND status = 'open'`,
+		moderation.TargetListing, p.ID,
+	).Scan(&reason, &flags)
+	if err != nil {
+		t.Fatalf("expected an automatic report for listing %d: %v", p.ID, err)
+	}
+	if reason != moderation.ReasonAutoFlagged || len(flags) == 0 {
+		t.Errorf("report = %s %v, expected %s with flags", reason, flags, moderation.ReasonAutoFlagged)
+	}
+}
@@ -0,0 +1,565 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"go-backend/internal/auth"
+	"go-backend/internal/booking"
+	"go-backend/internal/models"
+	"go-backend/internal/webhook"
+)
+
+// modificationColumns are selected to scan a models.BookingModification
+const modificationColumns = `id, booking_id, requested_by, status,
+	old_start_date::text, old_end_date::text, old_guests, old_total,
+	new_start_date::text, new_end_date::text, new_guests,
+	nights, accommodation_total, service_fee, new_total, price_difference,
+	message, responded_by, responded_at, response_note, created_at`
+
+func scanModification(row pgx.Row, m *models.BookingModification) error {
+	return row.Scan(&m.ID, &m.BookingID, &m.RequestedBy, &m.Status,
+		&m.OldStartDate, &m.OldEndDate, &m.OldGuests, &m.OldTotal,
+		&m.NewStartDate, &m.NewEndDate, &m.NewGuests,
+		&m.Nights, &m.AccommodationTotal, &m.ServiceFee, &m.NewTotal, &m.PriceDifference,
+		&m.Message, &m.RespondedBy, &m.RespondedAt, &m.ResponseNote, &m.CreatedAt)
+}
+
+// handleBookingModifications routes /api/bookings/{id}/modifications[/{mid}/approve|reject|withdraw]
+func (s *Server) handleBookingModifications(w http.ResponseWriter, r *http.Request, user auth.UserContext, id int, rest []string) {
+	switch {
+	case len(rest) == 0:
+		switch r.Method {
+		case http.MethodGet:
+			s.listModifications(w, r, user, id)
+		case http.MethodPost:
+			s.requestModification(w, r, user, id)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+
+	case len(rest) == 2:
+		mid, err := strconv.Atoi(rest[0])
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid modification id")
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		switch rest[1] {
+		case "approve":
+			s.approveModification(w, r, user, id, mid)
+		case "reject":
+			s.closeModification(w, r, user, id, mid, models.ModificationRejected)
+		case "withdraw":
+			s.closeModification(w, r, user, id, mid, models.ModificationWithdrawn)
+		default:
+			writeError(w, http.StatusNotFound, "not found")
+		}
+
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// bookingParties returns the guest and the property owner of a booking
+func bookingParties(ctx context.Context, q querier, id int) (guestID int, ownerID *int, err error) {
+	err = q.QueryRow(ctx,
+		`SELECT b.user_id, p.owner_id FROM bookings b
+		 JOIN properties p ON p.id = b.property_id WHERE b.id = $1`, id,
+	).Scan(&guestID, &ownerID)
+	return guestID, ownerID, err
+}
+
+// listModifications returns the modification history of a booking to its
+// guest, the host and admins
+func (s *Server) listModifications(w http.ResponseWriter, r *http.Request, user auth.UserContext, id int) {
+	guestID, ownerID, err := bookingParties(r.Context(), s.db.Pool, id)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading booking", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	isHost := ownerID != nil && *ownerID == user.UserID
+	if guestID != user.UserID && !isHost && user.Role != auth.RoleAdmin {
+		writeError(w, http.StatusForbidden, "access denied")
+		return
+	}
+
+	rows, err := s.db.Pool.Query(r.Context(),
+		`SELECT `+modificationColumns+` FROM booking_modifications
+		 WHERE booking_id = $1 ORDER BY created_at DESC`, id)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+
+	mods := []models.BookingModification{}
+	for rows.Next() {
+		var m models.BookingModification
+		if err := scanModification(rows, &m); err != nil {
+			slog.ErrorContext(r.Context(), "scan error", "component", "modifications", "error", err)
+			writeError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		mods = append(mods, m)
+	}
+
+	writeJSON(w, http.StatusOK, mods)
+}
+
+// requestModification lets the guest propose new dates or a new guest count.
+// The new stay is checked against the stay rules and availability, ignoring
+// the booking itself, and priced at the booking's nightly rate.
+func (s *Server) requestModification(w http.ResponseWriter, r *http.Request, user auth.UserContext, id int) {
+	type req struct {
+		StartDate     string `json:"start_date"`
+		EndDate       string `json:"end_date"`
+		Guests        int    `json:"guests"`
+		PaymentMethod string `json:"payment_method"`
+		Message       string `json:"message"`
+	}
+	var body req
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	ctx := r.Context()
+	var b models.Booking
+	err := scanBooking(s.db.Pool.QueryRow(ctx,
+		`SELECT `+bookingColumns+` FROM bookings WHERE id = $1`, id), &b)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading booking", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if b.UserID != user.UserID {
+		writeError(w, http.StatusForbidden, "only the guest can request changes to a booking")
+		return
+	}
+	if b.Status != "confirmed" {
+		writeError(w, http.StatusConflict, "only confirmed bookings can be modified")
+		return
+	}
+
+	// Unset fields keep their current value
+	if body.StartDate == "" {
+		body.StartDate = b.StartDate
+	}
+	if body.EndDate == "" {
+		body.EndDate = b.EndDate
+	}
+	if body.Guests == 0 {
+		body.Guests = b.Guests
+	}
+	if body.Guests < 0 {
+		writeError(w, http.StatusBadRequest, "guests must be positive")
+		return
+	}
+	if body.StartDate == b.StartDate && body.EndDate == b.EndDate && body.Guests == b.Guests {
+		writeError(w, http.StatusBadRequest, "no changes requested")
+		return
+	}
+
+	start, end, err := booking.ParseDates(body.StartDate, body.EndDate)
+	if err != nil {
+		writeViolation(w, http.StatusBadRequest, err.(*booking.Violation))
+		return
+	}
+
+	var capacity int
+	err = s.db.Pool.QueryRow(ctx, `SELECT guests FROM properties WHERE id = $1`, b.PropertyID).Scan(&capacity)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading property", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	rules, err := loadStayRules(ctx, s.db.Pool, b.PropertyID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading stay rules", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	stay := booking.Stay{Start: start, End: end, Guests: body.Guests, Capacity: capacity}
+	if err := rules.CheckStay(stay, booking.Today(time.Now())); err != nil {
+		v := err.(*booking.Violation)
+		writeViolation(w, violationStatus(v), v)
+		return
+	}
+
+	available, err := isAvailable(ctx, s.db.Pool, b.PropertyID, start, end, rules.PreparationDays, b.ID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error checking availability", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if !available {
+		writeViolation(w, http.StatusConflict, &booking.Violation{
+			Code:    booking.CodeDatesNotAvailable,
+			Message: "property is not available for selected dates",
+		})
+		return
+	}
+
+	price := booking.Quote(b.NightlyRate, stay.Nights())
+	difference := booking.RoundMoney(price.Total - b.TotalPrice)
+	if difference > 0 && body.PaymentMethod == "" {
+		writeError(w, http.StatusBadRequest, "payment_method is required when the new total is higher")
+		return
+	}
+
+	var paymentMethod, message *string
+	if difference > 0 {
+		paymentMethod = &body.PaymentMethod
+	}
+	if body.Message != "" {
+		message = &body.Message
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error starting transaction", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// The partial unique index allows one pending request per booking, so
+	// concurrent requests cannot both be created
+	var m models.BookingModification
+	err = scanModification(tx.QueryRow(ctx,
+		`INSERT INTO booking_modifications (booking_id, requested_by,
+		 old_start_date, old_end_date, old_guests, old_total,
+		 new_start_date, new_end_date, new_guests,
+		 nights, accommodation_total, service_fee, new_total, price_difference,
+		 payment_method, message)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		 ON CONFLICT (booking_id) WHERE status = 'pending' DO NOTHING
+		 RETURNING `+modificationColumns,
+		b.ID, user.UserID, b.StartDate, b.EndDate, b.Guests, b.TotalPrice,
+		start, end, body.Guests,
+		price.Nights, price.Accommodation, price.ServiceFee, price.Total, difference,
+		paymentMethod, message), &m)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusConflict, "a modification request is already pending")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error creating modification", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	if err := notifyBooking(ctx, tx, b.ID, bookingModificationRequested, user.UserID); err != nil {
+		slog.ErrorContext(r.Context(), "database error queueing notifications", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(r.Context(), "database error committing modification", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "modification requested",
+		"component", "modifications", "modification_id", m.ID, "booking_id", b.ID)
+	writeJSON(w, http.StatusCreated, m)
+}
+
+// readNote decodes an optional {"note": "..."} body
+func readNote(r *http.Request) (*string, error) {
+	var body struct {
+		Note string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if body.Note == "" {
+		return nil, nil
+	}
+	return &body.Note, nil
+}
+
+// approveModification applies a pending modification. The stay rules and
+// availability are checked again, the booking is updated and the price difference is charged or
+// refunded, all in one transaction.
+func (s *Server) approveModification(w http.ResponseWriter, r *http.Request, user auth.UserContext, id, mid int) {
+	note, err := readNote(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error starting transaction", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the property first, like new reservations do, so the dates cannot
+	// be taken while the change is applied
+	var (
+		propertyID int
+		ownerID    *int
+		capacity   int
+	)
+	err = tx.QueryRow(ctx,
+		`SELECT p.id, p.owner_id, p.guests FROM properties p
+		 JOIN bookings b ON b.property_id = p.id
+		 WHERE b.id = $1 FOR UPDATE OF p`, id,
+	).Scan(&propertyID, &ownerID, &capacity)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading booking", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if user.Role != auth.RoleAdmin && (ownerID == nil || *ownerID != user.UserID) {
+		writeError(w, http.StatusForbidden, "only the host can approve changes")
+		return
+	}
+
+	var b models.Booking
+	if err := scanBooking(tx.QueryRow(ctx,
+		`SELECT `+bookingColumns+` FROM bookings WHERE id = $1 FOR UPDATE`, id), &b); err != nil {
+		slog.ErrorContext(r.Context(), "database error loading booking", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	var m models.BookingModification
+	err = scanModification(tx.QueryRow(ctx,
+		`SELECT `+modificationColumns+` FROM booking_modifications
+		 WHERE id = $1 AND booking_id = $2 FOR UPDATE`, mid, id), &m)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "modification not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading modification", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if m.Status != models.ModificationPending {
+		writeError(w, http.StatusConflict, "modification is already "+m.Status)
+		return
+	}
+	if b.Status != "confirmed" {
+		writeError(w, http.StatusConflict, "only confirmed bookings can be modified")
+		return
+	}
+
+	start, end, err := booking.ParseDates(m.NewStartDate, m.NewEndDate)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "invalid stored dates",
+			"component", "modifications", "modification_id", mid, "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	rules, err := loadStayRules(ctx, tx, propertyID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading stay rules", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	// The request may have waited: the stay must still meet the current rules
+	// as of today, e.g. not start in the past or inside the advance notice
+	stay := booking.Stay{Start: start, End: end, Guests: m.NewGuests, Capacity: capacity}
+	if err := rules.CheckStay(stay, booking.Today(time.Now())); err != nil {
+		v := err.(*booking.Violation)
+		writeViolation(w, violationStatus(v), v)
+		return
+	}
+	available, err := isAvailable(ctx, tx, propertyID, start, end, rules.PreparationDays, id)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error checking availability", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if !available {
+		writeViolation(w, http.StatusConflict, &booking.Violation{
+			Code:    booking.CodeDatesNotAvailable,
+			Message: "property is no longer available for the requested dates",
+		})
+		return
+	}
+
+	err = scanBooking(tx.QueryRow(ctx,
+		`UPDATE bookings
+		 SET start_date = $2, end_date = $3, guests = $4, nights = $5,
+		     accommodation_total = $6, service_fee = $7, total_price = $8
+		 WHERE id = $1
+		 RETURNING `+bookingColumns,
+		id, start, end, m.NewGuests, m.Nights, m.AccommodationTotal, m.ServiceFee, m.NewTotal), &b)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error updating booking", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	// Settle the price difference before committing so a failed payment
+	// leaves the booking unchanged
+	switch {
+	case m.PriceDifference > 0:
+		var paymentMethod string
+		err = tx.QueryRow(ctx,
+			`SELECT COALESCE(payment_method, '') FROM booking_modifications WHERE id = $1`, mid,
+		).Scan(&paymentMethod)
+		if err == nil {
+			err = s.chargeBooking(ctx, tx, id, b.UserID, m.PriceDifference, paymentMethod)
+		}
+	case m.PriceDifference < 0:
+		err = s.refundBooking(ctx, tx, id, -m.PriceDifference)
+	}
+	var v *booking.Violation
+	switch {
+	case errors.As(err, &v):
+		writeViolation(w, violationStatus(v), v)
+		return
+	case errors.Is(err, errPaymentProvider):
+		slog.ErrorContext(r.Context(), "payment error", "component", "modifications", "modification_id", mid, "error", err)
+		writeError(w, http.StatusBadGateway, "payment provider error")
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "database error settling",
+			"component", "modifications", "modification_id", mid, "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	err = scanModification(tx.QueryRow(ctx,
+		`UPDATE booking_modifications
+		 SET status = 'approved', responded_by = $2, responded_at = CURRENT_TIMESTAMP,
+		     response_note = $3, payment_method = NULL
+		 WHERE id = $1
+		 RETURNING `+modificationColumns, mid, user.UserID, note), &m)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error updating modification", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := webhook.Enqueue(ctx, tx, webhook.EventBookingModified, b); err != nil {
+		slog.ErrorContext(r.Context(), "database error queueing webhook", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := notifyBooking(ctx, tx, id, bookingModificationApproved, user.UserID); err != nil {
+		slog.ErrorContext(r.Context(), "database error queueing notifications", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(r.Context(), "database error committing approval", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "modification approved",
+		"component", "modifications", "modification_id", mid, "booking_id", id, "price_difference", m.PriceDifference)
+	writeJSON(w, http.StatusOK, map[string]any{"booking": b, "modification": m})
+}
+
+// closeModification rejects (host or admin) or withdraws (guest) a pending modification
+func (s *Server) closeModification(w http.ResponseWriter, r *http.Request, user auth.UserContext, id, mid int, status string) {
+	note, err := readNote(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	guestID, ownerID, err := bookingParties(r.Context(), s.db.Pool, id)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading booking", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	isHost := ownerID != nil && *ownerID == user.UserID
+	switch status {
+	case models.ModificationRejected:
+		if !isHost && user.Role != auth.RoleAdmin {
+			writeError(w, http.StatusForbidden, "only the host can reject changes")
+			return
+		}
+	case models.ModificationWithdrawn:
+		if guestID != user.UserID {
+			writeError(w, http.StatusForbidden, "only the guest can withdraw a request")
+			return
+		}
+	}
+
+	tx, err := s.db.Pool.Begin(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error starting transaction", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	var m models.BookingModification
+	err = scanModification(tx.QueryRow(r.Context(),
+		`UPDATE booking_modifications
+		 SET status = $3, responded_by = $4, responded_at = CURRENT_TIMESTAMP,
+		     response_note = $5, payment_method = NULL
+		 WHERE id = $1 AND booking_id = $2 AND status = 'pending'
+		 RETURNING `+modificationColumns, mid, id, status, user.UserID, note), &m)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusConflict, "modification not found or no longer pending")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error updating modification", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	event := bookingModificationRejected
+	if status == models.ModificationWithdrawn {
+		event = bookingModificationWithdrawn
+	}
+	if err := notifyBooking(r.Context(), tx, id, event, user.UserID); err != nil {
+		slog.ErrorContext(r.Context(), "database error queueing notifications", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := tx.Commit(r.Context()); err != nil {
+		slog.ErrorContext(r.Context(), "database error committing modification", "component", "modifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "modification answered", "component", "modifications", "modification_id", mid, "status", status)
+	writeJSON(w, http.StatusOK, m)
+}
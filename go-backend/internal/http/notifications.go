@@ -0,0 +1,639 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"go-backend/internal/auth"
+	"go-backend/internal/booking"
+	"go-backend/internal/mail"
+	"go-backend/internal/models"
+	"go-backend/internal/notification"
+	"go-backend/internal/realtime"
+)
+
+// handleNotifications lists the user's notifications, newest first. ?unread=true
+// returns only unread ones and ?type= one type.
+func (s *Server) handleNotifications(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := auth.UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	limit, offset, ok := parsePagination(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "limit must be positive and offset must not be negative")
+		return
+	}
+
+	query := `SELECT ` + notification.Columns + ` FROM notifications n WHERE n.user_id = $1`
+	args := []any{user.UserID}
+
+	q := r.URL.Query()
+	switch q.Get("unread") {
+	case "", "false":
+	case "true":
+		query += ` AND n.read_at IS NULL`
+	default:
+		writeError(w, http.StatusBadRequest, "unread must be true or false")
+		return
+	}
+	if v := q.Get("type"); v != "" {
+		if !notification.ValidType(v) {
+			writeError(w, http.StatusBadRequest, "invalid type")
+			return
+		}
+		args = append(args, v)
+		query += ` AND n.type = $` + strconv.Itoa(len(args))
+	}
+	args = append(args, limit, offset)
+	query += ` ORDER BY n.id DESC LIMIT $` + strconv.Itoa(len(args)-1) + ` OFFSET $` + strconv.Itoa(len(args))
+
+	rows, err := s.db.Pool.Query(r.Context(), query, args...)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "notifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+
+	notes := []models.Notification{}
+	for rows.Next() {
+		var n models.Notification
+		if err := notification.Scan(rows, &n); err != nil {
+			slog.ErrorContext(r.Context(), "scan error", "component", "notifications", "error", err)
+			writeError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		notes = append(notes, n)
+	}
+
+	writeJSON(w, http.StatusOK, notes)
+}
+
+// handleNotificationByID serves /api/notifications/unread-count, read-all,
+// preferences, and DELETE /api/notifications/{id} and
+// POST /api/notifications/{id}/read
+func (s *Server) handleNotificationByID(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) == 3 {
+		switch parts[2] {
+		case "unread-count":
+			s.notificationUnreadCount(w, r, user)
+			return
+		case "read-all":
+			s.markAllNotificationsRead(w, r, user)
+			return
+		case "preferences":
+			s.handleNotificationPreferences(w, r, user)
+			return
+		}
+	}
+
+	id, ok := parseIDFromPath(r.URL.Path)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	switch {
+	case len(parts) == 3 && r.Method == http.MethodDelete:
+		s.deleteNotification(w, r, user, id)
+	case len(parts) == 4 && parts[3] == "read" && r.Method == http.MethodPost:
+		s.markNotificationRead(w, r, user, id)
+	case len(parts) == 3 || (len(parts) == 4 && parts[3] == "read"):
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// notificationUnreadCount returns the number of unread notifications, for the
+// bell badge
+func (s *Server) notificationUnreadCount(w http.ResponseWriter, r *http.Request, user auth.UserContext) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var count int
+	err := s.db.Pool.QueryRow(r.Context(),
+		`SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`, user.UserID,
+	).Scan(&count)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error counting unread notifications", "component", "notifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{"unread_count": count})
+}
+
+// markNotificationRead marks one notification read and tells the user's
+// other devices
+func (s *Server) markNotificationRead(w http.ResponseWriter, r *http.Request, user auth.UserContext, id int) {
+	ctx := r.Context()
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error starting transaction", "component", "notifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var n models.Notification
+	err = notification.Scan(tx.QueryRow(ctx,
+		`UPDATE notifications n SET read_at = CURRENT_TIMESTAMP
+		 WHERE n.id = $1 AND n.user_id = $2 AND n.read_at IS NULL
+		 RETURNING `+notification.Columns, id, user.UserID), &n)
+	if err == pgx.ErrNoRows {
+		// Already read, or not the user's
+		err = notification.Scan(tx.QueryRow(ctx,
+			`SELECT `+notification.Columns+` FROM notifications n WHERE n.id = $1 AND n.user_id = $2`,
+			id, user.UserID), &n)
+		if err == pgx.ErrNoRows {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error", "component", "notifications", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		writeJSON(w, http.StatusOK, n)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error marking notification read", "component", "notifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	_, err = realtime.Publish(ctx, tx, realtime.EventNotificationsRead, []int{user.UserID},
+		map[string]any{"ids": []int{id}})
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error committing read state", "component", "notifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, n)
+}
+
+// markAllNotificationsRead marks all of the user's notifications read, or
+// only those of ?type=
+func (s *Server) markAllNotificationsRead(w http.ResponseWriter, r *http.Request, user auth.UserContext) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := `UPDATE notifications SET read_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND read_at IS NULL`
+	args := []any{user.UserID}
+	event := map[string]any{"all": true}
+	if v := r.URL.Query().Get("type"); v != "" {
+		if !notification.ValidType(v) {
+			writeError(w, http.StatusBadRequest, "invalid type")
+			return
+		}
+		args = append(args, v)
+		query += ` AND type = $2`
+		event["type"] = v
+	}
+
+	ctx := r.Context()
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error starting transaction", "component", "notifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, query, args...)
+	if err == nil && tag.RowsAffected() > 0 {
+		_, err = realtime.Publish(ctx, tx, realtime.EventNotificationsRead, []int{user.UserID}, event)
+	}
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error marking notifications read", "component", "notifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int64{"updated": tag.RowsAffected()})
+}
+
+// deleteNotification removes one of the user's notifications
+func (s *Server) deleteNotification(w http.ResponseWriter, r *http.Request, user auth.UserContext, id int) {
+	tag, err := s.db.Pool.Exec(r.Context(),
+		`DELETE FROM notifications WHERE id = $1 AND user_id = $2`, id, user.UserID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error deleting notification", "component", "notifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleNotificationPreferences reads and updates which types of notification
+// the user receives in the app and by email, as an object keyed by type.
+// Updates may name a subset of the types and channels; system notifications
+// cannot be turned off.
+func (s *Server) handleNotificationPreferences(w http.ResponseWriter, r *http.Request, user auth.UserContext) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var body map[string]struct {
+			InApp *bool `json:"in_app"`
+			Email *bool `json:"email"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+		for typ, pref := range body {
+			if !notification.ValidType(typ) {
+				writeError(w, http.StatusBadRequest, "unknown notification type "+typ)
+				return
+			}
+			if pref.InApp == nil && pref.Email == nil {
+				writeError(w, http.StatusBadRequest, "in_app or email is required for "+typ)
+				return
+			}
+			off := (pref.InApp != nil && !*pref.InApp) || (pref.Email != nil && !*pref.Email)
+			if !notification.Configurable(typ) && off {
+				writeError(w, http.StatusBadRequest, typ+" notifications cannot be turned off")
+				return
+			}
+		}
+
+		tx, err := s.db.Pool.Begin(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error starting transaction", "component", "notifications", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		defer tx.Rollback(r.Context())
+		for typ, pref := range body {
+			_, err = tx.Exec(r.Context(),
+				`INSERT INTO notification_preferences AS np (user_id, type, in_app, email)
+				 VALUES ($1, $2, COALESCE($3, TRUE), COALESCE($4, TRUE))
+				 ON CONFLICT (user_id, type) DO UPDATE
+				 SET in_app = COALESCE($3, np.in_app), email = COALESCE($4, np.email),
+				     updated_at = CURRENT_TIMESTAMP`,
+				user.UserID, typ, pref.InApp, pref.Email)
+			if err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = tx.Commit(r.Context())
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error saving preferences", "component", "notifications", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	prefs := make(map[string]models.NotificationPreference, len(notification.Types))
+	for _, typ := range notification.Types {
+		prefs[typ] = models.NotificationPreference{InApp: true, Email: true}
+	}
+	rows, err := s.db.Pool.Query(r.Context(),
+		`SELECT type, in_app, email FROM notification_preferences WHERE user_id = $1`, user.UserID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading preferences", "component", "notifications", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			typ  string
+			pref models.NotificationPreference
+		)
+		if err := rows.Scan(&typ, &pref.InApp, &pref.Email); err != nil {
+			slog.ErrorContext(r.Context(), "scan error", "component", "notifications", "error", err)
+			writeError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		if notification.Configurable(typ) {
+			prefs[typ] = pref
+		}
+	}
+
+	writeJSON(w, http.StatusOK, prefs)
+}
+
+// notify publishes the notifications returned by build under a savepoint of
+// db, logging rather than returning a failure: a missed in-app notification
+// must not undo the change it announces. Emails are queued by the callers in
+// their own transaction, as the outbox must not lose them.
+func notify(ctx context.Context, db notification.TxStarter, build func(tx pgx.Tx) ([]notification.Notification, error)) {
+	tx, err := db.Begin(ctx)
+	if err == nil {
+		defer tx.Rollback(ctx)
+		var notes []notification.Notification
+		if notes, err = build(tx); err == nil {
+			if err = notification.Publish(ctx, tx, notes...); err == nil {
+				err = tx.Commit(ctx)
+			}
+		}
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "error publishing notifications", "component", "notifications", "error", err)
+	}
+}
+
+// bookingNotice is the text of the notifications sent to the guest and the
+// host about a booking event. Formats take the property title, check-in and
+// check-out dates; an empty title sends nothing to that party. Parties who are
+// notified are also sent the Email template, if any.
+type bookingNotice struct {
+	Type, Priority           string
+	GuestTitle, GuestMessage string
+	HostTitle, HostMessage   string
+	Email                    string
+}
+
+// Booking events
+const (
+	bookingConfirmed             = "confirmed"
+	bookingPaymentFailed         = "payment_failed"
+	bookingCancelled             = "cancelled"
+	bookingModificationRequested = "modification_requested"
+	bookingModificationApproved  = "modification_approved"
+	bookingModificationRejected  = "modification_rejected"
+	bookingModificationWithdrawn = "modification_withdrawn"
+	bookingCheckedIn             = "checked_in"
+)
+
+var bookingNotices = map[string]bookingNotice{
+	bookingConfirmed: {
+		Type: notification.TypeBooking, Priority: notification.PriorityHigh,
+		GuestTitle: "Booking confirmed", GuestMessage: "Your stay at %s from %s to %s is confirmed.",
+		HostTitle: "New booking", HostMessage: "%s is booked from %s to %s.",
+		Email: mail.TemplateBookingConfirmed,
+	},
+	bookingPaymentFailed: {
+		Type: notification.TypePayment, Priority: notification.PriorityHigh,
+		GuestTitle:   "Payment failed",
+		GuestMessage: "Your booking at %s from %s to %s was cancelled because the payment did not go through.",
+	},
+	bookingCancelled: {
+		Type: notification.TypeBooking, Priority: notification.PriorityHigh,
+		GuestTitle: "Booking cancelled", GuestMessage: "Your stay at %s from %s to %s was cancelled.",
+		HostTitle: "Booking cancelled", HostMessage: "The booking of %s from %s to %s was cancelled.",
+		Email: mail.TemplateBookingCancelled,
+	},
+	bookingModificationRequested: {
+		Type: notification.TypeBooking, Priority: notification.PriorityMedium,
+		HostTitle: "Change requested", HostMessage: "A guest asked to change their stay at %s (%s to %s).",
+	},
+	bookingModificationApproved: {
+		Type: notification.TypeBooking, Priority: notification.PriorityMedium,
+		GuestTitle: "Change approved", GuestMessage: "Your stay at %s is now from %s to %s.",
+		HostTitle: "Change approved", HostMessage: "The stay at %s is now from %s to %s.",
+	},
+	bookingModificationRejected: {
+		Type: notification.TypeBooking, Priority: notification.PriorityMedium,
+		GuestTitle: "Change declined", GuestMessage: "Your stay at %s remains from %s to %s.",
+		HostTitle: "Change declined", HostMessage: "The stay at %s remains from %s to %s.",
+	},
+	bookingModificationWithdrawn: {
+		Type: notification.TypeBooking, Priority: notification.PriorityLow,
+		HostTitle: "Change withdrawn", HostMessage: "The guest withdrew their change to the stay at %s (%s to %s).",
+	},
+	bookingCheckedIn: {
+		Type: notification.TypeBooking, Priority: notification.PriorityLow,
+		GuestTitle: "Checked in", GuestMessage: "You are checked in to %s from %s to %s. Enjoy your stay.",
+		HostTitle: "Guest checked in", HostMessage: "Your guest checked in to %s (%s to %s).",
+	},
+}
+
+// notifyBooking notifies the guest and the host of a booking event, except
+// the user who caused it (0 for none). Call it with the transaction making the
+// change, after the booking is updated. Emails are queued in tx and an error
+// queueing them is returned, so the change and its emails commit together;
+// in-app notifications are published under a savepoint by notify.
+func notifyBooking(ctx context.Context, tx pgx.Tx, id int, event string, actorID int) error {
+	tmpl := bookingNotices[event]
+	var (
+		guestID, hostID int
+		property        string
+		start, end      time.Time
+	)
+	err := tx.QueryRow(ctx,
+		`SELECT b.user_id, COALESCE(p.owner_id, 0), p.title, b.start_date, b.end_date
+		 FROM bookings b JOIN properties p ON p.id = b.property_id WHERE b.id = $1`, id,
+	).Scan(&guestID, &hostID, &property, &start, &end)
+	if err != nil {
+		return err
+	}
+
+	from, to := start.Format(booking.DateLayout), end.Format(booking.DateLayout)
+	path := fmt.Sprintf("/bookings/%d", id)
+	var notes []notification.Notification
+	add := func(userID int, title, message string) error {
+		if userID == 0 || userID == actorID || title == "" {
+			return nil
+		}
+		notes = append(notes, notification.Notification{
+			UserID:      userID,
+			Type:        tmpl.Type,
+			Priority:    tmpl.Priority,
+			Title:       title,
+			Message:     fmt.Sprintf(message, property, from, to),
+			ActionLabel: "View booking",
+			ActionURL:   path,
+		})
+		if tmpl.Email == "" {
+			return nil
+		}
+		_, err := mail.Enqueue(ctx, tx, userID, tmpl.Email, map[string]any{
+			"Property": property, "CheckIn": from, "CheckOut": to, "Path": path, "Host": userID == hostID,
+		})
+		return err
+	}
+	if err := add(guestID, tmpl.GuestTitle, tmpl.GuestMessage); err != nil {
+		return err
+	}
+	if hostID != guestID {
+		if err := add(hostID, tmpl.HostTitle, tmpl.HostMessage); err != nil {
+			return err
+		}
+	}
+
+	notify(ctx, tx, func(pgx.Tx) ([]notification.Notification, error) {
+		return notes, nil
+	})
+	return nil
+}
+
+// notifyReviewPending asks the party of a booking who has not reviewed yet for
+// their review, once the other party has. Reviews are published when both
+// are in or when the review window closes.
+func notifyReviewPending(ctx context.Context, tx pgx.Tx, bookingID int) {
+	notify(ctx, tx, func(tx pgx.Tx) ([]notification.Notification, error) {
+		var (
+			guestID, hostID     int
+			property            string
+			guestDone, hostDone bool
+		)
+		err := tx.QueryRow(ctx,
+			`SELECT b.user_id, COALESCE(p.owner_id, 0), p.title,
+			 EXISTS(SELECT 1 FROM reviews WHERE booking_id = b.id),
+			 EXISTS(SELECT 1 FROM guest_reviews WHERE booking_id = b.id)
+			 FROM bookings b JOIN properties p ON p.id = b.property_id WHERE b.id = $1`, bookingID,
+		).Scan(&guestID, &hostID, &property, &guestDone, &hostDone)
+		if err != nil {
+			return nil, err
+		}
+
+		n := notification.Notification{
+			Type:        notification.TypeReview,
+			ActionLabel: "Write review",
+			ActionURL:   fmt.Sprintf("/bookings/%d/review", bookingID),
+		}
+		switch {
+		case guestDone && !hostDone && hostID != 0:
+			n.UserID = hostID
+			n.Title = "Review your guest"
+			n.Message = fmt.Sprintf("Your guest reviewed their stay at %s. Both reviews are published once you review them.", property)
+		case hostDone && !guestDone:
+			n.UserID = guestID
+			n.Title = "Review your stay"
+			n.Message = fmt.Sprintf("Your host at %s reviewed your stay. Both reviews are published once you review it.", property)
+		default:
+			return nil, nil
+		}
+		return []notification.Notification{n}, nil
+	})
+}
+
+// notifyReviewResponse tells the author of a review that the host responded
+func notifyReviewResponse(ctx context.Context, db notification.TxStarter, rv models.Review) {
+	if rv.AuthorID == nil {
+		return // deleted account
+	}
+	notify(ctx, db, func(tx pgx.Tx) ([]notification.Notification, error) {
+		var property string
+		err := tx.QueryRow(ctx, `SELECT title FROM properties WHERE id = $1`, rv.PropertyID).Scan(&property)
+		return []notification.Notification{{
+			UserID:      *rv.AuthorID,
+			Type:        notification.TypeReview,
+			Title:       "The host responded to your review",
+			Message:     fmt.Sprintf("The host of %s responded to your review.", property),
+			ActionLabel: "View review",
+			ActionURL:   fmt.Sprintf("/properties/%d", rv.PropertyID),
+		}}, err
+	})
+}
+
+// messagePreviewLength is the length of the message text shown in a new
+// message notification
+const messagePreviewLength = 100
+
+// notifyMessage notifies the recipients of a message who have not muted the
+// conversation. Unread messages of a conversation share one notification, and
+// only the first is emailed so a conversation does not flood the inbox. As in
+// notifyBooking, emails are queued in tx and errors queueing them returned.
+func notifyMessage(ctx context.Context, tx pgx.Tx, m models.Message, participants []int) error {
+	var sender string
+	var recipients []int
+	var firstUnread []bool
+	err := tx.QueryRow(ctx,
+		`SELECT COALESCE((SELECT name FROM users WHERE id = $2), ''),
+		 COALESCE(array_agg(u), '{}'),
+		 COALESCE(array_agg(NOT EXISTS (
+		   SELECT 1 FROM messages m
+		   WHERE m.conversation_id = $1 AND m.id < $4 AND m.hidden_at IS NULL
+		     AND m.sender_id IS DISTINCT FROM u
+		     AND m.id > COALESCE((SELECT last_read_message_id FROM conversation_participants
+		                          WHERE conversation_id = $1 AND user_id = u), 0))), '{}')
+		 FROM unnest($3::int[]) u
+		 WHERE u <> $2 AND NOT EXISTS (
+		   SELECT 1 FROM conversation_participants
+		   WHERE conversation_id = $1 AND user_id = u AND muted_at IS NOT NULL)`,
+		m.ConversationID, m.SenderID, participants, m.ID,
+	).Scan(&sender, &recipients, &firstUnread)
+	if err != nil {
+		return err
+	}
+
+	preview := m.Text
+	if preview == "" && len(m.Attachments) > 0 {
+		preview = "Sent an attachment"
+	}
+	title := "New message"
+	if sender != "" {
+		title = "New message from " + sender
+	}
+
+	preview = notification.Truncate(preview, messagePreviewLength)
+	path := fmt.Sprintf("/messages/%d", m.ConversationID)
+
+	notes := make([]notification.Notification, 0, len(recipients))
+	for i, userID := range recipients {
+		notes = append(notes, notification.Notification{
+			UserID:      userID,
+			Type:        notification.TypeMessage,
+			Title:       title,
+			Message:     preview,
+			ActionLabel: "Reply",
+			ActionURL:   path,
+			GroupKey:    conversationGroupKey(m.ConversationID),
+		})
+		if firstUnread[i] {
+			_, err := mail.Enqueue(ctx, tx, userID, mail.TemplateNewMessage, map[string]any{
+				"Sender": sender, "Preview": preview, "Path": path,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	notify(ctx, tx, func(pgx.Tx) ([]notification.Notification, error) {
+		return notes, nil
+	})
+	return nil
+}
+
+// conversationGroupKey groups the new message notifications of a conversation
+func conversationGroupKey(cid int) string {
+	return "conversation:" + strconv.Itoa(cid)
+}
@@ -0,0 +1,192 @@
+package httpapi
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"go-backend/internal/auth"
+	"go-backend/internal/mail"
+)
+
+const (
+	// passwordResetTTL is how long a password reset link can be used
+	passwordResetTTL = time.Hour
+	// passwordResetInterval is the least time between two reset emails to
+	// one account
+	passwordResetInterval = time.Minute
+)
+
+// hashResetToken returns the stored form of a password reset token
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// handlePasswordResetRequest emails a password reset link to the address
+// given, if it belongs to an account. The response is the same either way so
+// it does not reveal which addresses are registered.
+func (s *Server) handlePasswordResetRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if !isValidEmail(body.Email) {
+		writeError(w, http.StatusBadRequest, "invalid email format")
+		return
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		slog.ErrorContext(r.Context(), "error generating token", "component", "password_reset", "error", err)
+		writeError(w, http.StatusInternalServerError, "could not generate token")
+		return
+	}
+	token := hex.EncodeToString(b)
+
+	ctx := r.Context()
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error starting transaction", "component", "password_reset", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the user so concurrent requests do not both send an email
+	var userID int
+	var recent bool
+	err = tx.QueryRow(ctx,
+		`SELECT u.id, EXISTS (SELECT 1 FROM password_reset_tokens t
+		   WHERE t.user_id = u.id AND t.created_at > CURRENT_TIMESTAMP - $2::interval)
+		 FROM users u WHERE u.email = $1 AND u.suspended_at IS NULL FOR UPDATE OF u`,
+		body.Email, fmt.Sprintf("%d seconds", int(passwordResetInterval.Seconds())),
+	).Scan(&userID, &recent)
+	if err == pgx.ErrNoRows || recent {
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "ok"})
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading user", "component", "password_reset", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	// Only the newest link works
+	_, err = tx.Exec(ctx,
+		`UPDATE password_reset_tokens SET used_at = CURRENT_TIMESTAMP
+		 WHERE user_id = $1 AND used_at IS NULL`, userID)
+	if err == nil {
+		_, err = tx.Exec(ctx,
+			`INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+			 VALUES ($1, $2, CURRENT_TIMESTAMP + $3::interval)`,
+			userID, hashResetToken(token), fmt.Sprintf("%d seconds", int(passwordResetTTL.Seconds())))
+	}
+	if err == nil {
+		_, err = mail.Enqueue(ctx, tx, userID, mail.TemplatePasswordReset, map[string]any{
+			"Path":         "/reset-password?token=" + url.QueryEscape(token),
+			"ValidMinutes": int(passwordResetTTL.Minutes()),
+		})
+	}
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error issuing token", "component", "password_reset", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "reset link sent", "component", "password_reset", "user_id", userID)
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "ok"})
+}
+
+// handlePasswordResetConfirm sets a new password with a token from a reset
+// email. Tokens work once. Tokens issued before the change stop working;
+// existing sessions stay signed in until their JWT expires.
+func (s *Server) handlePasswordResetConfirm(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	body.Token = strings.TrimSpace(body.Token)
+	if body.Token == "" {
+		writeError(w, http.StatusBadRequest, "token is required")
+		return
+	}
+	if err := auth.ValidatePassword(body.Password); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	hash, err := auth.HashPassword(body.Password)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error hashing password", "component", "password_reset", "error", err)
+		writeError(w, http.StatusInternalServerError, "could not hash password")
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error starting transaction", "component", "password_reset", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int
+	err = tx.QueryRow(ctx,
+		`UPDATE password_reset_tokens SET used_at = CURRENT_TIMESTAMP
+		 WHERE token_hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		 RETURNING user_id`, hashResetToken(body.Token),
+	).Scan(&userID)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusBadRequest, "reset link is invalid or has expired")
+		return
+	}
+	if err == nil {
+		_, err = tx.Exec(ctx, `UPDATE users SET password_hash = $2 WHERE id = $1`, userID, hash)
+	}
+	if err == nil {
+		_, err = tx.Exec(ctx,
+			`UPDATE password_reset_tokens SET used_at = CURRENT_TIMESTAMP
+			 WHERE user_id = $1 AND used_at IS NULL`, userID)
+	}
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error resetting password", "component", "password_reset", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "password reset", "component", "password_reset", "user_id", userID)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
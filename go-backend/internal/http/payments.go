@@ -0,0 +1,470 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+
+	"go-backend/internal/booking"
+	"go-backend/internal/metrics"
+	"go-backend/internal/models"
+	"go-backend/internal/payments"
+	"go-backend/internal/webhook"
+)
+
+// errPaymentProvider wraps provider errors that are not declines, such as an
+// unreachable gateway
+var errPaymentProvider = errors.New("payment provider error")
+
+// maxWebhookSize limits the size of webhook payloads
+const maxWebhookSize = 1 << 20
+
+// payForBooking authorizes and captures the booking total. The booking is
+// confirmed once the payment is captured; if the payment fails the booking is
+// cancelled so the dates are released and any authorization is voided. A
+// payment captured after the booking stopped being pending is refunded.
+func (s *Server) payForBooking(ctx context.Context, b models.Booking, paymentMethod string) (models.Booking, error) {
+	var paymentID int
+	err := s.db.Pool.QueryRow(ctx,
+		`INSERT INTO payments (booking_id, user_id, provider, amount, currency)
+		 VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		b.ID, b.UserID, s.payments.Name(), b.TotalPrice, s.currency,
+	).Scan(&paymentID)
+	if err != nil {
+		return b, err
+	}
+
+	intent, err := s.payments.Authorize(ctx, payments.AuthorizeRequest{
+		Amount:        payments.ToCents(b.TotalPrice),
+		Currency:      s.currency,
+		PaymentMethod: paymentMethod,
+		Reference:     fmt.Sprintf("booking-%d", b.ID),
+	})
+	if err == nil {
+		if err := s.setPaymentStatus(ctx, paymentID, intent); err != nil {
+			return b, err
+		}
+		authorized := intent
+		intent, err = s.payments.Capture(ctx, authorized.ID)
+		if err != nil {
+			s.voidAuthorization(ctx, b.ID, authorized.ID)
+		}
+	}
+	if err != nil {
+		return s.failPayment(ctx, b, paymentID, intent, err)
+	}
+
+	if intent.Status != payments.StatusCaptured {
+		// The provider will report the outcome by webhook
+		return b, s.setPaymentStatus(ctx, paymentID, intent)
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return b, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := setPaymentStatusTx(ctx, tx, paymentID, intent); err != nil {
+		return b, err
+	}
+	if err := postCapture(ctx, tx, paymentID); err != nil {
+		return b, err
+	}
+	b, confirmed, err := confirmPaidBooking(ctx, tx, b.ID)
+	if err != nil {
+		return b, err
+	}
+	if !confirmed {
+		return s.refundUnconfirmed(ctx, tx, b, paymentID, intent)
+	}
+	return b, tx.Commit(ctx)
+}
+
+// confirmPaidBooking confirms a pending booking whose payment was captured,
+// queueing its webhook and notifications in tx. It reports whether the booking
+// is confirmed, either now or already by another capture of the same payment.
+func confirmPaidBooking(ctx context.Context, tx pgx.Tx, bookingID int) (models.Booking, bool, error) {
+	var b models.Booking
+	err := scanBooking(tx.QueryRow(ctx,
+		`UPDATE bookings SET status = 'confirmed'
+		 WHERE id = $1 AND status = 'pending'
+		 RETURNING `+bookingColumns, bookingID), &b)
+	if err == pgx.ErrNoRows {
+		err = scanBooking(tx.QueryRow(ctx,
+			`SELECT `+bookingColumns+` FROM bookings WHERE id = $1`, bookingID), &b)
+		return b, err == nil && b.Status == "confirmed", err
+	}
+	if err != nil {
+		return b, false, err
+	}
+	if err := webhook.Enqueue(ctx, tx, webhook.EventBookingConfirmed, b); err != nil {
+		return b, false, err
+	}
+	if err := notifyBooking(ctx, tx, b.ID, bookingConfirmed, 0); err != nil {
+		return b, false, err
+	}
+	return b, true, nil
+}
+
+// refundCapture refunds what is left of a captured payment and records the
+// refund in tx. Provider errors are wrapped in errPaymentProvider.
+func (s *Server) refundCapture(ctx context.Context, tx pgx.Tx, paymentID int, intent payments.Intent) error {
+	refunded, err := s.payments.Refund(ctx, intent.ID, intent.Amount-intent.Refunded)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errPaymentProvider, err)
+	}
+	if err := setPaymentStatusTx(ctx, tx, paymentID, refunded); err != nil {
+		return err
+	}
+	return postRefund(ctx, tx, paymentID, refunded.Refunded, refunded.Refunded-intent.Refunded)
+}
+
+// voidAuthorization releases the funds held by an authorization that could
+// not be captured. Intents the provider has already released are left alone.
+func (s *Server) voidAuthorization(ctx context.Context, bookingID int, intentID string) {
+	_, err := s.payments.Void(ctx, intentID)
+	if err != nil && !errors.Is(err, payments.ErrInvalidState) {
+		slog.ErrorContext(ctx, "error voiding authorization",
+			"component", "payments", "booking_id", bookingID, "intent_id", intentID, "error", err)
+	}
+}
+
+// refundUnconfirmed refunds a payment captured for a booking that stopped
+// being pending while it was paid for, e.g. because it expired. The capture is
+// recorded in tx even if the refund fails, so the payment can be found and
+// refunded later.
+func (s *Server) refundUnconfirmed(ctx context.Context, tx pgx.Tx, b models.Booking, paymentID int, intent payments.Intent) (models.Booking, error) {
+	refundErr := s.refundCapture(ctx, tx, paymentID, intent)
+	if refundErr != nil && !errors.Is(refundErr, errPaymentProvider) {
+		return b, refundErr
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return b, err
+	}
+	if refundErr != nil {
+		slog.ErrorContext(ctx, "error refunding payment of unconfirmed booking",
+			"component", "payments", "booking_id", b.ID, "payment_id", paymentID, "error", refundErr)
+		return b, refundErr
+	}
+
+	slog.WarnContext(ctx, "payment refunded, booking no longer pending",
+		"component", "payments", "booking_id", b.ID, "payment_id", paymentID)
+	return b, &booking.Violation{
+		Code:    booking.CodeDatesNotAvailable,
+		Message: "booking is no longer pending; the payment was refunded",
+	}
+}
+
+// failPayment records a failed payment and cancels the booking it was for
+func (s *Server) failPayment(ctx context.Context, b models.Booking, paymentID int, intent payments.Intent, cause error) (models.Booking, error) {
+	reason := intent.FailureReason
+	var decline *payments.DeclineError
+	if errors.As(cause, &decline) {
+		reason = decline.Code
+	}
+	if reason == "" {
+		reason = cause.Error()
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return b, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		`UPDATE payments SET provider_ref = COALESCE(NULLIF($2, ''), provider_ref),
+		 status = 'failed', failure_reason = $3, updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $1`, paymentID, intent.ID, reason)
+	if err != nil {
+		return b, err
+	}
+	err = updateBookingStatus(ctx, tx, b.ID, bookingPaymentFailed, webhook.EventBookingCancelled,
+		`UPDATE bookings
+		 SET status = 'cancelled', cancelled_at = CURRENT_TIMESTAMP,
+		     cancellation_reason = 'payment_failed', refund_amount = 0
+		 WHERE id = $1 AND status = 'pending'`)
+	if err != nil {
+		return b, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return b, err
+	}
+
+	slog.WarnContext(ctx, "payment failed", "component", "payments", "booking_id", b.ID, "reason", reason)
+	metrics.PaymentsFailed.Inc()
+	if decline != nil {
+		return b, &booking.Violation{
+			Code:    booking.CodePaymentDeclined,
+			Message: "payment was declined",
+			Details: map[string]any{"reason": decline.Code},
+		}
+	}
+	return b, fmt.Errorf("%w: %v", errPaymentProvider, cause)
+}
+
+func (s *Server) setPaymentStatus(ctx context.Context, paymentID int, intent payments.Intent) error {
+	return setPaymentStatusTx(ctx, s.db.Pool, paymentID, intent)
+}
+
+// setPaymentStatusTx stores the provider reference and state of an intent
+func setPaymentStatusTx(ctx context.Context, q querier, paymentID int, intent payments.Intent) error {
+	_, err := q.Exec(ctx,
+		`UPDATE payments SET provider_ref = $2, status = $3,
+		 refunded_amount = $4, updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $1`,
+		paymentID, intent.ID, intent.Status, payments.FromCents(intent.Refunded))
+	return err
+}
+
+// refundBooking returns amount to the guest through the provider, starting
+// with the most recent payment. It must be called inside the caller's
+// transaction so a failed refund leaves the booking untouched. Bookings without
+// a captured payment are not refunded.
+func (s *Server) refundBooking(ctx context.Context, tx pgx.Tx, bookingID int, amount float64) error {
+	remaining := payments.ToCents(amount)
+	if remaining <= 0 {
+		return nil
+	}
+
+	type capturedPayment struct {
+		id       int
+		ref      string
+		paid     float64
+		refunded float64
+	}
+	rows, err := tx.Query(ctx,
+		`SELECT id, provider_ref, amount, refunded_amount FROM payments
+		 WHERE booking_id = $1 AND provider = $2 AND status IN ('captured', 'partially_refunded')
+		 ORDER BY id DESC FOR UPDATE`,
+		bookingID, s.payments.Name())
+	if err != nil {
+		return err
+	}
+	captured, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (capturedPayment, error) {
+		var p capturedPayment
+		err := row.Scan(&p.id, &p.ref, &p.paid, &p.refunded)
+		return p, err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, p := range captured {
+		if remaining <= 0 {
+			break
+		}
+		cents := payments.ToCents(p.paid) - payments.ToCents(p.refunded)
+		if cents > remaining {
+			cents = remaining
+		}
+		if cents <= 0 {
+			continue
+		}
+
+		intent, err := s.payments.Refund(ctx, p.ref, cents)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errPaymentProvider, err)
+		}
+		if err := setPaymentStatusTx(ctx, tx, p.id, intent); err != nil {
+			return err
+		}
+		if err := postRefund(ctx, tx, p.id, intent.Refunded, intent.Refunded-payments.ToCents(p.refunded)); err != nil {
+			return err
+		}
+		remaining -= cents
+	}
+	return nil
+}
+
+// chargeBooking collects an additional amount for a booking, such as the price
+// difference of a modification. It runs inside the caller's transaction; a
+// declined payment returns a *booking.Violation and nothing is recorded.
+func (s *Server) chargeBooking(ctx context.Context, tx pgx.Tx, bookingID, userID int, amount float64, paymentMethod string) error {
+	intent, err := s.payments.Authorize(ctx, payments.AuthorizeRequest{
+		Amount:        payments.ToCents(amount),
+		Currency:      s.currency,
+		PaymentMethod: paymentMethod,
+		Reference:     fmt.Sprintf("booking-%d", bookingID),
+	})
+	if err == nil {
+		authorized := intent
+		intent, err = s.payments.Capture(ctx, authorized.ID)
+		if err != nil {
+			s.voidAuthorization(ctx, bookingID, authorized.ID)
+		}
+	}
+	var decline *payments.DeclineError
+	if errors.As(err, &decline) {
+		slog.WarnContext(ctx, "additional payment declined",
+			"component", "payments", "booking_id", bookingID, "code", decline.Code)
+		return &booking.Violation{
+			Code:    booking.CodePaymentDeclined,
+			Message: "payment was declined",
+			Details: map[string]any{"reason": decline.Code},
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", errPaymentProvider, err)
+	}
+	if intent.Status != payments.StatusCaptured {
+		return fmt.Errorf("%w: payment not captured (%s)", errPaymentProvider, intent.Status)
+	}
+
+	var paymentID int
+	err = tx.QueryRow(ctx,
+		`INSERT INTO payments (booking_id, user_id, provider, provider_ref, amount, currency, status)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		bookingID, userID, s.payments.Name(), intent.ID, amount, s.currency, intent.Status,
+	).Scan(&paymentID)
+	if err != nil {
+		return err
+	}
+	return postCapture(ctx, tx, paymentID)
+}
+
+// handlePaymentWebhook receives signed provider events. Events are recorded
+// once, so redelivered webhooks are acknowledged without being applied again.
+func (s *Server) handlePaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookSize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+	event, err := s.payments.VerifyWebhook(payload, r.Header.Get(payments.SignatureHeader))
+	if err != nil {
+		slog.WarnContext(r.Context(), "rejected webhook", "component", "payments", "error", err)
+		writeError(w, http.StatusBadRequest, "invalid signature")
+		return
+	}
+
+	if err := s.applyPaymentEvent(r.Context(), event, payload); err != nil {
+		slog.ErrorContext(r.Context(), "error applying event", "component", "payments", "event_id", event.ID, "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// applyPaymentEvent records an event and moves the payment and its booking to
+// the state it reports. Events for unknown intents or that would move a payment
+// backwards are recorded but otherwise ignored.
+func (s *Server) applyPaymentEvent(ctx context.Context, event payments.Event, payload []byte) error {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx,
+		`INSERT INTO payment_events (provider, event_id, type, payload)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (provider, event_id) DO NOTHING`,
+		s.payments.Name(), event.ID, event.Type, payload)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		slog.InfoContext(ctx, "duplicate event ignored", "component", "payments", "event_id", event.ID)
+		return nil
+	}
+
+	var (
+		paymentID int
+		bookingID int
+		status    string
+		refunded  float64
+	)
+	err = tx.QueryRow(ctx,
+		`SELECT id, booking_id, status, refunded_amount FROM payments
+		 WHERE provider = $1 AND provider_ref = $2 FOR UPDATE`,
+		s.payments.Name(), event.Intent.ID,
+	).Scan(&paymentID, &bookingID, &status, &refunded)
+	if err == pgx.ErrNoRows {
+		return tx.Commit(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	intent := event.Intent
+	prevRefunded := payments.ToCents(refunded)
+	changed := status != intent.Status || intent.Refunded > prevRefunded
+	if !changed || intent.Refunded < prevRefunded || !payments.CanTransition(status, intent.Status) {
+		return tx.Commit(ctx)
+	}
+	if err := setPaymentStatusTx(ctx, tx, paymentID, intent); err != nil {
+		return err
+	}
+
+	switch intent.Status {
+	case payments.StatusCaptured:
+		err = postCapture(ctx, tx, paymentID)
+		if err == nil {
+			err = s.confirmOrRefund(ctx, tx, bookingID, paymentID, intent)
+		}
+	case payments.StatusPartiallyRefunded, payments.StatusRefunded:
+		err = postRefund(ctx, tx, paymentID, intent.Refunded, intent.Refunded-prevRefunded)
+	case payments.StatusFailed:
+		_, err = tx.Exec(ctx,
+			`UPDATE payments SET failure_reason = $2 WHERE id = $1`, paymentID, intent.FailureReason)
+		if err == nil {
+			err = updateBookingStatus(ctx, tx, bookingID, bookingPaymentFailed, webhook.EventBookingCancelled,
+				`UPDATE bookings
+				 SET status = 'cancelled', cancelled_at = CURRENT_TIMESTAMP,
+				     cancellation_reason = 'payment_failed', refund_amount = 0
+				 WHERE id = $1 AND status = 'pending'`)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "event applied",
+		"component", "payments", "event_id", event.ID, "payment_id", paymentID, "from", status, "to", intent.Status)
+	return tx.Commit(ctx)
+}
+
+// confirmOrRefund confirms the booking of a payment reported captured by
+// webhook, refunding the capture if the booking is no longer pending. A failed
+// refund is returned so the event is redelivered and the refund retried.
+func (s *Server) confirmOrRefund(ctx context.Context, tx pgx.Tx, bookingID, paymentID int, intent payments.Intent) error {
+	b, confirmed, err := confirmPaidBooking(ctx, tx, bookingID)
+	if err != nil || confirmed {
+		return err
+	}
+	if err := s.refundCapture(ctx, tx, paymentID, intent); err != nil {
+		return err
+	}
+	slog.WarnContext(ctx, "payment refunded, booking no longer pending",
+		"component", "payments", "booking_id", b.ID, "payment_id", paymentID, "status", b.Status)
+	return nil
+}
+
+// updateBookingStatus runs a status update for a booking reported by a payment
+// and, if the booking changed, queues webhookEvent and notifies its guest and
+// host of event
+func updateBookingStatus(ctx context.Context, tx pgx.Tx, bookingID int, event, webhookEvent, update string) error {
+	tag, err := tx.Exec(ctx, update, bookingID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+	if err := enqueueBookingWebhook(ctx, tx, webhookEvent, bookingID); err != nil {
+		return err
+	}
+	return notifyBooking(ctx, tx, bookingID, event, 0)
+}
@@ -0,0 +1,87 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go-backend/internal/payments"
+)
+
+func TestWebhookCaptureOfExpiredBookingIsRefunded(t *testing.T) {
+	s, db := newTestServer(t)
+	ctx := context.Background()
+	provider := s.payments.(*payments.FakeProvider)
+
+	var userID, propertyID, bookingID, paymentID int
+	email := fmt.Sprintf("guest-%d@example.com", time.Now().UnixNano())
+	err := db.Pool.QueryRow(ctx,
+		`INSERT INTO users (email, name, password_hash) VALUES ($1, 'Guest', 'x') RETURNING id`,
+		email).Scan(&userID)
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	err = db.Pool.QueryRow(ctx,
+		`INSERT INTO properties (title, location, price_per_night, guests, bedrooms, bathrooms, type, owner_id)
+		 VALUES ('Loft', 'Lisbon', 100, 2, 1, 1, 'apartment', $1) RETURNING id`,
+		userID).Scan(&propertyID)
+	if err != nil {
+		t.Fatalf("create property: %v", err)
+	}
+	err = db.Pool.QueryRow(ctx,
+		`INSERT INTO bookings (user_id, property_id, start_date, end_date, guests, status,
+		   nightly_rate, nights, accommodation_total, service_fee, total_price)
+		 VALUES ($1, $2, CURRENT_DATE + 30, CURRENT_DATE + 32, 2, 'expired', 100, 2, 200, 20, 220)
+		 RETURNING id`,
+		userID, propertyID).Scan(&bookingID)
+	if err != nil {
+		t.Fatalf("create booking: %v", err)
+	}
+
+	intent, err := provider.Authorize(ctx, payments.AuthorizeRequest{
+		Amount: 22000, Currency: "usd", PaymentMethod: payments.FakeMethodVisa,
+		Reference: fmt.Sprintf("booking-%d", bookingID),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Pool.QueryRow(ctx,
+		`INSERT INTO payments (booking_id, user_id, provider, provider_ref, amount, currency, status)
+		 VALUES ($1, $2, $3, $4, 220, 'usd', $5) RETURNING id`,
+		bookingID, userID, provider.Name(), intent.ID, intent.Status).Scan(&paymentID)
+	if err != nil {
+		t.Fatalf("create payment: %v", err)
+	}
+	captured, err := provider.Capture(ctx, intent.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := payments.Event{ID: fmt.Sprintf("evt_test_%d", paymentID), Type: payments.EventCaptured, Intent: captured}
+	if err := s.applyPaymentEvent(ctx, event, []byte(`{}`)); err != nil {
+		t.Fatalf("apply event: %v", err)
+	}
+
+	var (
+		paymentStatus string
+		refunded      float64
+		bookingStatus string
+	)
+	err = db.Pool.QueryRow(ctx,
+		`SELECT pay.status, pay.refunded_amount, b.status
+		 FROM payments pay JOIN bookings b ON b.id = pay.booking_id
+		 WHERE pay.id = $1`, paymentID).Scan(&paymentStatus, &refunded, &bookingStatus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paymentStatus != payments.StatusRefunded || payments.ToCents(refunded) != captured.Amount {
+		t.Errorf("payment = %s %.2f, expected %s %.2f", paymentStatus, refunded, payments.StatusRefunded, payments.FromCents(captured.Amount))
+	}
+	if bookingStatus != "expired" {
+		t.Errorf("booking status = %s, expected expired", bookingStatus)
+	}
+	if _, err := provider.Refund(ctx, intent.ID, 1); err == nil {
+		t.Error("expected the provider intent to be fully refunded")
+	}
+}
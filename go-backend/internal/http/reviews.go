@@ -0,0 +1,450 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"go-backend/internal/auth"
+	"go-backend/internal/booking"
+	"go-backend/internal/models"
+	"go-backend/internal/moderation"
+	"go-backend/internal/review"
+)
+
+// reviewColumns is the column list read by scanReview, selected FROM reviewTables
+const reviewColumns = `r.id, r.booking_id, r.property_id, r.author_id, COALESCE(u.name, ''),
+	r.rating, r.cleanliness, r.location, r.value, r.communication, r.comment,
+	r.host_response, r.host_responded_at, r.published_at, r.hidden_at, r.created_at`
+
+const reviewTables = `reviews r LEFT JOIN users u ON u.id = r.author_id`
+
+// scanReview scans a row selected with reviewColumns
+func scanReview(row pgx.Row, rv *models.Review) error {
+	return row.Scan(&rv.ID, &rv.BookingID, &rv.PropertyID, &rv.AuthorID, &rv.AuthorName,
+		&rv.Rating, &rv.Cleanliness, &rv.Location, &rv.Value, &rv.Communication, &rv.Comment,
+		&rv.HostResponse, &rv.HostRespondedAt, &rv.PublishedAt, &rv.HiddenAt, &rv.CreatedAt)
+}
+
+// canSeeReview reports whether user may read a review: published reviews not
+// hidden by moderators are visible to everyone, others only to their author
+// and admins
+func canSeeReview(user auth.UserContext, authorID *int, publishedAt, hiddenAt *time.Time) bool {
+	return (publishedAt != nil && hiddenAt == nil) || user.Role == auth.RoleAdmin ||
+		(authorID != nil && *authorID == user.UserID)
+}
+
+// writeHiddenReview responds to a request for a review the user cannot see
+func writeHiddenReview(w http.ResponseWriter, publishedAt *time.Time) {
+	if publishedAt == nil {
+		writeError(w, http.StatusNotFound, "review is hidden until both reviews are submitted or the review window closes")
+		return
+	}
+	writeError(w, http.StatusNotFound, "not found")
+}
+
+// reviewableStay is a booking being reviewed, loaded by lockReviewableStay
+type reviewableStay struct {
+	PropertyID int
+	GuestID    int
+	OwnerID    *int
+	Status     string
+	EndDate    time.Time
+}
+
+// lockReviewableStay locks a booking for a new review and loads it
+func lockReviewableStay(ctx context.Context, tx pgx.Tx, bookingID int) (reviewableStay, error) {
+	var st reviewableStay
+	propertyID, err := review.Lock(ctx, tx, bookingID)
+	if err != nil {
+		return st, err
+	}
+	st.PropertyID = propertyID
+	err = tx.QueryRow(ctx,
+		`SELECT b.user_id, p.owner_id, b.status, b.end_date
+		 FROM bookings b JOIN properties p ON p.id = b.property_id
+		 WHERE b.id = $1`, bookingID,
+	).Scan(&st.GuestID, &st.OwnerID, &st.Status, &st.EndDate)
+	return st, err
+}
+
+// checkReviewWindow writes an error if the stay cannot be reviewed (yet or
+// anymore) and returns false
+func checkReviewWindow(w http.ResponseWriter, st reviewableStay) bool {
+	if st.Status != "completed" {
+		writeError(w, http.StatusConflict, "only completed stays can be reviewed")
+		return false
+	}
+	if !review.WindowOpen(st.EndDate, booking.Today(time.Now())) {
+		writeError(w, http.StatusConflict, "the review window for this stay has closed")
+		return false
+	}
+	return true
+}
+
+// handleBookingReview serves /api/bookings/{id}/review
+func (s *Server) handleBookingReview(w http.ResponseWriter, r *http.Request, user auth.UserContext, id int) {
+	switch r.Method {
+	case http.MethodGet:
+		guestID, ownerID, err := bookingParties(r.Context(), s.db.Pool, id)
+		if err == pgx.ErrNoRows {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error", "component", "reviews", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		isGuest := guestID == user.UserID
+		isHost := ownerID != nil && *ownerID == user.UserID
+		if !isGuest && !isHost && user.Role != auth.RoleAdmin {
+			writeError(w, http.StatusForbidden, "access denied")
+			return
+		}
+
+		var rv models.Review
+		err = scanReview(s.db.Pool.QueryRow(r.Context(),
+			`SELECT `+reviewColumns+` FROM `+reviewTables+` WHERE r.booking_id = $1`, id), &rv)
+		if err == pgx.ErrNoRows {
+			writeError(w, http.StatusNotFound, "booking has not been reviewed")
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error", "component", "reviews", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		if !canSeeReview(user, rv.AuthorID, rv.PublishedAt, rv.HiddenAt) {
+			writeHiddenReview(w, rv.PublishedAt)
+			return
+		}
+		writeJSON(w, http.StatusOK, rv)
+
+	case http.MethodPost:
+		s.createReview(w, r, user, id)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// createReview lets the guest review a completed booking once within the
+// review window. If the host has already reviewed the guest both reviews are
+// published and the property's rating is updated in the same transaction.
+func (s *Server) createReview(w http.ResponseWriter, r *http.Request, user auth.UserContext, bookingID int) {
+	type req struct {
+		review.Scores
+		Comment string `json:"comment"`
+	}
+	var body req
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := body.Scores.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	comment, err := review.NormalizeComment(body.Comment)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error starting transaction", "component", "reviews", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	st, err := lockReviewableStay(ctx, tx, bookingID)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading booking", "component", "reviews", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if st.GuestID != user.UserID {
+		writeError(w, http.StatusForbidden, "only the guest can review a booking")
+		return
+	}
+	if !checkReviewWindow(w, st) {
+		return
+	}
+
+	var reviewID int
+	err = tx.QueryRow(ctx,
+		`INSERT INTO reviews (booking_id, property_id, author_id, rating,
+		 cleanliness, location, value, communication, comment)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 ON CONFLICT (booking_id) DO NOTHING
+		 RETURNING id`,
+		bookingID, st.PropertyID, user.UserID, body.Rating,
+		body.Cleanliness, body.Location, body.Value, body.Communication, comment,
+	).Scan(&reviewID)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusConflict, "booking has already been reviewed")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error creating review", "component", "reviews", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	if !publishIfBothReviewed(ctx, w, tx, bookingID) {
+		return
+	}
+
+	var rv models.Review
+	err = scanReview(tx.QueryRow(ctx,
+		`SELECT `+reviewColumns+` FROM `+reviewTables+` WHERE r.id = $1`, reviewID), &rv)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading review", "component", "reviews", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(r.Context(), "database error committing review", "component", "reviews", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "review created",
+		"component", "reviews", "review_id", rv.ID, "booking_id", bookingID,
+		"property_id", st.PropertyID, "rating", rv.Rating, "published", rv.PublishedAt != nil)
+	s.prescreen(r.Context(), moderation.TargetReview, rv.ID, comment)
+	writeJSON(w, http.StatusCreated, rv)
+}
+
+// listPropertyReviews returns a page of a property's published reviews,
+// newest first, with the property's average scores
+func (s *Server) listPropertyReviews(w http.ResponseWriter, r *http.Request, propertyID int) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, offset, ok := parsePagination(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "limit must be positive and offset must not be negative")
+		return
+	}
+
+	var exists bool
+	err := s.db.Pool.QueryRow(r.Context(),
+		`SELECT EXISTS(SELECT 1 FROM properties WHERE id = $1)`, propertyID,
+	).Scan(&exists)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "reviews", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if !exists {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	page := models.ReviewPage{Reviews: []models.Review{}, Limit: limit, Offset: offset}
+	sum := &page.Summary
+	err = s.db.Pool.QueryRow(r.Context(),
+		`SELECT COUNT(*),
+		 COALESCE(ROUND(AVG(rating), 1), 0)::float8,
+		 COALESCE(ROUND(AVG(cleanliness), 1), 0)::float8,
+		 COALESCE(ROUND(AVG(location), 1), 0)::float8,
+		 COALESCE(ROUND(AVG(value), 1), 0)::float8,
+		 COALESCE(ROUND(AVG(communication), 1), 0)::float8
+		 FROM reviews WHERE property_id = $1 AND published_at IS NOT NULL AND hidden_at IS NULL`, propertyID,
+	).Scan(&sum.Count, &sum.Rating, &sum.Cleanliness, &sum.Location, &sum.Value, &sum.Communication)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "reviews", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	page.Total = sum.Count
+
+	rows, err := s.db.Pool.Query(r.Context(),
+		`SELECT `+reviewColumns+` FROM `+reviewTables+`
+		 WHERE r.property_id = $1 AND r.published_at IS NOT NULL AND r.hidden_at IS NULL
+		 ORDER BY r.published_at DESC, r.id DESC
+		 LIMIT $2 OFFSET $3`, propertyID, limit, offset)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "reviews", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rv models.Review
+		if err := scanReview(rows, &rv); err != nil {
+			slog.ErrorContext(r.Context(), "scan error", "component", "reviews", "error", err)
+			writeError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		page.Reviews = append(page.Reviews, rv)
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// handleReviewByID serves /api/reviews/{id} and /api/reviews/{id}/response
+func (s *Server) handleReviewByID(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	id, ok := parseIDFromPath(r.URL.Path)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 3:
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var rv models.Review
+		err := scanReview(s.db.Pool.QueryRow(r.Context(),
+			`SELECT `+reviewColumns+` FROM `+reviewTables+` WHERE r.id = $1`, id), &rv)
+		if err == nil && !canSeeReview(user, rv.AuthorID, rv.PublishedAt, rv.HiddenAt) {
+			err = pgx.ErrNoRows
+		}
+		if err == pgx.ErrNoRows {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error", "component", "reviews", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		writeJSON(w, http.StatusOK, rv)
+
+	case len(parts) == 4 && parts[3] == "response":
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		s.respondToReview(w, r, user, id)
+
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// respondToReview publishes the host's public response to a published review.
+// Each review can be answered once.
+func (s *Server) respondToReview(w http.ResponseWriter, r *http.Request, user auth.UserContext, id int) {
+	type req struct {
+		Response string `json:"response"`
+	}
+	var body req
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	response, err := review.NormalizeResponse(body.Response)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var (
+		ownerID   *int
+		published bool
+		responded bool
+	)
+	err = s.db.Pool.QueryRow(r.Context(),
+		`SELECT p.owner_id, r.published_at IS NOT NULL, r.host_response IS NOT NULL
+		 FROM reviews r JOIN properties p ON p.id = r.property_id
+		 WHERE r.id = $1`, id,
+	).Scan(&ownerID, &published, &responded)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "reviews", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if ownerID == nil || *ownerID != user.UserID {
+		writeError(w, http.StatusForbidden, "only the host can respond to a review")
+		return
+	}
+	if !published {
+		writeError(w, http.StatusConflict, "review is not published yet")
+		return
+	}
+	if responded {
+		writeError(w, http.StatusConflict, "review already has a response")
+		return
+	}
+
+	// The IS NULL check keeps a concurrent second response from overwriting the first
+	tag, err := s.db.Pool.Exec(r.Context(),
+		`UPDATE reviews SET host_response = $2, host_responded_at = CURRENT_TIMESTAMP
+		 WHERE id = $1 AND host_response IS NULL`, id, response)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error saving response", "component", "reviews", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		writeError(w, http.StatusConflict, "review already has a response")
+		return
+	}
+
+	var rv models.Review
+	err = scanReview(s.db.Pool.QueryRow(r.Context(),
+		`SELECT `+reviewColumns+` FROM `+reviewTables+` WHERE r.id = $1`, id), &rv)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading review", "component", "reviews", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	notifyReviewResponse(r.Context(), s.db.Pool, rv)
+
+	slog.InfoContext(r.Context(), "host responded to review", "component", "reviews", "review_id", id)
+	s.prescreen(r.Context(), moderation.TargetReview, id, response)
+	writeJSON(w, http.StatusOK, rv)
+}
+
+// publishIfBothReviewed publishes a booking's reviews once both the guest and
+// the host have reviewed, and otherwise asks the other party for their review.
+// The booking must be locked by tx. It writes an error response and returns
+// false on failure.
+func publishIfBothReviewed(ctx context.Context, w http.ResponseWriter, tx pgx.Tx, bookingID int) bool {
+	both, err := review.BothSubmitted(ctx, tx, bookingID)
+	if err == nil && both {
+		_, err = review.Publish(ctx, tx, bookingID)
+	} else if err == nil {
+		notifyReviewPending(ctx, tx, bookingID)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "database error publishing reviews", "component", "reviews", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return false
+	}
+	return true
+}
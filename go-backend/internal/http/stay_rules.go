@@ -0,0 +1,159 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"go-backend/internal/auth"
+	"go-backend/internal/booking"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// querier is implemented by both the connection pool and transactions
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// loadStayRules returns the stay rules of a property, falling back to defaults
+func loadStayRules(ctx context.Context, q querier, propertyID int) (booking.Rules, error) {
+	rules := booking.DefaultRules()
+	err := q.QueryRow(ctx,
+		`SELECT min_nights, max_nights, check_in_days, check_out_days,
+		 advance_notice_days, booking_window_days, preparation_days
+		 FROM property_stay_rules WHERE property_id = $1`, propertyID,
+	).Scan(&rules.MinNights, &rules.MaxNights, &rules.CheckInDays, &rules.CheckOutDays,
+		&rules.AdvanceNoticeDays, &rules.BookingWindowDays, &rules.PreparationDays)
+	if err == pgx.ErrNoRows {
+		return booking.DefaultRules(), nil
+	}
+	return rules, err
+}
+
+// requirePropertyOwner checks that the authenticated user owns the property or is an admin.
+// It writes the error response and returns false if access is denied.
+func (s *Server) requirePropertyOwner(w http.ResponseWriter, r *http.Request, propertyID int) (auth.UserContext, bool) {
+	user, err := auth.UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "authentication required")
+		return user, false
+	}
+
+	var ownerID *int
+	err = s.db.Pool.QueryRow(r.Context(), `SELECT owner_id FROM properties WHERE id = $1`, propertyID).Scan(&ownerID)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not found")
+		return user, false
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "database error")
+		return user, false
+	}
+
+	if user.Role != auth.RoleAdmin && (ownerID == nil || *ownerID != user.UserID) {
+		writeError(w, http.StatusForbidden, "you can only manage your own properties")
+		return user, false
+	}
+	return user, true
+}
+
+func (s *Server) handlePropertyRules(w http.ResponseWriter, r *http.Request, id int) {
+	switch r.Method {
+	case http.MethodGet:
+		var exists bool
+		err := s.db.Pool.QueryRow(r.Context(),
+			`SELECT EXISTS(SELECT 1 FROM properties WHERE id = $1)`, id).Scan(&exists)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error", "component", "stay_rules", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		if !exists {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+
+		rules, err := loadStayRules(r.Context(), s.db.Pool, id)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error", "component", "stay_rules", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		writeJSON(w, http.StatusOK, rules)
+
+	case http.MethodPut:
+		s.updatePropertyRules(w, r, id)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) updatePropertyRules(w http.ResponseWriter, r *http.Request, id int) {
+	if _, ok := s.requirePropertyOwner(w, r, id); !ok {
+		return
+	}
+
+	rules := booking.DefaultRules()
+	if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if rules.CheckInDays == nil {
+		rules.CheckInDays = []int{}
+	}
+	if rules.CheckOutDays == nil {
+		rules.CheckOutDays = []int{}
+	}
+	if err := rules.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tx, err := s.db.Pool.Begin(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error starting transaction", "component", "stay_rules", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	_, err = tx.Exec(r.Context(),
+		`INSERT INTO property_stay_rules (property_id, min_nights, max_nights, check_in_days, check_out_days,
+		 advance_notice_days, booking_window_days, preparation_days)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 ON CONFLICT (property_id) DO UPDATE SET
+		   min_nights = EXCLUDED.min_nights,
+		   max_nights = EXCLUDED.max_nights,
+		   check_in_days = EXCLUDED.check_in_days,
+		   check_out_days = EXCLUDED.check_out_days,
+		   advance_notice_days = EXCLUDED.advance_notice_days,
+		   booking_window_days = EXCLUDED.booking_window_days,
+		   preparation_days = EXCLUDED.preparation_days,
+		   updated_at = CURRENT_TIMESTAMP`,
+		id, rules.MinNights, rules.MaxNights, rules.CheckInDays, rules.CheckOutDays,
+		rules.AdvanceNoticeDays, rules.BookingWindowDays, rules.PreparationDays)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error updating rules", "component", "stay_rules", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := enqueuePropertyWebhook(r.Context(), tx, id); err != nil {
+		slog.ErrorContext(r.Context(), "database error queueing webhook", "component", "stay_rules", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if err := tx.Commit(r.Context()); err != nil {
+		slog.ErrorContext(r.Context(), "database error committing", "component", "stay_rules", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "stay rules updated", "component", "stay_rules", "property_id", id)
+	writeJSON(w, http.StatusOK, rules)
+}
@@ -0,0 +1,677 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+
+	"go-backend/internal/auth"
+	"go-backend/internal/models"
+	"go-backend/internal/notification"
+	"go-backend/internal/support"
+)
+
+// ticketColumns is the column list read by scanTicket, selected FROM support_tickets t
+const ticketColumns = `t.id, t.user_id, t.booking_id, t.category, t.priority, t.status, t.subject, t.assignee_id,
+	t.first_response_due_at, t.resolution_due_at, t.first_responded_at, t.resolved_at, t.closed_at,
+	t.first_response_breached_at, t.resolution_breached_at, t.created_at, t.updated_at`
+
+// scanTicket scans a row selected with ticketColumns
+func scanTicket(row pgx.Row, t *models.SupportTicket) error {
+	err := row.Scan(&t.ID, &t.UserID, &t.BookingID, &t.Category, &t.Priority, &t.Status, &t.Subject, &t.AssigneeID,
+		&t.FirstResponseDueAt, &t.ResolutionDueAt, &t.FirstRespondedAt, &t.ResolvedAt, &t.ClosedAt,
+		&t.FirstResponseBreachedAt, &t.ResolutionBreachedAt, &t.CreatedAt, &t.UpdatedAt)
+	t.Reference = support.Reference(t.ID)
+	return err
+}
+
+// supportReplyColumns is the column list read by scanSupportReply, selected
+// FROM support_replies sr LEFT JOIN users u ON u.id = sr.author_id
+const supportReplyColumns = `sr.id, sr.ticket_id, sr.author_id, COALESCE(u.name, ''), sr.from_agent,
+	sr.body, sr.internal, sr.created_at`
+
+// scanSupportReply scans a row selected with supportReplyColumns
+func scanSupportReply(row pgx.Row, sr *models.SupportReply) error {
+	return row.Scan(&sr.ID, &sr.TicketID, &sr.AuthorID, &sr.AuthorName, &sr.FromAgent,
+		&sr.Body, &sr.Internal, &sr.CreatedAt)
+}
+
+// slaIntervals returns the SLA targets of a priority as interval arguments
+func slaIntervals(priority string) (firstResponse, resolution string) {
+	sla := support.SLAFor(priority)
+	return fmt.Sprintf("%d seconds", int(sla.FirstResponse.Seconds())),
+		fmt.Sprintf("%d seconds", int(sla.Resolution.Seconds()))
+}
+
+// readReplyBody trims a reply or opening message and checks its length
+func readReplyBody(body string) (string, string) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", "message is required"
+	}
+	if utf8.RuneCountInString(body) > support.MaxBodyLength {
+		return "", "message is limited to 5000 characters"
+	}
+	return body, ""
+}
+
+// handleSupportTickets lists tickets and opens new ones. Users see their own
+// tickets; agents see every ticket and can filter the queue by ?status=,
+// ?priority=, ?category=, ?assignee= (an id, "me" or "none"), ?user_id= and
+// ?breached=true, ordered newest first or, with ?sort=due, by resolution due
+// date.
+func (s *Server) handleSupportTickets(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.listSupportTickets(w, r, user)
+	case http.MethodPost:
+		s.createSupportTicket(w, r, user)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) listSupportTickets(w http.ResponseWriter, r *http.Request, user auth.UserContext) {
+	limit, offset, ok := parsePagination(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "limit must be positive and offset must not be negative")
+		return
+	}
+
+	agent := support.IsAgent(user.Role)
+	query := `SELECT ` + ticketColumns + ` FROM support_tickets t WHERE 1=1`
+	args := []any{}
+	q := r.URL.Query()
+
+	if !agent {
+		args = append(args, user.UserID)
+		query += ` AND t.user_id = $` + strconv.Itoa(len(args))
+	}
+	if v := q.Get("status"); v != "" {
+		if !support.ValidStatus(v) {
+			writeError(w, http.StatusBadRequest, "status must be one of "+strings.Join(support.Statuses, ", "))
+			return
+		}
+		args = append(args, v)
+		query += ` AND t.status = $` + strconv.Itoa(len(args))
+	}
+	if v := q.Get("category"); v != "" {
+		if !support.ValidCategory(v) {
+			writeError(w, http.StatusBadRequest, "category must be one of "+strings.Join(support.Categories, ", "))
+			return
+		}
+		args = append(args, v)
+		query += ` AND t.category = $` + strconv.Itoa(len(args))
+	}
+	if agent {
+		if v := q.Get("priority"); v != "" {
+			if !support.ValidPriority(v) {
+				writeError(w, http.StatusBadRequest, "priority must be one of "+strings.Join(support.Priorities, ", "))
+				return
+			}
+			args = append(args, v)
+			query += ` AND t.priority = $` + strconv.Itoa(len(args))
+		}
+		switch v := q.Get("assignee"); v {
+		case "":
+		case "none":
+			query += ` AND t.assignee_id IS NULL`
+		default:
+			assigneeID := user.UserID
+			if v != "me" {
+				var err error
+				if assigneeID, err = strconv.Atoi(v); err != nil || assigneeID <= 0 {
+					writeError(w, http.StatusBadRequest, "assignee must be an id, me or none")
+					return
+				}
+			}
+			args = append(args, assigneeID)
+			query += ` AND t.assignee_id = $` + strconv.Itoa(len(args))
+		}
+		if v := q.Get("user_id"); v != "" {
+			userID, err := strconv.Atoi(v)
+			if err != nil || userID <= 0 {
+				writeError(w, http.StatusBadRequest, "invalid user_id")
+				return
+			}
+			args = append(args, userID)
+			query += ` AND t.user_id = $` + strconv.Itoa(len(args))
+		}
+		if q.Get("breached") == "true" {
+			query += ` AND (t.first_response_breached_at IS NOT NULL OR t.resolution_breached_at IS NOT NULL)`
+		}
+	}
+
+	switch q.Get("sort") {
+	case "", "recent":
+		query += ` ORDER BY t.id DESC`
+	case "due":
+		query += ` ORDER BY t.resolution_due_at, t.id`
+	default:
+		writeError(w, http.StatusBadRequest, "sort must be recent or due")
+		return
+	}
+	args = append(args, limit, offset)
+	query += ` LIMIT $` + strconv.Itoa(len(args)-1) + ` OFFSET $` + strconv.Itoa(len(args))
+
+	rows, err := s.db.Pool.Query(r.Context(), query, args...)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "support", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+
+	tickets := []models.SupportTicket{}
+	for rows.Next() {
+		var t models.SupportTicket
+		if err := scanTicket(rows, &t); err != nil {
+			slog.ErrorContext(r.Context(), "scan error", "component", "support", "error", err)
+			writeError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		tickets = append(tickets, t)
+	}
+
+	writeJSON(w, http.StatusOK, tickets)
+}
+
+// createSupportTicket opens a ticket with the user's first message. A ticket
+// may be linked to a booking the user is the guest or host of.
+func (s *Server) createSupportTicket(w http.ResponseWriter, r *http.Request, user auth.UserContext) {
+	var body struct {
+		Category  string `json:"category"`
+		Subject   string `json:"subject"`
+		Message   string `json:"message"`
+		BookingID *int   `json:"booking_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if !support.ValidCategory(body.Category) {
+		writeError(w, http.StatusBadRequest, "category must be one of "+strings.Join(support.Categories, ", "))
+		return
+	}
+	subject := strings.TrimSpace(body.Subject)
+	if subject == "" {
+		writeError(w, http.StatusBadRequest, "subject is required")
+		return
+	}
+	if utf8.RuneCountInString(subject) > support.MaxSubjectLength {
+		writeError(w, http.StatusBadRequest, "subject is limited to 200 characters")
+		return
+	}
+	message, msg := readReplyBody(body.Message)
+	if msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	ctx := r.Context()
+	if body.BookingID != nil {
+		var allowed bool
+		err := s.db.Pool.QueryRow(ctx,
+			`SELECT b.user_id = $2 OR p.owner_id = $2
+			 FROM bookings b JOIN properties p ON p.id = b.property_id WHERE b.id = $1`,
+			*body.BookingID, user.UserID).Scan(&allowed)
+		if err == pgx.ErrNoRows || (err == nil && !allowed) {
+			writeError(w, http.StatusBadRequest, "booking_id must be one of your bookings")
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error loading booking", "component", "support", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error starting transaction", "component", "support", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	priority := support.DefaultPriority(body.Category)
+	firstResponse, resolution := slaIntervals(priority)
+	var t models.SupportTicket
+	err = scanTicket(tx.QueryRow(ctx,
+		`INSERT INTO support_tickets AS t (user_id, booking_id, category, priority, subject,
+		 first_response_due_at, resolution_due_at)
+		 VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP + $6::interval, CURRENT_TIMESTAMP + $7::interval)
+		 RETURNING `+ticketColumns,
+		user.UserID, body.BookingID, body.Category, priority, subject, firstResponse, resolution), &t)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error creating ticket", "component", "support", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	reply, err := insertSupportReply(ctx, tx, t.ID, user.UserID, false, message, false)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error adding message", "component", "support", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	t.Replies = []models.SupportReply{reply}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(r.Context(), "database error committing ticket", "component", "support", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "ticket opened",
+		"component", "support", "ticket", t.Reference, "category", t.Category, "priority", t.Priority)
+	writeJSON(w, http.StatusCreated, t)
+}
+
+// handleSupportTicketByID routes /api/support/tickets/{id} and
+// /api/support/tickets/{id}/replies
+func (s *Server) handleSupportTicketByID(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 4 || len(parts) > 5 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	id, err := strconv.Atoi(parts[3])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	switch {
+	case len(parts) == 4 && r.Method == http.MethodGet:
+		s.getSupportTicket(w, r, user, id)
+	case len(parts) == 4 && r.Method == http.MethodPut:
+		s.updateSupportTicket(w, r, user, id)
+	case len(parts) == 5 && parts[4] == "replies" && r.Method == http.MethodPost:
+		s.replyToSupportTicket(w, r, user, id)
+	case len(parts) == 5 && parts[4] != "replies":
+		writeError(w, http.StatusNotFound, "not found")
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// loadTicket loads a ticket the user may see, writing the error response if
+// it does not exist or is someone else's. With lock, the row is locked.
+func loadTicket(ctx context.Context, w http.ResponseWriter, q querier, user auth.UserContext, id int, lock bool) (models.SupportTicket, bool) {
+	var t models.SupportTicket
+	query := `SELECT ` + ticketColumns + ` FROM support_tickets t WHERE t.id = $1`
+	if lock {
+		query += ` FOR UPDATE`
+	}
+	err := scanTicket(q.QueryRow(ctx, query, id), &t)
+	if err == nil && t.UserID != user.UserID && !support.IsAgent(user.Role) {
+		err = pgx.ErrNoRows
+	}
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not found")
+		return t, false
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "database error loading ticket", "component", "support", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return t, false
+	}
+	return t, true
+}
+
+// getSupportTicket returns a ticket with its thread. Internal notes are only
+// included for agents.
+func (s *Server) getSupportTicket(w http.ResponseWriter, r *http.Request, user auth.UserContext, id int) {
+	t, ok := loadTicket(r.Context(), w, s.db.Pool, user, id, false)
+	if !ok {
+		return
+	}
+
+	rows, err := s.db.Pool.Query(r.Context(),
+		`SELECT `+supportReplyColumns+`
+		 FROM support_replies sr LEFT JOIN users u ON u.id = sr.author_id
+		 WHERE sr.ticket_id = $1 AND (NOT sr.internal OR $2)
+		 ORDER BY sr.id`, id, support.IsAgent(user.Role))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error loading replies", "component", "support", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+
+	t.Replies = []models.SupportReply{}
+	for rows.Next() {
+		var sr models.SupportReply
+		if err := scanSupportReply(rows, &sr); err != nil {
+			slog.ErrorContext(r.Context(), "scan error", "component", "support", "error", err)
+			writeError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		t.Replies = append(t.Replies, sr)
+	}
+
+	writeJSON(w, http.StatusOK, t)
+}
+
+// updateSupportTicket changes a ticket's status, priority or assignee. Agents
+// may change any of them; users may only close their tickets. A new priority
+// moves the SLA targets, counted from when the ticket was opened; an
+// assignee_id of 0 unassigns the ticket.
+func (s *Server) updateSupportTicket(w http.ResponseWriter, r *http.Request, user auth.UserContext, id int) {
+	var body struct {
+		Status     *string `json:"status"`
+		Priority   *string `json:"priority"`
+		AssigneeID *int    `json:"assignee_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	agent := support.IsAgent(user.Role)
+	if !agent && (body.Priority != nil || body.AssigneeID != nil) {
+		writeError(w, http.StatusForbidden, "only support agents can change the priority or assignee")
+		return
+	}
+	if body.Priority != nil && !support.ValidPriority(*body.Priority) {
+		writeError(w, http.StatusBadRequest, "priority must be one of "+strings.Join(support.Priorities, ", "))
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error starting transaction", "component", "support", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	t, ok := loadTicket(ctx, w, tx, user, id, true)
+	if !ok {
+		return
+	}
+	if body.Status != nil && *body.Status != t.Status && !support.CanSetStatus(t.Status, *body.Status, agent) {
+		if !agent {
+			writeError(w, http.StatusForbidden, "you can only close your tickets")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "status must be one of "+strings.Join(support.Statuses, ", "))
+		return
+	}
+
+	var newAssignee *int
+	if body.AssigneeID != nil && *body.AssigneeID != 0 {
+		var role string
+		err := tx.QueryRow(ctx, `SELECT role FROM users WHERE id = $1`, *body.AssigneeID).Scan(&role)
+		if err == pgx.ErrNoRows || (err == nil && !support.IsAgent(role)) {
+			writeError(w, http.StatusBadRequest, "assignee must be a support agent")
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error loading assignee", "component", "support", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		newAssignee = body.AssigneeID
+	}
+
+	status, priority, assignee := t.Status, t.Priority, t.AssigneeID
+	if body.Status != nil {
+		status = *body.Status
+	}
+	if body.Priority != nil {
+		priority = *body.Priority
+	}
+	if body.AssigneeID != nil {
+		assignee = newAssignee
+	}
+	firstResponse, resolution := slaIntervals(priority)
+
+	// Due dates are recomputed on every update so a priority change moves them;
+	// a breach is forgotten if the new target has not passed yet
+	err = scanTicket(tx.QueryRow(ctx,
+		`UPDATE support_tickets t
+		 SET status = $2, priority = $3, assignee_id = $4,
+		     first_response_due_at = created_at + $5::interval,
+		     resolution_due_at = created_at + $6::interval,
+		     first_response_breached_at = CASE WHEN created_at + $5::interval > CURRENT_TIMESTAMP
+		         THEN NULL ELSE first_response_breached_at END,
+		     resolution_breached_at = CASE WHEN created_at + $6::interval > CURRENT_TIMESTAMP
+		         THEN NULL ELSE resolution_breached_at END,
+		     resolved_at = CASE WHEN $2 = 'resolved' THEN COALESCE(resolved_at, CURRENT_TIMESTAMP)
+		         WHEN $2 = 'closed' THEN resolved_at END,
+		     closed_at = CASE WHEN $2 = 'closed' THEN COALESCE(closed_at, CURRENT_TIMESTAMP) END,
+		     updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $1
+		 RETURNING `+ticketColumns,
+		id, status, priority, assignee, firstResponse, resolution), &t)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error updating ticket", "component", "support", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	if newAssignee != nil && *newAssignee != user.UserID {
+		notifySupport(ctx, tx, t, *newAssignee, notification.PriorityMedium,
+			"Ticket assigned to you", fmt.Sprintf("%s: %s", t.Reference, t.Subject))
+	}
+	if agent && t.UserID != user.UserID && body.Status != nil && *body.Status == support.StatusResolved {
+		notifySupport(ctx, tx, t, t.UserID, notification.PriorityMedium,
+			"Your support request was resolved",
+			fmt.Sprintf("%s: %s. Reply within 7 days if you still need help.", t.Reference, t.Subject))
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(r.Context(), "database error committing ticket update", "component", "support", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "ticket updated",
+		"component", "support", "ticket", t.Reference, "status", t.Status, "priority", t.Priority)
+	writeJSON(w, http.StatusOK, t)
+}
+
+// replyToSupportTicket adds a reply to a ticket thread. A public reply from an
+// agent counts as the first response, assigns an unassigned ticket to them and
+// leaves the ticket waiting on the user; a user's reply reopens it. Internal
+// notes, which only agents can add, change nothing.
+func (s *Server) replyToSupportTicket(w http.ResponseWriter, r *http.Request, user auth.UserContext, id int) {
+	var body struct {
+		Body     string `json:"body"`
+		Internal bool   `json:"internal"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	text, msg := readReplyBody(body.Body)
+	if msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	agent := support.IsAgent(user.Role)
+	if body.Internal && !agent {
+		writeError(w, http.StatusForbidden, "only support agents can add internal notes")
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error starting transaction", "component", "support", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	t, ok := loadTicket(ctx, w, tx, user, id, true)
+	if !ok {
+		return
+	}
+	// Agents replying to their own ticket reply as its user
+	fromAgent := agent && t.UserID != user.UserID
+
+	if !body.Internal {
+		status, err := support.StatusAfterReply(t.Status, fromAgent)
+		if err != nil {
+			writeError(w, http.StatusConflict, "ticket is closed; open a new ticket")
+			return
+		}
+		err = scanTicket(tx.QueryRow(ctx,
+			`UPDATE support_tickets t
+			 SET status = $2, resolved_at = NULL, updated_at = CURRENT_TIMESTAMP,
+			     first_responded_at = CASE WHEN $3 THEN COALESCE(first_responded_at, CURRENT_TIMESTAMP)
+			         ELSE first_responded_at END,
+			     assignee_id = CASE WHEN $3 THEN COALESCE(assignee_id, $4) ELSE assignee_id END
+			 WHERE id = $1
+			 RETURNING `+ticketColumns,
+			id, status, fromAgent, user.UserID), &t)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error updating ticket", "component", "support", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+	}
+
+	reply, err := insertSupportReply(ctx, tx, id, user.UserID, fromAgent, text, body.Internal)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error adding reply", "component", "support", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	switch {
+	case body.Internal:
+	case fromAgent:
+		notifySupport(ctx, tx, t, t.UserID, notification.PriorityMedium,
+			"Support replied to your request",
+			fmt.Sprintf("%s: %s", t.Reference, notification.Truncate(text, messagePreviewLength)))
+	case t.AssigneeID != nil && *t.AssigneeID != user.UserID:
+		notifySupport(ctx, tx, t, *t.AssigneeID, notification.PriorityMedium,
+			"New reply on "+t.Reference, notification.Truncate(text, messagePreviewLength))
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.ErrorContext(r.Context(), "database error committing reply", "component", "support", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, reply)
+}
+
+// insertSupportReply adds a reply to a ticket thread
+func insertSupportReply(ctx context.Context, q querier, ticketID, authorID int, fromAgent bool, body string, internal bool) (models.SupportReply, error) {
+	var sr models.SupportReply
+	err := scanSupportReply(q.QueryRow(ctx,
+		`WITH sr AS (
+		   INSERT INTO support_replies (ticket_id, author_id, from_agent, body, internal)
+		   VALUES ($1, $2, $3, $4, $5)
+		   RETURNING *
+		 )
+		 SELECT `+supportReplyColumns+` FROM sr LEFT JOIN users u ON u.id = sr.author_id`,
+		ticketID, authorID, fromAgent, body, internal), &sr)
+	return sr, err
+}
+
+// notifySupport notifies a user about a ticket. Notifications about the
+// same ticket replace each other while unread.
+func notifySupport(ctx context.Context, db notification.TxStarter, t models.SupportTicket, userID int, priority, title, message string) {
+	notify(ctx, db, func(pgx.Tx) ([]notification.Notification, error) {
+		return []notification.Notification{{
+			UserID:      userID,
+			Type:        notification.TypeSystem,
+			Priority:    priority,
+			Title:       title,
+			Message:     message,
+			ActionLabel: "View ticket",
+			ActionURL:   fmt.Sprintf("/support/tickets/%d", t.ID),
+			GroupKey:    fmt.Sprintf("support:%d:%d", t.ID, userID),
+		}}, nil
+	})
+}
+
+// handleAdminUserByID handles PUT /api/admin/users/{id}/role, which gives a
+// user the user, support or admin role. The role is carried by the token, so
+// it takes effect when the user next signs in. Admins cannot change their own
+// role.
+func (s *Server) handleAdminUserByID(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 5 || parts[4] != "role" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(parts[3])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var body struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	valid := false
+	for _, role := range auth.Roles {
+		valid = valid || role == body.Role
+	}
+	if !valid {
+		writeError(w, http.StatusBadRequest, "role must be one of "+strings.Join(auth.Roles, ", "))
+		return
+	}
+	if id == user.UserID {
+		writeError(w, http.StatusConflict, "you cannot change your own role")
+		return
+	}
+
+	var u models.UserResponse
+	err = s.db.Pool.QueryRow(r.Context(),
+		`UPDATE users SET role = $2 WHERE id = $1 RETURNING id, email, name, role, created_at`,
+		id, body.Role).Scan(&u.ID, &u.Email, &u.Name, &u.Role, &u.CreatedAt)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error updating role", "component", "admin_users", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "user role changed", "component", "admin_users", "target_user_id", u.ID, "role", u.Role)
+	writeJSON(w, http.StatusOK, u)
+}
@@ -0,0 +1,501 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+
+	"go-backend/internal/auth"
+	"go-backend/internal/models"
+	"go-backend/internal/webhook"
+)
+
+// maxWebhookDescriptionLength limits subscription descriptions
+const maxWebhookDescriptionLength = 255
+
+// subscriptionColumns is the column list read by scanSubscription
+const subscriptionColumns = `id, url, events, description, active, created_by, created_at, updated_at`
+
+// scanSubscription scans a row selected with subscriptionColumns
+func scanSubscription(row pgx.Row, sub *models.WebhookSubscription) error {
+	return row.Scan(&sub.ID, &sub.URL, &sub.Events, &sub.Description, &sub.Active, &sub.CreatedBy,
+		&sub.CreatedAt, &sub.UpdatedAt)
+}
+
+// deliveryColumns is the column list read by scanDelivery, selected FROM
+// webhook_deliveries d JOIN webhook_events e
+const deliveryColumns = `d.id, d.subscription_id, d.event_id, e.type, d.status, d.attempts,
+	CASE WHEN d.status = 'pending' THEN d.next_attempt_at END, d.last_status_code, d.last_error,
+	d.delivered_at, d.created_at`
+
+// scanDelivery scans a row selected with deliveryColumns, followed by extra
+// destinations for additional columns
+func scanDelivery(row pgx.Row, d *models.WebhookDelivery, extra ...any) error {
+	return row.Scan(append([]any{&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.DeliveredAt, &d.CreatedAt}, extra...)...)
+}
+
+// enqueueBookingWebhook queues a booking event carrying the booking as it is
+// in the transaction q
+func enqueueBookingWebhook(ctx context.Context, q querier, event string, bookingID int) error {
+	var b models.Booking
+	if err := scanBooking(q.QueryRow(ctx, `SELECT `+bookingColumns+` FROM bookings WHERE id = $1`, bookingID), &b); err != nil {
+		return err
+	}
+	return webhook.Enqueue(ctx, q, event, b)
+}
+
+// enqueuePropertyWebhook queues property.updated carrying the property as it
+// is in the transaction q, for changes to the listing or to its rules, policy,
+// calendar or feeds
+func enqueuePropertyWebhook(ctx context.Context, q querier, propertyID int) error {
+	var p models.Property
+	err := q.QueryRow(ctx,
+		`SELECT id, title, location, price_per_night, rating, reviews,
+		 guests, bedrooms, bathrooms, type, amenities, COALESCE(image, ''), owner_id, timezone, created_at
+		 FROM properties WHERE id = $1`, propertyID,
+	).Scan(&p.ID, &p.Title, &p.Location, &p.PricePerNight, &p.Rating,
+		&p.Reviews, &p.Guests, &p.Bedrooms, &p.Bathrooms, &p.Type,
+		&p.Amenities, &p.Image, &p.OwnerID, &p.Timezone, &p.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return webhook.Enqueue(ctx, q, webhook.EventPropertyUpdated, p)
+}
+
+// writeWebhookError writes a subscription validation error
+func writeWebhookError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, webhook.ErrUnknownEvent):
+		writeError(w, http.StatusBadRequest, "events must be among "+strings.Join(webhook.Events, ", "))
+	default:
+		writeError(w, http.StatusBadRequest, err.Error())
+	}
+}
+
+// handleAdminWebhooks lists webhook subscriptions and creates them. The
+// signing secret is only returned on creation.
+func (s *Server) handleAdminWebhooks(w http.ResponseWriter, r *http.Request) {
+	user, err := auth.UserFromContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		limit, offset, ok := parsePagination(r)
+		if !ok {
+			writeError(w, http.StatusBadRequest, "limit must be positive and offset must not be negative")
+			return
+		}
+		rows, err := s.db.Pool.Query(r.Context(),
+			`SELECT `+subscriptionColumns+` FROM webhook_subscriptions ORDER BY id LIMIT $1 OFFSET $2`,
+			limit, offset)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error", "component", "webhooks", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		defer rows.Close()
+
+		subs := []models.WebhookSubscription{}
+		for rows.Next() {
+			var sub models.WebhookSubscription
+			if err := scanSubscription(rows, &sub); err != nil {
+				slog.ErrorContext(r.Context(), "scan error", "component", "webhooks", "error", err)
+				writeError(w, http.StatusInternalServerError, "scan error")
+				return
+			}
+			subs = append(subs, sub)
+		}
+		writeJSON(w, http.StatusOK, subs)
+
+	case http.MethodPost:
+		var body struct {
+			URL         string   `json:"url"`
+			Events      []string `json:"events"`
+			Description string   `json:"description"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+		body.URL = strings.TrimSpace(body.URL)
+		if err := webhook.ValidateURL(body.URL); err != nil {
+			writeWebhookError(w, err)
+			return
+		}
+		if err := webhook.ValidateEvents(body.Events); err != nil {
+			writeWebhookError(w, err)
+			return
+		}
+		if utf8.RuneCountInString(body.Description) > maxWebhookDescriptionLength {
+			writeError(w, http.StatusBadRequest, "description is limited to 255 characters")
+			return
+		}
+
+		secret, err := webhook.NewSecret()
+		if err != nil {
+			slog.ErrorContext(r.Context(), "error generating secret", "component", "webhooks", "error", err)
+			writeError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+
+		var sub models.WebhookSubscription
+		err = scanSubscription(s.db.Pool.QueryRow(r.Context(),
+			`INSERT INTO webhook_subscriptions (url, events, secret, description, created_by)
+			 VALUES ($1, $2, $3, $4, $5)
+			 RETURNING `+subscriptionColumns,
+			body.URL, body.Events, secret, body.Description, user.UserID), &sub)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "database error creating subscription", "component", "webhooks", "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		sub.Secret = secret
+
+		slog.InfoContext(r.Context(), "subscription created", "component", "webhooks", "subscription_id", sub.ID, "events", sub.Events)
+		writeJSON(w, http.StatusCreated, sub)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAdminWebhookByID routes /api/admin/webhooks/{id} and its
+// sub-resources: rotate-secret, deliveries, deliveries/{id} and
+// deliveries/{id}/redeliver
+func (s *Server) handleAdminWebhookByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 4 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	id, err := strconv.Atoi(parts[3])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	switch {
+	case len(parts) == 4:
+		switch r.Method {
+		case http.MethodGet:
+			s.getWebhookSubscription(w, r, id)
+		case http.MethodPut:
+			s.updateWebhookSubscription(w, r, id)
+		case http.MethodDelete:
+			s.deleteWebhookSubscription(w, r, id)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+
+	case len(parts) == 5 && parts[4] == "rotate-secret":
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		s.rotateWebhookSecret(w, r, id)
+
+	case len(parts) == 5 && parts[4] == "deliveries":
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		s.listWebhookDeliveries(w, r, id)
+
+	case len(parts) >= 6 && len(parts) <= 7 && parts[4] == "deliveries":
+		deliveryID, err := strconv.Atoi(parts[5])
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid delivery id")
+			return
+		}
+		switch {
+		case len(parts) == 6 && r.Method == http.MethodGet:
+			s.getWebhookDelivery(w, r, id, deliveryID)
+		case len(parts) == 7 && parts[6] == "redeliver" && r.Method == http.MethodPost:
+			s.redeliverWebhook(w, r, id, deliveryID)
+		case len(parts) == 7 && parts[6] != "redeliver":
+			writeError(w, http.StatusNotFound, "not found")
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) getWebhookSubscription(w http.ResponseWriter, r *http.Request, id int) {
+	var sub models.WebhookSubscription
+	err := scanSubscription(s.db.Pool.QueryRow(r.Context(),
+		`SELECT `+subscriptionColumns+` FROM webhook_subscriptions WHERE id = $1`, id), &sub)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "webhooks", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	writeJSON(w, http.StatusOK, sub)
+}
+
+// updateWebhookSubscription changes the fields present in the body; setting
+// active to false pauses deliveries, which are held until it is resumed
+func (s *Server) updateWebhookSubscription(w http.ResponseWriter, r *http.Request, id int) {
+	var body struct {
+		URL         *string   `json:"url"`
+		Events      *[]string `json:"events"`
+		Description *string   `json:"description"`
+		Active      *bool     `json:"active"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if body.URL != nil {
+		*body.URL = strings.TrimSpace(*body.URL)
+		if err := webhook.ValidateURL(*body.URL); err != nil {
+			writeWebhookError(w, err)
+			return
+		}
+	}
+	var events []string
+	if body.Events != nil {
+		events = *body.Events
+		if err := webhook.ValidateEvents(events); err != nil {
+			writeWebhookError(w, err)
+			return
+		}
+	}
+	if body.Description != nil && utf8.RuneCountInString(*body.Description) > maxWebhookDescriptionLength {
+		writeError(w, http.StatusBadRequest, "description is limited to 255 characters")
+		return
+	}
+
+	var sub models.WebhookSubscription
+	err := scanSubscription(s.db.Pool.QueryRow(r.Context(),
+		`UPDATE webhook_subscriptions
+		 SET url = COALESCE($2, url), events = COALESCE($3, events),
+		     description = COALESCE($4, description), active = COALESCE($5, active),
+		     updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $1
+		 RETURNING `+subscriptionColumns,
+		id, body.URL, events, body.Description, body.Active), &sub)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error updating subscription", "component", "webhooks", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	writeJSON(w, http.StatusOK, sub)
+}
+
+// deleteWebhookSubscription deletes a subscription with its deliveries
+func (s *Server) deleteWebhookSubscription(w http.ResponseWriter, r *http.Request, id int) {
+	tag, err := s.db.Pool.Exec(r.Context(), `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error deleting subscription", "component", "webhooks", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// rotateWebhookSecret replaces a subscription's signing secret and returns
+// the new one. Pending deliveries are signed with the new secret.
+func (s *Server) rotateWebhookSecret(w http.ResponseWriter, r *http.Request, id int) {
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error generating secret", "component", "webhooks", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	var sub models.WebhookSubscription
+	err = scanSubscription(s.db.Pool.QueryRow(r.Context(),
+		`UPDATE webhook_subscriptions SET secret = $2, updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $1
+		 RETURNING `+subscriptionColumns, id, secret), &sub)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error rotating secret", "component", "webhooks", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	sub.Secret = secret
+	writeJSON(w, http.StatusOK, sub)
+}
+
+// listWebhookDeliveries lists a subscription's deliveries, newest first,
+// optionally filtered by ?status= and ?event=
+func (s *Server) listWebhookDeliveries(w http.ResponseWriter, r *http.Request, id int) {
+	limit, offset, ok := parsePagination(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "limit must be positive and offset must not be negative")
+		return
+	}
+
+	var exists bool
+	err := s.db.Pool.QueryRow(r.Context(),
+		`SELECT EXISTS (SELECT 1 FROM webhook_subscriptions WHERE id = $1)`, id).Scan(&exists)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "webhooks", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	if !exists {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	query := `SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries d JOIN webhook_events e ON e.id = d.event_id
+		WHERE d.subscription_id = $1`
+	args := []any{id}
+	q := r.URL.Query()
+	if status := q.Get("status"); status != "" {
+		if status != webhook.StatusPending && status != webhook.StatusDelivered && status != webhook.StatusDead {
+			writeError(w, http.StatusBadRequest, "status must be pending, delivered or dead")
+			return
+		}
+		args = append(args, status)
+		query += ` AND d.status = $` + strconv.Itoa(len(args))
+	}
+	if event := q.Get("event"); event != "" {
+		if !webhook.ValidEvent(event) {
+			writeError(w, http.StatusBadRequest, "invalid event")
+			return
+		}
+		args = append(args, event)
+		query += ` AND e.type = $` + strconv.Itoa(len(args))
+	}
+	args = append(args, limit, offset)
+	query += ` ORDER BY d.id DESC LIMIT $` + strconv.Itoa(len(args)-1) + ` OFFSET $` + strconv.Itoa(len(args))
+
+	rows, err := s.db.Pool.Query(r.Context(), query, args...)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "webhooks", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := scanDelivery(rows, &d); err != nil {
+			slog.ErrorContext(r.Context(), "scan error", "component", "webhooks", "error", err)
+			writeError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		deliveries = append(deliveries, d)
+	}
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// getWebhookDelivery returns a delivery with the payload it posts and the log
+// of its attempts
+func (s *Server) getWebhookDelivery(w http.ResponseWriter, r *http.Request, subscriptionID, id int) {
+	var d models.WebhookDelivery
+	var env webhook.Envelope
+	err := scanDelivery(s.db.Pool.QueryRow(r.Context(),
+		`SELECT `+deliveryColumns+`, e.created_at, e.data
+		 FROM webhook_deliveries d JOIN webhook_events e ON e.id = d.event_id
+		 WHERE d.id = $1 AND d.subscription_id = $2`, id, subscriptionID),
+		&d, &env.CreatedAt, &env.Data)
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "webhooks", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	env.ID, env.Type = d.EventID, d.EventType
+	if d.Payload, err = json.Marshal(env); err != nil {
+		slog.ErrorContext(r.Context(), "error encoding payload", "component", "webhooks", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	rows, err := s.db.Pool.Query(r.Context(),
+		`SELECT attempt, status_code, error, response_body, duration_ms, created_at
+		 FROM webhook_delivery_attempts WHERE delivery_id = $1 ORDER BY id`, id)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error", "component", "webhooks", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	defer rows.Close()
+
+	d.AttemptLog = []models.WebhookDeliveryAttempt{}
+	for rows.Next() {
+		var a models.WebhookDeliveryAttempt
+		if err := rows.Scan(&a.Attempt, &a.StatusCode, &a.Error, &a.ResponseBody, &a.DurationMS, &a.CreatedAt); err != nil {
+			slog.ErrorContext(r.Context(), "scan error", "component", "webhooks", "error", err)
+			writeError(w, http.StatusInternalServerError, "scan error")
+			return
+		}
+		d.AttemptLog = append(d.AttemptLog, a)
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+// redeliverWebhook queues a delivered or dead delivery again with a fresh
+// set of attempts. The event is posted with its original ID and payload.
+func (s *Server) redeliverWebhook(w http.ResponseWriter, r *http.Request, subscriptionID, id int) {
+	var d models.WebhookDelivery
+	err := scanDelivery(s.db.Pool.QueryRow(r.Context(),
+		`WITH updated AS (
+		   UPDATE webhook_deliveries
+		   SET status = 'pending', attempts = 0, next_attempt_at = CURRENT_TIMESTAMP, delivered_at = NULL
+		   WHERE id = $1 AND subscription_id = $2 AND status <> 'pending'
+		   RETURNING *
+		 )
+		 SELECT `+deliveryColumns+` FROM updated d JOIN webhook_events e ON e.id = d.event_id`,
+		id, subscriptionID), &d)
+	if err == pgx.ErrNoRows {
+		var status string
+		err = s.db.Pool.QueryRow(r.Context(),
+			`SELECT status FROM webhook_deliveries WHERE id = $1 AND subscription_id = $2`,
+			id, subscriptionID).Scan(&status)
+		if err == nil {
+			writeError(w, http.StatusConflict, "delivery is already pending")
+			return
+		}
+	}
+	if err == pgx.ErrNoRows {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "database error redelivering", "component", "webhooks", "error", err)
+		writeError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "delivery queued for redelivery", "component", "webhooks", "delivery_id", d.ID)
+	writeJSON(w, http.StatusAccepted, d)
+}
@@ -0,0 +1,145 @@
+package ical
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// MaxFeedSize limits how much of an external feed is read
+const MaxFeedSize = 5 << 20
+
+// maxRedirects is how many redirects a feed fetch follows
+const maxRedirects = 5
+
+// Feed URL errors
+var (
+	ErrInvalidURL     = errors.New("url must be an http or https URL")
+	ErrBlockedAddress = errors.New("feed address is not allowed")
+)
+
+// Fetcher retrieves external calendar feeds
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) (io.ReadCloser, error)
+}
+
+// HTTPFetcher fetches feeds over HTTP(S). Feeds are registered by hosts, so
+// it refuses to connect to loopback, private, link-local and other internal
+// addresses, checked on the resolved IP of every connection including those
+// of redirects.
+type HTTPFetcher struct {
+	client *http.Client
+
+	// blocked reports whether an address may not be dialed
+	blocked func(netip.Addr) bool
+}
+
+// NewHTTPFetcher creates a fetcher with the given request timeout
+func NewHTTPFetcher(timeout time.Duration) *HTTPFetcher {
+	f := &HTTPFetcher{blocked: BlockedAddr}
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil || f.blocked(ap.Addr()) {
+				return ErrBlockedAddress
+			}
+			return nil
+		},
+	}
+	f.client = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// No proxy: the dialer must see the feed's own address
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+			return checkURL(req.URL, f.blocked)
+		},
+	}
+	return f
+}
+
+// Fetch downloads a feed. The caller must close the returned body.
+func (f *HTTPFetcher) Fetch(ctx context.Context, rawURL string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	if err := checkURL(req.URL, f.blocked); err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/calendar")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch feed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetch feed: unexpected status %d", resp.StatusCode)
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(resp.Body, MaxFeedSize), resp.Body}, nil
+}
+
+// ValidateURL checks a feed URL before it is registered: it must be an http
+// or https URL without credentials, and must not name an internal host.
+// Host names are checked again on every connection as they resolve.
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ErrInvalidURL
+	}
+	return checkURL(u, BlockedAddr)
+}
+
+func checkURL(u *url.URL, blocked func(netip.Addr) bool) error {
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return ErrInvalidURL
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrBlockedAddress
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && blocked(addr) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// BlockedAddr reports whether an address is internal: loopback, private,
+// link-local (which includes cloud metadata endpoints), multicast,
+// unspecified or shared address space
+func BlockedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return !addr.IsValid() ||
+		addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified() ||
+		(addr.Is4() && addr.As4()[0] == 0) ||
+		sharedAddressSpace.Contains(addr)
+}
@@ -0,0 +1,239 @@
+// Package ical reads and writes the subset of RFC 5545 calendars used for
+// availability sync with other rental platforms.
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	dateLayout     = "20060102"
+	dateTimeLayout = "20060102T150405"
+	maxLineOctets  = 75
+)
+
+// ErrNoCalendar is returned when the input does not contain a VCALENDAR
+var ErrNoCalendar = errors.New("no VCALENDAR found")
+
+// Event is an all-day calendar event covering the nights in [Start, End)
+type Event struct {
+	UID     string
+	Summary string
+	Start   time.Time
+	End     time.Time
+}
+
+// Parse reads the VEVENTs of a calendar. Timed events are truncated to whole
+// days in their own time zone; cancelled events are skipped.
+func Parse(r io.Reader) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		events      []Event
+		current     *Event
+		cancelled   bool
+		hasEnd      bool
+		hasCalendar bool
+	)
+
+	for _, line := range lines {
+		name, params, value := splitProperty(line)
+		switch name {
+		case "BEGIN":
+			switch strings.ToUpper(value) {
+			case "VCALENDAR":
+				hasCalendar = true
+			case "VEVENT":
+				current = &Event{}
+				cancelled, hasEnd = false, false
+			}
+		case "END":
+			if strings.ToUpper(value) != "VEVENT" || current == nil {
+				continue
+			}
+			if !current.Start.IsZero() && !cancelled {
+				if !hasEnd || !current.End.After(current.Start) {
+					current.End = current.Start.AddDate(0, 0, 1)
+				}
+				events = append(events, *current)
+			}
+			current = nil
+		}
+
+		if current == nil {
+			continue
+		}
+		switch name {
+		case "UID":
+			current.UID = value
+		case "SUMMARY":
+			current.Summary = unescapeText(value)
+		case "STATUS":
+			cancelled = strings.EqualFold(value, "CANCELLED")
+		case "DTSTART":
+			t, err := parseDate(value, params)
+			if err != nil {
+				return nil, fmt.Errorf("DTSTART: %w", err)
+			}
+			current.Start = t
+		case "DTEND":
+			t, err := parseDate(value, params)
+			if err != nil {
+				return nil, fmt.Errorf("DTEND: %w", err)
+			}
+			current.End = t
+			hasEnd = true
+		}
+	}
+
+	if !hasCalendar {
+		return nil, ErrNoCalendar
+	}
+	return events, nil
+}
+
+// Write encodes events as an RFC 5545 calendar of all-day events
+func Write(w io.Writer, name string, events []Event, stamp time.Time) error {
+	sorted := make([]Event, len(events))
+	copy(sorted, events)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//GoRent//Availability Export//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:" + escapeText(name),
+	}
+	for _, e := range sorted {
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+e.UID,
+			"DTSTAMP:"+stamp.UTC().Format(dateTimeLayout)+"Z",
+			"DTSTART;VALUE=DATE:"+e.Start.Format(dateLayout),
+			"DTEND;VALUE=DATE:"+e.End.Format(dateLayout),
+			"SUMMARY:"+escapeText(e.Summary),
+			"END:VEVENT",
+		)
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	bw := bufio.NewWriter(w)
+	for _, line := range lines {
+		if _, err := bw.WriteString(fold(line)); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// unfold joins continuation lines (those starting with a space or tab)
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// splitProperty splits "NAME;PARAM=x:VALUE" into its parts, ignoring colons inside quoted params
+func splitProperty(line string) (string, map[string]string, string) {
+	inQuotes := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			inQuotes = !inQuotes
+		}
+		if c == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return strings.ToUpper(line), nil, ""
+	}
+
+	head, value := line[:colon], line[colon+1:]
+	parts := strings.Split(head, ";")
+	params := map[string]string{}
+	for _, p := range parts[1:] {
+		if k, v, ok := strings.Cut(p, "="); ok {
+			params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, value
+}
+
+// parseDate parses DATE and DATE-TIME values and truncates them to a UTC date
+func parseDate(value string, params map[string]string) (time.Time, error) {
+	if len(value) == len(dateLayout) {
+		return time.Parse(dateLayout, value)
+	}
+
+	loc := time.UTC
+	if strings.HasSuffix(value, "Z") {
+		value = strings.TrimSuffix(value, "Z")
+	} else if tzid := params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+
+	t, err := time.ParseInLocation(dateTimeLayout, value, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC), nil
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+var textUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+func unescapeText(s string) string {
+	return textUnescaper.Replace(s)
+}
+
+// fold splits a content line into 75-octet chunks terminated by CRLF,
+// without breaking UTF-8 sequences
+func fold(line string) string {
+	var b strings.Builder
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = maxLineOctets - 1 // continuation lines start with a space
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
@@ -0,0 +1,199 @@
+// Package idempotency lets clients safely retry mutating requests. The first
+// response for an Idempotency-Key is stored and replayed for later requests
+// with the same key, so a retried booking is not created twice.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Header is the request header carrying the client's key
+const Header = "Idempotency-Key"
+
+// ReplayedHeader is set on responses replayed from a stored record
+const ReplayedHeader = "Idempotent-Replayed"
+
+// DefaultTTL is how long stored responses are kept
+const DefaultTTL = 24 * time.Hour
+
+// MaxKeyLength is the longest key accepted
+const MaxKeyLength = 255
+
+// maxBodySize is the largest request body accepted with a key. Larger bodies
+// are rejected rather than truncated, as the fingerprint must cover the whole
+// request.
+const maxBodySize = 1 << 20
+
+// ErrInProgress is returned by Store.Begin while the first request for a key
+// has not completed yet
+var ErrInProgress = errors.New("request with this idempotency key is in progress")
+
+// Scope identifies a key: keys are unique per user and route
+type Scope struct {
+	UserID int
+	Key    string
+	Method string
+	Path   string
+}
+
+// Record is a stored response
+type Record struct {
+	Fingerprint string
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// Store persists records. Implementations must be safe for concurrent use.
+type Store interface {
+	// Begin claims scope for a request with the given fingerprint. It returns
+	// nil if the caller should handle the request, the stored record if the
+	// key was already used, or ErrInProgress if the same request is still
+	// being handled.
+	Begin(ctx context.Context, scope Scope, fingerprint string) (*Record, error)
+	// Complete stores the response for a claimed scope
+	Complete(ctx context.Context, scope Scope, rec Record) error
+	// Release forgets a claimed scope so the request can be retried
+	Release(ctx context.Context, scope Scope) error
+}
+
+// Fingerprint hashes the request body so reuse of a key with a different
+// request can be detected
+func Fingerprint(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Middleware applies idempotency keys to POST, PUT and DELETE requests that
+// carry the Idempotency-Key header. userID returns the authenticated user;
+// requests without a user are passed through. Responses with a 5xx status are
+// not stored so the client can retry them.
+func Middleware(store Store, userID func(*http.Request) (int, bool)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			if key == "" || !isMutating(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > MaxKeyLength {
+				writeError(w, http.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+				return
+			}
+			uid, ok := userID(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid body")
+				return
+			}
+			if len(body) > maxBodySize {
+				writeError(w, http.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			scope := Scope{UserID: uid, Key: key, Method: r.Method, Path: r.URL.Path}
+			fingerprint := Fingerprint(body)
+
+			rec, err := store.Begin(r.Context(), scope, fingerprint)
+			switch {
+			case errors.Is(err, ErrInProgress):
+				writeError(w, http.StatusConflict, err.Error())
+				return
+			case err != nil:
+				slog.ErrorContext(r.Context(), "error claiming key", "component", "idempotency", "error", err)
+				writeError(w, http.StatusInternalServerError, "database error")
+				return
+			case rec != nil:
+				if rec.Fingerprint != fingerprint {
+					writeError(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request")
+					return
+				}
+				if rec.ContentType != "" {
+					w.Header().Set("Content-Type", rec.ContentType)
+				}
+				w.Header().Set(ReplayedHeader, "true")
+				w.WriteHeader(rec.StatusCode)
+				_, _ = w.Write(rec.Body)
+				return
+			}
+
+			// Use a fresh context so the record is saved even if the client went away
+			ctx := context.WithoutCancel(r.Context())
+
+			// A panicking handler would otherwise leave the key in progress
+			// until it expires
+			defer func() {
+				if p := recover(); p != nil {
+					if err := store.Release(ctx, scope); err != nil {
+						slog.ErrorContext(ctx, "error releasing key", "component", "idempotency", "error", err)
+					}
+					panic(p)
+				}
+			}()
+
+			rw := &recorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, r)
+
+			if rw.status >= 500 {
+				err = store.Release(ctx, scope)
+			} else {
+				err = store.Complete(ctx, scope, Record{
+					Fingerprint: fingerprint,
+					StatusCode:  rw.status,
+					ContentType: rw.Header().Get("Content-Type"),
+					Body:        rw.body.Bytes(),
+				})
+			}
+			if err != nil {
+				slog.ErrorContext(r.Context(), "error storing response", "component", "idempotency", "error", err)
+			}
+		})
+	}
+}
+
+func isMutating(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodDelete
+}
+
+// recorder passes a response through while keeping a copy
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,206 @@
+package idempotency
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[Scope]*Record
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{records: make(map[Scope]*Record)}
+}
+
+func (s *memoryStore) Begin(ctx context.Context, scope Scope, fingerprint string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.records[scope]
+	if !ok {
+		s.records[scope] = &Record{Fingerprint: fingerprint}
+		return nil, nil
+	}
+	if rec.StatusCode == 0 && rec.Fingerprint == fingerprint {
+		return nil, ErrInProgress
+	}
+	copied := *rec
+	return &copied, nil
+}
+
+func (s *memoryStore) Complete(ctx context.Context, scope Scope, rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[scope] = &rec
+	return nil
+}
+
+func (s *memoryStore) Release(ctx context.Context, scope Scope) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, scope)
+	return nil
+}
+
+func testUser(r *http.Request) (int, bool) {
+	return 1, true
+}
+
+func newCountingHandler(status int) (http.Handler, *int) {
+	calls := 0
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(`{"echo":` + string(body) + `}`))
+	}), &calls
+}
+
+func do(h http.Handler, method, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/bookings", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(Header, key)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestReplay(t *testing.T) {
+	next, calls := newCountingHandler(http.StatusCreated)
+	h := Middleware(newMemoryStore(), testUser)(next)
+
+	first := do(h, http.MethodPost, "abc", `{"id":1}`)
+	second := do(h, http.MethodPost, "abc", `{"id":1}`)
+
+	if *calls != 1 {
+		t.Fatalf("handler called %d times, expected 1", *calls)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %q, expected %d %q", second.Code, second.Body.String(), first.Code, first.Body.String())
+	}
+	if second.Header().Get(ReplayedHeader) != "true" {
+		t.Error("expected replayed header on second response")
+	}
+	if first.Header().Get(ReplayedHeader) != "" {
+		t.Error("first response must not be marked as replayed")
+	}
+}
+
+func TestFingerprintMismatch(t *testing.T) {
+	next, calls := newCountingHandler(http.StatusCreated)
+	h := Middleware(newMemoryStore(), testUser)(next)
+
+	do(h, http.MethodPost, "abc", `{"id":1}`)
+	rec := do(h, http.MethodPost, "abc", `{"id":2}`)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, expected 422", rec.Code)
+	}
+	if *calls != 1 {
+		t.Errorf("handler called %d times, expected 1", *calls)
+	}
+}
+
+func TestKeysAreScopedByRoute(t *testing.T) {
+	next, calls := newCountingHandler(http.StatusOK)
+	h := Middleware(newMemoryStore(), testUser)(next)
+
+	do(h, http.MethodPost, "abc", `{}`)
+	do(h, http.MethodDelete, "abc", `{}`)
+
+	if *calls != 2 {
+		t.Errorf("handler called %d times, expected 2", *calls)
+	}
+}
+
+func TestServerErrorsAreNotStored(t *testing.T) {
+	next, calls := newCountingHandler(http.StatusBadGateway)
+	h := Middleware(newMemoryStore(), testUser)(next)
+
+	do(h, http.MethodPost, "abc", `{}`)
+	do(h, http.MethodPost, "abc", `{}`)
+
+	if *calls != 2 {
+		t.Errorf("handler called %d times, expected 2", *calls)
+	}
+}
+
+func TestPassThrough(t *testing.T) {
+	next, calls := newCountingHandler(http.StatusOK)
+	h := Middleware(newMemoryStore(), testUser)(next)
+
+	do(h, http.MethodPost, "", `{}`)
+	do(h, http.MethodPost, "", `{}`)
+	do(h, http.MethodGet, "abc", ``)
+	do(h, http.MethodGet, "abc", ``)
+
+	if *calls != 4 {
+		t.Errorf("handler called %d times, expected 4", *calls)
+	}
+}
+
+func TestInProgress(t *testing.T) {
+	store := newMemoryStore()
+	scope := Scope{UserID: 1, Key: "abc", Method: http.MethodPost, Path: "/api/bookings"}
+	if _, err := store.Begin(context.Background(), scope, Fingerprint([]byte(`{}`))); err != nil {
+		t.Fatal(err)
+	}
+
+	next, calls := newCountingHandler(http.StatusOK)
+	rec := do(Middleware(store, testUser)(next), http.MethodPost, "abc", `{}`)
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("status = %d, expected 409", rec.Code)
+	}
+	if *calls != 0 {
+		t.Errorf("handler called %d times, expected 0", *calls)
+	}
+}
+
+func TestKeyTooLong(t *testing.T) {
+	next, _ := newCountingHandler(http.StatusOK)
+	rec := do(Middleware(newMemoryStore(), testUser)(next), http.MethodPost, strings.Repeat("k", MaxKeyLength+1), `{}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, expected 400", rec.Code)
+	}
+}
+
+func TestBodyTooLarge(t *testing.T) {
+	next, calls := newCountingHandler(http.StatusOK)
+	rec := do(Middleware(newMemoryStore(), testUser)(next), http.MethodPost, "abc", strings.Repeat("x", maxBodySize+1))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, expected 413", rec.Code)
+	}
+	if *calls != 0 {
+		t.Errorf("handler called %d times, expected 0", *calls)
+	}
+}
+
+func TestPanicReleasesKey(t *testing.T) {
+	store := newMemoryStore()
+	panicking := Middleware(store, testUser)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected the panic to be passed on")
+			}
+		}()
+		do(panicking, http.MethodPost, "abc", `{}`)
+	}()
+
+	next, calls := newCountingHandler(http.StatusCreated)
+	rec := do(Middleware(store, testUser)(next), http.MethodPost, "abc", `{}`)
+	if rec.Code != http.StatusCreated || *calls != 1 {
+		t.Errorf("retry = %d with %d calls, expected 201 with 1", rec.Code, *calls)
+	}
+}
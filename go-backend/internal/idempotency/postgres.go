@@ -0,0 +1,93 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"go-backend/internal/database"
+)
+
+// PostgresStore keeps records in the idempotency_keys table. Records older
+// than ttl are treated as expired and may be claimed again.
+type PostgresStore struct {
+	db  *database.DB
+	ttl time.Duration
+}
+
+// NewPostgresStore creates a store whose records expire after ttl
+func NewPostgresStore(db *database.DB, ttl time.Duration) *PostgresStore {
+	return &PostgresStore{db: db, ttl: ttl}
+}
+
+// Begin claims scope, taking over an expired record if there is one
+func (s *PostgresStore) Begin(ctx context.Context, scope Scope, fingerprint string) (*Record, error) {
+	expiry := fmt.Sprintf("%d seconds", int(s.ttl.Seconds()))
+
+	var id int
+	err := s.db.Pool.QueryRow(ctx,
+		`INSERT INTO idempotency_keys (user_id, key, method, path, fingerprint)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (user_id, key, method, path) DO UPDATE
+		 SET fingerprint = EXCLUDED.fingerprint, status_code = NULL, content_type = NULL,
+		     response_body = NULL, created_at = CURRENT_TIMESTAMP
+		 WHERE idempotency_keys.created_at < CURRENT_TIMESTAMP - $6::interval
+		 RETURNING id`,
+		scope.UserID, scope.Key, scope.Method, scope.Path, fingerprint, expiry,
+	).Scan(&id)
+	if err == nil {
+		return nil, nil
+	}
+	if err != pgx.ErrNoRows {
+		return nil, err
+	}
+
+	// The key is taken by a live record
+	var (
+		rec         Record
+		statusCode  *int
+		contentType *string
+	)
+	err = s.db.Pool.QueryRow(ctx,
+		`SELECT fingerprint, status_code, content_type, response_body
+		 FROM idempotency_keys
+		 WHERE user_id = $1 AND key = $2 AND method = $3 AND path = $4`,
+		scope.UserID, scope.Key, scope.Method, scope.Path,
+	).Scan(&rec.Fingerprint, &statusCode, &contentType, &rec.Body)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode == nil {
+		if rec.Fingerprint != fingerprint {
+			return &rec, nil
+		}
+		return nil, ErrInProgress
+	}
+	rec.StatusCode = *statusCode
+	if contentType != nil {
+		rec.ContentType = *contentType
+	}
+	return &rec, nil
+}
+
+// Complete stores the response for scope
+func (s *PostgresStore) Complete(ctx context.Context, scope Scope, rec Record) error {
+	_, err := s.db.Pool.Exec(ctx,
+		`UPDATE idempotency_keys
+		 SET status_code = $5, content_type = $6, response_body = $7
+		 WHERE user_id = $1 AND key = $2 AND method = $3 AND path = $4`,
+		scope.UserID, scope.Key, scope.Method, scope.Path,
+		rec.StatusCode, rec.ContentType, rec.Body)
+	return err
+}
+
+// Release deletes the record for scope
+func (s *PostgresStore) Release(ctx context.Context, scope Scope) error {
+	_, err := s.db.Pool.Exec(ctx,
+		`DELETE FROM idempotency_keys
+		 WHERE user_id = $1 AND key = $2 AND method = $3 AND path = $4`,
+		scope.UserID, scope.Key, scope.Method, scope.Path)
+	return err
+}
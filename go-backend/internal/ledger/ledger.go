@@ -0,0 +1,140 @@
+// Package ledger records money movements as balanced double-entry journals.
+// Amounts are in cents; every journal's debits equal its credits.
+package ledger
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Accounts
+const (
+	AccountGuestPayments = "guest_payments" // money collected from guests
+	AccountHostPayable   = "host_payable"   // owed to hosts, tracked per host and booking
+	AccountPlatformFees  = "platform_fees"  // service fees earned by the platform
+	AccountRefunds       = "refunds"        // money returned to guests
+	AccountHostPayouts   = "host_payouts"   // money released to hosts
+)
+
+// Journal kinds
+const (
+	KindCapture = "capture"
+	KindRefund  = "refund"
+	KindPayout  = "payout"
+)
+
+// Errors
+var (
+	ErrEmptyJournal   = errors.New("journal has no entries")
+	ErrUnbalanced     = errors.New("journal debits and credits do not balance")
+	ErrInvalidEntry   = errors.New("entry must have exactly one positive debit or credit")
+	ErrUnknownAccount = errors.New("unknown account")
+)
+
+var accounts = map[string]bool{
+	AccountGuestPayments: true,
+	AccountHostPayable:   true,
+	AccountPlatformFees:  true,
+	AccountRefunds:       true,
+	AccountHostPayouts:   true,
+}
+
+// Entry is one side of a journal
+type Entry struct {
+	Account   string
+	HostID    int // 0 when the entry is not attributed to a host
+	BookingID int // 0 when the entry is not attributed to a booking
+	Debit     int64
+	Credit    int64
+}
+
+// Journal is a set of entries posted together. Reference identifies the
+// business event so the same event is never posted twice.
+type Journal struct {
+	Kind      string
+	Reference string
+	PaymentID int // 0 when not related to a payment
+	Entries   []Entry
+}
+
+// Validate checks that the journal is balanced and its entries well formed
+func (j Journal) Validate() error {
+	if len(j.Entries) == 0 {
+		return ErrEmptyJournal
+	}
+	var debits, credits int64
+	for _, e := range j.Entries {
+		if !accounts[e.Account] {
+			return fmt.Errorf("%w: %s", ErrUnknownAccount, e.Account)
+		}
+		if e.Debit < 0 || e.Credit < 0 || (e.Debit == 0) == (e.Credit == 0) {
+			return ErrInvalidEntry
+		}
+		debits += e.Debit
+		credits += e.Credit
+	}
+	if debits != credits {
+		return fmt.Errorf("%w: debits %d, credits %d", ErrUnbalanced, debits, credits)
+	}
+	return nil
+}
+
+// Capture records a captured booking payment: the guest's money is split
+// between the host and the platform fee.
+func Capture(ref string, paymentID, bookingID, hostID int, total, fee int64) Journal {
+	j := Journal{Kind: KindCapture, Reference: ref, PaymentID: paymentID}
+	j.Entries = append(j.Entries, Entry{Account: AccountGuestPayments, BookingID: bookingID, Debit: total})
+	if host := total - fee; host > 0 {
+		j.Entries = append(j.Entries, Entry{Account: AccountHostPayable, HostID: hostID, BookingID: bookingID, Credit: host})
+	}
+	if fee > 0 {
+		j.Entries = append(j.Entries, Entry{Account: AccountPlatformFees, BookingID: bookingID, Credit: fee})
+	}
+	return j
+}
+
+// Refund records amount returned to a guest. The refund is taken from the host
+// and the platform fee in proportion to their share of the original total.
+func Refund(ref string, paymentID, bookingID, hostID int, total, fee, amount int64) Journal {
+	hostPart, feePart := Split(total, fee, amount)
+	j := Journal{Kind: KindRefund, Reference: ref, PaymentID: paymentID}
+	if hostPart > 0 {
+		j.Entries = append(j.Entries, Entry{Account: AccountHostPayable, HostID: hostID, BookingID: bookingID, Debit: hostPart})
+	}
+	if feePart > 0 {
+		j.Entries = append(j.Entries, Entry{Account: AccountPlatformFees, BookingID: bookingID, Debit: feePart})
+	}
+	j.Entries = append(j.Entries, Entry{Account: AccountRefunds, BookingID: bookingID, Credit: amount})
+	return j
+}
+
+// PayoutItem is the amount released to a host for one booking
+type PayoutItem struct {
+	BookingID int
+	Amount    int64
+}
+
+// Payout records funds released to a host for a batch of bookings
+func Payout(ref string, hostID int, items []PayoutItem) Journal {
+	j := Journal{Kind: KindPayout, Reference: ref}
+	var total int64
+	for _, item := range items {
+		j.Entries = append(j.Entries, Entry{Account: AccountHostPayable, HostID: hostID, BookingID: item.BookingID, Debit: item.Amount})
+		total += item.Amount
+	}
+	j.Entries = append(j.Entries, Entry{Account: AccountHostPayouts, HostID: hostID, Credit: total})
+	return j
+}
+
+// Split divides amount between the host and the platform fee in the same
+// proportion as fee is of total. The fee share is rounded to the nearest cent.
+func Split(total, fee, amount int64) (hostPart, feePart int64) {
+	if total <= 0 || fee <= 0 {
+		return amount, 0
+	}
+	feePart = (amount*fee + total/2) / total
+	if feePart > fee {
+		feePart = fee
+	}
+	return amount - feePart, feePart
+}
@@ -0,0 +1,111 @@
+package ledger
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		journal  Journal
+		expected error
+	}{
+		{"empty", Journal{}, ErrEmptyJournal},
+		{"balanced", Journal{Entries: []Entry{
+			{Account: AccountGuestPayments, Debit: 100},
+			{Account: AccountHostPayable, Credit: 90},
+			{Account: AccountPlatformFees, Credit: 10},
+		}}, nil},
+		{"unbalanced", Journal{Entries: []Entry{
+			{Account: AccountGuestPayments, Debit: 100},
+			{Account: AccountHostPayable, Credit: 90},
+		}}, ErrUnbalanced},
+		{"debit and credit", Journal{Entries: []Entry{
+			{Account: AccountGuestPayments, Debit: 100, Credit: 100},
+		}}, ErrInvalidEntry},
+		{"zero entry", Journal{Entries: []Entry{
+			{Account: AccountGuestPayments},
+		}}, ErrInvalidEntry},
+		{"negative", Journal{Entries: []Entry{
+			{Account: AccountGuestPayments, Debit: -5},
+			{Account: AccountHostPayable, Credit: -5},
+		}}, ErrInvalidEntry},
+		{"unknown account", Journal{Entries: []Entry{
+			{Account: "cash", Debit: 100},
+			{Account: AccountHostPayable, Credit: 100},
+		}}, ErrUnknownAccount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.journal.Validate()
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Validate() = %v, expected %v", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCaptureRefundPayoutBalance(t *testing.T) {
+	capture := Capture("capture:1", 1, 10, 7, 66000, 6000)
+	refund := Refund("refund:1:33000", 1, 10, 7, 66000, 6000, 33000)
+
+	hostPayable := int64(0)
+	for _, j := range []Journal{capture, refund} {
+		if err := j.Validate(); err != nil {
+			t.Fatalf("%s journal invalid: %v", j.Kind, err)
+		}
+		for _, e := range j.Entries {
+			if e.Account == AccountHostPayable {
+				if e.HostID != 7 || e.BookingID != 10 {
+					t.Errorf("host entry attributed to host %d booking %d", e.HostID, e.BookingID)
+				}
+				hostPayable += e.Credit - e.Debit
+			}
+		}
+	}
+	if hostPayable != 30000 {
+		t.Errorf("host payable = %d, expected 30000", hostPayable)
+	}
+
+	payout := Payout("payout:1", 7, []PayoutItem{{BookingID: 10, Amount: hostPayable}})
+	if err := payout.Validate(); err != nil {
+		t.Fatalf("payout journal invalid: %v", err)
+	}
+}
+
+func TestCaptureWithoutFee(t *testing.T) {
+	j := Capture("capture:2", 2, 11, 7, 5000, 0)
+	if err := j.Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	if len(j.Entries) != 2 {
+		t.Errorf("expected 2 entries, got %d", len(j.Entries))
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		total, fee, amount int64
+		host, feePart      int64
+	}{
+		{66000, 6000, 66000, 60000, 6000},
+		{66000, 6000, 33000, 30000, 3000},
+		{1100, 100, 1, 1, 0},
+		{1100, 100, 6, 5, 1},
+		{5000, 0, 2500, 2500, 0},
+		{0, 0, 100, 100, 0},
+	}
+
+	for _, tt := range tests {
+		host, fee := Split(tt.total, tt.fee, tt.amount)
+		if host != tt.host || fee != tt.feePart {
+			t.Errorf("Split(%d, %d, %d) = %d, %d, expected %d, %d",
+				tt.total, tt.fee, tt.amount, host, fee, tt.host, tt.feePart)
+		}
+		if host+fee != tt.amount {
+			t.Errorf("Split(%d, %d, %d) does not add up", tt.total, tt.fee, tt.amount)
+		}
+	}
+}
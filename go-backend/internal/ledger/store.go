@@ -0,0 +1,42 @@
+package ledger
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Post validates and stores a journal inside tx. Journals are unique by
+// reference: posting a reference that already exists does nothing and
+// returns false.
+func Post(ctx context.Context, tx pgx.Tx, j Journal) (bool, error) {
+	if err := j.Validate(); err != nil {
+		return false, err
+	}
+
+	var journalID int
+	err := tx.QueryRow(ctx,
+		`INSERT INTO ledger_journals (kind, reference, payment_id)
+		 VALUES ($1, $2, NULLIF($3, 0))
+		 ON CONFLICT (reference) DO NOTHING
+		 RETURNING id`,
+		j.Kind, j.Reference, j.PaymentID,
+	).Scan(&journalID)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for _, e := range j.Entries {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO ledger_entries (journal_id, account, host_id, booking_id, debit, credit)
+			 VALUES ($1, $2, NULLIF($3, 0), NULLIF($4, 0), $5, $6)`,
+			journalID, e.Account, e.HostID, e.BookingID, e.Debit, e.Credit)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync/atomic"
+)
+
+// HeaderRequestID carries the ID of a request in both directions
+const HeaderRequestID = "X-Request-ID"
+
+// MaxRequestIDLength is the longest request ID accepted from a client
+const MaxRequestIDLength = 64
+
+type contextKey struct{}
+
+// requestInfo is shared by every context derived from the request's, so the
+// user ID set after authentication is seen by the access log
+type requestInfo struct {
+	id     string
+	userID atomic.Int64
+}
+
+func requestInfoFrom(ctx context.Context) *requestInfo {
+	if ctx == nil {
+		return nil
+	}
+	info, _ := ctx.Value(contextKey{}).(*requestInfo)
+	return info
+}
+
+// WithRequestID returns a context carrying a request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, &requestInfo{id: id})
+}
+
+// RequestID returns the request ID of a context, or "" if it has none
+func RequestID(ctx context.Context) string {
+	if info := requestInfoFrom(ctx); info != nil {
+		return info.id
+	}
+	return ""
+}
+
+// SetUserID records the authenticated user of a request. It has no effect
+// on a context without a request ID.
+func SetUserID(ctx context.Context, userID int) {
+	if info := requestInfoFrom(ctx); info != nil {
+		info.userID.Store(int64(userID))
+	}
+}
+
+// NewRequestID returns a random request ID
+func NewRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ValidRequestID reports whether a client-supplied request ID is safe to
+// log: at most MaxRequestIDLength letters, digits, dots, dashes and
+// underscores
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > MaxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.' || c == '-' || c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+// Package logging builds the structured logger of the API. Log lines are
+// written with log/slog, carry the ID of the request they belong to and, by
+// default, have personal data redacted.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Formats
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Config configures the logger
+type Config struct {
+	// Level is the lowest level logged: debug, info, warn or error
+	Level string
+	// Format is json or text
+	Format string
+	// RedactPII replaces personal data such as emails and names
+	RedactPII bool
+}
+
+// ParseLevel parses a level name, defaulting to info for unknown names
+func ParseLevel(name string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// New returns a logger writing to w. Every record logged with a context
+// carrying request info gets request_id and user_id attributes.
+func New(w io.Writer, cfg Config) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLevel(cfg.Level)}
+	if cfg.RedactPII {
+		opts.ReplaceAttr = redactAttr
+	}
+
+	var h slog.Handler
+	if strings.EqualFold(cfg.Format, FormatText) {
+		h = slog.NewTextHandler(w, opts)
+	} else {
+		h = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(&contextHandler{Handler: h, redact: cfg.RedactPII})
+}
+
+// contextHandler adds the request info of the context to each record and
+// redacts the message
+type contextHandler struct {
+	slog.Handler
+	redact bool
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.redact {
+		if msg := RedactText(r.Message); msg != r.Message {
+			redacted := slog.NewRecord(r.Time, r.Level, msg, r.PC)
+			r.Attrs(func(a slog.Attr) bool {
+				redacted.AddAttrs(a)
+				return true
+			})
+			r = redacted
+		}
+	}
+	if info := requestInfoFrom(ctx); info != nil {
+		r.AddAttrs(slog.String("request_id", info.id))
+		if userID := info.userID.Load(); userID != 0 {
+			r.AddAttrs(slog.Int64("user_id", userID))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs), redact: h.redact}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name), redact: h.redact}
+}
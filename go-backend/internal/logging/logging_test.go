@@ -0,0 +1,102 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func logLine(t *testing.T, cfg Config, log func(*slog.Logger)) map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	log(New(&buf, cfg))
+	if buf.Len() == 0 {
+		return nil
+	}
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("invalid JSON log line %q: %v", buf.String(), err)
+	}
+	return line
+}
+
+func TestRequestInfoIsLogged(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "req-1")
+	SetUserID(ctx, 42)
+
+	line := logLine(t, Config{Format: FormatJSON}, func(l *slog.Logger) {
+		l.InfoContext(ctx, "booking created", "booking_id", 7)
+	})
+	if line["request_id"] != "req-1" || line["user_id"] != float64(42) || line["booking_id"] != float64(7) {
+		t.Errorf("unexpected log line: %v", line)
+	}
+
+	line = logLine(t, Config{}, func(l *slog.Logger) {
+		l.Info("no request")
+	})
+	if _, ok := line["request_id"]; ok {
+		t.Errorf("request_id logged without a request: %v", line)
+	}
+}
+
+func TestLevel(t *testing.T) {
+	line := logLine(t, Config{Level: "warn"}, func(l *slog.Logger) {
+		l.Info("hidden")
+	})
+	if line != nil {
+		t.Errorf("info logged at warn level: %v", line)
+	}
+
+	if got := ParseLevel("DEBUG"); got != slog.LevelDebug {
+		t.Errorf("ParseLevel(DEBUG) = %v", got)
+	}
+	if got := ParseLevel("verbose"); got != slog.LevelInfo {
+		t.Errorf("ParseLevel(verbose) = %v, want info", got)
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	log := func(l *slog.Logger) {
+		l.Error("mail to jane@example.com failed",
+			"email", "jane@example.com",
+			"name", "Jane Doe",
+			"error", errors.New("550 unknown user jane@example.com"),
+			"note", "reply to bob@example.org",
+			"user_id", 3)
+	}
+
+	line := logLine(t, Config{RedactPII: true}, log)
+	if line["email"] != Redacted || line["name"] != Redacted || line["user_id"] != float64(3) {
+		t.Errorf("sensitive keys not redacted: %v", line)
+	}
+	for _, key := range []string{"msg", "error", "note"} {
+		if s, _ := line[key].(string); strings.Contains(s, "@") || !strings.Contains(s, Redacted) {
+			t.Errorf("%s not redacted: %q", key, s)
+		}
+	}
+
+	line = logLine(t, Config{}, log)
+	if line["email"] != "jane@example.com" || line["msg"] != "mail to jane@example.com failed" {
+		t.Errorf("redacted without RedactPII: %v", line)
+	}
+}
+
+func TestValidRequestID(t *testing.T) {
+	for _, id := range []string{"abc", "3f2a-41.b_c", NewRequestID(), strings.Repeat("a", MaxRequestIDLength)} {
+		if !ValidRequestID(id) {
+			t.Errorf("ValidRequestID(%q) = false", id)
+		}
+	}
+	for _, id := range []string{"", "has space", "line\nbreak", `quote"`, strings.Repeat("a", MaxRequestIDLength+1)} {
+		if ValidRequestID(id) {
+			t.Errorf("ValidRequestID(%q) = true", id)
+		}
+	}
+	if NewRequestID() == NewRequestID() {
+		t.Error("NewRequestID returned the same ID twice")
+	}
+}
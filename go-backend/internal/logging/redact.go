@@ -0,0 +1,64 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces redacted values
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys whose values are always redacted
+var sensitiveKeys = map[string]bool{
+	"email":         true,
+	"name":          true,
+	"first_name":    true,
+	"last_name":     true,
+	"phone":         true,
+	"address":       true,
+	"ip":            true,
+	"remote_addr":   true,
+	"password":      true,
+	"token":         true,
+	"access_token":  true,
+	"authorization": true,
+	"secret":        true,
+}
+
+// SensitiveKey reports whether the values of an attribute key are redacted
+func SensitiveKey(key string) bool {
+	return sensitiveKeys[strings.ToLower(key)]
+}
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// RedactText replaces the email addresses in s
+func RedactText(s string) string {
+	if !strings.Contains(s, "@") {
+		return s
+	}
+	return emailPattern.ReplaceAllString(s, Redacted)
+}
+
+// redactAttr is the slog.HandlerOptions.ReplaceAttr of a redacting logger:
+// it replaces the values of sensitive keys and the emails found in strings
+// and errors
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if SensitiveKey(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		if s := a.Value.String(); strings.Contains(s, "@") {
+			return slog.String(a.Key, RedactText(s))
+		}
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			if s := err.Error(); strings.Contains(s, "@") {
+				return slog.String(a.Key, RedactText(s))
+			}
+		}
+	}
+	return a
+}
@@ -0,0 +1,110 @@
+// Package mail renders and sends transactional email. Flows queue emails in
+// the mail_outbox table inside the transaction making the change they
+// announce, and the MailSender worker delivers them, retrying with backoff.
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/textproto"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"go-backend/internal/notification"
+)
+
+// Templates
+const (
+	TemplateBookingConfirmed = "booking_confirmed"
+	TemplateBookingCancelled = "booking_cancelled"
+	TemplateNewMessage       = "new_message"
+	TemplatePasswordReset    = "password_reset"
+)
+
+// templateTypes maps each template to the notification type whose email
+// preference controls it; account emails are always sent
+var templateTypes = map[string]string{
+	TemplateBookingConfirmed: notification.TypeBooking,
+	TemplateBookingCancelled: notification.TypeBooking,
+	TemplateNewMessage:       notification.TypeMessage,
+	TemplatePasswordReset:    "",
+}
+
+// Delivery limits
+const (
+	// MaxAttempts is how many times delivery is tried before an email is
+	// marked failed
+	MaxAttempts = 8
+
+	baseBackoff = time.Minute
+	maxBackoff  = 6 * time.Hour
+)
+
+// ErrUnknownTemplate is returned when queueing an email with a template that
+// does not exist
+var ErrUnknownTemplate = errors.New("unknown mail template")
+
+// Message is a rendered email
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender delivers rendered email
+type Sender interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// Querier is satisfied by pools and transactions
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// Enqueue queues an email to a user, rendered in their language when it is
+// delivered. The user's name is added to data as Name. Emails controlled by
+// a notification type are skipped if the user turned emails of that type
+// off; Enqueue reports whether the email was queued.
+func Enqueue(ctx context.Context, q Querier, userID int, template string, data map[string]any) (bool, error) {
+	typ, ok := templateTypes[template]
+	if !ok {
+		return false, ErrUnknownTemplate
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return false, err
+	}
+
+	tag, err := q.Exec(ctx,
+		`INSERT INTO mail_outbox (user_id, recipient, locale, template, data)
+		 SELECT u.id, u.email, u.locale, $2, $3::jsonb || jsonb_build_object('Name', u.name)
+		 FROM users u
+		 WHERE u.id = $1 AND NOT EXISTS (
+		   SELECT 1 FROM notification_preferences
+		   WHERE user_id = u.id AND type = $4 AND NOT email)`,
+		userID, template, payload, typ)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Backoff returns how long to wait before the next delivery attempt after
+// the given number of failed attempts: a minute, doubling up to six hours
+func Backoff(attempts int) time.Duration {
+	d := baseBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+// IsPermanent reports whether a delivery error will not go away on retry,
+// such as a rejected recipient
+func IsPermanent(err error) bool {
+	var protoErr *textproto.Error
+	return errors.As(err, &protoErr) && protoErr.Code >= 500
+}
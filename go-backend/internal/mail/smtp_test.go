@@ -0,0 +1,237 @@
+package mail
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	netmail "net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// smtpServer is an in-process SMTP server stand-in that records the messages
+// it receives. Recipients in reject are refused with the given reply.
+type smtpServer struct {
+	ln     net.Listener
+	reject map[string]string
+
+	mu       sync.Mutex
+	messages []receivedMessage
+}
+
+type receivedMessage struct {
+	from, to string
+	data     string
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpServer{ln: ln, reject: map[string]string{}}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP test")
+
+	var msg receivedMessage
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(cmd) {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250-localhost")
+			tp.PrintfLine("250 8BITMIME")
+		case "MAIL":
+			from, _, _ := strings.Cut(strings.TrimPrefix(arg, "FROM:"), " ") // drop parameters
+			msg = receivedMessage{from: strings.Trim(from, "<>")}
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			msg.to = strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>")
+			if reply, ok := s.reject[msg.to]; ok {
+				tp.PrintfLine("%s", reply)
+				continue
+			}
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			tp.PrintfLine("354 Go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			tp.PrintfLine("250 Queued")
+		case "RSET", "NOOP":
+			tp.PrintfLine("250 OK")
+		case "QUIT":
+			tp.PrintfLine("221 Bye")
+			return
+		default:
+			tp.PrintfLine("502 Not implemented")
+		}
+	}
+}
+
+func (s *smtpServer) received() []receivedMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]receivedMessage(nil), s.messages...)
+}
+
+func newTestSender(t *testing.T, srv *smtpServer) *SMTP {
+	t.Helper()
+	sender, err := NewSMTP(SMTPConfig{Host: "127.0.0.1", Port: srv.port(), From: "GoRent <no-reply@gorent.example>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sender
+}
+
+func TestSMTPSend(t *testing.T) {
+	srv := newSMTPServer(t)
+	sender := newTestSender(t, srv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := sender.Send(ctx, Message{
+		To:      "Ana López <ana@example.com>",
+		Subject: "Tu estancia está confirmada",
+		Text:    "Hola Ana:\n.\nUna línea que es bastante larga para que quoted-printable tenga que partirla en dos líneas distintas.\n",
+		HTML:    "<p>Hola Ana</p>",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := srv.received()
+	if len(got) != 1 {
+		t.Fatalf("received %d messages, want 1", len(got))
+	}
+	if got[0].from != "no-reply@gorent.example" || got[0].to != "ana@example.com" {
+		t.Errorf("envelope = %s -> %s", got[0].from, got[0].to)
+	}
+
+	msg, err := netmail.ReadMessage(strings.NewReader(got[0].data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Tu estancia está confirmada" {
+		t.Errorf("subject = %q (%v)", subject, err)
+	}
+	if msg.Header.Get("Message-ID") == "" || msg.Header.Get("Date") == "" {
+		t.Error("missing Message-ID or Date header")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("content type = %q (%v)", mediaType, err)
+	}
+	parts := map[string]string{}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(p) // decodes quoted-printable
+		if err != nil {
+			t.Fatal(err)
+		}
+		mediaType, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		parts[mediaType] = strings.ReplaceAll(string(body), "\r\n", "\n")
+	}
+	if !strings.Contains(parts["text/plain"], "Hola Ana:\n.\nUna línea") ||
+		!strings.Contains(parts["text/plain"], "en dos líneas distintas.") {
+		t.Errorf("text part = %q", parts["text/plain"])
+	}
+	if parts["text/html"] != "<p>Hola Ana</p>" {
+		t.Errorf("HTML part = %q", parts["text/html"])
+	}
+}
+
+func TestSMTPRejections(t *testing.T) {
+	srv := newSMTPServer(t)
+	srv.reject["gone@example.com"] = "550 5.1.1 No such user"
+	srv.reject["busy@example.com"] = "451 4.3.0 Try again later"
+	sender := newTestSender(t, srv)
+
+	tests := []struct {
+		to        string
+		permanent bool
+	}{
+		{"gone@example.com", true},
+		{"busy@example.com", false},
+		{"not an address", true},
+	}
+	for _, tt := range tests {
+		err := sender.Send(context.Background(), Message{To: tt.to, Subject: "Hi", Text: "Hi", HTML: "Hi"})
+		if err == nil {
+			t.Errorf("%s: sent", tt.to)
+			continue
+		}
+		if IsPermanent(err) != tt.permanent {
+			t.Errorf("%s: IsPermanent(%v) = %v, want %v", tt.to, err, !tt.permanent, tt.permanent)
+		}
+	}
+	if n := len(srv.received()); n != 0 {
+		t.Errorf("received %d messages, want 0", n)
+	}
+}
+
+func TestSMTPUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	sender, err := NewSMTP(SMTPConfig{Host: "127.0.0.1", Port: port, From: "no-reply@gorent.example"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sender.Send(context.Background(), Message{To: "ana@example.com", Subject: "Hi"})
+	if err == nil || IsPermanent(err) {
+		t.Errorf("Send to closed port = %v, want a temporary error", err)
+	}
+}
+
+func TestNewSMTPInvalidFrom(t *testing.T) {
+	if _, err := NewSMTP(SMTPConfig{Host: "localhost", Port: 25, From: "not an address"}); err == nil {
+		t.Error("invalid sender address accepted")
+	}
+}
@@ -0,0 +1,108 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// DefaultLocale is used for users whose language has no templates
+const DefaultLocale = "en"
+
+// Locales lists the languages templates are available in
+var Locales = []string{"en", "es"}
+
+//go:embed templates
+var templateFS embed.FS
+
+// page is the pair of templates of one email in one language. The text
+// template defines the subject as "subject".
+type page struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Renderer renders emails from the embedded templates. Each template has a
+// text and an HTML version per locale; HTML versions share a layout.
+type Renderer struct {
+	pages map[string]page // by locale + "/" + template
+}
+
+// NewRenderer parses the templates. Links in emails are made absolute with
+// baseURL, the address of the web app.
+func NewRenderer(baseURL string) (*Renderer, error) {
+	baseURL = strings.TrimRight(baseURL, "/")
+	funcs := map[string]any{
+		"link": func(path string) string { return baseURL + path },
+	}
+
+	r := &Renderer{pages: make(map[string]page)}
+	for _, locale := range Locales {
+		for name := range templateTypes {
+			dir := "templates/" + locale + "/"
+			text, err := texttemplate.New(name+".txt").Funcs(funcs).Option("missingkey=error").
+				ParseFS(templateFS, dir+name+".txt")
+			if err != nil {
+				return nil, err
+			}
+			if text.Lookup("subject") == nil {
+				return nil, fmt.Errorf("%s%s.txt does not define a subject", dir, name)
+			}
+			html, err := htmltemplate.New("layout.html").Funcs(funcs).Option("missingkey=error").
+				ParseFS(templateFS, "templates/layout.html", dir+name+".html")
+			if err != nil {
+				return nil, err
+			}
+			r.pages[locale+"/"+name] = page{text: text, html: html}
+		}
+	}
+	return r, nil
+}
+
+// Render renders a template in the given locale, falling back to the default
+// locale. The returned message has no recipient.
+func (r *Renderer) Render(name, locale string, data map[string]any) (Message, error) {
+	p, ok := r.pages[MatchLocale(locale)+"/"+name]
+	if !ok {
+		return Message{}, ErrUnknownTemplate
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := p.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := p.text.Execute(&text, data); err != nil {
+		return Message{}, err
+	}
+	if err := p.html.Execute(&html, data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+// MatchLocale returns the supported locale best matching a language tag or
+// an Accept-Language list, trying each language in order and then its
+// primary subtag, e.g. "es-MX" matches "es". It returns DefaultLocale if none
+// match.
+func MatchLocale(tags string) string {
+	for _, tag := range strings.Split(tags, ",") {
+		tag, _, _ = strings.Cut(tag, ";")
+		tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+		primary, _, _ := strings.Cut(tag, "-")
+		for _, candidate := range []string{tag, primary} {
+			for _, locale := range Locales {
+				if candidate == locale {
+					return locale
+				}
+			}
+		}
+	}
+	return DefaultLocale
+}
@@ -0,0 +1,98 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+)
+
+// sampleData has the fields each template uses, as they are queued
+var sampleData = map[string]map[string]any{
+	TemplateBookingConfirmed: {"Name": "Ana", "Property": "Beach <House>", "CheckIn": "2024-06-01", "CheckOut": "2024-06-05", "Path": "/bookings/12", "Host": false},
+	TemplateBookingCancelled: {"Name": "Ana", "Property": "Beach <House>", "CheckIn": "2024-06-01", "CheckOut": "2024-06-05", "Path": "/bookings/12", "Host": true},
+	TemplateNewMessage:       {"Name": "Ana", "Sender": "Bob", "Preview": "Is <b>early</b> check-in possible?", "Path": "/messages/3"},
+	TemplatePasswordReset:    {"Name": "Ana", "Path": "/reset-password?token=abc", "ValidMinutes": 60},
+}
+
+func TestRenderAllTemplates(t *testing.T) {
+	r, err := NewRenderer("https://gorent.example/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, locale := range Locales {
+		for name := range templateTypes {
+			t.Run(locale+"/"+name, func(t *testing.T) {
+				m, err := r.Render(name, locale, sampleData[name])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if m.Subject == "" || strings.Contains(m.Subject, "\n") {
+					t.Errorf("subject = %q", m.Subject)
+				}
+				link := "https://gorent.example" + sampleData[name]["Path"].(string)
+				if !strings.Contains(m.Text, link) {
+					t.Errorf("text does not contain %s:\n%s", link, m.Text)
+				}
+				if !strings.Contains(m.HTML, "Ana") || !strings.Contains(m.HTML, "https://gorent.example") {
+					t.Errorf("HTML is missing the name or link:\n%s", m.HTML)
+				}
+				if strings.Contains(m.HTML, "<House>") || strings.Contains(m.HTML, "<b>early") {
+					t.Errorf("HTML does not escape data:\n%s", m.HTML)
+				}
+			})
+		}
+	}
+}
+
+func TestRenderLocales(t *testing.T) {
+	r, err := NewRenderer("http://localhost:3000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := sampleData[TemplatePasswordReset]
+
+	es, err := r.Render(TemplatePasswordReset, "es-MX", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if es.Subject != "Restablece tu contraseña de GoRent" {
+		t.Errorf("es subject = %q", es.Subject)
+	}
+	fallback, err := r.Render(TemplatePasswordReset, "de", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fallback.Subject != "Reset your GoRent password" {
+		t.Errorf("fallback subject = %q", fallback.Subject)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	r, err := NewRenderer("http://localhost:3000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Render("welcome", "en", nil); err != ErrUnknownTemplate {
+		t.Errorf("unknown template: err = %v", err)
+	}
+	if _, err := r.Render(TemplateNewMessage, "en", map[string]any{"Name": "Ana"}); err == nil {
+		t.Error("missing fields rendered without error")
+	}
+}
+
+func TestMatchLocale(t *testing.T) {
+	tests := map[string]string{
+		"":                          "en",
+		"es":                        "es",
+		"ES_es":                     "es",
+		"es-419":                    "es",
+		"de-DE":                     "en",
+		"fr-CA,fr;q=0.9,es;q=0.8":   "es",
+		"en-GB,en;q=0.9":            "en",
+		" pt-BR ; q=1 , es ; q=0.5": "es",
+	}
+	for in, want := range tests {
+		if got := MatchLocale(in); got != want {
+			t.Errorf("MatchLocale(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
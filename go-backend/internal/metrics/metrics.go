@@ -0,0 +1,180 @@
+// Package metrics defines the Prometheus metrics of the API: HTTP traffic,
+// the database pool, background workers and business events. They are
+// registered in Registry and served by Handler.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gorent"
+
+// Registry holds every metric of the API, plus the Go runtime and process
+// collectors
+var Registry = prometheus.NewRegistry()
+
+// HTTP metrics, labelled by the route pattern that served the request
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests served, by method, route pattern and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Time to serve HTTP requests, by method, route pattern and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "HTTP requests being served, including open event streams.",
+	})
+)
+
+// Worker metrics, labelled by worker name
+var (
+	WorkerUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "up",
+		Help:      "Whether a background worker is running.",
+	}, []string{"worker"})
+
+	WorkerRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "runs_total",
+		Help:      "Completed runs of a background worker.",
+	}, []string{"worker"})
+
+	WorkerErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "errors_total",
+		Help:      "Runs of a background worker that ended with an error.",
+	}, []string{"worker"})
+
+	WorkerRunDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "run_duration_seconds",
+		Help:      "Time taken by a run of a background worker.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 4, 8),
+	}, []string{"worker"})
+
+	WorkerLastRun = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "last_run_timestamp_seconds",
+		Help:      "When the last run of a background worker finished.",
+	}, []string{"worker"})
+)
+
+// Business metrics
+var (
+	UsersRegistered = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "users_registered_total",
+		Help:      "Accounts created.",
+	})
+
+	SignInFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sign_in_failures_total",
+		Help:      "Rejected sign-ins, by reason: invalid_credentials or suspended.",
+	}, []string{"reason"})
+
+	BookingsCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bookings_created_total",
+		Help:      "Bookings created, by initial status.",
+	}, []string{"status"})
+
+	BookingsCancelled = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bookings_cancelled_total",
+		Help:      "Bookings cancelled by guests or hosts.",
+	})
+
+	BookingsExpired = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bookings_expired_total",
+		Help:      "Pending bookings expired by the booking checker.",
+	})
+
+	PaymentsFailed = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payments_failed_total",
+		Help:      "Booking payments declined or failed.",
+	})
+)
+
+// Sign-in failure reasons
+const (
+	ReasonInvalidCredentials = "invalid_credentials"
+	ReasonSuspended          = "suspended"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests, HTTPRequestDuration, HTTPRequestsInFlight,
+		WorkerUp, WorkerRuns, WorkerErrors, WorkerRunDuration, WorkerLastRun,
+		UsersRegistered, SignInFailures, BookingsCreated, BookingsCancelled, BookingsExpired, PaymentsFailed,
+	)
+}
+
+// Handler serves the metrics of Registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveRequest records a served HTTP request. Requests that matched no
+// route are recorded under "unmatched" and non-standard methods under
+// "other" to keep the label set bounded.
+func ObserveRequest(method, route string, status int, d time.Duration) {
+	if route == "" {
+		route = "unmatched"
+	}
+	method = methodLabel(method)
+	code := strconv.Itoa(status)
+	HTTPRequests.WithLabelValues(method, route, code).Inc()
+	HTTPRequestDuration.WithLabelValues(method, route, code).Observe(d.Seconds())
+}
+
+// methodLabel returns method if it is a standard HTTP method, or "other"
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return "other"
+	}
+}
+
+// ObserveWorkerRun records a run of a worker that started at start and
+// ended with err
+func ObserveWorkerRun(worker string, start time.Time, err error) {
+	now := time.Now()
+	WorkerRuns.WithLabelValues(worker).Inc()
+	if err != nil {
+		WorkerErrors.WithLabelValues(worker).Inc()
+	}
+	WorkerRunDuration.WithLabelValues(worker).Observe(now.Sub(start).Seconds())
+	WorkerLastRun.WithLabelValues(worker).Set(float64(now.Unix()))
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveRequest(t *testing.T) {
+	ObserveRequest("GET", "/api/bookings/", 200, 30*time.Millisecond)
+	ObserveRequest("GET", "", 404, time.Millisecond)
+	ObserveRequest("FOO", "", 404, time.Millisecond)
+	ObserveRequest("get", "", 404, time.Millisecond)
+
+	if got := testutil.ToFloat64(HTTPRequests.WithLabelValues("GET", "/api/bookings/", "200")); got != 1 {
+		t.Errorf("requests for /api/bookings/ = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(HTTPRequests.WithLabelValues("GET", "unmatched", "404")); got != 1 {
+		t.Errorf("unmatched requests = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(HTTPRequests.WithLabelValues("other", "unmatched", "404")); got != 2 {
+		t.Errorf("requests with other methods = %v, want 2", got)
+	}
+}
+
+func TestObserveWorkerRun(t *testing.T) {
+	start := time.Now().Add(-2 * time.Second)
+	ObserveWorkerRun("TestWorker", start, nil)
+	ObserveWorkerRun("TestWorker", start, errors.New("boom"))
+
+	if got := testutil.ToFloat64(WorkerRuns.WithLabelValues("TestWorker")); got != 2 {
+		t.Errorf("runs = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(WorkerErrors.WithLabelValues("TestWorker")); got != 1 {
+		t.Errorf("errors = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(WorkerLastRun.WithLabelValues("TestWorker")); got < float64(start.Unix()) {
+		t.Errorf("last run = %v, want after %d", got, start.Unix())
+	}
+}
+
+func TestPoolCollector(t *testing.T) {
+	// The pool connects lazily, so no database is needed
+	pool, err := pgxpool.New(context.Background(), "postgres://gorent@127.0.0.1:1/gorent?pool_max_conns=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	c := NewPoolCollector(pool)
+	if got := testutil.CollectAndCount(c); got != 10 {
+		t.Errorf("collected %d pool metrics, want 10", got)
+	}
+	expected := `
+# HELP gorent_db_pool_max_conns Largest size of the pool.
+# TYPE gorent_db_pool_max_conns gauge
+gorent_db_pool_max_conns 4
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected), "gorent_db_pool_max_conns"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	BookingsCreated.WithLabelValues("pending").Inc()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+
+	for _, name := range []string{`gorent_bookings_created_total{status="pending"}`, "go_goroutines", "gorent_http_requests_in_flight"} {
+		if !strings.Contains(string(body), name) {
+			t.Errorf("metrics output is missing %s", name)
+		}
+	}
+}
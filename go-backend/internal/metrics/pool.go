@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector exports the statistics of a pgx connection pool at scrape
+// time
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns     *prometheus.Desc
+	idleConns         *prometheus.Desc
+	constructingConns *prometheus.Desc
+	totalConns        *prometheus.Desc
+	maxConns          *prometheus.Desc
+	acquires          *prometheus.Desc
+	emptyAcquires     *prometheus.Desc
+	canceledAcquires  *prometheus.Desc
+	acquireDuration   *prometheus.Desc
+	emptyAcquireWait  *prometheus.Desc
+}
+
+func poolDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+}
+
+// NewPoolCollector returns a collector of the statistics of pool
+func NewPoolCollector(pool *pgxpool.Pool) prometheus.Collector {
+	return &poolCollector{
+		pool:              pool,
+		acquiredConns:     poolDesc("acquired_conns", "Connections currently in use."),
+		idleConns:         poolDesc("idle_conns", "Idle connections in the pool."),
+		constructingConns: poolDesc("constructing_conns", "Connections being opened."),
+		totalConns:        poolDesc("total_conns", "Open connections in the pool."),
+		maxConns:          poolDesc("max_conns", "Largest size of the pool."),
+		acquires:          poolDesc("acquires_total", "Connections acquired from the pool."),
+		emptyAcquires:     poolDesc("empty_acquires_total", "Acquires that had to wait for a connection."),
+		canceledAcquires:  poolDesc("canceled_acquires_total", "Acquires canceled before getting a connection."),
+		acquireDuration:   poolDesc("acquire_duration_seconds_total", "Time spent acquiring connections."),
+		emptyAcquireWait:  poolDesc("empty_acquire_wait_seconds_total", "Time spent waiting for a connection when the pool was empty."),
+	}
+}
+
+// RegisterPool adds the statistics of pool to Registry
+func RegisterPool(pool *pgxpool.Pool) error {
+	return Registry.Register(NewPoolCollector(pool))
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.constructingConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.acquires
+	ch <- c.emptyAcquires
+	ch <- c.canceledAcquires
+	ch <- c.acquireDuration
+	ch <- c.emptyAcquireWait
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.constructingConns, prometheus.GaugeValue, float64(s.ConstructingConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquires, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquireWait, prometheus.CounterValue, s.EmptyAcquireWaitTime().Seconds())
+}
@@ -0,0 +1,4 @@
+-- 000003_add_property_stay_rules.down.sql
+-- Remove per-property stay rules
+
+DROP TABLE IF EXISTS property_stay_rules;
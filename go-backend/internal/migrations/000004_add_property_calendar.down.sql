@@ -0,0 +1,4 @@
+-- 000004_add_property_calendar.down.sql
+-- Remove blocked dates
+
+DROP TABLE IF EXISTS property_calendar;
@@ -0,0 +1,9 @@
+-- 000005_add_ical_sync.down.sql
+-- Remove iCal sync
+
+DROP TABLE IF EXISTS property_ical_conflicts;
+DELETE FROM property_calendar WHERE feed_id IS NOT NULL;
+ALTER TABLE property_calendar DROP COLUMN IF EXISTS feed_id;
+DROP TABLE IF EXISTS property_ical_feeds;
+DROP INDEX IF EXISTS idx_properties_ical_token;
+ALTER TABLE properties DROP COLUMN IF EXISTS ical_token;
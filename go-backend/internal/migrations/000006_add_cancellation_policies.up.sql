@@ -0,0 +1,29 @@
+-- 000006_add_cancellation_policies.up.sql
+-- Cancellation policies, stored price breakdown and soft-cancelled bookings
+
+ALTER TABLE properties ADD COLUMN cancellation_policy VARCHAR(20) NOT NULL DEFAULT 'flexible';
+ALTER TABLE properties ADD COLUMN cancellation_tiers JSONB NOT NULL DEFAULT '[]';
+
+-- Price breakdown and policy are copied onto the booking when it is created
+ALTER TABLE bookings ADD COLUMN nightly_rate DECIMAL(10, 2) NOT NULL DEFAULT 0;
+ALTER TABLE bookings ADD COLUMN nights INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE bookings ADD COLUMN accommodation_total DECIMAL(10, 2) NOT NULL DEFAULT 0;
+ALTER TABLE bookings ADD COLUMN service_fee DECIMAL(10, 2) NOT NULL DEFAULT 0;
+ALTER TABLE bookings ADD COLUMN total_price DECIMAL(10, 2) NOT NULL DEFAULT 0;
+ALTER TABLE bookings ADD COLUMN cancellation_policy VARCHAR(20) NOT NULL DEFAULT 'flexible';
+ALTER TABLE bookings ADD COLUMN cancellation_tiers JSONB NOT NULL DEFAULT '[]';
+
+ALTER TABLE bookings ADD COLUMN cancelled_at TIMESTAMP;
+ALTER TABLE bookings ADD COLUMN cancelled_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE bookings ADD COLUMN cancellation_reason TEXT;
+ALTER TABLE bookings ADD COLUMN refund_amount DECIMAL(10, 2);
+
+-- Backfill prices for existing bookings
+UPDATE bookings b
+SET nightly_rate = p.price_per_night,
+    nights = b.end_date - b.start_date,
+    accommodation_total = p.price_per_night * (b.end_date - b.start_date),
+    service_fee = ROUND(p.price_per_night * (b.end_date - b.start_date) * 0.10, 2),
+    total_price = ROUND(p.price_per_night * (b.end_date - b.start_date) * 1.10, 2)
+FROM properties p
+WHERE p.id = b.property_id;
@@ -0,0 +1,7 @@
+-- 000008_add_ledger.down.sql
+-- Remove ledger and host payouts
+
+DROP TABLE IF EXISTS host_payout_items;
+DROP TABLE IF EXISTS host_payouts;
+DROP TABLE IF EXISTS ledger_entries;
+DROP TABLE IF EXISTS ledger_journals;
@@ -0,0 +1,4 @@
+-- 000009_add_idempotency_keys.down.sql
+-- Remove idempotency keys
+
+DROP TABLE IF EXISTS idempotency_keys;
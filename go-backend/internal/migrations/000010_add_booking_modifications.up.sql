@@ -0,0 +1,33 @@
+-- 000010_add_booking_modifications.up.sql
+-- Guest requests to change the dates or guest count of a booking
+
+CREATE TABLE IF NOT EXISTS booking_modifications (
+    id SERIAL PRIMARY KEY,
+    booking_id INTEGER NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    requested_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    old_start_date DATE NOT NULL,
+    old_end_date DATE NOT NULL,
+    old_guests INTEGER NOT NULL,
+    old_total DECIMAL(10, 2) NOT NULL,
+    new_start_date DATE NOT NULL,
+    new_end_date DATE NOT NULL,
+    new_guests INTEGER NOT NULL,
+    nights INTEGER NOT NULL,
+    accommodation_total DECIMAL(10, 2) NOT NULL,
+    service_fee DECIMAL(10, 2) NOT NULL,
+    new_total DECIMAL(10, 2) NOT NULL,
+    price_difference DECIMAL(10, 2) NOT NULL,
+    payment_method VARCHAR(255),
+    message TEXT,
+    responded_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    responded_at TIMESTAMP,
+    response_note TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_booking_modifications_booking_id ON booking_modifications(booking_id);
+
+-- At most one open request per booking
+CREATE UNIQUE INDEX IF NOT EXISTS idx_booking_modifications_pending
+    ON booking_modifications(booking_id) WHERE status = 'pending';
@@ -0,0 +1,10 @@
+-- 000011_add_booking_lifecycle.down.sql
+-- Remove booking lifecycle tracking
+
+DROP TABLE IF EXISTS booking_events;
+DROP INDEX IF EXISTS idx_bookings_status;
+
+ALTER TABLE bookings DROP COLUMN IF EXISTS no_show;
+ALTER TABLE bookings DROP COLUMN IF EXISTS checked_in_at;
+
+ALTER TABLE properties DROP COLUMN IF EXISTS timezone;
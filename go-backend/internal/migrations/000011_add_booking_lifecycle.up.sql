@@ -0,0 +1,20 @@
+-- 000011_add_booking_lifecycle.up.sql
+-- Property time zones, check-in tracking and booking lifecycle events
+
+ALTER TABLE properties ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS checked_in_at TIMESTAMP;
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS no_show BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_bookings_status ON bookings(status);
+
+-- Each lifecycle event is recorded once per booking
+CREATE TABLE IF NOT EXISTS booking_events (
+    id SERIAL PRIMARY KEY,
+    booking_id INTEGER NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (booking_id, type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_booking_events_created_at ON booking_events(created_at);
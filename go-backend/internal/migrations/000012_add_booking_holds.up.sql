@@ -0,0 +1,20 @@
+-- 000012_add_booking_holds.up.sql
+-- Short-lived holds that reserve dates while a guest checks out
+
+CREATE TABLE IF NOT EXISTS booking_holds (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    property_id INTEGER NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    guests INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active', -- active | converted | released | expired
+    expires_at TIMESTAMP NOT NULL,
+    booking_id INTEGER REFERENCES bookings(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (end_date > start_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_booking_holds_active_property ON booking_holds(property_id, start_date, end_date) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_booking_holds_active_user ON booking_holds(user_id) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_booking_holds_expires_at ON booking_holds(expires_at) WHERE status = 'active';
@@ -0,0 +1,25 @@
+-- 000013_add_reviews.up.sql
+-- Guest reviews of completed stays; properties.rating and reviews are derived from them
+
+CREATE TABLE IF NOT EXISTS reviews (
+    id SERIAL PRIMARY KEY,
+    booking_id INTEGER NOT NULL UNIQUE REFERENCES bookings(id) ON DELETE CASCADE,
+    property_id INTEGER NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    cleanliness SMALLINT NOT NULL CHECK (cleanliness BETWEEN 1 AND 5),
+    location SMALLINT NOT NULL CHECK (location BETWEEN 1 AND 5),
+    value SMALLINT NOT NULL CHECK (value BETWEEN 1 AND 5),
+    communication SMALLINT NOT NULL CHECK (communication BETWEEN 1 AND 5),
+    comment TEXT NOT NULL DEFAULT '',
+    host_response TEXT,
+    host_responded_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_reviews_property_id ON reviews(property_id, created_at DESC);
+
+-- Ratings no longer come from static values
+UPDATE properties p SET
+    rating = COALESCE((SELECT ROUND(AVG(r.rating), 1) FROM reviews r WHERE r.property_id = p.id), 0),
+    reviews = (SELECT COUNT(*) FROM reviews r WHERE r.property_id = p.id);
@@ -0,0 +1,13 @@
+-- 000015_add_moderation.down.sql
+-- Remove moderation
+
+DROP TABLE IF EXISTS moderation_actions;
+DROP TABLE IF EXISTS reports;
+
+ALTER TABLE users DROP COLUMN IF EXISTS warnings;
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_at;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS hidden_at;
+ALTER TABLE properties DROP COLUMN IF EXISTS hidden_at;
+ALTER TABLE guest_reviews DROP COLUMN IF EXISTS hidden_at;
+ALTER TABLE reviews DROP COLUMN IF EXISTS hidden_at;
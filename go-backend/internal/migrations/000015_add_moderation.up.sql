@@ -0,0 +1,50 @@
+-- 000015_add_moderation.up.sql
+-- Abuse reports, moderator actions and their audit trail
+
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS hidden_at TIMESTAMP;
+ALTER TABLE guest_reviews ADD COLUMN IF NOT EXISTS hidden_at TIMESTAMP;
+ALTER TABLE properties ADD COLUMN IF NOT EXISTS hidden_at TIMESTAMP;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS hidden_at TIMESTAMP;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS warnings INTEGER NOT NULL DEFAULT 0;
+
+-- reporter_id is NULL for reports created by the automatic pre-screen
+CREATE TABLE IF NOT EXISTS reports (
+    id SERIAL PRIMARY KEY,
+    reporter_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    target_type VARCHAR(20) NOT NULL, -- review | guest_review | listing | message | user
+    target_id INTEGER NOT NULL,
+    reason VARCHAR(50) NOT NULL,
+    details TEXT,
+    flags TEXT[] NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'open', -- open | resolved | dismissed
+    resolved_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_reports_open ON reports(created_at) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS idx_reports_target ON reports(target_type, target_id);
+
+-- One open report per reporter and target, and one open automatic report per target
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open_reporter
+    ON reports(reporter_id, target_type, target_id) WHERE status = 'open' AND reporter_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open_automatic
+    ON reports(target_type, target_id) WHERE status = 'open' AND reporter_id IS NULL;
+
+-- Audit trail of every moderation action; actor_id is NULL for the pre-screen
+CREATE TABLE IF NOT EXISTS moderation_actions (
+    id SERIAL PRIMARY KEY,
+    report_id INTEGER REFERENCES reports(id) ON DELETE SET NULL,
+    target_type VARCHAR(20) NOT NULL,
+    target_id INTEGER NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    subject_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    note TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_target ON moderation_actions(target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_created_at ON moderation_actions(created_at);
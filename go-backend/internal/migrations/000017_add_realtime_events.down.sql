@@ -0,0 +1,4 @@
+-- 000017_add_realtime_events.down.sql
+-- Remove stored realtime events
+
+DROP TABLE IF EXISTS realtime_events;
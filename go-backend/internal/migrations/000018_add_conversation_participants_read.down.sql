@@ -0,0 +1,14 @@
+-- 000018_add_conversation_participants_read.down.sql
+-- Restore the single read flag on messages
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS read BOOLEAN DEFAULT FALSE;
+
+UPDATE messages m SET read = EXISTS(
+    SELECT 1 FROM conversation_participants cp
+    WHERE cp.conversation_id = m.conversation_id
+      AND cp.user_id IS DISTINCT FROM m.sender_id
+      AND cp.last_read_message_id >= m.id
+);
+
+DROP INDEX IF EXISTS idx_messages_conversation_id_id;
+DROP TABLE IF EXISTS conversation_participants;
@@ -0,0 +1,4 @@
+-- 000019_add_message_attachments.down.sql
+-- Remove message attachments; stored files are left on disk
+
+DROP TABLE IF EXISTS message_attachments;
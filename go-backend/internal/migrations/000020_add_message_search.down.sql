@@ -0,0 +1,8 @@
+-- 000020_add_message_search.down.sql
+-- Remove message search and conversation archiving
+
+ALTER TABLE conversation_participants DROP COLUMN IF EXISTS muted_at;
+ALTER TABLE conversation_participants DROP COLUMN IF EXISTS archived_at;
+
+DROP INDEX IF EXISTS idx_messages_search_vector;
+ALTER TABLE messages DROP COLUMN IF EXISTS search_vector;
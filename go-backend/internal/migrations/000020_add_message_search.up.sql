@@ -0,0 +1,11 @@
+-- 000020_add_message_search.up.sql
+-- Full-text search over messages, and per-participant archiving and muting
+-- of conversations
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('english', text)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_messages_search_vector ON messages USING GIN (search_vector);
+
+ALTER TABLE conversation_participants ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP;
+ALTER TABLE conversation_participants ADD COLUMN IF NOT EXISTS muted_at TIMESTAMP;
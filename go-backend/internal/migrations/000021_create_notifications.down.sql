@@ -0,0 +1,5 @@
+-- 000021_create_notifications.down.sql
+-- Remove notifications
+
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notifications;
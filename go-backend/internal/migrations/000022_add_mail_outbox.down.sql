@@ -0,0 +1,7 @@
+-- 000022_add_mail_outbox.down.sql
+-- Remove the mail outbox and password reset tokens
+
+DROP TABLE IF EXISTS password_reset_tokens;
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS email;
+ALTER TABLE users DROP COLUMN IF EXISTS locale;
+DROP TABLE IF EXISTS mail_outbox;
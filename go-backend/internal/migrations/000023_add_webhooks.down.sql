@@ -0,0 +1,7 @@
+-- 000023_add_webhooks.down.sql
+-- Remove webhook subscriptions, events and deliveries
+
+DROP TABLE IF EXISTS webhook_delivery_attempts;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_events;
+DROP TABLE IF EXISTS webhook_subscriptions;
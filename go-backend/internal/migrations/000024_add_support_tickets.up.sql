@@ -0,0 +1,46 @@
+-- 000024_add_support_tickets.up.sql
+-- Support tickets with threaded replies, internal notes and SLA timers
+
+CREATE TABLE IF NOT EXISTS support_tickets (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    booking_id INTEGER REFERENCES bookings(id) ON DELETE SET NULL,
+    category VARCHAR(20) NOT NULL
+        CHECK (category IN ('booking', 'payment', 'account', 'technical', 'host', 'other')),
+    priority VARCHAR(10) NOT NULL DEFAULT 'normal'
+        CHECK (priority IN ('low', 'normal', 'high', 'urgent')),
+    status VARCHAR(20) NOT NULL DEFAULT 'open'
+        CHECK (status IN ('open', 'pending', 'resolved', 'closed')),
+    subject VARCHAR(200) NOT NULL,
+    assignee_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    -- SLA targets, derived from the priority and the creation time
+    first_response_due_at TIMESTAMP NOT NULL,
+    resolution_due_at TIMESTAMP NOT NULL,
+    first_responded_at TIMESTAMP,
+    resolved_at TIMESTAMP,
+    closed_at TIMESTAMP,
+    -- Set by the SLA worker when a target is missed
+    first_response_breached_at TIMESTAMP,
+    resolution_breached_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_support_tickets_user_id ON support_tickets(user_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_support_tickets_assignee_id ON support_tickets(assignee_id, status);
+CREATE INDEX IF NOT EXISTS idx_support_tickets_active
+    ON support_tickets(resolution_due_at) WHERE status IN ('open', 'pending');
+
+CREATE TABLE IF NOT EXISTS support_replies (
+    id SERIAL PRIMARY KEY,
+    ticket_id INTEGER NOT NULL REFERENCES support_tickets(id) ON DELETE CASCADE,
+    author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    -- Whether the author replied as a support agent
+    from_agent BOOLEAN NOT NULL DEFAULT FALSE,
+    body TEXT NOT NULL,
+    -- Internal notes are only visible to agents
+    internal BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_support_replies_ticket_id ON support_replies(ticket_id, id);
@@ -4,7 +4,7 @@ import (
 	"embed"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
@@ -40,6 +40,6 @@ func Run(dsn string) error {
 		return fmt.Errorf("database is in dirty state at version %d", version)
 	}
 
-	log.Printf("Database migrations completed (version: %d)", version)
+	slog.Info("database migrations completed", "version", version)
 	return nil
 }
@@ -3,12 +3,23 @@ package models
 import "time"
 
 type Booking struct {
-	ID         int       `json:"id"`
-	UserID     int       `json:"user_id"`
-	PropertyID int       `json:"property_id"`
-	StartDate  string    `json:"start_date"`
-	EndDate    string    `json:"end_date"`
-	Guests     int       `json:"guests"`
-	Status     string    `json:"status"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID                 int        `json:"id"`
+	UserID             int        `json:"user_id"`
+	PropertyID         int        `json:"property_id"`
+	StartDate          string     `json:"start_date"`
+	EndDate            string     `json:"end_date"`
+	Guests             int        `json:"guests"`
+	Status             string     `json:"status"`
+	NightlyRate        float64    `json:"nightly_rate"`
+	Nights             int        `json:"nights"`
+	AccommodationTotal float64    `json:"accommodation_total"`
+	ServiceFee         float64    `json:"service_fee"`
+	TotalPrice         float64    `json:"total_price"`
+	CancellationPolicy string     `json:"cancellation_policy"`
+	CancelledAt        *time.Time `json:"cancelled_at,omitempty"`
+	CancellationReason *string    `json:"cancellation_reason,omitempty"`
+	RefundAmount       *float64   `json:"refund_amount,omitempty"`
+	CheckedInAt        *time.Time `json:"checked_in_at,omitempty"`
+	NoShow             bool       `json:"no_show"`
+	CreatedAt          time.Time  `json:"created_at"`
 }
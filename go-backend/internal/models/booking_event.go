@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Booking lifecycle event types
+const (
+	BookingEventStarted   = "booking.started"    // check-in day reached
+	BookingEventCheckedIn = "booking.checked_in" // guest arrival confirmed
+	BookingEventNoShow    = "booking.no_show"    // guest did not check in
+	BookingEventCompleted = "booking.completed"  // check-out day reached
+)
+
+// BookingEvent records a lifecycle transition of a booking
+type BookingEvent struct {
+	ID        int       `json:"id"`
+	BookingID int       `json:"booking_id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// Calendar day states
+const (
+	DayAvailable = "available"
+	DayBooked    = "booked"
+	DayBlocked   = "blocked"
+)
+
+// CalendarDay is the availability of a property on a single night
+type CalendarDay struct {
+	Date   string  `json:"date"`
+	Status string  `json:"status"` // available | booked | blocked
+	Price  float64 `json:"price"`
+	Note   string  `json:"note,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Booking hold states
+const (
+	HoldActive    = "active"
+	HoldConverted = "converted"
+	HoldReleased  = "released"
+	HoldExpired   = "expired"
+)
+
+// BookingHold reserves dates for a guest for a few minutes while they check out.
+// Converting the hold creates the booking.
+type BookingHold struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"user_id"`
+	PropertyID int       `json:"property_id"`
+	StartDate  string    `json:"start_date"`
+	EndDate    string    `json:"end_date"`
+	Guests     int       `json:"guests"`
+	Status     string    `json:"status"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	BookingID  *int      `json:"booking_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// ICalFeed is an external calendar imported into a property's blocked dates
+type ICalFeed struct {
+	ID           int            `json:"id"`
+	PropertyID   int            `json:"property_id"`
+	Name         string         `json:"name"`
+	URL          string         `json:"url"`
+	LastSyncedAt *time.Time     `json:"last_synced_at"`
+	LastError    *string        `json:"last_error"`
+	CreatedAt    time.Time      `json:"created_at"`
+	Conflicts    []ICalConflict `json:"conflicts"`
+}
+
+// ICalConflict is an imported event that overlaps a booking on this platform
+type ICalConflict struct {
+	BookingID  int       `json:"booking_id"`
+	EventUID   string    `json:"event_uid"`
+	Summary    string    `json:"summary"`
+	StartDate  string    `json:"start_date"`
+	EndDate    string    `json:"end_date"`
+	DetectedAt time.Time `json:"detected_at"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// HostEarnings summarizes what a host has earned and been paid
+type HostEarnings struct {
+	Currency        string            `json:"currency"`
+	PayoutDelayDays int               `json:"payout_delay_days"`
+	Earned          float64           `json:"earned"`
+	Refunded        float64           `json:"refunded"`
+	PaidOut         float64           `json:"paid_out"`
+	Balance         float64           `json:"balance"`   // earned - refunded - paid out
+	Available       float64           `json:"available"` // part of the balance due in the next payout
+	Bookings        []BookingEarnings `json:"bookings"`
+}
+
+// BookingEarnings is a host's share of one booking
+type BookingEarnings struct {
+	BookingID     int     `json:"booking_id"`
+	PropertyID    int     `json:"property_id"`
+	PropertyTitle string  `json:"property_title"`
+	StartDate     string  `json:"start_date"`
+	ReleaseDate   string  `json:"release_date"`
+	Earned        float64 `json:"earned"`
+	Refunded      float64 `json:"refunded"`
+	PaidOut       float64 `json:"paid_out"`
+	Balance       float64 `json:"balance"`
+}
+
+// HostPayout is a batch of funds released to a host
+type HostPayout struct {
+	ID        int              `json:"id"`
+	HostID    int              `json:"host_id"`
+	Amount    float64          `json:"amount"`
+	Currency  string           `json:"currency"`
+	Status    string           `json:"status"`
+	CreatedAt time.Time        `json:"created_at"`
+	Items     []HostPayoutItem `json:"items"`
+}
+
+// HostPayoutItem is the amount of a payout attributed to one booking
+type HostPayoutItem struct {
+	BookingID int     `json:"booking_id"`
+	Amount    float64 `json:"amount"`
+}
+
+// AccountBalance is the activity of one ledger account
+type AccountBalance struct {
+	Account string  `json:"account"`
+	Debits  float64 `json:"debits"`
+	Credits float64 `json:"credits"`
+	Balance float64 `json:"balance"` // credits - debits
+}
+
+// PaymentMismatch is a payment whose ledger postings do not match its recorded amounts
+type PaymentMismatch struct {
+	PaymentID      int     `json:"payment_id"`
+	BookingID      int     `json:"booking_id"`
+	Status         string  `json:"status"`
+	Captured       float64 `json:"captured"`
+	LedgerCaptured float64 `json:"ledger_captured"`
+	Refunded       float64 `json:"refunded"`
+	LedgerRefunded float64 `json:"ledger_refunded"`
+}
+
+// Reconciliation is the admin report comparing the ledger with payments
+type Reconciliation struct {
+	GeneratedAt        time.Time         `json:"generated_at"`
+	Balanced           bool              `json:"balanced"`
+	Accounts           []AccountBalance  `json:"accounts"`
+	UnbalancedJournals []int             `json:"unbalanced_journals"`
+	PaymentMismatches  []PaymentMismatch `json:"payment_mismatches"`
+}
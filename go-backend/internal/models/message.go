@@ -3,18 +3,59 @@ package models
 import "time"
 
 type Message struct {
+	ID             int          `json:"id"`
+	ConversationID int          `json:"conversation_id"`
+	SenderID       int          `json:"sender_id"`
+	Text           string       `json:"text"`
+	Attachments    []Attachment `json:"attachments"`
+	CreatedAt      time.Time    `json:"created_at"`
+	Read           bool         `json:"read"` // read by the recipient
+}
+
+// Attachment is a file uploaded to a conversation. MessageID is nil until a
+// message is sent with it. Images have dimensions and a thumbnail.
+type Attachment struct {
 	ID             int       `json:"id"`
 	ConversationID int       `json:"conversation_id"`
-	SenderID       int       `json:"sender_id"`
-	Text           string    `json:"text"`
+	MessageID      *int      `json:"message_id"`
+	Filename       string    `json:"filename"`
+	ContentType    string    `json:"content_type"`
+	SizeBytes      int64     `json:"size_bytes"`
+	Width          *int      `json:"width,omitempty"`
+	Height         *int      `json:"height,omitempty"`
+	URL            string    `json:"url"`
+	ThumbnailURL   string    `json:"thumbnail_url,omitempty"`
 	CreatedAt      time.Time `json:"created_at"`
-	Read           bool      `json:"read"`
 }
 
+// Conversation is a conversation between a guest and a host about a property
+// and optionally a booking, as seen by one of them. GuestID and HostID are nil
+// once that user's account was deleted.
 type Conversation struct {
-	ID          int       `json:"id"`
-	ContactName string    `json:"contact_name"`
-	ContactType string    `json:"contact_type"` // host | guest | support
-	Property    string    `json:"property_name"`
-	Messages    []Message `json:"messages"`
+	ID            int        `json:"id"`
+	GuestID       *int       `json:"guest_id"`
+	HostID        *int       `json:"host_id"`
+	PropertyID    *int       `json:"property_id"`
+	Property      string     `json:"property_name"`
+	BookingID     *int       `json:"booking_id"`
+	ContactID     *int       `json:"contact_id"`   // the other participant
+	ContactName   string     `json:"contact_name"` // the other participant's name
+	ContactType   string     `json:"contact_type"` // host | guest, the other participant's role
+	LastMessage   string     `json:"last_message"`
+	LastMessageAt *time.Time `json:"last_message_at"`
+	UnreadCount   int        `json:"unread_count"` // messages from the other participant not yet read
+	Archived      bool       `json:"archived"`     // archived by the viewer
+	Muted         bool       `json:"muted"`        // muted by the viewer
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// MessageSearchResult is a message matching a search, with the conversation
+// it belongs to and an HTML-escaped snippet highlighting the matched words
+// in <mark> tags
+type MessageSearchResult struct {
+	Message      Message `json:"message"`
+	PropertyID   *int    `json:"property_id"`
+	PropertyName string  `json:"property_name"`
+	ContactName  string  `json:"contact_name"`
+	Snippet      string  `json:"snippet"`
 }
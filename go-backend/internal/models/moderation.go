@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Report is a user's report of a review, listing, message or user, or an
+// automatic report from the pre-screen (ReporterID is nil)
+type Report struct {
+	ID         int        `json:"id"`
+	ReporterID *int       `json:"reporter_id"`
+	TargetType string     `json:"target_type"`
+	TargetID   int        `json:"target_id"`
+	Reason     string     `json:"reason"`
+	Details    *string    `json:"details,omitempty"`
+	Flags      []string   `json:"flags"`
+	Status     string     `json:"status"`
+	ResolvedBy *int       `json:"resolved_by,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// QueuedReport is a report in the moderation queue with a preview of its target
+type QueuedReport struct {
+	Report
+	Preview     string `json:"preview"`      // text of the reported content or the user's name
+	Hidden      bool   `json:"hidden"`       // content is hidden or user is suspended
+	OpenReports int    `json:"open_reports"` // open reports for the same target
+}
+
+// ModerationAction is an entry in the moderation audit trail
+type ModerationAction struct {
+	ID            int       `json:"id"`
+	ReportID      *int      `json:"report_id,omitempty"`
+	TargetType    string    `json:"target_type"`
+	TargetID      int       `json:"target_id"`
+	Action        string    `json:"action"`
+	ActorID       *int      `json:"actor_id"` // nil for the pre-screen
+	SubjectUserID *int      `json:"subject_user_id,omitempty"`
+	Note          *string   `json:"note,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
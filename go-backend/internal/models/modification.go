@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Booking modification states
+const (
+	ModificationPending   = "pending"
+	ModificationApproved  = "approved"
+	ModificationRejected  = "rejected"
+	ModificationWithdrawn = "withdrawn"
+)
+
+// BookingModification is a guest's request to change the dates or guest count
+// of a booking. The old values are kept so the history shows what changed.
+type BookingModification struct {
+	ID                 int        `json:"id"`
+	BookingID          int        `json:"booking_id"`
+	RequestedBy        *int       `json:"requested_by"`
+	Status             string     `json:"status"`
+	OldStartDate       string     `json:"old_start_date"`
+	OldEndDate         string     `json:"old_end_date"`
+	OldGuests          int        `json:"old_guests"`
+	OldTotal           float64    `json:"old_total"`
+	NewStartDate       string     `json:"new_start_date"`
+	NewEndDate         string     `json:"new_end_date"`
+	NewGuests          int        `json:"new_guests"`
+	Nights             int        `json:"nights"`
+	AccommodationTotal float64    `json:"accommodation_total"`
+	ServiceFee         float64    `json:"service_fee"`
+	NewTotal           float64    `json:"new_total"`
+	PriceDifference    float64    `json:"price_difference"` // positive is charged, negative is refunded
+	Message            *string    `json:"message,omitempty"`
+	RespondedBy        *int       `json:"responded_by,omitempty"`
+	RespondedAt        *time.Time `json:"responded_at,omitempty"`
+	ResponseNote       *string    `json:"response_note,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
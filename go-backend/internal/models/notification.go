@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Notification is an in-app notification. Type is booking, payment, review,
+// message, system or reminder; Priority is low, medium or high.
+type Notification struct {
+	ID          int        `json:"id"`
+	Type        string     `json:"type"`
+	Title       string     `json:"title"`
+	Message     string     `json:"message"`
+	Priority    string     `json:"priority"`
+	ActionLabel *string    `json:"action_label"`
+	ActionURL   *string    `json:"action_url"`
+	Read        bool       `json:"read"`
+	ReadAt      *time.Time `json:"read_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// NotificationPreference is whether a user receives a type of notification in
+// the app and by email
+type NotificationPreference struct {
+	InApp bool `json:"in_app"`
+	Email bool `json:"email"`
+}
@@ -16,5 +16,6 @@ type Property struct {
 	Amenities     []string  `json:"amenities"`
 	Image         string    `json:"image"`
 	OwnerID       *int      `json:"owner_id,omitempty"`
+	Timezone      string    `json:"timezone"`
 	CreatedAt     time.Time `json:"created_at"`
 }
@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// Review is a guest's review of a completed stay. It is hidden until the host
+// has reviewed the guest too or the review window has closed.
+type Review struct {
+	ID              int        `json:"id"`
+	BookingID       int        `json:"booking_id"`
+	PropertyID      int        `json:"property_id"`
+	AuthorID        *int       `json:"author_id"`
+	AuthorName      string     `json:"author_name"`
+	Rating          int        `json:"rating"`
+	Cleanliness     int        `json:"cleanliness"`
+	Location        int        `json:"location"`
+	Value           int        `json:"value"`
+	Communication   int        `json:"communication"`
+	Comment         string     `json:"comment"`
+	HostResponse    *string    `json:"host_response,omitempty"`
+	HostRespondedAt *time.Time `json:"host_responded_at,omitempty"`
+	PublishedAt     *time.Time `json:"published_at"`        // nil until both sides reviewed or the window closed
+	HiddenAt        *time.Time `json:"hidden_at,omitempty"` // set when hidden by a moderator
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// GuestReview is a host's review of a guest after a completed stay, published
+// together with the guest's review
+type GuestReview struct {
+	ID            int        `json:"id"`
+	BookingID     int        `json:"booking_id"`
+	GuestID       int        `json:"guest_id"`
+	AuthorID      *int       `json:"author_id"`
+	AuthorName    string     `json:"author_name"`
+	Rating        int        `json:"rating"`
+	Cleanliness   int        `json:"cleanliness"`
+	Communication int        `json:"communication"`
+	HouseRules    int        `json:"house_rules"`
+	Comment       string     `json:"comment"`
+	PublishedAt   *time.Time `json:"published_at"`        // nil until both sides reviewed or the window closed
+	HiddenAt      *time.Time `json:"hidden_at,omitempty"` // set when hidden by a moderator
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// GuestProfile is what hosts see about a guest
+type GuestProfile struct {
+	ID             int           `json:"id"`
+	Name           string        `json:"name"`
+	MemberSince    time.Time     `json:"member_since"`
+	CompletedStays int           `json:"completed_stays"`
+	GuestRating    float64       `json:"guest_rating"`
+	GuestReviews   int           `json:"guest_reviews"`
+	Reviews        []GuestReview `json:"reviews"`
+	Limit          int           `json:"limit"`
+	Offset         int           `json:"offset"`
+}
+
+// ReviewSummary holds a property's average scores
+type ReviewSummary struct {
+	Count         int     `json:"count"`
+	Rating        float64 `json:"rating"`
+	Cleanliness   float64 `json:"cleanliness"`
+	Location      float64 `json:"location"`
+	Value         float64 `json:"value"`
+	Communication float64 `json:"communication"`
+}
+
+// ReviewPage is one page of a property's reviews
+type ReviewPage struct {
+	Summary ReviewSummary `json:"summary"`
+	Reviews []Review      `json:"reviews"`
+	Total   int           `json:"total"`
+	Limit   int           `json:"limit"`
+	Offset  int           `json:"offset"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// SupportTicket is a user's request for help. Status is open, pending
+// (waiting on the user), resolved or closed; the due dates are the SLA
+// targets for the first agent reply and the resolution.
+type SupportTicket struct {
+	ID                      int            `json:"id"`
+	Reference               string         `json:"reference"`
+	UserID                  int            `json:"user_id"`
+	BookingID               *int           `json:"booking_id"`
+	Category                string         `json:"category"`
+	Priority                string         `json:"priority"`
+	Status                  string         `json:"status"`
+	Subject                 string         `json:"subject"`
+	AssigneeID              *int           `json:"assignee_id"`
+	FirstResponseDueAt      time.Time      `json:"first_response_due_at"`
+	ResolutionDueAt         time.Time      `json:"resolution_due_at"`
+	FirstRespondedAt        *time.Time     `json:"first_responded_at"`
+	ResolvedAt              *time.Time     `json:"resolved_at"`
+	ClosedAt                *time.Time     `json:"closed_at"`
+	FirstResponseBreachedAt *time.Time     `json:"first_response_breached_at,omitempty"`
+	ResolutionBreachedAt    *time.Time     `json:"resolution_breached_at,omitempty"`
+	CreatedAt               time.Time      `json:"created_at"`
+	UpdatedAt               time.Time      `json:"updated_at"`
+	Replies                 []SupportReply `json:"replies,omitempty"`
+}
+
+// SupportReply is a message in a ticket thread. Internal replies are notes
+// between agents that the user does not see.
+type SupportReply struct {
+	ID         int       `json:"id"`
+	TicketID   int       `json:"ticket_id"`
+	AuthorID   *int      `json:"author_id"`
+	AuthorName string    `json:"author_name"`
+	FromAgent  bool      `json:"from_agent"`
+	Body       string    `json:"body"`
+	Internal   bool      `json:"internal"`
+	CreatedAt  time.Time `json:"created_at"`
+}
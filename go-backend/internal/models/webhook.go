@@ -0,0 +1,49 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookSubscription is a partner endpoint receiving events. Secret is only
+// returned when the subscription is created or its secret rotated.
+type WebhookSubscription struct {
+	ID          int       `json:"id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	Description string    `json:"description"`
+	Active      bool      `json:"active"`
+	Secret      string    `json:"secret,omitempty"`
+	CreatedBy   *int      `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is the delivery of an event to a subscription. Status is
+// pending, delivered or dead; NextAttemptAt is only set while pending.
+type WebhookDelivery struct {
+	ID             int                      `json:"id"`
+	SubscriptionID int                      `json:"subscription_id"`
+	EventID        int                      `json:"event_id"`
+	EventType      string                   `json:"event_type"`
+	Status         string                   `json:"status"`
+	Attempts       int                      `json:"attempts"`
+	NextAttemptAt  *time.Time               `json:"next_attempt_at"`
+	LastStatusCode *int                     `json:"last_status_code"`
+	LastError      *string                  `json:"last_error"`
+	DeliveredAt    *time.Time               `json:"delivered_at"`
+	CreatedAt      time.Time                `json:"created_at"`
+	Payload        json.RawMessage          `json:"payload,omitempty"`
+	AttemptLog     []WebhookDeliveryAttempt `json:"attempt_log,omitempty"`
+}
+
+// WebhookDeliveryAttempt is a logged delivery attempt. StatusCode is nil when
+// the receiver could not be reached.
+type WebhookDeliveryAttempt struct {
+	Attempt      int       `json:"attempt"`
+	StatusCode   *int      `json:"status_code"`
+	Error        *string   `json:"error"`
+	ResponseBody *string   `json:"response_body"`
+	DurationMS   int       `json:"duration_ms"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,123 @@
+package moderation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Contact masking rules
+const (
+	RuleEmail = "email"
+	RulePhone = "phone"
+	RuleURL   = "url"
+	RuleNone  = "none" // disables the built-in rules
+)
+
+// DefaultMaskReplacement is shown in place of masked contact details
+const DefaultMaskReplacement = "[contact info hidden]"
+
+// Separators used to write addresses without "@" and ".": "@", "(at)",
+// "[at]", " at " and ".", "(dot)", "[.]", " dot "
+const (
+	atSep  = `(?:\s*@\s*|\s*[\[({<]\s*(?:at|@)\s*[\])}>]\s*|\s+at\s+)`
+	dotSep = `(?:\.|\s*[\[({<]\s*(?:dot|\.)\s*[\])}>]\s*|\s+dot\s+)`
+	// Obfuscated domains only count with a common top-level domain, so
+	// ordinary sentences containing "at" and "dot" are left alone
+	maskTLDs  = `(?:com|net|org|edu|gov|io|co|me|info|biz|ly|app|uk|de|fr|ca|au|us|ru)`
+	digitWord = `(?:\d|\b(?:zero|one|two|three|four|five|six|seven|eight|nine)\b)`
+)
+
+var (
+	maskEmailPattern = regexp.MustCompile(`(?i)` + emailPattern.String() +
+		`|[a-z0-9._%+-]+` + atSep + `[a-z0-9-]+(?:` + dotSep + `[a-z0-9-]+)*` + dotSep + maskTLDs + `\b`)
+	maskPhonePattern = regexp.MustCompile(`(?i)\+?(?:` + digitWord + `[\s().-]*){7,}` + digitWord)
+	maskURLPattern   = regexp.MustCompile(`(?i)\b(?:https?|hxxps?)://\S+|\bwww` + dotSep + `\S+` +
+		`|\b[a-z0-9-]+(?:` + dotSep + `[a-z0-9-]+)*` + dotSep + maskTLDs + `\b(?:/\S*)?`)
+
+	digitWordPattern = regexp.MustCompile(`(?i)\d|\b(?:zero|one|two|three|four|five|six|seven|eight|nine)\b`)
+	// datesPattern matches one date or a range of dates, which look like phone numbers
+	datesPattern = regexp.MustCompile(`^(?:\d{4}-\d{1,2}-\d{1,2}|\d{1,2}[./-]\d{1,2}[./-]\d{2,4})` +
+		`(?:\s*-\s*(?:\d{4}-\d{1,2}-\d{1,2}|\d{1,2}[./-]\d{1,2}[./-]\d{2,4}))?$`)
+)
+
+// maskRule masks the matches of a pattern, recorded as flags of kind
+type maskRule struct {
+	kind    string
+	pattern *regexp.Regexp
+	skip    func(match string) bool // leaves a match unmasked if it returns true
+}
+
+// Masker hides contact details in messages, including forms written to
+// dodge filters such as "jane at mail dot com" or spelled-out digits
+type Masker struct {
+	rules       []maskRule
+	replacement string
+}
+
+// NewMasker creates a masker applying the named built-in rules followed by
+// extra regular expressions. An empty replacement uses DefaultMaskReplacement.
+func NewMasker(rules, patterns []string, replacement string) (*Masker, error) {
+	if replacement == "" {
+		replacement = DefaultMaskReplacement
+	}
+	m := &Masker{replacement: replacement}
+
+	// Built-in rules always run in this order, so an email address is not
+	// also masked as a link
+	enabled := make(map[string]bool)
+	for _, r := range rules {
+		switch r = strings.ToLower(strings.TrimSpace(r)); r {
+		case RuleEmail, RulePhone, RuleURL:
+			enabled[r] = true
+		case RuleNone, "":
+		default:
+			return nil, fmt.Errorf("unknown masking rule %q", r)
+		}
+	}
+	if enabled[RuleEmail] {
+		m.rules = append(m.rules, maskRule{kind: FlagContactInfo, pattern: maskEmailPattern})
+	}
+	if enabled[RulePhone] {
+		m.rules = append(m.rules, maskRule{kind: FlagContactInfo, pattern: maskPhonePattern, skip: notPhoneNumber})
+	}
+	if enabled[RuleURL] {
+		m.rules = append(m.rules, maskRule{kind: FlagLink, pattern: maskURLPattern})
+	}
+
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid masking pattern %q: %w", p, err)
+		}
+		m.rules = append(m.rules, maskRule{kind: FlagContactInfo, pattern: re})
+	}
+	return m, nil
+}
+
+// Enabled reports whether the masker has any rules
+func (m *Masker) Enabled() bool {
+	return len(m.rules) > 0
+}
+
+// Mask replaces contact details in text and returns what was masked
+func (m *Masker) Mask(text string) (string, []Flag) {
+	var flags []Flag
+	for _, rule := range m.rules {
+		text = rule.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if rule.skip != nil && rule.skip(match) {
+				return match
+			}
+			flags = append(flags, Flag{Kind: rule.kind, Match: strings.TrimSpace(match)})
+			return m.replacement
+		})
+	}
+	return text, flags
+}
+
+// notPhoneNumber reports whether a phone pattern match has too few digits or
+// is a date
+func notPhoneNumber(match string) bool {
+	match = strings.TrimSpace(match)
+	return len(digitWordPattern.FindAllString(match, -1)) < minPhoneDigits || datesPattern.MatchString(match)
+}
@@ -0,0 +1,78 @@
+package moderation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMask(t *testing.T) {
+	m, err := NewMasker([]string{RuleEmail, RulePhone, RuleURL}, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const hidden = DefaultMaskReplacement
+
+	tests := []struct {
+		name  string
+		text  string
+		want  string
+		flags []Flag
+	}{
+		{"clean", "Is the pool heated? We arrive at 5pm.", "Is the pool heated? We arrive at 5pm.", nil},
+		{"email", "Mail jane.doe@example.com please", "Mail " + hidden + " please",
+			[]Flag{{FlagContactInfo, "jane.doe@example.com"}}},
+		{"spaced email", "jane @ example.com", hidden, []Flag{{FlagContactInfo, "jane @ example.com"}}},
+		{"at dot words", "write to jane at gmail dot com!", "write to " + hidden + "!",
+			[]Flag{{FlagContactInfo, "jane at gmail dot com"}}},
+		{"bracketed", "jane(at)mail[dot]co[dot]uk", hidden, []Flag{{FlagContactInfo, "jane(at)mail[dot]co[dot]uk"}}},
+		{"phone", "Call +1 (415) 555-0123 anytime", "Call " + hidden + " anytime",
+			[]Flag{{FlagContactInfo, "+1 (415) 555-0123"}}},
+		{"spelled phone", "text four one five 555 zero one two three", "text " + hidden,
+			[]Flag{{FlagContactInfo, "four one five 555 zero one two three"}}},
+		{"short numbers", "2 adults, 3 kids, room 12, door code 4821", "2 adults, 3 kids, room 12, door code 4821", nil},
+		{"dates", "Can we do 2024-06-01 - 2024-06-05 or 12.07.2024?", "Can we do 2024-06-01 - 2024-06-05 or 12.07.2024?", nil},
+		{"url", "See https://example.org/rooms now", "See " + hidden + " now",
+			[]Flag{{FlagLink, "https://example.org/rooms"}}},
+		{"dot domain", "book on cheaprooms dot com instead", "book on " + hidden + " instead",
+			[]Flag{{FlagLink, "cheaprooms dot com"}}},
+		{"www", "www.example.net", hidden, []Flag{{FlagLink, "www.example.net"}}},
+		{"sentence with at and dot", "Look at the dot on the map. Come early", "Look at the dot on the map. Come early", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, flags := m.Mask(tt.text)
+			if got != tt.want {
+				t.Errorf("Mask(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+			if !reflect.DeepEqual(flags, tt.flags) {
+				t.Errorf("Mask(%q) flags = %v, want %v", tt.text, flags, tt.flags)
+			}
+		})
+	}
+}
+
+func TestMaskerRules(t *testing.T) {
+	m, err := NewMasker([]string{"phone"}, []string{`(?i)\bwhatsapp\b`}, "***")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, flags := m.Mask("WhatsApp me on 4155550123 or jane@example.com")
+	if want := "*** me on *** or jane@example.com"; got != want {
+		t.Errorf("Mask = %q, want %q", got, want)
+	}
+	if len(flags) != 2 {
+		t.Errorf("flags = %v, want 2", flags)
+	}
+
+	none, err := NewMasker([]string{RuleNone}, nil, "")
+	if err != nil || none.Enabled() {
+		t.Errorf("none: enabled=%v err=%v", none.Enabled(), err)
+	}
+	if _, err := NewMasker([]string{"fax"}, nil, ""); err == nil {
+		t.Error("unknown rule accepted")
+	}
+	if _, err := NewMasker(nil, []string{"("}, ""); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+}
@@ -0,0 +1,104 @@
+// Package moderation defines what users can report, the actions moderators
+// can take and an automatic pre-screen of user-written text.
+package moderation
+
+import "fmt"
+
+// Reportable targets
+const (
+	TargetReview      = "review"
+	TargetGuestReview = "guest_review"
+	TargetListing     = "listing"
+	TargetMessage     = "message"
+	TargetUser        = "user"
+)
+
+// Report reasons chosen by users
+const (
+	ReasonSpam          = "spam"
+	ReasonOffensive     = "offensive"
+	ReasonHarassment    = "harassment"
+	ReasonFraud         = "fraud"
+	ReasonInappropriate = "inappropriate"
+	ReasonOffPlatform   = "off_platform" // asks to pay or talk outside the platform
+	ReasonOther         = "other"
+)
+
+// ReasonAutoFlagged is used for reports created by the pre-screen
+const ReasonAutoFlagged = "auto_flagged"
+
+// MaxDetailsLength limits the free-text details of a report
+const MaxDetailsLength = 2000
+
+// Report states
+const (
+	StatusOpen      = "open"
+	StatusResolved  = "resolved"
+	StatusDismissed = "dismissed"
+)
+
+// Moderator actions. Hide and restore apply to content (restore on a user
+// lifts a suspension); warn and suspend apply to the user behind the target.
+const (
+	ActionHide     = "hide"
+	ActionRestore  = "restore"
+	ActionWarn     = "warn"
+	ActionSuspend  = "suspend"
+	ActionDismiss  = "dismiss"
+	ActionAutoFlag = "auto_flag" // recorded when the pre-screen flags content
+	ActionMask     = "mask"      // recorded when contact details are masked in a message
+)
+
+var targets = map[string]bool{
+	TargetReview:      true,
+	TargetGuestReview: true,
+	TargetListing:     true,
+	TargetMessage:     true,
+	TargetUser:        true,
+}
+
+var reasons = map[string]bool{
+	ReasonSpam:          true,
+	ReasonOffensive:     true,
+	ReasonHarassment:    true,
+	ReasonFraud:         true,
+	ReasonInappropriate: true,
+	ReasonOffPlatform:   true,
+	ReasonOther:         true,
+}
+
+// ValidTarget reports whether t is a reportable target type
+func ValidTarget(t string) bool {
+	return targets[t]
+}
+
+// ValidReason reports whether r is a reason users can report with
+func ValidReason(r string) bool {
+	return reasons[r]
+}
+
+// CheckAction returns an error if a moderator cannot take action on target
+func CheckAction(target, action string) error {
+	if !ValidTarget(target) {
+		return fmt.Errorf("unknown target type %q", target)
+	}
+	switch action {
+	case ActionRestore, ActionWarn, ActionSuspend, ActionDismiss:
+		return nil
+	case ActionHide:
+		if target == TargetUser {
+			return fmt.Errorf("users cannot be hidden, use %q", ActionSuspend)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown action %q", action)
+	}
+}
+
+// ReportStatus returns the state reports are closed with after action
+func ReportStatus(action string) string {
+	if action == ActionDismiss {
+		return StatusDismissed
+	}
+	return StatusResolved
+}
@@ -0,0 +1,51 @@
+package moderation
+
+import "testing"
+
+func TestCheckAction(t *testing.T) {
+	tests := []struct {
+		target  string
+		action  string
+		wantErr bool
+	}{
+		{TargetReview, ActionHide, false},
+		{TargetMessage, ActionRestore, false},
+		{TargetListing, ActionSuspend, false},
+		{TargetUser, ActionWarn, false},
+		{TargetUser, ActionRestore, false},
+		{TargetUser, ActionHide, true},
+		{TargetReview, ActionAutoFlag, true},
+		{TargetReview, "delete", true},
+		{"booking", ActionHide, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target+"/"+tt.action, func(t *testing.T) {
+			err := CheckAction(tt.target, tt.action)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckAction() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidReason(t *testing.T) {
+	if !ValidReason(ReasonSpam) || !ValidReason(ReasonOther) {
+		t.Error("Expected user reasons to be valid")
+	}
+	if ValidReason(ReasonAutoFlagged) {
+		t.Error("Users must not report with the pre-screen reason")
+	}
+	if ValidReason("") {
+		t.Error("Expected empty reason to be invalid")
+	}
+}
+
+func TestReportStatus(t *testing.T) {
+	if got := ReportStatus(ActionDismiss); got != StatusDismissed {
+		t.Errorf("ReportStatus(dismiss) = %s", got)
+	}
+	if got := ReportStatus(ActionHide); got != StatusResolved {
+		t.Errorf("ReportStatus(hide) = %s", got)
+	}
+}
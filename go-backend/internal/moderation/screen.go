@@ -0,0 +1,161 @@
+package moderation
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Pre-screen flag kinds
+const (
+	FlagProfanity   = "profanity"
+	FlagContactInfo = "contact_info"
+	FlagLink        = "link"
+)
+
+// Flag is something the pre-screen found in a text
+type Flag struct {
+	Kind  string `json:"kind"`
+	Match string `json:"match"`
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	phonePattern = regexp.MustCompile(`\+?\d[\d\s().-]{6,}\d`)
+	linkPattern  = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+|\b[a-z0-9-]+\.(?:com|net|org|io|co|me|info|biz|ly|app)\b(?:/\S*)?`)
+)
+
+// minPhoneDigits is how many digits a number needs to be treated as a phone number
+const minPhoneDigits = 8
+
+// Screener flags profanity from word lists, contact details and links
+type Screener struct {
+	words   map[string]bool // single words
+	phrases []string        // multi-word entries, space separated
+}
+
+// NewScreener creates a screener for the given words and phrases, matched
+// case-insensitively on word boundaries
+func NewScreener(words []string) *Screener {
+	s := &Screener{words: make(map[string]bool)}
+	for _, w := range words {
+		tokens := tokenize(w)
+		switch len(tokens) {
+		case 0:
+		case 1:
+			s.words[tokens[0]] = true
+		default:
+			s.phrases = append(s.phrases, strings.Join(tokens, " "))
+		}
+	}
+	return s
+}
+
+// LoadWordLists reads word list files with one word or phrase per line.
+// Blank lines and lines starting with # are ignored.
+func LoadWordLists(paths ...string) ([]string, error) {
+	return readLists("word list", paths)
+}
+
+// LoadPatterns reads files of masking patterns, one regular expression per
+// line, in the same format as word lists
+func LoadPatterns(paths ...string) ([]string, error) {
+	return readLists("pattern list", paths)
+}
+
+// readLists reads the non-blank, non-comment lines of files
+func readLists(kind string, paths []string) ([]string, error) {
+	var lines []string
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", kind, err)
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			lines = append(lines, line)
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read %s %s: %w", kind, path, err)
+		}
+	}
+	return lines, nil
+}
+
+// Screen returns everything flagged in text, grouped by kind
+func (s *Screener) Screen(text string) []Flag {
+	var flags []Flag
+
+	tokens := tokenize(text)
+	for _, t := range tokens {
+		if s.words[t] {
+			flags = append(flags, Flag{Kind: FlagProfanity, Match: t})
+		}
+	}
+	if len(s.phrases) > 0 {
+		joined := " " + strings.Join(tokens, " ") + " "
+		for _, p := range s.phrases {
+			if strings.Contains(joined, " "+p+" ") {
+				flags = append(flags, Flag{Kind: FlagProfanity, Match: p})
+			}
+		}
+	}
+
+	for _, m := range emailPattern.FindAllString(text, -1) {
+		flags = append(flags, Flag{Kind: FlagContactInfo, Match: m})
+	}
+	for _, m := range phonePattern.FindAllString(text, -1) {
+		if countDigits(m) >= minPhoneDigits {
+			flags = append(flags, Flag{Kind: FlagContactInfo, Match: strings.TrimSpace(m)})
+		}
+	}
+
+	// Addresses were already flagged as contact info
+	withoutEmails := emailPattern.ReplaceAllString(text, " ")
+	for _, m := range linkPattern.FindAllString(withoutEmails, -1) {
+		flags = append(flags, Flag{Kind: FlagLink, Match: m})
+	}
+
+	return flags
+}
+
+// Kinds returns the distinct kinds of flags, sorted
+func Kinds(flags []Flag) []string {
+	seen := make(map[string]bool)
+	var kinds []string
+	for _, f := range flags {
+		if !seen[f.Kind] {
+			seen[f.Kind] = true
+			kinds = append(kinds, f.Kind)
+		}
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// tokenize lowercases text and splits it into words
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func countDigits(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			n++
+		}
+	}
+	return n
+}
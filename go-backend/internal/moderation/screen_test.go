@@ -0,0 +1,75 @@
+package moderation
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestScreen(t *testing.T) {
+	s := NewScreener([]string{"darn", "Heck", "total scam"})
+
+	tests := []struct {
+		name string
+		text string
+		want []Flag
+	}{
+		{"clean", "Lovely place, great host!", nil},
+		{"word", "What a DARN mess", []Flag{{FlagProfanity, "darn"}}},
+		{"word inside another word", "The darning needle", nil},
+		{"phrase", "This is a total   scam.", []Flag{{FlagProfanity, "total scam"}}},
+		{"email", "Mail me at jane.doe@example.com", []Flag{{FlagContactInfo, "jane.doe@example.com"}}},
+		{"phone", "Call +1 (415) 555-0123 anytime", []Flag{{FlagContactInfo, "+1 (415) 555-0123"}}},
+		{"short number", "We were 2 adults and 3 kids in room 12", nil},
+		{"url", "See https://example.org/rooms", []Flag{{FlagLink, "https://example.org/rooms"}}},
+		{"bare domain", "Book on cheaprooms.com instead", []Flag{{FlagLink, "cheaprooms.com"}}},
+		{"www", "www.example.net", []Flag{{FlagLink, "www.example.net"}}},
+		{"mixed", "heck, text me on 4155550123", []Flag{
+			{FlagProfanity, "heck"},
+			{FlagContactInfo, "4155550123"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.Screen(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Screen(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKinds(t *testing.T) {
+	flags := []Flag{{FlagLink, "a.com"}, {FlagProfanity, "x"}, {FlagLink, "b.com"}}
+	want := []string{FlagLink, FlagProfanity}
+	if got := Kinds(flags); !reflect.DeepEqual(got, want) {
+		t.Errorf("Kinds() = %v, want %v", got, want)
+	}
+}
+
+func TestLoadWordLists(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	if err := os.WriteFile(a, []byte("# comment\ndarn\n\n  heck  \n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("total scam\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	words, err := LoadWordLists(a, b)
+	if err != nil {
+		t.Fatalf("LoadWordLists() error = %v", err)
+	}
+	want := []string{"darn", "heck", "total scam"}
+	if !reflect.DeepEqual(words, want) {
+		t.Errorf("LoadWordLists() = %v, want %v", words, want)
+	}
+
+	if _, err := LoadWordLists(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
@@ -0,0 +1,198 @@
+// Package notification stores in-app notifications and pushes them to the
+// user over the realtime channel. Flows publish them inside their own
+// transactions, so a notification exists only if the change it announces
+// was committed.
+package notification
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+
+	"go-backend/internal/models"
+	"go-backend/internal/realtime"
+)
+
+// Notification types, matching the categories of the notifications page
+const (
+	TypeBooking  = "booking"
+	TypePayment  = "payment"
+	TypeReview   = "review"
+	TypeMessage  = "message"
+	TypeSystem   = "system"
+	TypeReminder = "reminder"
+)
+
+// Types lists every notification type
+var Types = []string{TypeBooking, TypePayment, TypeReview, TypeMessage, TypeSystem, TypeReminder}
+
+// Priorities
+const (
+	PriorityLow    = "low"
+	PriorityMedium = "medium"
+	PriorityHigh   = "high"
+)
+
+const (
+	// MaxTitleLength and MaxMessageLength bound the text of a notification in
+	// characters; longer text is truncated
+	MaxTitleLength   = 120
+	MaxMessageLength = 500
+)
+
+// Errors
+var (
+	ErrInvalidType     = errors.New("invalid notification type")
+	ErrInvalidPriority = errors.New("invalid notification priority")
+	ErrMissingTitle    = errors.New("notification title is required")
+)
+
+// ValidType reports whether t is a notification type
+func ValidType(t string) bool {
+	for _, v := range Types {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Configurable reports whether users can turn off notifications of type t.
+// System notices about the account are always delivered.
+func Configurable(t string) bool {
+	return ValidType(t) && t != TypeSystem
+}
+
+// Notification is a notification to publish to a user
+type Notification struct {
+	UserID      int
+	Type        string
+	Title       string
+	Message     string
+	Priority    string // defaults to medium
+	ActionLabel string
+	ActionURL   string
+	// GroupKey collapses repeated notifications: while a notification with
+	// the same key is unread, publishing replaces it instead of adding another
+	GroupKey string
+}
+
+// Normalize validates n, defaults its priority and truncates its text
+func (n *Notification) Normalize() error {
+	if !ValidType(n.Type) {
+		return ErrInvalidType
+	}
+	switch n.Priority {
+	case "":
+		n.Priority = PriorityMedium
+	case PriorityLow, PriorityMedium, PriorityHigh:
+	default:
+		return ErrInvalidPriority
+	}
+	n.Title = Truncate(strings.TrimSpace(n.Title), MaxTitleLength)
+	if n.Title == "" {
+		return ErrMissingTitle
+	}
+	n.Message = Truncate(strings.TrimSpace(n.Message), MaxMessageLength)
+	return nil
+}
+
+// Truncate shortens s to at most max characters, ending with an ellipsis
+func Truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:max-1])) + "…"
+}
+
+// Columns is the column list read by Scan, selected FROM notifications n
+const Columns = `n.id, n.type, n.title, n.message, n.priority, n.action_label, n.action_url,
+	n.read_at IS NOT NULL, n.read_at, n.created_at`
+
+// Scan scans a row selected with Columns
+func Scan(row pgx.Row, n *models.Notification) error {
+	return row.Scan(&n.ID, &n.Type, &n.Title, &n.Message, &n.Priority, &n.ActionLabel, &n.ActionURL,
+		&n.Read, &n.ReadAt, &n.CreatedAt)
+}
+
+// TxStarter is satisfied by pools and transactions; a transaction starts a
+// savepoint
+type TxStarter interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// Publish stores notifications and pushes each to its user. Types the user
+// turned off are skipped. The notifications are written under a savepoint
+// when db is a transaction, so a failure leaves the caller's transaction
+// usable; callers log the error rather than failing the change they announce.
+func Publish(ctx context.Context, db TxStarter, notes ...Notification) error {
+	if len(notes) == 0 {
+		return nil
+	}
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, n := range notes {
+		if err := publish(ctx, tx, n); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// publish stores one notification and announces it
+func publish(ctx context.Context, tx pgx.Tx, n Notification) error {
+	if err := n.Normalize(); err != nil {
+		return err
+	}
+
+	var stored models.Notification
+	err := Scan(tx.QueryRow(ctx,
+		`INSERT INTO notifications AS n (user_id, type, title, message, priority, action_label, action_url, group_key)
+		 SELECT $1::int, $2::text, $3::text, $4::text, $5::text, NULLIF($6::text, ''), NULLIF($7::text, ''), NULLIF($8::text, '')
+		 WHERE NOT EXISTS (SELECT 1 FROM notification_preferences
+		                   WHERE user_id = $1 AND type = $2 AND NOT in_app)
+		 ON CONFLICT (user_id, group_key) WHERE read_at IS NULL DO UPDATE SET
+		   title = EXCLUDED.title, message = EXCLUDED.message, priority = EXCLUDED.priority,
+		   action_label = EXCLUDED.action_label, action_url = EXCLUDED.action_url,
+		   created_at = CURRENT_TIMESTAMP
+		 RETURNING `+Columns,
+		n.UserID, n.Type, n.Title, n.Message, n.Priority, n.ActionLabel, n.ActionURL, n.GroupKey,
+	), &stored)
+	if err == pgx.ErrNoRows {
+		return nil // turned off by the user
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = realtime.Publish(ctx, tx, realtime.EventNotificationCreated, []int{n.UserID}, stored)
+	return err
+}
+
+// MarkGroupRead marks a user's unread notification with a group key read,
+// e.g. the new message notification of a conversation they opened, and tells
+// their devices
+func MarkGroupRead(ctx context.Context, q realtime.Querier, userID int, groupKey string) error {
+	var id int
+	err := q.QueryRow(ctx,
+		`UPDATE notifications SET read_at = CURRENT_TIMESTAMP
+		 WHERE user_id = $1 AND group_key = $2 AND read_at IS NULL
+		 RETURNING id`, userID, groupKey,
+	).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = realtime.Publish(ctx, q, realtime.EventNotificationsRead, []int{userID}, map[string]any{"ids": []int{id}})
+	return err
+}
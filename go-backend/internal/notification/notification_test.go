@@ -0,0 +1,62 @@
+package notification
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestNormalize(t *testing.T) {
+	n := Notification{UserID: 1, Type: TypeBooking, Title: "  Booking confirmed ", Message: "See you soon"}
+	if err := n.Normalize(); err != nil {
+		t.Fatal(err)
+	}
+	if n.Priority != PriorityMedium {
+		t.Errorf("priority = %q, want medium", n.Priority)
+	}
+	if n.Title != "Booking confirmed" {
+		t.Errorf("title = %q", n.Title)
+	}
+
+	tests := []struct {
+		name string
+		n    Notification
+		want error
+	}{
+		{"unknown type", Notification{Type: "promo", Title: "x"}, ErrInvalidType},
+		{"unknown priority", Notification{Type: TypeSystem, Title: "x", Priority: "urgent"}, ErrInvalidPriority},
+		{"blank title", Notification{Type: TypeReview, Title: "   "}, ErrMissingTitle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.n.Normalize(); !errors.Is(err, tt.want) {
+				t.Errorf("Normalize() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeTruncates(t *testing.T) {
+	n := Notification{Type: TypeMessage, Title: strings.Repeat("é", 200), Message: strings.Repeat("word ", 200)}
+	if err := n.Normalize(); err != nil {
+		t.Fatal(err)
+	}
+	if got := utf8.RuneCountInString(n.Title); got != MaxTitleLength {
+		t.Errorf("title has %d characters, want %d", got, MaxTitleLength)
+	}
+	if got := utf8.RuneCountInString(n.Message); got > MaxMessageLength || !strings.HasSuffix(n.Message, "…") {
+		t.Errorf("message has %d characters: %q", got, n.Message[len(n.Message)-10:])
+	}
+}
+
+func TestConfigurable(t *testing.T) {
+	for _, typ := range Types {
+		if got, want := Configurable(typ), typ != TypeSystem; got != want {
+			t.Errorf("Configurable(%q) = %v, want %v", typ, got, want)
+		}
+	}
+	if Configurable("promo") {
+		t.Error("unknown type is configurable")
+	}
+}
@@ -0,0 +1,114 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTPProvider talks to a gateway exposing the FakeServer API
+type HTTPProvider struct {
+	baseURL string
+	secret  []byte
+	client  *http.Client
+
+	// Now returns the current time; tests may replace it
+	Now func() time.Time
+}
+
+// NewHTTPProvider creates a client for the gateway at baseURL. Webhooks are
+// verified with secret.
+func NewHTTPProvider(baseURL, secret string) *HTTPProvider {
+	return &HTTPProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		secret:  []byte(secret),
+		client:  &http.Client{Timeout: 15 * time.Second},
+		Now:     time.Now,
+	}
+}
+
+// Name returns the provider name
+func (p *HTTPProvider) Name() string {
+	return "http"
+}
+
+// Authorize reserves funds
+func (p *HTTPProvider) Authorize(ctx context.Context, req AuthorizeRequest) (Intent, error) {
+	return p.call(ctx, "/v1/intents", map[string]any{
+		"amount":         req.Amount,
+		"currency":       req.Currency,
+		"payment_method": req.PaymentMethod,
+		"reference":      req.Reference,
+	})
+}
+
+// Capture collects previously authorized funds
+func (p *HTTPProvider) Capture(ctx context.Context, intentID string) (Intent, error) {
+	return p.call(ctx, "/v1/intents/"+intentID+"/capture", map[string]any{})
+}
+
+// Void releases authorized funds
+func (p *HTTPProvider) Void(ctx context.Context, intentID string) (Intent, error) {
+	return p.call(ctx, "/v1/intents/"+intentID+"/void", map[string]any{})
+}
+
+// Refund returns amount cents to the guest
+func (p *HTTPProvider) Refund(ctx context.Context, intentID string, amount int64) (Intent, error) {
+	return p.call(ctx, "/v1/intents/"+intentID+"/refund", map[string]any{"amount": amount})
+}
+
+// VerifyWebhook checks a webhook signature and decodes its event
+func (p *HTTPProvider) VerifyWebhook(payload []byte, signature string) (Event, error) {
+	if err := VerifySignature(p.secret, payload, signature, p.Now()); err != nil {
+		return Event{}, err
+	}
+	var e Event
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return Event{}, fmt.Errorf("decode event: %w", err)
+	}
+	return e, nil
+}
+
+func (p *HTTPProvider) call(ctx context.Context, path string, body any) (Intent, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return Intent{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return Intent{}, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return Intent{}, fmt.Errorf("payment gateway: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Error  string `json:"error"`
+		Intent Intent `json:"intent"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return Intent{}, fmt.Errorf("decode response: %w", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return out.Intent, nil
+	case http.StatusPaymentRequired:
+		return out.Intent, &DeclineError{Code: out.Error}
+	case http.StatusNotFound:
+		return Intent{}, ErrIntentNotFound
+	case http.StatusConflict:
+		return Intent{}, ErrInvalidState
+	default:
+		return Intent{}, fmt.Errorf("payment gateway: %s (%s)", resp.Status, out.Error)
+	}
+}
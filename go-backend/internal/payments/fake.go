@@ -0,0 +1,216 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Test payment methods understood by FakeProvider. Any other non-empty token succeeds.
+const (
+	FakeMethodVisa              = "tok_visa"
+	FakeMethodDeclined          = "tok_declined"
+	FakeMethodInsufficientFunds = "tok_insufficient_funds"
+	FakeMethodCaptureFails      = "tok_capture_fails"
+)
+
+// FakeProvider is a deterministic in-process provider for development and tests.
+// Intent and event IDs are sequential, and the clock can be replaced.
+type FakeProvider struct {
+	mu       sync.Mutex
+	secret   []byte
+	seq      int
+	intents  map[string]*fakeIntent
+	handlers []func(Event)
+
+	// Now returns the current time; tests may replace it
+	Now func() time.Time
+}
+
+type fakeIntent struct {
+	Intent
+	paymentMethod string
+}
+
+// NewFakeProvider creates a fake provider that signs webhooks with secret
+func NewFakeProvider(secret string) *FakeProvider {
+	return &FakeProvider{
+		secret:  []byte(secret),
+		intents: make(map[string]*fakeIntent),
+		Now:     time.Now,
+	}
+}
+
+// Name returns the provider name
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+// OnEvent registers a handler called synchronously for every event
+func (p *FakeProvider) OnEvent(fn func(Event)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handlers = append(p.handlers, fn)
+}
+
+// Authorize reserves funds on the given test payment method
+func (p *FakeProvider) Authorize(ctx context.Context, req AuthorizeRequest) (Intent, error) {
+	if req.Amount <= 0 {
+		return Intent{}, ErrInvalidAmount
+	}
+	if req.PaymentMethod == "" {
+		return Intent{}, fmt.Errorf("payment method is required")
+	}
+
+	p.mu.Lock()
+	p.seq++
+	intent := &fakeIntent{
+		Intent: Intent{
+			ID:        fmt.Sprintf("pi_fake_%06d", p.seq),
+			Amount:    req.Amount,
+			Currency:  req.Currency,
+			Status:    StatusAuthorized,
+			Reference: req.Reference,
+		},
+		paymentMethod: req.PaymentMethod,
+	}
+
+	var err error
+	eventType := EventAuthorized
+	switch req.PaymentMethod {
+	case FakeMethodDeclined:
+		err = &DeclineError{Code: "card_declined"}
+	case FakeMethodInsufficientFunds:
+		err = &DeclineError{Code: "insufficient_funds"}
+	}
+	if err != nil {
+		intent.Status = StatusFailed
+		intent.FailureReason = err.(*DeclineError).Code
+		eventType = EventFailed
+	}
+	p.intents[intent.ID] = intent
+	result := intent.Intent
+	p.mu.Unlock()
+
+	p.emit(eventType, result)
+	return result, err
+}
+
+// Capture collects an authorized intent
+func (p *FakeProvider) Capture(ctx context.Context, intentID string) (Intent, error) {
+	p.mu.Lock()
+	intent, ok := p.intents[intentID]
+	if !ok {
+		p.mu.Unlock()
+		return Intent{}, ErrIntentNotFound
+	}
+	if intent.Status != StatusAuthorized {
+		p.mu.Unlock()
+		return intent.Intent, ErrInvalidState
+	}
+
+	var err error
+	eventType := EventCaptured
+	if intent.paymentMethod == FakeMethodCaptureFails {
+		err = &DeclineError{Code: "capture_failed"}
+		intent.Status = StatusFailed
+		intent.FailureReason = "capture_failed"
+		eventType = EventFailed
+	} else {
+		intent.Status = StatusCaptured
+	}
+	result := intent.Intent
+	p.mu.Unlock()
+
+	p.emit(eventType, result)
+	return result, err
+}
+
+// Void releases an authorized intent
+func (p *FakeProvider) Void(ctx context.Context, intentID string) (Intent, error) {
+	p.mu.Lock()
+	intent, ok := p.intents[intentID]
+	if !ok {
+		p.mu.Unlock()
+		return Intent{}, ErrIntentNotFound
+	}
+	if intent.Status != StatusAuthorized {
+		p.mu.Unlock()
+		return intent.Intent, ErrInvalidState
+	}
+	intent.Status = StatusVoided
+	result := intent.Intent
+	p.mu.Unlock()
+
+	p.emit(EventVoided, result)
+	return result, nil
+}
+
+// Refund returns part or all of a captured intent
+func (p *FakeProvider) Refund(ctx context.Context, intentID string, amount int64) (Intent, error) {
+	p.mu.Lock()
+	intent, ok := p.intents[intentID]
+	if !ok {
+		p.mu.Unlock()
+		return Intent{}, ErrIntentNotFound
+	}
+	if intent.Status != StatusCaptured && intent.Status != StatusPartiallyRefunded {
+		p.mu.Unlock()
+		return intent.Intent, ErrInvalidState
+	}
+	if amount <= 0 || intent.Refunded+amount > intent.Amount {
+		p.mu.Unlock()
+		return intent.Intent, ErrInvalidAmount
+	}
+
+	intent.Refunded += amount
+	intent.Status = StatusPartiallyRefunded
+	if intent.Refunded == intent.Amount {
+		intent.Status = StatusRefunded
+	}
+	result := intent.Intent
+	p.mu.Unlock()
+
+	p.emit(EventRefunded, result)
+	return result, nil
+}
+
+// VerifyWebhook checks a webhook produced by this provider
+func (p *FakeProvider) VerifyWebhook(payload []byte, signature string) (Event, error) {
+	if err := VerifySignature(p.secret, payload, signature, p.Now()); err != nil {
+		return Event{}, err
+	}
+	var e Event
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return Event{}, fmt.Errorf("decode event: %w", err)
+	}
+	return e, nil
+}
+
+// SignEvent encodes and signs an event the way webhooks are delivered
+func (p *FakeProvider) SignEvent(e Event) ([]byte, string, error) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return nil, "", err
+	}
+	return payload, Sign(p.secret, payload, p.Now()), nil
+}
+
+func (p *FakeProvider) emit(eventType string, intent Intent) {
+	p.mu.Lock()
+	p.seq++
+	e := Event{
+		ID:        fmt.Sprintf("evt_fake_%06d", p.seq),
+		Type:      eventType,
+		Intent:    intent,
+		CreatedAt: p.Now(),
+	}
+	handlers := append([]func(Event){}, p.handlers...)
+	p.mu.Unlock()
+
+	for _, h := range handlers {
+		h(e)
+	}
+}
@@ -0,0 +1,115 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFakeProvider_AuthorizeCaptureRefund(t *testing.T) {
+	p := NewFakeProvider("whsec_test")
+	var events []Event
+	p.OnEvent(func(e Event) { events = append(events, e) })
+	ctx := context.Background()
+
+	intent, err := p.Authorize(ctx, AuthorizeRequest{Amount: 10000, Currency: "usd", PaymentMethod: FakeMethodVisa, Reference: "booking-1"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if intent.ID != "pi_fake_000001" || intent.Status != StatusAuthorized {
+		t.Errorf("Unexpected intent: %+v", intent)
+	}
+
+	intent, err = p.Capture(ctx, intent.ID)
+	if err != nil || intent.Status != StatusCaptured {
+		t.Fatalf("Expected captured intent, got %+v (err: %v)", intent, err)
+	}
+
+	intent, err = p.Refund(ctx, intent.ID, 4000)
+	if err != nil || intent.Status != StatusPartiallyRefunded || intent.Refunded != 4000 {
+		t.Fatalf("Expected partial refund, got %+v (err: %v)", intent, err)
+	}
+	intent, err = p.Refund(ctx, intent.ID, 6000)
+	if err != nil || intent.Status != StatusRefunded {
+		t.Fatalf("Expected full refund, got %+v (err: %v)", intent, err)
+	}
+	if _, err := p.Refund(ctx, intent.ID, 1); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Expected ErrInvalidState refunding a refunded intent, got %v", err)
+	}
+
+	expected := []string{EventAuthorized, EventCaptured, EventRefunded, EventRefunded}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d", len(expected), len(events))
+	}
+	for i, typ := range expected {
+		if events[i].Type != typ {
+			t.Errorf("Event %d: expected %s, got %s", i, typ, events[i].Type)
+		}
+	}
+}
+
+func TestFakeProvider_Declines(t *testing.T) {
+	p := NewFakeProvider("whsec_test")
+	ctx := context.Background()
+
+	intent, err := p.Authorize(ctx, AuthorizeRequest{Amount: 500, Currency: "usd", PaymentMethod: FakeMethodDeclined})
+	var decline *DeclineError
+	if !errors.As(err, &decline) || decline.Code != "card_declined" {
+		t.Fatalf("Expected card_declined, got %v", err)
+	}
+	if !errors.Is(err, ErrDeclined) || intent.Status != StatusFailed {
+		t.Errorf("Expected failed intent wrapping ErrDeclined, got %+v (err: %v)", intent, err)
+	}
+
+	intent, err = p.Authorize(ctx, AuthorizeRequest{Amount: 500, Currency: "usd", PaymentMethod: FakeMethodCaptureFails})
+	if err != nil {
+		t.Fatalf("Expected authorization to succeed, got %v", err)
+	}
+	if _, err := p.Capture(ctx, intent.ID); !errors.Is(err, ErrDeclined) {
+		t.Errorf("Expected capture to be declined, got %v", err)
+	}
+
+	if _, err := p.Capture(ctx, "pi_missing"); !errors.Is(err, ErrIntentNotFound) {
+		t.Errorf("Expected ErrIntentNotFound, got %v", err)
+	}
+}
+
+func TestFakeProvider_Void(t *testing.T) {
+	p := NewFakeProvider("whsec_test")
+	ctx := context.Background()
+
+	intent, err := p.Authorize(ctx, AuthorizeRequest{Amount: 500, Currency: "usd", PaymentMethod: FakeMethodVisa})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	intent, err = p.Void(ctx, intent.ID)
+	if err != nil || intent.Status != StatusVoided {
+		t.Fatalf("Expected voided intent, got %+v (err: %v)", intent, err)
+	}
+	if _, err := p.Capture(ctx, intent.ID); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Expected ErrInvalidState capturing a voided intent, got %v", err)
+	}
+	if _, err := p.Void(ctx, intent.ID); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Expected ErrInvalidState voiding twice, got %v", err)
+	}
+}
+
+func TestFakeProvider_VerifyWebhook(t *testing.T) {
+	p := NewFakeProvider("whsec_test")
+	payload, sig, err := p.SignEvent(Event{ID: "evt_1", Type: EventCaptured, Intent: Intent{ID: "pi_1"}})
+	if err != nil {
+		t.Fatalf("Failed to sign event: %v", err)
+	}
+
+	e, err := p.VerifyWebhook(payload, sig)
+	if err != nil {
+		t.Fatalf("Expected valid webhook, got %v", err)
+	}
+	if e.ID != "evt_1" || e.Intent.ID != "pi_1" {
+		t.Errorf("Unexpected event: %+v", e)
+	}
+
+	if _, err := NewFakeProvider("other").VerifyWebhook(payload, sig); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature, got %v", err)
+	}
+}
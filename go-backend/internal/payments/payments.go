@@ -0,0 +1,123 @@
+// Package payments abstracts payment providers behind a small interface so
+// bookings can be paid for without depending on a specific vendor.
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Payment states, shared by provider intents and the payments table
+const (
+	StatusPending           = "pending"
+	StatusAuthorized        = "authorized"
+	StatusCaptured          = "captured"
+	StatusPartiallyRefunded = "partially_refunded"
+	StatusRefunded          = "refunded"
+	StatusVoided            = "voided"
+	StatusFailed            = "failed"
+)
+
+// Webhook event types
+const (
+	EventAuthorized = "payment.authorized"
+	EventCaptured   = "payment.captured"
+	EventFailed     = "payment.failed"
+	EventRefunded   = "payment.refunded"
+	EventVoided     = "payment.voided"
+)
+
+// Errors
+var (
+	ErrDeclined         = errors.New("payment declined")
+	ErrIntentNotFound   = errors.New("payment intent not found")
+	ErrInvalidState     = errors.New("invalid payment state for this operation")
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+// Intent is a provider-side payment
+type Intent struct {
+	ID            string `json:"id"`
+	Amount        int64  `json:"amount"` // in cents
+	Currency      string `json:"currency"`
+	Status        string `json:"status"`
+	Refunded      int64  `json:"refunded"` // in cents
+	Reference     string `json:"reference"`
+	FailureReason string `json:"failure_reason,omitempty"`
+}
+
+// Event is a provider notification delivered by webhook
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Intent    Intent    `json:"intent"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuthorizeRequest describes a payment to authorize
+type AuthorizeRequest struct {
+	Amount        int64  // in cents
+	Currency      string // ISO 4217, lower case
+	PaymentMethod string // provider token for the guest's payment method
+	Reference     string // our reference, e.g. "booking-42"
+}
+
+// Provider is implemented by payment gateways
+type Provider interface {
+	Name() string
+	// Authorize reserves funds. Declined payments return an Intent with
+	// StatusFailed and an error wrapping ErrDeclined.
+	Authorize(ctx context.Context, req AuthorizeRequest) (Intent, error)
+	// Capture collects previously authorized funds
+	Capture(ctx context.Context, intentID string) (Intent, error)
+	// Void releases authorized funds that will not be captured
+	Void(ctx context.Context, intentID string) (Intent, error)
+	// Refund returns amount cents of a captured payment to the guest
+	Refund(ctx context.Context, intentID string, amount int64) (Intent, error)
+	// VerifyWebhook checks a webhook signature and decodes its event
+	VerifyWebhook(payload []byte, signature string) (Event, error)
+}
+
+var transitions = map[string][]string{
+	StatusPending:           {StatusAuthorized, StatusFailed},
+	StatusAuthorized:        {StatusCaptured, StatusVoided, StatusFailed},
+	StatusCaptured:          {StatusPartiallyRefunded, StatusRefunded},
+	StatusPartiallyRefunded: {StatusPartiallyRefunded, StatusRefunded},
+}
+
+// CanTransition reports whether a payment may move from one state to another
+func CanTransition(from, to string) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ToCents converts a decimal amount to cents
+func ToCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// FromCents converts cents to a decimal amount
+func FromCents(cents int64) float64 {
+	return float64(cents) / 100
+}
+
+// DeclineError is returned when a payment method is declined
+type DeclineError struct {
+	Code string
+}
+
+func (e *DeclineError) Error() string {
+	return fmt.Sprintf("payment declined: %s", e.Code)
+}
+
+func (e *DeclineError) Unwrap() error {
+	return ErrDeclined
+}
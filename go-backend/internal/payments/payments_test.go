@@ -0,0 +1,71 @@
+package payments
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		expected bool
+	}{
+		{StatusPending, StatusAuthorized, true},
+		{StatusAuthorized, StatusCaptured, true},
+		{StatusAuthorized, StatusVoided, true},
+		{StatusCaptured, StatusRefunded, true},
+		{StatusPartiallyRefunded, StatusPartiallyRefunded, true},
+		{StatusPending, StatusCaptured, false},
+		{StatusFailed, StatusCaptured, false},
+		{StatusRefunded, StatusCaptured, false},
+		{StatusVoided, StatusCaptured, false},
+	}
+
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.expected {
+			t.Errorf("CanTransition(%s, %s) = %v, expected %v", tt.from, tt.to, got, tt.expected)
+		}
+	}
+}
+
+func TestCents(t *testing.T) {
+	if got := ToCents(494.97); got != 49497 {
+		t.Errorf("Expected 49497, got %d", got)
+	}
+	if got := FromCents(49497); got != 494.97 {
+		t.Errorf("Expected 494.97, got %v", got)
+	}
+}
+
+func TestSignature(t *testing.T) {
+	secret := []byte("whsec_test")
+	payload := []byte(`{"id":"evt_1"}`)
+	now := time.Unix(1700000000, 0)
+
+	sig := Sign(secret, payload, now)
+	if err := VerifySignature(secret, payload, sig, now.Add(time.Minute)); err != nil {
+		t.Errorf("Expected valid signature, got %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		secret    []byte
+		payload   []byte
+		signature string
+		now       time.Time
+	}{
+		{"wrong secret", []byte("other"), payload, sig, now},
+		{"tampered payload", secret, []byte(`{"id":"evt_2"}`), sig, now},
+		{"stale", secret, payload, sig, now.Add(10 * time.Minute)},
+		{"malformed", secret, payload, "v1=abc", now},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySignature(tt.secret, tt.payload, tt.signature, tt.now)
+			if !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Expected ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// FakeServer exposes a FakeProvider over HTTP and delivers its events as signed
+// webhooks, standing in for a remote payment gateway during local development.
+//
+// API (JSON):
+//
+//	POST /v1/intents                {amount, currency, payment_method, reference}
+//	POST /v1/intents/{id}/capture
+//	POST /v1/intents/{id}/void
+//	POST /v1/intents/{id}/refund    {amount}
+type FakeServer struct {
+	provider   *FakeProvider
+	webhookURL string
+	client     *http.Client
+}
+
+// NewFakeServer creates a stand-in gateway. Events are posted to webhookURL
+// when it is not empty.
+func NewFakeServer(provider *FakeProvider, webhookURL string) *FakeServer {
+	s := &FakeServer{
+		provider:   provider,
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+	if webhookURL != "" {
+		provider.OnEvent(func(e Event) {
+			go s.deliver(e)
+		})
+	}
+	return s
+}
+
+func (s *FakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "v1" || parts[1] != "intents" {
+		writeAPIError(w, http.StatusNotFound, "not_found")
+		return
+	}
+
+	var (
+		intent Intent
+		err    error
+	)
+	switch {
+	case len(parts) == 2:
+		var body struct {
+			Amount        int64  `json:"amount"`
+			Currency      string `json:"currency"`
+			PaymentMethod string `json:"payment_method"`
+			Reference     string `json:"reference"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid_json")
+			return
+		}
+		intent, err = s.provider.Authorize(r.Context(), AuthorizeRequest{
+			Amount:        body.Amount,
+			Currency:      body.Currency,
+			PaymentMethod: body.PaymentMethod,
+			Reference:     body.Reference,
+		})
+	case len(parts) == 4 && parts[3] == "capture":
+		intent, err = s.provider.Capture(r.Context(), parts[2])
+	case len(parts) == 4 && parts[3] == "void":
+		intent, err = s.provider.Void(r.Context(), parts[2])
+	case len(parts) == 4 && parts[3] == "refund":
+		var body struct {
+			Amount int64 `json:"amount"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid_json")
+			return
+		}
+		intent, err = s.provider.Refund(r.Context(), parts[2], body.Amount)
+	default:
+		writeAPIError(w, http.StatusNotFound, "not_found")
+		return
+	}
+
+	var decline *DeclineError
+	switch {
+	case errors.As(err, &decline):
+		writeAPIJSON(w, http.StatusPaymentRequired, map[string]any{"error": decline.Code, "intent": intent})
+	case errors.Is(err, ErrIntentNotFound):
+		writeAPIError(w, http.StatusNotFound, "intent_not_found")
+	case errors.Is(err, ErrInvalidState):
+		writeAPIError(w, http.StatusConflict, "invalid_state")
+	case err != nil:
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeAPIJSON(w, http.StatusOK, map[string]any{"intent": intent})
+	}
+}
+
+// deliver posts a signed event to the webhook URL, retrying a few times
+func (s *FakeServer) deliver(e Event) {
+	payload, signature, err := s.provider.SignEvent(e)
+	if err != nil {
+		slog.Error("error signing event", "component", "fake_payments", "event_id", e.ID, "error", err)
+		return
+	}
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookURL, bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(SignatureHeader, signature)
+
+		resp, err := s.client.Do(req)
+		cancel()
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 300 {
+				return
+			}
+			err = errors.New(resp.Status)
+		}
+		slog.Warn("webhook attempt failed", "component", "fake_payments", "event_id", e.ID, "attempt", attempt, "error", err)
+		time.Sleep(time.Duration(attempt) * time.Second)
+	}
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code string) {
+	writeAPIJSON(w, status, map[string]string{"error": code})
+}
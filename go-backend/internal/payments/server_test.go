@@ -0,0 +1,86 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPProviderWithFakeServer(t *testing.T) {
+	const secret = "whsec_test"
+	client := NewHTTPProvider("", secret)
+
+	webhooks := make(chan Event, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		e, err := client.VerifyWebhook(payload, r.Header.Get(SignatureHeader))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		webhooks <- e
+	}))
+	defer receiver.Close()
+
+	gateway := httptest.NewServer(NewFakeServer(NewFakeProvider(secret), receiver.URL))
+	defer gateway.Close()
+	client.baseURL = gateway.URL
+	ctx := context.Background()
+
+	intent, err := client.Authorize(ctx, AuthorizeRequest{Amount: 2500, Currency: "usd", PaymentMethod: FakeMethodVisa, Reference: "booking-7"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if intent.Status != StatusAuthorized || intent.Reference != "booking-7" {
+		t.Errorf("Unexpected intent: %+v", intent)
+	}
+
+	if intent, err = client.Capture(ctx, intent.ID); err != nil || intent.Status != StatusCaptured {
+		t.Fatalf("Expected captured intent, got %+v (err: %v)", intent, err)
+	}
+	if intent, err = client.Refund(ctx, intent.ID, 2500); err != nil || intent.Status != StatusRefunded {
+		t.Fatalf("Expected refunded intent, got %+v (err: %v)", intent, err)
+	}
+
+	intent, err = client.Authorize(ctx, AuthorizeRequest{Amount: 1000, Currency: "usd", PaymentMethod: FakeMethodVisa})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if intent, err = client.Void(ctx, intent.ID); err != nil || intent.Status != StatusVoided {
+		t.Fatalf("Expected voided intent, got %+v (err: %v)", intent, err)
+	}
+	if _, err := client.Void(ctx, intent.ID); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Expected ErrInvalidState, got %v", err)
+	}
+
+	if _, err := client.Authorize(ctx, AuthorizeRequest{Amount: 100, Currency: "usd", PaymentMethod: FakeMethodInsufficientFunds}); !errors.Is(err, ErrDeclined) {
+		t.Errorf("Expected decline, got %v", err)
+	}
+	if _, err := client.Capture(ctx, "pi_missing"); !errors.Is(err, ErrIntentNotFound) {
+		t.Errorf("Expected ErrIntentNotFound, got %v", err)
+	}
+
+	// authorized twice, captured, refunded, voided, failed
+	seen := map[string]int{}
+	timeout := time.After(5 * time.Second)
+	for i := 0; i < 6; i++ {
+		select {
+		case e := <-webhooks:
+			seen[e.Type]++
+		case <-timeout:
+			t.Fatalf("Timed out waiting for webhooks, got %v", seen)
+		}
+	}
+	if seen[EventAuthorized] != 2 {
+		t.Errorf("Expected two %s webhooks, got %d", EventAuthorized, seen[EventAuthorized])
+	}
+	for _, typ := range []string{EventCaptured, EventRefunded, EventVoided, EventFailed} {
+		if seen[typ] != 1 {
+			t.Errorf("Expected one %s webhook, got %d", typ, seen[typ])
+		}
+	}
+}
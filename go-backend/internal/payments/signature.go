@@ -0,0 +1,62 @@
+package payments
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carries the webhook signature
+const SignatureHeader = "X-Payment-Signature"
+
+// SignatureTolerance is how old a webhook signature may be
+const SignatureTolerance = 5 * time.Minute
+
+// Sign produces a "t=<unix>,v1=<hex>" signature of payload at time ts
+func Sign(secret []byte, payload []byte, ts time.Time) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + t + ",v1=" + computeMAC(secret, t, payload)
+}
+
+// VerifySignature checks a signature produced by Sign, rejecting stale timestamps
+func VerifySignature(secret []byte, payload []byte, signature string, now time.Time) error {
+	var t, v1 string
+	for _, part := range strings.Split(signature, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			t = v
+		case "v1":
+			v1 = v
+		}
+	}
+	if t == "" || v1 == "" {
+		return ErrInvalidSignature
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	age := now.Sub(time.Unix(unix, 0))
+	if age > SignatureTolerance || age < -SignatureTolerance {
+		return ErrInvalidSignature
+	}
+
+	expected := computeMAC(secret, t, payload)
+	if !hmac.Equal([]byte(expected), []byte(v1)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func computeMAC(secret []byte, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
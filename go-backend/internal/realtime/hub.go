@@ -0,0 +1,267 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go-backend/internal/database"
+)
+
+// Hub forwards events announced on Channel to the subscribers connected to
+// this instance. It runs as a worker holding one dedicated connection.
+type Hub struct {
+	db *database.DB
+
+	mu     sync.Mutex
+	subs   map[int]map[*Subscription]struct{}
+	closed bool
+	lastID int64 // highest stored event dispatched, to catch up after reconnecting
+}
+
+// Subscription receives the events of one user. C is closed when the
+// subscriber falls more than BufferSize events behind or the hub shuts down;
+// the client should then reconnect and replay from its last event ID.
+type Subscription struct {
+	UserID int
+	C      <-chan Event
+
+	ch  chan Event
+	hub *Hub
+}
+
+// NewHub creates a hub; Start must run for events to be received
+func NewHub(db *database.DB) *Hub {
+	return &Hub{db: db, subs: make(map[int]map[*Subscription]struct{})}
+}
+
+// Name returns the worker name
+func (h *Hub) Name() string {
+	return "RealtimeHub"
+}
+
+// Subscribe registers a subscriber for a user's events
+func (h *Hub) Subscribe(userID int) *Subscription {
+	ch := make(chan Event, BufferSize)
+	sub := &Subscription{UserID: userID, C: ch, ch: ch, hub: h}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(ch)
+		return sub
+	}
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[*Subscription]struct{})
+	}
+	h.subs[userID][sub] = struct{}{}
+	return sub
+}
+
+// Close unsubscribes; it is safe to call more than once
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// remove drops a subscription and closes its channel. h.mu must be held.
+func (h *Hub) remove(sub *Subscription) {
+	subs := h.subs[sub.UserID]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subs, sub.UserID)
+	}
+	close(sub.ch)
+}
+
+// Dispatch delivers an event to its users' subscribers on this instance.
+// Subscribers whose buffer is full are dropped rather than blocking the hub.
+func (h *Hub) Dispatch(ev Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if ev.ID > h.lastID {
+		h.lastID = ev.ID
+	}
+	for _, userID := range ev.UserIDs {
+		for sub := range h.subs[userID] {
+			select {
+			case sub.ch <- ev:
+			default:
+				slog.Warn("dropping slow subscriber", "worker", h.Name(), "user_id", userID)
+				h.remove(sub)
+			}
+		}
+	}
+}
+
+// Close ends all subscriptions and refuses new ones, so open streams finish
+// during shutdown
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for _, subs := range h.subs {
+		for sub := range subs {
+			h.remove(sub)
+		}
+	}
+}
+
+// Start listens for notifications until ctx is cancelled, reconnecting with
+// backoff if the connection is lost
+func (h *Hub) Start(ctx context.Context) {
+	defer h.Close()
+
+	backoff := time.Second
+	for {
+		err := h.listen(ctx)
+		if ctx.Err() != nil {
+			slog.InfoContext(ctx, "context cancelled, stopping", "worker", h.Name())
+			return
+		}
+		slog.WarnContext(ctx, "listener stopped, reconnecting", "worker", h.Name(), "error", err, "backoff", backoff)
+
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "context cancelled, stopping", "worker", h.Name())
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// listen holds a connection listening on Channel and dispatches notifications
+func (h *Hub) listen(ctx context.Context) error {
+	conn, err := h.db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `LISTEN `+Channel); err != nil {
+		return err
+	}
+	defer func() {
+		// The connection returns to the pool, so it must stop listening
+		unlisten, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		conn.Exec(unlisten, `UNLISTEN `+Channel)
+	}()
+	slog.InfoContext(ctx, "listening", "worker", h.Name(), "channel", Channel)
+
+	if err := h.catchUp(ctx); err != nil {
+		return err
+	}
+
+	for {
+		n, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var msg notification
+		if err := json.Unmarshal([]byte(n.Payload), &msg); err != nil {
+			slog.ErrorContext(ctx, "invalid notification", "worker", h.Name(), "payload", n.Payload, "error", err)
+			continue
+		}
+		switch {
+		case msg.Event != nil:
+			h.Dispatch(*msg.Event)
+		case msg.ID > 0:
+			ev, err := h.load(ctx, msg.ID)
+			if err != nil {
+				slog.ErrorContext(ctx, "error loading event", "worker", h.Name(), "event_id", msg.ID, "error", err)
+				continue
+			}
+			h.Dispatch(ev)
+		}
+	}
+}
+
+// catchUp dispatches stored events announced while the hub was reconnecting
+func (h *Hub) catchUp(ctx context.Context) error {
+	h.mu.Lock()
+	since := h.lastID
+	h.mu.Unlock()
+	if since == 0 {
+		return nil
+	}
+
+	rows, err := h.db.Pool.Query(ctx,
+		`SELECT id, type, user_ids, data FROM realtime_events WHERE id > $1 ORDER BY id`, since)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var ev Event
+		if err := rows.Scan(&ev.ID, &ev.Type, &ev.UserIDs, &ev.Data); err != nil {
+			return err
+		}
+		events = append(events, ev)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, ev := range events {
+		h.Dispatch(ev)
+	}
+	return nil
+}
+
+// load reads a stored event
+func (h *Hub) load(ctx context.Context, id int64) (Event, error) {
+	ev := Event{ID: id}
+	err := h.db.Pool.QueryRow(ctx,
+		`SELECT type, user_ids, data FROM realtime_events WHERE id = $1`, id,
+	).Scan(&ev.Type, &ev.UserIDs, &ev.Data)
+	return ev, err
+}
+
+// Replay returns up to ReplayLimit stored events for a user after the event
+// with ID since, which must be positive. expired is true if events after
+// since may already have been pruned, in which case the client must reload
+// its state instead.
+func (h *Hub) Replay(ctx context.Context, userID int, since int64) (events []Event, expired bool, err error) {
+	var oldest int64
+	err = h.db.Pool.QueryRow(ctx,
+		`SELECT COALESCE(MIN(id), (SELECT last_value + 1 FROM realtime_events_id_seq)) FROM realtime_events`,
+	).Scan(&oldest)
+	if err != nil {
+		return nil, false, err
+	}
+	if since < oldest-1 {
+		return nil, true, nil
+	}
+
+	rows, err := h.db.Pool.Query(ctx,
+		`SELECT id, type, user_ids, data FROM realtime_events
+		 WHERE id > $1 AND user_ids @> ARRAY[$2::int]
+		 ORDER BY id LIMIT $3`, since, userID, ReplayLimit)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ev Event
+		if err := rows.Scan(&ev.ID, &ev.Type, &ev.UserIDs, &ev.Data); err != nil {
+			return nil, false, err
+		}
+		events = append(events, ev)
+	}
+	return events, false, rows.Err()
+}
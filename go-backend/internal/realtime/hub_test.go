@@ -0,0 +1,118 @@
+package realtime
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDispatchDeliversToUsers(t *testing.T) {
+	h := NewHub(nil)
+	guest := h.Subscribe(1)
+	host := h.Subscribe(2)
+	other := h.Subscribe(3)
+	defer guest.Close()
+	defer host.Close()
+	defer other.Close()
+
+	h.Dispatch(Event{ID: 7, Type: EventMessageCreated, UserIDs: []int{1, 2}})
+
+	for _, sub := range []*Subscription{guest, host} {
+		select {
+		case ev := <-sub.C:
+			if ev.ID != 7 {
+				t.Errorf("user %d got event %d, want 7", sub.UserID, ev.ID)
+			}
+		default:
+			t.Errorf("user %d got no event", sub.UserID)
+		}
+	}
+	select {
+	case ev := <-other.C:
+		t.Errorf("user 3 got event %d", ev.ID)
+	default:
+	}
+}
+
+func TestDispatchDeliversToEverySubscriptionOfUser(t *testing.T) {
+	h := NewHub(nil)
+	phone := h.Subscribe(1)
+	laptop := h.Subscribe(1)
+	defer phone.Close()
+	defer laptop.Close()
+
+	h.Dispatch(Event{Type: EventTyping, UserIDs: []int{1}})
+
+	if len(phone.C) != 1 || len(laptop.C) != 1 {
+		t.Errorf("got %d and %d events, want 1 each", len(phone.C), len(laptop.C))
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	h := NewHub(nil)
+	slow := h.Subscribe(1)
+
+	for i := 1; i <= BufferSize+1; i++ {
+		h.Dispatch(Event{ID: int64(i), Type: EventMessageCreated, UserIDs: []int{1}})
+	}
+
+	n := 0
+	for range slow.C {
+		n++
+	}
+	if n != BufferSize {
+		t.Errorf("received %d events before the channel closed, want %d", n, BufferSize)
+	}
+
+	// Closing a dropped subscription is a no-op
+	slow.Close()
+}
+
+func TestCloseEndsSubscriptions(t *testing.T) {
+	h := NewHub(nil)
+	sub := h.Subscribe(1)
+	h.Close()
+
+	if _, ok := <-sub.C; ok {
+		t.Error("subscription still open after Close")
+	}
+	late := h.Subscribe(2)
+	if _, ok := <-late.C; ok {
+		t.Error("subscription after Close is open")
+	}
+	sub.Close()
+}
+
+func TestDispatchTracksLastID(t *testing.T) {
+	h := NewHub(nil)
+	h.Dispatch(Event{ID: 5, UserIDs: []int{1}})
+	h.Dispatch(Event{Type: EventTyping, UserIDs: []int{1}})
+	h.Dispatch(Event{ID: 3, UserIDs: []int{1}})
+
+	if h.lastID != 5 {
+		t.Errorf("lastID = %d, want 5", h.lastID)
+	}
+}
+
+func TestEncodeSignal(t *testing.T) {
+	n, err := encodeSignal(EventTyping, []int{1, 2}, map[string]int{"conversation_id": 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg notification
+	if err := json.Unmarshal(n, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.ID != 0 || msg.Event == nil || msg.Event.Type != EventTyping || len(msg.Event.UserIDs) != 2 {
+		t.Errorf("decoded %+v", msg)
+	}
+	if string(msg.Event.Data) != `{"conversation_id":4}` {
+		t.Errorf("data = %s", msg.Event.Data)
+	}
+
+	_, err = encodeSignal(EventTyping, []int{1}, strings.Repeat("x", maxSignalSize))
+	if !errors.Is(err, ErrSignalTooLarge) {
+		t.Errorf("err = %v, want ErrSignalTooLarge", err)
+	}
+}
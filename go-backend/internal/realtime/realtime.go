@@ -0,0 +1,124 @@
+// Package realtime pushes events such as new messages to connected users.
+// Events are stored in realtime_events and announced with Postgres NOTIFY, so
+// every API instance can forward them to its own subscribers and clients that
+// reconnect can replay what they missed.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Channel is the Postgres NOTIFY channel events are announced on
+const Channel = "realtime_events"
+
+// Event types
+const (
+	EventMessageCreated = "message.created"
+	EventMessagesRead   = "messages.read"
+	// EventConversationUpdated is sent to a participant's other devices when
+	// they archive or mute a conversation
+	EventConversationUpdated = "conversation.updated"
+	EventNotificationCreated = "notification.created"
+	// EventNotificationsRead is sent when notifications are marked read on
+	// another device
+	EventNotificationsRead = "notifications.read"
+	EventTyping            = "typing" // not stored, cannot be replayed
+)
+
+const (
+	// Retention is how long stored events can be replayed
+	Retention = time.Hour
+	// ReplayLimit is how many events are loaded per replay query
+	ReplayLimit = 500
+	// BufferSize is how many events a subscriber can fall behind before it is dropped
+	BufferSize = 64
+	// HeartbeatInterval is how often idle streams send a keep-alive
+	HeartbeatInterval = 25 * time.Second
+	// maxSignalSize keeps ephemeral payloads under the 8000 byte NOTIFY limit
+	maxSignalSize = 7500
+)
+
+// ErrSignalTooLarge is returned for ephemeral events that do not fit in a notification
+var ErrSignalTooLarge = errors.New("realtime: signal payload too large")
+
+// Event is delivered to the users in UserIDs. Stored events have an ID clients
+// resume from; ephemeral events such as typing indicators have none.
+type Event struct {
+	ID      int64           `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	UserIDs []int           `json:"user_ids"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Querier is satisfied by pgx transactions and pools
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// notification is the NOTIFY payload: the ID of a stored event, or a whole
+// ephemeral event
+type notification struct {
+	ID    int64  `json:"id,omitempty"`
+	Event *Event `json:"event,omitempty"`
+}
+
+// Publish stores an event for users and announces it to all instances. In a
+// transaction, the event is only delivered if it commits.
+func Publish(ctx context.Context, q Querier, eventType string, userIDs []int, data any) (int64, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err = q.QueryRow(ctx,
+		`INSERT INTO realtime_events (type, user_ids, data) VALUES ($1, $2, $3) RETURNING id`,
+		eventType, userIDs, payload,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := json.Marshal(notification{ID: id})
+	if err != nil {
+		return 0, err
+	}
+	if _, err := q.Exec(ctx, `SELECT pg_notify($1, $2)`, Channel, string(n)); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// Signal announces an ephemeral event that is not stored, such as a typing
+// indicator. Users who are not connected never see it.
+func Signal(ctx context.Context, q Querier, eventType string, userIDs []int, data any) error {
+	n, err := encodeSignal(eventType, userIDs, data)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(ctx, `SELECT pg_notify($1, $2)`, Channel, string(n))
+	return err
+}
+
+// encodeSignal builds the notification payload of an ephemeral event
+func encodeSignal(eventType string, userIDs []int, data any) ([]byte, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	n, err := json.Marshal(notification{Event: &Event{Type: eventType, UserIDs: userIDs, Data: payload}})
+	if err != nil {
+		return nil, err
+	}
+	if len(n) > maxSignalSize {
+		return nil, ErrSignalTooLarge
+	}
+	return n, nil
+}
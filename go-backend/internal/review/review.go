@@ -0,0 +1,120 @@
+// Package review validates reviews and publishes them. Guests review the
+// property and hosts review the guest after a completed stay; both reviews
+// stay hidden until both are submitted or the review window closes.
+package review
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// WindowDays is how many days after check-out both sides can review
+const WindowDays = 14
+
+// Score range for the overall rating and each category
+const (
+	MinScore = 1
+	MaxScore = 5
+)
+
+// Text limits in characters
+const (
+	MaxCommentLength  = 5000
+	MaxResponseLength = 2000
+)
+
+var (
+	ErrScoreOutOfRange = errors.New("score out of range")
+	ErrCommentTooLong  = fmt.Errorf("comment must be at most %d characters", MaxCommentLength)
+	ErrEmptyResponse   = errors.New("response is required")
+	ErrResponseTooLong = fmt.Errorf("response must be at most %d characters", MaxResponseLength)
+)
+
+// Scores are the overall rating and category sub-scores of a review
+type Scores struct {
+	Rating        int `json:"rating"`
+	Cleanliness   int `json:"cleanliness"`
+	Location      int `json:"location"`
+	Value         int `json:"value"`
+	Communication int `json:"communication"`
+}
+
+// Validate checks that every score is between MinScore and MaxScore
+func (s Scores) Validate() error {
+	return validateScores([]namedScore{
+		{"rating", s.Rating},
+		{"cleanliness", s.Cleanliness},
+		{"location", s.Location},
+		{"value", s.Value},
+		{"communication", s.Communication},
+	})
+}
+
+// GuestScores are a host's overall rating and category sub-scores of a guest
+type GuestScores struct {
+	Rating        int `json:"rating"`
+	Cleanliness   int `json:"cleanliness"`
+	Communication int `json:"communication"`
+	HouseRules    int `json:"house_rules"`
+}
+
+// Validate checks that every score is between MinScore and MaxScore
+func (s GuestScores) Validate() error {
+	return validateScores([]namedScore{
+		{"rating", s.Rating},
+		{"cleanliness", s.Cleanliness},
+		{"communication", s.Communication},
+		{"house_rules", s.HouseRules},
+	})
+}
+
+type namedScore struct {
+	name  string
+	score int
+}
+
+func validateScores(scores []namedScore) error {
+	for _, f := range scores {
+		if f.score < MinScore || f.score > MaxScore {
+			return fmt.Errorf("%w: %s must be between %d and %d", ErrScoreOutOfRange, f.name, MinScore, MaxScore)
+		}
+	}
+	return nil
+}
+
+// WindowClosesOn returns the first day on which a stay ending on checkOut can
+// no longer be reviewed
+func WindowClosesOn(checkOut time.Time) time.Time {
+	return checkOut.AddDate(0, 0, WindowDays)
+}
+
+// WindowOpen reports whether a stay ending on checkOut can still be reviewed
+// on today. Both must be dates without a time of day.
+func WindowOpen(checkOut, today time.Time) bool {
+	return today.Before(WindowClosesOn(checkOut))
+}
+
+// NormalizeComment trims a review comment and checks its length
+func NormalizeComment(comment string) (string, error) {
+	comment = strings.TrimSpace(comment)
+	if utf8.RuneCountInString(comment) > MaxCommentLength {
+		return "", ErrCommentTooLong
+	}
+	return comment, nil
+}
+
+// NormalizeResponse trims a host response and checks that it is present and
+// not too long
+func NormalizeResponse(response string) (string, error) {
+	response = strings.TrimSpace(response)
+	if response == "" {
+		return "", ErrEmptyResponse
+	}
+	if utf8.RuneCountInString(response) > MaxResponseLength {
+		return "", ErrResponseTooLong
+	}
+	return response, nil
+}
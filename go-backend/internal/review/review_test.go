@@ -0,0 +1,106 @@
+package review
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScoresValidate(t *testing.T) {
+	valid := Scores{Rating: 5, Cleanliness: 4, Location: 3, Value: 2, Communication: 1}
+
+	tests := []struct {
+		name    string
+		modify  func(s *Scores)
+		wantErr bool
+	}{
+		{"valid", func(s *Scores) {}, false},
+		{"missing rating", func(s *Scores) { s.Rating = 0 }, true},
+		{"rating too high", func(s *Scores) { s.Rating = 6 }, true},
+		{"negative category", func(s *Scores) { s.Value = -1 }, true},
+		{"missing category", func(s *Scores) { s.Communication = 0 }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid
+			tt.modify(&s)
+			err := s.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrScoreOutOfRange) {
+				t.Errorf("Expected ErrScoreOutOfRange, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNormalizeComment(t *testing.T) {
+	got, err := NormalizeComment("  Lovely stay \n")
+	if err != nil || got != "Lovely stay" {
+		t.Errorf("NormalizeComment() = %q, %v", got, err)
+	}
+
+	if got, err := NormalizeComment(""); err != nil || got != "" {
+		t.Errorf("Expected empty comment to be allowed, got %q, %v", got, err)
+	}
+
+	// Length is counted in characters, not bytes
+	if _, err := NormalizeComment(strings.Repeat("é", MaxCommentLength)); err != nil {
+		t.Errorf("Expected %d characters to be allowed, got %v", MaxCommentLength, err)
+	}
+	if _, err := NormalizeComment(strings.Repeat("a", MaxCommentLength+1)); !errors.Is(err, ErrCommentTooLong) {
+		t.Errorf("Expected ErrCommentTooLong, got %v", err)
+	}
+}
+
+func TestNormalizeResponse(t *testing.T) {
+	if _, err := NormalizeResponse("   "); !errors.Is(err, ErrEmptyResponse) {
+		t.Errorf("Expected ErrEmptyResponse, got %v", err)
+	}
+	if _, err := NormalizeResponse(strings.Repeat("a", MaxResponseLength+1)); !errors.Is(err, ErrResponseTooLong) {
+		t.Errorf("Expected ErrResponseTooLong, got %v", err)
+	}
+	if got, err := NormalizeResponse(" Thank you! "); err != nil || got != "Thank you!" {
+		t.Errorf("NormalizeResponse() = %q, %v", got, err)
+	}
+}
+
+func TestGuestScoresValidate(t *testing.T) {
+	valid := GuestScores{Rating: 4, Cleanliness: 5, Communication: 3, HouseRules: 5}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Expected valid scores, got %v", err)
+	}
+
+	missing := valid
+	missing.HouseRules = 0
+	if err := missing.Validate(); !errors.Is(err, ErrScoreOutOfRange) {
+		t.Errorf("Expected ErrScoreOutOfRange, got %v", err)
+	}
+}
+
+func TestWindowOpen(t *testing.T) {
+	checkOut := time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC)
+
+	if got := WindowClosesOn(checkOut); !got.Equal(time.Date(2025, 3, 19, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("WindowClosesOn() = %v", got)
+	}
+
+	tests := []struct {
+		today string
+		want  bool
+	}{
+		{"2025-03-05", true},
+		{"2025-03-18", true},
+		{"2025-03-19", false},
+		{"2025-04-01", false},
+	}
+	for _, tt := range tests {
+		today, _ := time.Parse("2006-01-02", tt.today)
+		if got := WindowOpen(checkOut, today); got != tt.want {
+			t.Errorf("WindowOpen(%s) = %v, want %v", tt.today, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+
+	"go-backend/internal/notification"
+)
+
+// Lock locks a booking's property and then the booking itself inside tx, in
+// the same order as other booking changes. It returns the property ID.
+func Lock(ctx context.Context, tx pgx.Tx, bookingID int) (int, error) {
+	var propertyID int
+	err := tx.QueryRow(ctx,
+		`SELECT p.id FROM properties p JOIN bookings b ON b.property_id = p.id
+		 WHERE b.id = $1 FOR UPDATE OF p`, bookingID,
+	).Scan(&propertyID)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(ctx, `SELECT 1 FROM bookings WHERE id = $1 FOR UPDATE`, bookingID)
+	return propertyID, err
+}
+
+// BothSubmitted reports whether the guest and the host have both reviewed a booking
+func BothSubmitted(ctx context.Context, tx pgx.Tx, bookingID int) (bool, error) {
+	var both bool
+	err := tx.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM reviews WHERE booking_id = $1)
+		    AND EXISTS(SELECT 1 FROM guest_reviews WHERE booking_id = $1)`, bookingID,
+	).Scan(&both)
+	return both, err
+}
+
+// Publish makes both reviews of a booking visible, updates the property's and
+// the guest's ratings and notifies the reviewed host and guest. The booking
+// must be locked with Lock. Reviews that are already published are left
+// unchanged; it returns how many were published.
+func Publish(ctx context.Context, tx pgx.Tx, bookingID int) (int, error) {
+	published := 0
+	var notes []notification.Notification
+
+	var propertyID int
+	err := tx.QueryRow(ctx,
+		`UPDATE reviews SET published_at = CURRENT_TIMESTAMP
+		 WHERE booking_id = $1 AND published_at IS NULL
+		 RETURNING property_id`, bookingID,
+	).Scan(&propertyID)
+	switch {
+	case err == nil:
+		published++
+		if err := refreshPropertyRating(ctx, tx, propertyID); err != nil {
+			return published, err
+		}
+		var (
+			hostID   *int
+			property string
+		)
+		err = tx.QueryRow(ctx, `SELECT owner_id, title FROM properties WHERE id = $1`, propertyID).Scan(&hostID, &property)
+		if err != nil {
+			return published, err
+		}
+		if hostID != nil {
+			notes = append(notes, notification.Notification{
+				UserID:      *hostID,
+				Type:        notification.TypeReview,
+				Title:       "New review",
+				Message:     fmt.Sprintf("A guest reviewed %s.", property),
+				ActionLabel: "View review",
+				ActionURL:   fmt.Sprintf("/properties/%d", propertyID),
+			})
+		}
+	case err != pgx.ErrNoRows:
+		return published, err
+	}
+
+	var guestID int
+	err = tx.QueryRow(ctx,
+		`UPDATE guest_reviews SET published_at = CURRENT_TIMESTAMP
+		 WHERE booking_id = $1 AND published_at IS NULL
+		 RETURNING guest_id`, bookingID,
+	).Scan(&guestID)
+	switch {
+	case err == nil:
+		published++
+		if err := refreshGuestRating(ctx, tx, guestID); err != nil {
+			return published, err
+		}
+		notes = append(notes, notification.Notification{
+			UserID:      guestID,
+			Type:        notification.TypeReview,
+			Title:       "Your host reviewed you",
+			Message:     "A review of your stay is now on your guest profile.",
+			ActionLabel: "View profile",
+			ActionURL:   fmt.Sprintf("/users/%d", guestID),
+		})
+	case err != pgx.ErrNoRows:
+		return published, err
+	}
+
+	// A missed notification must not keep the reviews from being published
+	if err := notification.Publish(ctx, tx, notes...); err != nil {
+		slog.ErrorContext(ctx, "error publishing notifications", "component", "reviews", "booking_id", bookingID, "error", err)
+	}
+	return published, nil
+}
+
+// RefreshRatings recomputes the ratings of a booking's property and guest,
+// e.g. after one of its reviews was hidden or restored. The booking must be
+// locked with Lock.
+func RefreshRatings(ctx context.Context, tx pgx.Tx, bookingID int) error {
+	var propertyID, guestID int
+	err := tx.QueryRow(ctx,
+		`SELECT property_id, user_id FROM bookings WHERE id = $1`, bookingID,
+	).Scan(&propertyID, &guestID)
+	if err != nil {
+		return err
+	}
+	if err := refreshPropertyRating(ctx, tx, propertyID); err != nil {
+		return err
+	}
+	return refreshGuestRating(ctx, tx, guestID)
+}
+
+// refreshPropertyRating recomputes a property's rating and review count from
+// its published reviews that are not hidden by moderators
+func refreshPropertyRating(ctx context.Context, tx pgx.Tx, propertyID int) error {
+	_, err := tx.Exec(ctx,
+		`UPDATE properties SET
+		 rating = COALESCE((SELECT ROUND(AVG(rating), 1) FROM reviews
+		                    WHERE property_id = $1 AND published_at IS NOT NULL AND hidden_at IS NULL), 0),
+		 reviews = (SELECT COUNT(*) FROM reviews
+		            WHERE property_id = $1 AND published_at IS NOT NULL AND hidden_at IS NULL)
+		 WHERE id = $1`, propertyID)
+	return err
+}
+
+// refreshGuestRating recomputes a user's guest rating and review count from
+// the published, not hidden reviews hosts wrote about them
+func refreshGuestRating(ctx context.Context, tx pgx.Tx, guestID int) error {
+	_, err := tx.Exec(ctx,
+		`UPDATE users SET
+		 guest_rating = COALESCE((SELECT ROUND(AVG(rating), 1) FROM guest_reviews
+		                          WHERE guest_id = $1 AND published_at IS NOT NULL AND hidden_at IS NULL), 0),
+		 guest_reviews = (SELECT COUNT(*) FROM guest_reviews
+		                  WHERE guest_id = $1 AND published_at IS NOT NULL AND hidden_at IS NULL)
+		 WHERE id = $1`, guestID)
+	return err
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalPutOpenDelete(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewLocal(filepath.Join(t.TempDir(), "files"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Put(ctx, "attachments/abc123", strings.NewReader("hello")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	rc, err := l.Open(ctx, "attachments/abc123")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "hello" {
+		t.Errorf("read %q, want hello", data)
+	}
+
+	if err := l.Put(ctx, "attachments/abc123", strings.NewReader("replaced")); err != nil {
+		t.Fatalf("Put replace: %v", err)
+	}
+	rc, _ = l.Open(ctx, "attachments/abc123")
+	data, _ = io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "replaced" {
+		t.Errorf("read %q after replace", data)
+	}
+
+	if err := l.Delete(ctx, "attachments/abc123"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := l.Open(ctx, "attachments/abc123"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open after delete: err = %v, want ErrNotFound", err)
+	}
+	if err := l.Delete(ctx, "attachments/abc123"); err != nil {
+		t.Errorf("Delete of missing object: %v", err)
+	}
+}
+
+func TestLocalPutLeavesNoTemporaryFiles(t *testing.T) {
+	root := t.TempDir()
+	l, _ := NewLocal(root)
+	if err := l.Put(context.Background(), "a/b", strings.NewReader("x")); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(root, "a"))
+	if len(entries) != 1 || entries[0].Name() != "b" {
+		t.Errorf("directory contains %v", entries)
+	}
+}
+
+func TestLocalRejectsInvalidKeys(t *testing.T) {
+	l, _ := NewLocal(t.TempDir())
+	for _, key := range []string{"", "../etc/passwd", "/abs", "a/../b", "a//b", "A/B", "a/.hidden", "a b"} {
+		if err := l.Put(context.Background(), key, strings.NewReader("x")); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q): err = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
+
+func TestNewKey(t *testing.T) {
+	a, err := NewKey("attachments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewKey("attachments")
+	if a == b {
+		t.Error("keys are not random")
+	}
+	if !strings.HasPrefix(a, "attachments/") || !ValidKey(a) {
+		t.Errorf("key %q", a)
+	}
+}
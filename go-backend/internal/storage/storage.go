@@ -0,0 +1,47 @@
+// Package storage keeps uploaded files behind an interface so the local
+// filesystem backend can be swapped for object storage.
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Errors
+var (
+	ErrNotFound   = errors.New("storage: object not found")
+	ErrInvalidKey = errors.New("storage: invalid key")
+)
+
+// Storage stores objects by key
+type Storage interface {
+	// Put stores data under key, replacing any existing object
+	Put(ctx context.Context, key string, data io.Reader) error
+	// Open returns the object's contents, or ErrNotFound
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes an object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// keyPattern allows slash-separated segments of lowercase letters, digits,
+// dots, dashes and underscores
+var keyPattern = regexp.MustCompile(`^[a-z0-9_-][a-z0-9._-]*(/[a-z0-9_-][a-z0-9._-]*)*$`)
+
+// ValidKey reports whether key is safe to use as a storage key
+func ValidKey(key string) bool {
+	return len(key) <= 255 && keyPattern.MatchString(key) && !strings.Contains(key, "..")
+}
+
+// NewKey returns a random key under prefix
+func NewKey(prefix string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + "/" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,154 @@
+// Package support holds the rules of the support ticket system: categories,
+// priorities and their SLA targets, and how replies and agents move tickets
+// between statuses.
+package support
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go-backend/internal/auth"
+)
+
+// Categories, matching the ticket form of the help center
+const (
+	CategoryBooking   = "booking"
+	CategoryPayment   = "payment"
+	CategoryAccount   = "account"
+	CategoryTechnical = "technical"
+	CategoryHost      = "host"
+	CategoryOther     = "other"
+)
+
+// Categories lists the ticket categories
+var Categories = []string{
+	CategoryBooking, CategoryPayment, CategoryAccount, CategoryTechnical, CategoryHost, CategoryOther,
+}
+
+// Priorities, lowest first
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
+)
+
+// Priorities lists the ticket priorities, lowest first
+var Priorities = []string{PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent}
+
+// Statuses. Pending tickets wait on the user; resolved tickets are closed
+// automatically after AutoCloseAfter unless the user replies.
+const (
+	StatusOpen     = "open"
+	StatusPending  = "pending"
+	StatusResolved = "resolved"
+	StatusClosed   = "closed"
+)
+
+// Statuses lists the ticket statuses
+var Statuses = []string{StatusOpen, StatusPending, StatusResolved, StatusClosed}
+
+// Limits
+const (
+	MaxSubjectLength = 200
+	MaxBodyLength    = 5000
+
+	// AutoCloseAfter is how long a resolved ticket stays open to a reply
+	AutoCloseAfter = 7 * 24 * time.Hour
+)
+
+// Errors
+var (
+	ErrTicketClosed      = errors.New("ticket is closed")
+	ErrInvalidTransition = errors.New("invalid status change")
+)
+
+// SLA is how soon a ticket must get its first agent reply and be resolved,
+// counted from when it was opened
+type SLA struct {
+	FirstResponse time.Duration
+	Resolution    time.Duration
+}
+
+// slas are the SLA targets of each priority
+var slas = map[string]SLA{
+	PriorityUrgent: {FirstResponse: time.Hour, Resolution: 8 * time.Hour},
+	PriorityHigh:   {FirstResponse: 4 * time.Hour, Resolution: 24 * time.Hour},
+	PriorityNormal: {FirstResponse: 24 * time.Hour, Resolution: 72 * time.Hour},
+	PriorityLow:    {FirstResponse: 48 * time.Hour, Resolution: 7 * 24 * time.Hour},
+}
+
+// SLAFor returns the SLA targets of a priority
+func SLAFor(priority string) SLA {
+	return slas[priority]
+}
+
+// DueDates returns when a ticket of the given priority opened at created is
+// due its first response and its resolution
+func DueDates(priority string, created time.Time) (firstResponse, resolution time.Time) {
+	sla := SLAFor(priority)
+	return created.Add(sla.FirstResponse), created.Add(sla.Resolution)
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidCategory reports whether c is a ticket category
+func ValidCategory(c string) bool { return contains(Categories, c) }
+
+// ValidPriority reports whether p is a ticket priority
+func ValidPriority(p string) bool { return contains(Priorities, p) }
+
+// ValidStatus reports whether s is a ticket status
+func ValidStatus(s string) bool { return contains(Statuses, s) }
+
+// DefaultPriority is the priority of a new ticket; payment problems are
+// handled first
+func DefaultPriority(category string) string {
+	if category == CategoryPayment {
+		return PriorityHigh
+	}
+	return PriorityNormal
+}
+
+// IsAgent reports whether a role works support tickets
+func IsAgent(role string) bool {
+	return role == auth.RoleSupport || role == auth.RoleAdmin
+}
+
+// StatusAfterReply returns the status of a ticket after a public reply: an
+// agent's reply leaves it waiting on the user, and a user's reply reopens it.
+// Closed tickets take no replies.
+func StatusAfterReply(status string, fromAgent bool) (string, error) {
+	if status == StatusClosed {
+		return "", ErrTicketClosed
+	}
+	if fromAgent {
+		return StatusPending, nil
+	}
+	return StatusOpen, nil
+}
+
+// CanSetStatus reports whether a ticket can be moved from one status to
+// another: agents may make any change, users may only close their tickets
+func CanSetStatus(from, to string, agent bool) bool {
+	if !ValidStatus(to) {
+		return false
+	}
+	if agent {
+		return true
+	}
+	return to == StatusClosed && from != StatusClosed
+}
+
+// Reference is the reference of a ticket shown to users, e.g. GR-000042
+func Reference(id int) string {
+	return fmt.Sprintf("GR-%06d", id)
+}
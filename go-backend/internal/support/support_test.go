@@ -0,0 +1,104 @@
+package support
+
+import (
+	"testing"
+	"time"
+
+	"go-backend/internal/auth"
+)
+
+func TestDueDates(t *testing.T) {
+	created := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		priority                  string
+		firstResponse, resolution time.Duration
+	}{
+		{PriorityUrgent, time.Hour, 8 * time.Hour},
+		{PriorityHigh, 4 * time.Hour, 24 * time.Hour},
+		{PriorityNormal, 24 * time.Hour, 72 * time.Hour},
+		{PriorityLow, 48 * time.Hour, 7 * 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		first, resolution := DueDates(tt.priority, created)
+		if !first.Equal(created.Add(tt.firstResponse)) || !resolution.Equal(created.Add(tt.resolution)) {
+			t.Errorf("DueDates(%s) = %v, %v", tt.priority, first, resolution)
+		}
+	}
+}
+
+func TestEveryPriorityHasAnSLA(t *testing.T) {
+	for _, p := range Priorities {
+		sla := SLAFor(p)
+		if sla.FirstResponse <= 0 || sla.Resolution < sla.FirstResponse {
+			t.Errorf("priority %s has SLA %+v", p, sla)
+		}
+	}
+}
+
+func TestDefaultPriority(t *testing.T) {
+	if got := DefaultPriority(CategoryPayment); got != PriorityHigh {
+		t.Errorf("payment: got %s, want high", got)
+	}
+	if got := DefaultPriority(CategoryAccount); got != PriorityNormal {
+		t.Errorf("account: got %s, want normal", got)
+	}
+}
+
+func TestStatusAfterReply(t *testing.T) {
+	tests := []struct {
+		status    string
+		fromAgent bool
+		want      string
+		err       error
+	}{
+		{StatusOpen, true, StatusPending, nil},
+		{StatusPending, false, StatusOpen, nil},
+		{StatusResolved, false, StatusOpen, nil},
+		{StatusResolved, true, StatusPending, nil},
+		{StatusClosed, false, "", ErrTicketClosed},
+		{StatusClosed, true, "", ErrTicketClosed},
+	}
+	for _, tt := range tests {
+		got, err := StatusAfterReply(tt.status, tt.fromAgent)
+		if got != tt.want || err != tt.err {
+			t.Errorf("StatusAfterReply(%s, %v) = %q, %v; want %q, %v", tt.status, tt.fromAgent, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestCanSetStatus(t *testing.T) {
+	tests := []struct {
+		from, to string
+		agent    bool
+		want     bool
+	}{
+		{StatusOpen, StatusResolved, true, true},
+		{StatusClosed, StatusOpen, true, true},
+		{StatusOpen, "escalated", true, false},
+		{StatusOpen, StatusClosed, false, true},
+		{StatusResolved, StatusClosed, false, true},
+		{StatusOpen, StatusResolved, false, false},
+		{StatusClosed, StatusOpen, false, false},
+		{StatusClosed, StatusClosed, false, false},
+	}
+	for _, tt := range tests {
+		if got := CanSetStatus(tt.from, tt.to, tt.agent); got != tt.want {
+			t.Errorf("CanSetStatus(%s, %s, agent=%v) = %v, want %v", tt.from, tt.to, tt.agent, got, tt.want)
+		}
+	}
+}
+
+func TestIsAgent(t *testing.T) {
+	if !IsAgent(auth.RoleSupport) || !IsAgent(auth.RoleAdmin) {
+		t.Error("support and admin roles should be agents")
+	}
+	if IsAgent(auth.RoleUser) {
+		t.Error("users should not be agents")
+	}
+}
+
+func TestReference(t *testing.T) {
+	if got := Reference(42); got != "GR-000042" {
+		t.Errorf("Reference(42) = %s", got)
+	}
+}
@@ -0,0 +1,105 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxResponseBody is how much of a receiver's response is kept in the
+// delivery log
+const MaxResponseBody = 1024
+
+// userAgent identifies deliveries to receivers
+const userAgent = "GoRent-Webhooks/1.0"
+
+// Request is one delivery attempt
+type Request struct {
+	URL        string
+	Secret     string
+	DeliveryID int
+	Event      string
+	Body       []byte
+}
+
+// Response is what a receiver answered. StatusCode is zero when no response
+// was received.
+type Response struct {
+	StatusCode int
+	Body       string
+	Duration   time.Duration
+}
+
+// StatusError is returned when a receiver answers with a non-2xx status
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("receiver responded with status %d", e.Code)
+}
+
+// Client posts signed deliveries
+type Client struct {
+	client *http.Client
+
+	// Now returns the current time; tests may replace it
+	Now func() time.Time
+}
+
+// NewClient creates a client whose attempts time out after timeout.
+// Redirects are not followed, so a receiver cannot bounce a signed delivery
+// to another address.
+func NewClient(timeout time.Duration) *Client {
+	return &Client{
+		client: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		Now: time.Now,
+	}
+}
+
+// Send posts a delivery. It fails if the receiver cannot be reached or does
+// not answer with a 2xx status; the response is returned either way for the
+// delivery log.
+func (c *Client) Send(ctx context.Context, req Request) (Response, error) {
+	var res Response
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return res, fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", userAgent)
+	httpReq.Header.Set(EventHeader, req.Event)
+	httpReq.Header.Set(DeliveryHeader, strconv.Itoa(req.DeliveryID))
+	httpReq.Header.Set(SignatureHeader, Sign(req.Secret, req.Body, c.Now()))
+
+	start := time.Now()
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		res.Duration = time.Since(start)
+		return res, err
+	}
+	defer resp.Body.Close()
+
+	// The body is only kept for the log, so a failed read does not fail
+	// the delivery
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, MaxResponseBody))
+	res.Duration = time.Since(start)
+	res.StatusCode = resp.StatusCode
+	// Postgres text holds neither invalid UTF-8 nor NUL bytes
+	res.Body = strings.ReplaceAll(string(bytes.ToValidUTF8(body, nil)), "\x00", "")
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return res, &StatusError{Code: resp.StatusCode}
+	}
+	return res, nil
+}
@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientSend(t *testing.T) {
+	body := []byte(`{"id":7,"type":"booking.created","data":{}}`)
+	var got *http.Request
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	c := NewClient(time.Second)
+	res, err := c.Send(context.Background(), Request{
+		URL: srv.URL, Secret: "whsec_test", DeliveryID: 42, Event: EventBookingCreated, Body: body,
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if res.StatusCode != http.StatusOK || res.Body != "ok" {
+		t.Errorf("response = %d %q, want 200 \"ok\"", res.StatusCode, res.Body)
+	}
+	if got.Method != http.MethodPost || got.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("request = %s %s", got.Method, got.Header.Get("Content-Type"))
+	}
+	if got.Header.Get(EventHeader) != EventBookingCreated || got.Header.Get(DeliveryHeader) != "42" {
+		t.Errorf("headers = %v", got.Header)
+	}
+	if string(gotBody) != string(body) {
+		t.Errorf("body = %s", gotBody)
+	}
+	if err := Verify("whsec_test", gotBody, got.Header.Get(SignatureHeader), time.Now()); err != nil {
+		t.Errorf("signature did not verify: %v", err)
+	}
+}
+
+func TestClientSendRejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(strings.Repeat("x", 2*MaxResponseBody)))
+	}))
+	defer srv.Close()
+
+	res, err := NewClient(time.Second).Send(context.Background(), Request{URL: srv.URL, Body: []byte("{}")})
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusInternalServerError {
+		t.Fatalf("err = %v, want StatusError 500", err)
+	}
+	if res.StatusCode != http.StatusInternalServerError || len(res.Body) != MaxResponseBody {
+		t.Errorf("response = %d with %d bytes, want 500 with %d bytes", res.StatusCode, len(res.Body), MaxResponseBody)
+	}
+}
+
+func TestClientSendDoesNotFollowRedirects(t *testing.T) {
+	followed := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		followed = true
+	}))
+	defer target.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+	}))
+	defer srv.Close()
+
+	res, err := NewClient(time.Second).Send(context.Background(), Request{URL: srv.URL, Body: []byte("{}")})
+	if err == nil || res.StatusCode != http.StatusTemporaryRedirect {
+		t.Errorf("got %d, %v; want a 307 failure", res.StatusCode, err)
+	}
+	if followed {
+		t.Error("redirect was followed")
+	}
+}
+
+func TestClientSendUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	res, err := NewClient(time.Second).Send(context.Background(), Request{URL: url, Body: []byte("{}")})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if res.StatusCode != 0 {
+		t.Errorf("StatusCode = %d, want 0", res.StatusCode)
+	}
+}
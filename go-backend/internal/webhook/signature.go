@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Delivery headers
+const (
+	// SignatureHeader carries the "t=<unix>,v1=<hex>" signature of the body
+	SignatureHeader = "X-GoRent-Signature"
+	// EventHeader carries the event type
+	EventHeader = "X-GoRent-Event"
+	// DeliveryHeader carries the delivery ID
+	DeliveryHeader = "X-GoRent-Delivery"
+)
+
+// SignatureTolerance is how old a signature receivers should accept
+const SignatureTolerance = 5 * time.Minute
+
+// ErrInvalidSignature is returned when a signature does not match its payload
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Sign produces a "t=<unix>,v1=<hex>" signature of payload at time ts: the
+// hex HMAC-SHA256 of "<unix>.<payload>" keyed with the subscription secret
+func Sign(secret string, payload []byte, ts time.Time) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + t + ",v1=" + computeMAC(secret, t, payload)
+}
+
+// Verify checks a signature produced by Sign, rejecting stale timestamps. It
+// is what receivers are expected to do.
+func Verify(secret string, payload []byte, signature string, now time.Time) error {
+	var t, v1 string
+	for _, part := range strings.Split(signature, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			t = v
+		case "v1":
+			v1 = v
+		}
+	}
+	if t == "" || v1 == "" {
+		return ErrInvalidSignature
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	age := now.Sub(time.Unix(unix, 0))
+	if age > SignatureTolerance || age < -SignatureTolerance {
+		return ErrInvalidSignature
+	}
+
+	expected := computeMAC(secret, t, payload)
+	if !hmac.Equal([]byte(expected), []byte(v1)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func computeMAC(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
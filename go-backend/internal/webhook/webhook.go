@@ -0,0 +1,158 @@
+// Package webhook delivers events to partner integrations. Flows queue an
+// event inside the transaction making the change it announces, with one
+// delivery per active subscription to its type, and the WebhookDispatcher
+// worker posts the deliveries, signed with each subscription's secret and
+// retried with backoff until they succeed or are dead-lettered.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Event types
+const (
+	EventBookingCreated   = "booking.created"
+	EventBookingConfirmed = "booking.confirmed"
+	EventBookingModified  = "booking.modified"
+	EventBookingCancelled = "booking.cancelled"
+	EventPropertyUpdated  = "property.updated"
+)
+
+// Events lists the event types subscriptions can select
+var Events = []string{
+	EventBookingCreated,
+	EventBookingConfirmed,
+	EventBookingModified,
+	EventBookingCancelled,
+	EventPropertyUpdated,
+}
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+// Delivery limits
+const (
+	// MaxAttempts is how many times delivery is tried before a delivery is
+	// dead-lettered
+	MaxAttempts = 10
+
+	// MaxURLLength limits the length of subscription URLs
+	MaxURLLength = 2048
+
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 12 * time.Hour
+)
+
+// Validation errors
+var (
+	ErrInvalidURL   = errors.New("url must be an absolute http or https URL")
+	ErrNoEvents     = errors.New("at least one event type is required")
+	ErrUnknownEvent = errors.New("unknown event type")
+)
+
+// ValidEvent reports whether typ is a known event type
+func ValidEvent(typ string) bool {
+	for _, e := range Events {
+		if e == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateEvents checks a subscription's event types
+func ValidateEvents(events []string) error {
+	if len(events) == 0 {
+		return ErrNoEvents
+	}
+	for _, e := range events {
+		if !ValidEvent(e) {
+			return ErrUnknownEvent
+		}
+	}
+	return nil
+}
+
+// ValidateURL checks a subscription's endpoint URL
+func ValidateURL(raw string) error {
+	if len(raw) > MaxURLLength {
+		return ErrInvalidURL
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return ErrInvalidURL
+	}
+	return nil
+}
+
+// NewSecret generates a signing secret for a subscription
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Querier is satisfied by pools and transactions
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// Enqueue queues an event with a delivery to every active subscription to its
+// type. Events nobody subscribes to are not stored.
+func Enqueue(ctx context.Context, q Querier, typ string, data any) error {
+	if !ValidEvent(typ) {
+		return ErrUnknownEvent
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec(ctx,
+		`WITH subscribers AS (
+		   SELECT id FROM webhook_subscriptions WHERE active AND $1 = ANY(events)
+		 ), event AS (
+		   INSERT INTO webhook_events (type, data)
+		   SELECT $1, $2::jsonb WHERE EXISTS (SELECT 1 FROM subscribers)
+		   RETURNING id
+		 )
+		 INSERT INTO webhook_deliveries (subscription_id, event_id)
+		 SELECT s.id, e.id FROM subscribers s CROSS JOIN event e`,
+		typ, payload)
+	return err
+}
+
+// Envelope is the body posted to subscribers. ID is the event ID, which is
+// the same for every delivery and retry of an event, so receivers can use it
+// to drop duplicates.
+type Envelope struct {
+	ID        int             `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Backoff returns how long to wait before the next delivery attempt after
+// the given number of failed attempts: thirty seconds, doubling up to twelve
+// hours
+func Backoff(attempts int) time.Duration {
+	d := baseBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
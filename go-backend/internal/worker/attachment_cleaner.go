@@ -0,0 +1,194 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go-backend/internal/database"
+	"go-backend/internal/metrics"
+	"go-backend/internal/storage"
+)
+
+// attachmentCleanupBatch is how many attachments are removed per transaction
+const attachmentCleanupBatch = 500
+
+// AttachmentCleaner deletes the files of orphaned attachments: those whose
+// conversation, message or uploader was deleted, and uploads never sent with a
+// message within the pending TTL
+type AttachmentCleaner struct {
+	db         *database.DB
+	storage    storage.Storage
+	pendingTTL time.Duration
+	interval   time.Duration
+
+	// Channel for manual trigger (useful for testing)
+	triggerCh chan struct{}
+
+	// Channel for results (for monitoring)
+	resultsCh chan AttachmentCleanupResult
+}
+
+// AttachmentCleanupResult contains the result of a cleanup cycle
+type AttachmentCleanupResult struct {
+	DeletedCount int64
+	Error        error
+	Timestamp    time.Time
+}
+
+// NewAttachmentCleaner creates a new attachment cleaner worker
+func NewAttachmentCleaner(db *database.DB, store storage.Storage, pendingTTL, interval time.Duration) *AttachmentCleaner {
+	return &AttachmentCleaner{
+		db:         db,
+		storage:    store,
+		pendingTTL: pendingTTL,
+		interval:   interval,
+		triggerCh:  make(chan struct{}, 1),
+		resultsCh:  make(chan AttachmentCleanupResult, 10),
+	}
+}
+
+// Name returns the worker name
+func (ac *AttachmentCleaner) Name() string {
+	return "AttachmentCleaner"
+}
+
+// TriggerCleanup allows manual triggering of a cleanup
+func (ac *AttachmentCleaner) TriggerCleanup() {
+	select {
+	case ac.triggerCh <- struct{}{}:
+	default:
+		// Channel full, cleanup already pending
+	}
+}
+
+// Results returns the results channel for monitoring
+func (ac *AttachmentCleaner) Results() <-chan AttachmentCleanupResult {
+	return ac.resultsCh
+}
+
+// Start begins the cleanup loop
+func (ac *AttachmentCleaner) Start(ctx context.Context) {
+	ticker := time.NewTicker(ac.interval)
+	defer ticker.Stop()
+
+	// Run immediately on start
+	ac.cleanup(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "context cancelled, stopping", "worker", ac.Name())
+			return
+
+		case <-ticker.C:
+			ac.cleanup(ctx)
+
+		case <-ac.triggerCh:
+			slog.DebugContext(ctx, "manual trigger received", "worker", ac.Name())
+			ac.cleanup(ctx)
+		}
+	}
+}
+
+// cleanup removes orphaned attachments in batches
+func (ac *AttachmentCleaner) cleanup(ctx context.Context) {
+	result := AttachmentCleanupResult{
+		Timestamp: time.Now(),
+	}
+
+	for {
+		n, err := ac.cleanupBatch(ctx)
+		result.DeletedCount += n
+		if err != nil {
+			slog.ErrorContext(ctx, "error deleting orphaned attachments", "worker", ac.Name(), "error", err)
+			result.Error = err
+			break
+		}
+		if n < attachmentCleanupBatch {
+			break
+		}
+	}
+
+	if result.DeletedCount > 0 {
+		slog.InfoContext(ctx, "orphaned attachments deleted", "worker", ac.Name(), "deleted_count", result.DeletedCount)
+	}
+
+	ac.sendResult(result)
+}
+
+// cleanupBatch deletes a batch of orphaned rows and their files. The rows
+// are deleted first, so a pending upload sent concurrently is either kept or
+// fails to attach; if a file cannot be removed the batch is rolled back and
+// retried next cycle.
+func (ac *AttachmentCleaner) cleanupBatch(ctx context.Context) (int64, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	tx, err := ac.db.Pool.Begin(queryCtx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(queryCtx)
+
+	rows, err := tx.Query(queryCtx,
+		`DELETE FROM message_attachments WHERE id IN (
+		   SELECT id FROM message_attachments
+		   WHERE conversation_id IS NULL OR uploader_id IS NULL
+		      OR (message_id IS NULL AND created_at < CURRENT_TIMESTAMP - $1::interval)
+		   ORDER BY id
+		   LIMIT $2
+		   FOR UPDATE SKIP LOCKED
+		 )
+		 RETURNING storage_key, thumbnail_key`,
+		fmt.Sprintf("%d seconds", int(ac.pendingTTL.Seconds())), attachmentCleanupBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		var thumbnailKey *string
+		if err := rows.Scan(&key, &thumbnailKey); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		keys = append(keys, key)
+		if thumbnailKey != nil {
+			keys = append(keys, *thumbnailKey)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	count := rows.CommandTag().RowsAffected()
+	if count == 0 {
+		return 0, nil
+	}
+
+	for _, key := range keys {
+		if err := ac.storage.Delete(queryCtx, key); err != nil {
+			return 0, fmt.Errorf("delete file %s: %w", key, err)
+		}
+	}
+
+	if err := tx.Commit(queryCtx); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// sendResult records the run in the worker metrics and sends the result to
+// the results channel (non-blocking)
+func (ac *AttachmentCleaner) sendResult(result AttachmentCleanupResult) {
+	metrics.ObserveWorkerRun(ac.Name(), result.Timestamp, result.Error)
+
+	select {
+	case ac.resultsCh <- result:
+	default:
+		// Channel full, discard old result
+	}
+}
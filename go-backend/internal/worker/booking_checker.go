@@ -2,10 +2,11 @@ package worker
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"time"
 
 	"go-backend/internal/database"
+	"go-backend/internal/metrics"
 )
 
 // BookingChecker checks for expired pending bookings and updates their status
@@ -67,14 +68,14 @@ func (bc *BookingChecker) Start(ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
-			log.Printf("[%s] Context cancelled, stopping", bc.Name())
+			slog.InfoContext(ctx, "context cancelled, stopping", "worker", bc.Name())
 			return
 
 		case <-ticker.C:
 			bc.checkExpiredBookings(ctx)
 
 		case <-bc.triggerCh:
-			log.Printf("[%s] Manual trigger received", bc.Name())
+			slog.DebugContext(ctx, "manual trigger received", "worker", bc.Name())
 			bc.checkExpiredBookings(ctx)
 		}
 	}
@@ -104,7 +105,7 @@ func (bc *BookingChecker) checkExpiredBookings(ctx context.Context) {
 
 	rows, err := bc.db.Pool.Query(queryCtx, query)
 	if err != nil {
-		log.Printf("[%s] Error checking expired bookings: %v", bc.Name(), err)
+		slog.ErrorContext(ctx, "error checking expired bookings", "worker", bc.Name(), "error", err)
 		result.Error = err
 		bc.sendResult(result)
 		return
@@ -115,30 +116,35 @@ func (bc *BookingChecker) checkExpiredBookings(ctx context.Context) {
 	for rows.Next() {
 		var id int
 		if err := rows.Scan(&id); err != nil {
-			log.Printf("[%s] Error scanning row: %v", bc.Name(), err)
+			slog.ErrorContext(ctx, "error scanning row", "worker", bc.Name(), "error", err)
 			continue
 		}
 		expiredIDs = append(expiredIDs, id)
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Printf("[%s] Error iterating rows: %v", bc.Name(), err)
+		slog.ErrorContext(ctx, "error iterating rows", "worker", bc.Name(), "error", err)
 		result.Error = err
 	}
 
 	result.ExpiredCount = len(expiredIDs)
 
 	if result.ExpiredCount > 0 {
-		log.Printf("[%s] Marked %d bookings as expired: %v", bc.Name(), result.ExpiredCount, expiredIDs)
+		slog.InfoContext(ctx, "bookings expired",
+			"worker", bc.Name(), "expired_count", result.ExpiredCount, "expired_ids", expiredIDs)
 	} else {
-		log.Printf("[%s] No expired bookings found", bc.Name())
+		slog.DebugContext(ctx, "no expired bookings found", "worker", bc.Name())
 	}
 
 	bc.sendResult(result)
 }
 
-// sendResult sends a result to the results channel (non-blocking)
+// sendResult records the run in the worker metrics and sends the result to
+// the results channel (non-blocking)
 func (bc *BookingChecker) sendResult(result BookingCheckResult) {
+	metrics.ObserveWorkerRun(bc.Name(), result.Timestamp, result.Error)
+	metrics.BookingsExpired.Add(float64(result.ExpiredCount))
+
 	select {
 	case bc.resultsCh <- result:
 	default:
@@ -0,0 +1,309 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go-backend/internal/booking"
+	"go-backend/internal/database"
+	"go-backend/internal/metrics"
+	"go-backend/internal/models"
+	"go-backend/internal/notification"
+)
+
+// BookingLifecycle moves confirmed bookings through their stay: to
+// 'in_progress' on the check-in day and to 'completed' once the check-out day
+// is reached, using each property's time zone for "today". Guests who have not
+// checked in by the end of their first day are flagged as no-shows. Every
+// transition records a booking event, once, for reviews and payouts to act on,
+// and notifies the guest and the host in the same transaction.
+//
+// Transitions are conditional updates, so running a cycle twice changes
+// nothing, and rows are claimed with SKIP LOCKED in batches to keep
+// transactions short.
+type BookingLifecycle struct {
+	db        *database.DB
+	interval  time.Duration
+	batchSize int
+
+	// Channel for manual trigger (useful for testing)
+	triggerCh chan struct{}
+
+	// Channel for results (for monitoring)
+	resultsCh chan BookingLifecycleResult
+}
+
+// BookingLifecycleResult contains the result of a lifecycle cycle
+type BookingLifecycleResult struct {
+	StartedCount   int
+	NoShowCount    int
+	CompletedCount int
+	Error          error
+	Timestamp      time.Time
+}
+
+// DefaultLifecycleBatchSize is the number of bookings updated per transaction
+const DefaultLifecycleBatchSize = 500
+
+// localToday is the current date in the property's time zone
+const localToday = `(CURRENT_TIMESTAMP AT TIME ZONE p.timezone)::date`
+
+// lifecycleTransition is one conditional status change. where selects the
+// bookings (aliased b, joined with properties p) and set is applied to them.
+// Notice formats take the property title, check-in and check-out dates; an
+// empty title notifies nobody on that side.
+type lifecycleTransition struct {
+	event string
+	where string
+	set   string
+
+	priority                 string
+	guestTitle, guestMessage string
+	hostTitle, hostMessage   string
+}
+
+var lifecycleTransitions = []lifecycleTransition{
+	{
+		event: models.BookingEventStarted,
+		where: `b.status = 'confirmed' AND b.start_date <= ` + localToday + ` AND b.end_date > ` + localToday,
+		set:   `status = 'in_progress'`,
+
+		priority:   notification.PriorityMedium,
+		guestTitle: "Check-in day", guestMessage: "Your stay at %s from %s to %s starts today.",
+		hostTitle: "Guest arriving", hostMessage: "Your guest arrives at %s today (%s to %s).",
+	},
+	{
+		// Checked before completion so one-night stays are flagged too
+		event: models.BookingEventNoShow,
+		where: `b.status = 'in_progress' AND b.checked_in_at IS NULL AND NOT b.no_show
+		        AND b.start_date + 1 <= ` + localToday,
+		set: `no_show = true`,
+
+		priority:   notification.PriorityHigh,
+		guestTitle: "Missed check-in", guestMessage: "You have not checked in to %s (%s to %s). Contact your host if you are still coming.",
+		hostTitle: "Guest did not check in", hostMessage: "Your guest at %s (%s to %s) has not checked in and was marked as a no-show.",
+	},
+	{
+		event: models.BookingEventCompleted,
+		where: `b.status IN ('confirmed', 'in_progress') AND b.end_date <= ` + localToday,
+		set:   `status = 'completed'`,
+
+		priority:   notification.PriorityMedium,
+		guestTitle: "Stay completed", guestMessage: "Your stay at %s from %s to %s is over. Leave a review of your host.",
+		hostTitle: "Stay completed", hostMessage: "The stay at %s from %s to %s is over. Leave a review of your guest.",
+	},
+}
+
+// NewBookingLifecycle creates a new booking lifecycle worker
+func NewBookingLifecycle(db *database.DB, interval time.Duration) *BookingLifecycle {
+	return &BookingLifecycle{
+		db:        db,
+		interval:  interval,
+		batchSize: DefaultLifecycleBatchSize,
+		triggerCh: make(chan struct{}, 1),
+		resultsCh: make(chan BookingLifecycleResult, 10),
+	}
+}
+
+// Name returns the worker name
+func (bl *BookingLifecycle) Name() string {
+	return "BookingLifecycle"
+}
+
+// TriggerCheck allows manual triggering of a cycle
+func (bl *BookingLifecycle) TriggerCheck() {
+	select {
+	case bl.triggerCh <- struct{}{}:
+	default:
+		// Channel full, check already pending
+	}
+}
+
+// Results returns the results channel for monitoring
+func (bl *BookingLifecycle) Results() <-chan BookingLifecycleResult {
+	return bl.resultsCh
+}
+
+// Start begins the lifecycle loop
+func (bl *BookingLifecycle) Start(ctx context.Context) {
+	ticker := time.NewTicker(bl.interval)
+	defer ticker.Stop()
+
+	// Run immediately on start
+	bl.advanceBookings(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "context cancelled, stopping", "worker", bl.Name())
+			return
+
+		case <-ticker.C:
+			bl.advanceBookings(ctx)
+
+		case <-bl.triggerCh:
+			slog.DebugContext(ctx, "manual trigger received", "worker", bl.Name())
+			bl.advanceBookings(ctx)
+		}
+	}
+}
+
+// advanceBookings applies every transition to all due bookings
+func (bl *BookingLifecycle) advanceBookings(ctx context.Context) {
+	result := BookingLifecycleResult{
+		Timestamp: time.Now(),
+	}
+
+	for _, t := range lifecycleTransitions {
+		count, err := bl.applyTransition(ctx, t)
+		if err != nil {
+			slog.ErrorContext(ctx, "error applying transition", "worker", bl.Name(), "event", t.event, "error", err)
+			result.Error = err
+		}
+		switch t.event {
+		case models.BookingEventStarted:
+			result.StartedCount = count
+		case models.BookingEventNoShow:
+			result.NoShowCount = count
+		case models.BookingEventCompleted:
+			result.CompletedCount = count
+		}
+	}
+
+	if result.StartedCount+result.NoShowCount+result.CompletedCount > 0 {
+		slog.InfoContext(ctx, "bookings advanced",
+			"worker", bl.Name(), "started", result.StartedCount, "no_shows", result.NoShowCount, "completed", result.CompletedCount)
+	} else {
+		slog.DebugContext(ctx, "no bookings to advance", "worker", bl.Name())
+	}
+
+	bl.sendResult(result)
+}
+
+// applyTransition updates due bookings batch by batch and returns how many changed
+func (bl *BookingLifecycle) applyTransition(ctx context.Context, t lifecycleTransition) (int, error) {
+	total := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+
+		count, err := bl.applyBatch(ctx, t)
+		if err != nil {
+			return total, err
+		}
+
+		total += count
+		if count < bl.batchSize {
+			return total, nil
+		}
+	}
+}
+
+// applyBatch applies a transition to one batch of due bookings and notifies
+// the parties of the bookings whose event is new, in one transaction
+func (bl *BookingLifecycle) applyBatch(ctx context.Context, t lifecycleTransition) (int, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := bl.db.Pool.Begin(queryCtx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(queryCtx)
+
+	rows, err := tx.Query(queryCtx, `
+		WITH due AS (
+			SELECT b.id FROM bookings b
+			JOIN properties p ON p.id = b.property_id
+			WHERE `+t.where+`
+			ORDER BY b.id
+			LIMIT $1
+			FOR UPDATE OF b SKIP LOCKED
+		), updated AS (
+			UPDATE bookings SET `+t.set+`
+			FROM due WHERE bookings.id = due.id
+			RETURNING bookings.id, bookings.user_id, bookings.property_id, bookings.start_date, bookings.end_date
+		), events AS (
+			INSERT INTO booking_events (booking_id, type)
+			SELECT id, $2 FROM updated
+			ON CONFLICT (booking_id, type) DO NOTHING
+			RETURNING booking_id
+		)
+		SELECT u.id, u.user_id, COALESCE(p.owner_id, 0), p.title, u.start_date, u.end_date,
+		       EXISTS(SELECT 1 FROM events e WHERE e.booking_id = u.id)
+		FROM updated u JOIN properties p ON p.id = u.property_id
+	`, bl.batchSize, t.event)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	var notes []notification.Notification
+	for rows.Next() {
+		var (
+			id, guestID, hostID int
+			property            string
+			start, end          time.Time
+			recorded            bool
+		)
+		if err := rows.Scan(&id, &guestID, &hostID, &property, &start, &end, &recorded); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		count++
+		if !recorded {
+			continue // already notified of this event
+		}
+		notes = append(notes, t.notices(id, guestID, hostID, property, start, end)...)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if err := notification.Publish(queryCtx, tx, notes...); err != nil {
+		return 0, err
+	}
+	return count, tx.Commit(queryCtx)
+}
+
+// notices returns the notifications of a transition for a booking's guest and
+// host
+func (t lifecycleTransition) notices(id, guestID, hostID int, property string, start, end time.Time) []notification.Notification {
+	from, to := start.Format(booking.DateLayout), end.Format(booking.DateLayout)
+	var notes []notification.Notification
+	add := func(userID int, title, message string) {
+		if userID == 0 || title == "" {
+			return
+		}
+		notes = append(notes, notification.Notification{
+			UserID:      userID,
+			Type:        notification.TypeBooking,
+			Priority:    t.priority,
+			Title:       title,
+			Message:     fmt.Sprintf(message, property, from, to),
+			ActionLabel: "View booking",
+			ActionURL:   fmt.Sprintf("/bookings/%d", id),
+		})
+	}
+	add(guestID, t.guestTitle, t.guestMessage)
+	if hostID != guestID {
+		add(hostID, t.hostTitle, t.hostMessage)
+	}
+	return notes
+}
+
+// sendResult records the run in the worker metrics and sends the result to
+// the results channel (non-blocking)
+func (bl *BookingLifecycle) sendResult(result BookingLifecycleResult) {
+	metrics.ObserveWorkerRun(bl.Name(), result.Timestamp, result.Error)
+
+	select {
+	case bl.resultsCh <- result:
+	default:
+		// Channel full, discard old result
+	}
+}
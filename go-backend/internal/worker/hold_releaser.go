@@ -0,0 +1,123 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go-backend/internal/database"
+	"go-backend/internal/metrics"
+)
+
+// HoldReleaser marks booking holds as expired once their TTL has passed.
+// Expired holds already stop blocking their dates; this keeps the table's
+// active holds accurate for listings and the per-user limit.
+type HoldReleaser struct {
+	db       *database.DB
+	interval time.Duration
+
+	// Channel for manual trigger (useful for testing)
+	triggerCh chan struct{}
+
+	// Channel for results (for monitoring)
+	resultsCh chan HoldReleaseResult
+}
+
+// HoldReleaseResult contains the result of a release cycle
+type HoldReleaseResult struct {
+	ExpiredCount int64
+	Error        error
+	Timestamp    time.Time
+}
+
+// NewHoldReleaser creates a new hold releaser worker
+func NewHoldReleaser(db *database.DB, interval time.Duration) *HoldReleaser {
+	return &HoldReleaser{
+		db:        db,
+		interval:  interval,
+		triggerCh: make(chan struct{}, 1),
+		resultsCh: make(chan HoldReleaseResult, 10),
+	}
+}
+
+// Name returns the worker name
+func (hr *HoldReleaser) Name() string {
+	return "HoldReleaser"
+}
+
+// TriggerRelease allows manual triggering of a release cycle
+func (hr *HoldReleaser) TriggerRelease() {
+	select {
+	case hr.triggerCh <- struct{}{}:
+	default:
+		// Channel full, release already pending
+	}
+}
+
+// Results returns the results channel for monitoring
+func (hr *HoldReleaser) Results() <-chan HoldReleaseResult {
+	return hr.resultsCh
+}
+
+// Start begins the release loop
+func (hr *HoldReleaser) Start(ctx context.Context) {
+	ticker := time.NewTicker(hr.interval)
+	defer ticker.Stop()
+
+	// Run immediately on start
+	hr.release(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "context cancelled, stopping", "worker", hr.Name())
+			return
+
+		case <-ticker.C:
+			hr.release(ctx)
+
+		case <-hr.triggerCh:
+			slog.DebugContext(ctx, "manual trigger received", "worker", hr.Name())
+			hr.release(ctx)
+		}
+	}
+}
+
+// release expires active holds past their expiry time
+func (hr *HoldReleaser) release(ctx context.Context) {
+	result := HoldReleaseResult{
+		Timestamp: time.Now(),
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := hr.db.Pool.Exec(queryCtx,
+		`UPDATE booking_holds SET status = 'expired'
+		 WHERE status = 'active' AND expires_at <= CURRENT_TIMESTAMP`)
+	if err != nil {
+		slog.ErrorContext(ctx, "error expiring holds", "worker", hr.Name(), "error", err)
+		result.Error = err
+		hr.sendResult(result)
+		return
+	}
+
+	result.ExpiredCount = tag.RowsAffected()
+	if result.ExpiredCount > 0 {
+		slog.InfoContext(ctx, "booking holds expired", "worker", hr.Name(), "expired_count", result.ExpiredCount)
+	}
+
+	hr.sendResult(result)
+}
+
+// sendResult records the run in the worker metrics and sends the result to
+// the results channel (non-blocking)
+func (hr *HoldReleaser) sendResult(result HoldReleaseResult) {
+	metrics.ObserveWorkerRun(hr.Name(), result.Timestamp, result.Error)
+
+	select {
+	case hr.resultsCh <- result:
+	default:
+		// Channel full, discard old result
+	}
+}
@@ -0,0 +1,284 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go-backend/internal/database"
+	"go-backend/internal/ical"
+	"go-backend/internal/metrics"
+)
+
+// icalHorizonDays limits how far ahead imported events are turned into blocked dates
+const icalHorizonDays = 730
+
+// ICalSync periodically imports external iCal feeds into blocked dates
+type ICalSync struct {
+	db       *database.DB
+	fetcher  ical.Fetcher
+	interval time.Duration
+
+	// Channel for manual trigger (useful for testing)
+	triggerCh chan struct{}
+
+	// Channel for results (for monitoring)
+	resultsCh chan ICalSyncResult
+}
+
+// ICalSyncResult contains the result of a sync cycle
+type ICalSyncResult struct {
+	FeedsSynced   int
+	FeedsFailed   int
+	NightsBlocked int
+	Conflicts     int
+	Error         error
+	Timestamp     time.Time
+}
+
+type icalFeed struct {
+	id         int
+	propertyID int
+	name       string
+	url        string
+}
+
+// NewICalSync creates a new iCal import worker
+func NewICalSync(db *database.DB, fetcher ical.Fetcher, interval time.Duration) *ICalSync {
+	return &ICalSync{
+		db:        db,
+		fetcher:   fetcher,
+		interval:  interval,
+		triggerCh: make(chan struct{}, 1),
+		resultsCh: make(chan ICalSyncResult, 10),
+	}
+}
+
+// Name returns the worker name
+func (s *ICalSync) Name() string {
+	return "ICalSync"
+}
+
+// TriggerSync allows manual triggering of a sync
+func (s *ICalSync) TriggerSync() {
+	select {
+	case s.triggerCh <- struct{}{}:
+	default:
+		// Channel full, sync already pending
+	}
+}
+
+// Results returns the results channel for monitoring
+func (s *ICalSync) Results() <-chan ICalSyncResult {
+	return s.resultsCh
+}
+
+// Start begins the sync loop
+func (s *ICalSync) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	// Run immediately on start
+	s.syncFeeds(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "context cancelled, stopping", "worker", s.Name())
+			return
+
+		case <-ticker.C:
+			s.syncFeeds(ctx)
+
+		case <-s.triggerCh:
+			slog.DebugContext(ctx, "manual trigger received", "worker", s.Name())
+			s.syncFeeds(ctx)
+		}
+	}
+}
+
+// syncFeeds imports every registered feed. A failing feed does not stop the others.
+func (s *ICalSync) syncFeeds(ctx context.Context) {
+	result := ICalSyncResult{
+		Timestamp: time.Now(),
+	}
+
+	feeds, err := s.loadFeeds(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "error loading feeds", "worker", s.Name(), "error", err)
+		result.Error = err
+		s.sendResult(result)
+		return
+	}
+
+	for _, feed := range feeds {
+		if ctx.Err() != nil {
+			break
+		}
+
+		nights, conflicts, err := s.syncFeed(ctx, feed)
+		if err != nil {
+			slog.ErrorContext(ctx, "error syncing feed",
+				"worker", s.Name(), "feed_id", feed.id, "property_id", feed.propertyID, "error", err)
+			result.FeedsFailed++
+			result.Error = err
+			s.recordFeedError(ctx, feed.id, err)
+			continue
+		}
+
+		result.FeedsSynced++
+		result.NightsBlocked += nights
+		result.Conflicts += conflicts
+		if conflicts > 0 {
+			slog.InfoContext(ctx, "feed conflicts with bookings",
+				"worker", s.Name(), "feed_id", feed.id, "property_id", feed.propertyID, "conflicts", conflicts)
+		}
+	}
+
+	slog.InfoContext(ctx, "feeds synced",
+		"worker", s.Name(), "feeds_synced", result.FeedsSynced,
+		"feeds_failed", result.FeedsFailed, "nights_blocked", result.NightsBlocked, "conflicts", result.Conflicts)
+	s.sendResult(result)
+}
+
+func (s *ICalSync) loadFeeds(ctx context.Context) ([]icalFeed, error) {
+	rows, err := s.db.Pool.Query(ctx,
+		`SELECT id, property_id, name, url FROM property_ical_feeds ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	feeds := make([]icalFeed, 0)
+	for rows.Next() {
+		var f icalFeed
+		if err := rows.Scan(&f.id, &f.propertyID, &f.name, &f.url); err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, f)
+	}
+	return feeds, rows.Err()
+}
+
+// syncFeed replaces the nights imported from a feed and records events that
+// overlap bookings made on this platform
+func (s *ICalSync) syncFeed(ctx context.Context, feed icalFeed) (int, int, error) {
+	fetchCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	body, err := s.fetcher.Fetch(fetchCtx, feed.url)
+	if err != nil {
+		return 0, 0, err
+	}
+	events, err := ical.Parse(body)
+	body.Close()
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: %v", errInvalidFeed, err)
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM property_calendar WHERE feed_id = $1`, feed.id); err != nil {
+		return 0, 0, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM property_ical_conflicts WHERE feed_id = $1`, feed.id); err != nil {
+		return 0, 0, err
+	}
+
+	y, m, d := time.Now().Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	horizon := today.AddDate(0, 0, icalHorizonDays)
+
+	nights, conflicts := 0, 0
+	for _, e := range events {
+		start, end := e.Start, e.End
+		if start.Before(today) {
+			start = today
+		}
+		if end.After(horizon) {
+			end = horizon
+		}
+		if !end.After(start) {
+			continue
+		}
+
+		// Nights the host blocked manually keep their own note
+		tag, err := tx.Exec(ctx,
+			`INSERT INTO property_calendar (property_id, date, note, feed_id)
+			 SELECT $1, d::date, $2, $3
+			 FROM generate_series($4::date, $5::date - 1, interval '1 day') AS d
+			 ON CONFLICT (property_id, date) DO NOTHING`,
+			feed.propertyID, "Imported from "+feed.name, feed.id, start, end)
+		if err != nil {
+			return 0, 0, err
+		}
+		nights += int(tag.RowsAffected())
+
+		tag, err = tx.Exec(ctx,
+			`INSERT INTO property_ical_conflicts (feed_id, booking_id, event_uid, summary, start_date, end_date)
+			 SELECT $1, id, $2, $3, $4, $5 FROM bookings
+			 WHERE property_id = $6
+			   AND status NOT IN ('cancelled', 'expired')
+			   AND start_date < $5 AND end_date > $4`,
+			feed.id, e.UID, e.Summary, start, end, feed.propertyID)
+		if err != nil {
+			return 0, 0, err
+		}
+		conflicts += int(tag.RowsAffected())
+	}
+
+	if _, err := tx.Exec(ctx,
+		`UPDATE property_ical_feeds SET last_synced_at = CURRENT_TIMESTAMP, last_error = NULL WHERE id = $1`,
+		feed.id); err != nil {
+		return 0, 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, 0, err
+	}
+	return nights, conflicts, nil
+}
+
+// errInvalidFeed wraps errors parsing a fetched feed
+var errInvalidFeed = errors.New("feed is not a valid calendar")
+
+// feedErrorMessage is the error shown to the host for a failed sync. Details
+// of connection errors are only logged, so the feed list does not reveal how
+// the addresses a feed points to respond.
+func feedErrorMessage(err error) string {
+	for _, known := range []error{ical.ErrBlockedAddress, ical.ErrInvalidURL, errInvalidFeed} {
+		if errors.Is(err, known) {
+			return known.Error()
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "feed did not respond in time"
+	}
+	return "could not fetch feed"
+}
+
+func (s *ICalSync) recordFeedError(ctx context.Context, feedID int, syncErr error) {
+	_, err := s.db.Pool.Exec(ctx,
+		`UPDATE property_ical_feeds SET last_error = $1 WHERE id = $2`, feedErrorMessage(syncErr), feedID)
+	if err != nil {
+		slog.ErrorContext(ctx, "error recording feed error", "worker", s.Name(), "error", err)
+	}
+}
+
+// sendResult records the run in the worker metrics and sends the result to
+// the results channel (non-blocking)
+func (s *ICalSync) sendResult(result ICalSyncResult) {
+	metrics.ObserveWorkerRun(s.Name(), result.Timestamp, result.Error)
+
+	select {
+	case s.resultsCh <- result:
+	default:
+		// Channel full, discard old result
+	}
+}
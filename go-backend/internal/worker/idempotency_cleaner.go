@@ -0,0 +1,124 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go-backend/internal/database"
+	"go-backend/internal/metrics"
+)
+
+// IdempotencyCleaner deletes stored idempotent responses once they expire
+type IdempotencyCleaner struct {
+	db       *database.DB
+	ttl      time.Duration
+	interval time.Duration
+
+	// Channel for manual trigger (useful for testing)
+	triggerCh chan struct{}
+
+	// Channel for results (for monitoring)
+	resultsCh chan IdempotencyCleanupResult
+}
+
+// IdempotencyCleanupResult contains the result of a cleanup cycle
+type IdempotencyCleanupResult struct {
+	DeletedCount int64
+	Error        error
+	Timestamp    time.Time
+}
+
+// NewIdempotencyCleaner creates a worker removing records older than ttl
+func NewIdempotencyCleaner(db *database.DB, ttl, interval time.Duration) *IdempotencyCleaner {
+	return &IdempotencyCleaner{
+		db:        db,
+		ttl:       ttl,
+		interval:  interval,
+		triggerCh: make(chan struct{}, 1),
+		resultsCh: make(chan IdempotencyCleanupResult, 10),
+	}
+}
+
+// Name returns the worker name
+func (ic *IdempotencyCleaner) Name() string {
+	return "IdempotencyCleaner"
+}
+
+// TriggerCleanup allows manual triggering of a cleanup
+func (ic *IdempotencyCleaner) TriggerCleanup() {
+	select {
+	case ic.triggerCh <- struct{}{}:
+	default:
+		// Channel full, cleanup already pending
+	}
+}
+
+// Results returns the results channel for monitoring
+func (ic *IdempotencyCleaner) Results() <-chan IdempotencyCleanupResult {
+	return ic.resultsCh
+}
+
+// Start begins the cleanup loop
+func (ic *IdempotencyCleaner) Start(ctx context.Context) {
+	ticker := time.NewTicker(ic.interval)
+	defer ticker.Stop()
+
+	// Run immediately on start
+	ic.cleanup(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "context cancelled, stopping", "worker", ic.Name())
+			return
+
+		case <-ticker.C:
+			ic.cleanup(ctx)
+
+		case <-ic.triggerCh:
+			slog.DebugContext(ctx, "manual trigger received", "worker", ic.Name())
+			ic.cleanup(ctx)
+		}
+	}
+}
+
+// cleanup deletes expired records
+func (ic *IdempotencyCleaner) cleanup(ctx context.Context) {
+	result := IdempotencyCleanupResult{
+		Timestamp: time.Now(),
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := ic.db.Pool.Exec(queryCtx,
+		`DELETE FROM idempotency_keys WHERE created_at < CURRENT_TIMESTAMP - $1::interval`,
+		fmt.Sprintf("%d seconds", int(ic.ttl.Seconds())))
+	if err != nil {
+		slog.ErrorContext(ctx, "error deleting expired keys", "worker", ic.Name(), "error", err)
+		result.Error = err
+		ic.sendResult(result)
+		return
+	}
+
+	result.DeletedCount = tag.RowsAffected()
+	if result.DeletedCount > 0 {
+		slog.InfoContext(ctx, "expired idempotency keys deleted", "worker", ic.Name(), "deleted_count", result.DeletedCount)
+	}
+
+	ic.sendResult(result)
+}
+
+// sendResult records the run in the worker metrics and sends the result to
+// the results channel (non-blocking)
+func (ic *IdempotencyCleaner) sendResult(result IdempotencyCleanupResult) {
+	metrics.ObserveWorkerRun(ic.Name(), result.Timestamp, result.Error)
+
+	select {
+	case ic.resultsCh <- result:
+	default:
+		// Channel full, discard old result
+	}
+}
@@ -0,0 +1,234 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go-backend/internal/database"
+	"go-backend/internal/mail"
+	"go-backend/internal/metrics"
+)
+
+const (
+	// mailBatch is how many emails are claimed at a time
+	mailBatch = 20
+	// mailLease is how long a claimed email is held before another worker may
+	// try it, in case this one stops mid-delivery
+	mailLease = 5 * time.Minute
+	// mailSendTimeout bounds the delivery of one email
+	mailSendTimeout = time.Minute
+)
+
+// MailSender delivers the emails queued in the outbox. Failed deliveries are
+// retried with exponential backoff; emails that are rejected outright or
+// still fail after mail.MaxAttempts are marked failed.
+type MailSender struct {
+	db       *database.DB
+	sender   mail.Sender
+	renderer *mail.Renderer
+	interval time.Duration
+
+	// Channel for manual trigger (useful for testing)
+	triggerCh chan struct{}
+
+	// Channel for results (for monitoring)
+	resultsCh chan MailSendResult
+}
+
+// MailSendResult contains the result of a delivery cycle
+type MailSendResult struct {
+	SentCount   int
+	RetryCount  int
+	FailedCount int
+	Error       error
+	Timestamp   time.Time
+}
+
+// NewMailSender creates a new mail sender worker
+func NewMailSender(db *database.DB, sender mail.Sender, renderer *mail.Renderer, interval time.Duration) *MailSender {
+	return &MailSender{
+		db:        db,
+		sender:    sender,
+		renderer:  renderer,
+		interval:  interval,
+		triggerCh: make(chan struct{}, 1),
+		resultsCh: make(chan MailSendResult, 10),
+	}
+}
+
+// Name returns the worker name
+func (ms *MailSender) Name() string {
+	return "MailSender"
+}
+
+// TriggerSend allows manual triggering of a delivery cycle
+func (ms *MailSender) TriggerSend() {
+	select {
+	case ms.triggerCh <- struct{}{}:
+	default:
+		// Channel full, delivery already pending
+	}
+}
+
+// Results returns the results channel for monitoring
+func (ms *MailSender) Results() <-chan MailSendResult {
+	return ms.resultsCh
+}
+
+// Start begins the delivery loop
+func (ms *MailSender) Start(ctx context.Context) {
+	ticker := time.NewTicker(ms.interval)
+	defer ticker.Stop()
+
+	// Run immediately on start
+	ms.deliver(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "context cancelled, stopping", "worker", ms.Name())
+			return
+
+		case <-ticker.C:
+			ms.deliver(ctx)
+
+		case <-ms.triggerCh:
+			slog.DebugContext(ctx, "manual trigger received", "worker", ms.Name())
+			ms.deliver(ctx)
+		}
+	}
+}
+
+// outboxEmail is a claimed outbox row
+type outboxEmail struct {
+	id        int
+	recipient string
+	locale    string
+	template  string
+	data      map[string]any
+	attempts  int
+}
+
+// deliver sends due emails in batches until none are left
+func (ms *MailSender) deliver(ctx context.Context) {
+	result := MailSendResult{
+		Timestamp: time.Now(),
+	}
+
+	for ctx.Err() == nil {
+		emails, err := ms.claim(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "error claiming emails", "worker", ms.Name(), "error", err)
+			result.Error = err
+			break
+		}
+		for _, e := range emails {
+			if err := ms.send(ctx, e, &result); err != nil {
+				slog.ErrorContext(ctx, "error recording delivery", "worker", ms.Name(), "email_id", e.id, "error", err)
+				result.Error = err
+			}
+		}
+		if len(emails) < mailBatch {
+			break
+		}
+	}
+
+	if result.SentCount+result.RetryCount+result.FailedCount > 0 {
+		slog.InfoContext(ctx, "emails sent",
+			"worker", ms.Name(), "sent_count", result.SentCount, "retry_count", result.RetryCount, "failed_count", result.FailedCount)
+	}
+
+	ms.sendResult(result)
+}
+
+// claim leases a batch of due emails by moving their next attempt past the
+// lease, so concurrent workers skip them and a crashed worker's emails are
+// retried once the lease runs out
+func (ms *MailSender) claim(ctx context.Context) ([]outboxEmail, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := ms.db.Pool.Query(queryCtx,
+		`UPDATE mail_outbox SET attempts = attempts + 1,
+		 next_attempt_at = CURRENT_TIMESTAMP + $2::interval
+		 WHERE id IN (
+		   SELECT id FROM mail_outbox
+		   WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+		   ORDER BY next_attempt_at, id
+		   LIMIT $1
+		   FOR UPDATE SKIP LOCKED
+		 )
+		 RETURNING id, recipient, locale, template, data, attempts`,
+		mailBatch, fmt.Sprintf("%d seconds", int(mailLease.Seconds())))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var emails []outboxEmail
+	for rows.Next() {
+		var e outboxEmail
+		if err := rows.Scan(&e.id, &e.recipient, &e.locale, &e.template, &e.data, &e.attempts); err != nil {
+			return nil, err
+		}
+		emails = append(emails, e)
+	}
+	return emails, rows.Err()
+}
+
+// send renders and delivers one email and records the outcome
+func (ms *MailSender) send(ctx context.Context, e outboxEmail, result *MailSendResult) error {
+	m, err := ms.renderer.Render(e.template, e.locale, e.data)
+	permanent := err != nil // a template that does not render will not on retry
+	if err == nil {
+		m.To = e.recipient
+		sendCtx, cancel := context.WithTimeout(ctx, mailSendTimeout)
+		err = ms.sender.Send(sendCtx, m)
+		cancel()
+		permanent = mail.IsPermanent(err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	switch {
+	case err == nil:
+		result.SentCount++
+		// The data may hold secrets such as reset tokens; it is not needed
+		// once the email is sent
+		_, err = ms.db.Pool.Exec(queryCtx,
+			`UPDATE mail_outbox SET status = 'sent', sent_at = CURRENT_TIMESTAMP,
+			 last_error = NULL, data = '{}' WHERE id = $1`, e.id)
+		return err
+
+	case permanent || e.attempts >= mail.MaxAttempts:
+		result.FailedCount++
+		slog.ErrorContext(ctx, "email failed permanently",
+			"worker", ms.Name(), "email_id", e.id, "template", e.template, "attempts", e.attempts, "error", err)
+		_, err = ms.db.Pool.Exec(queryCtx,
+			`UPDATE mail_outbox SET status = 'failed', last_error = $2 WHERE id = $1`, e.id, err.Error())
+		return err
+
+	default:
+		result.RetryCount++
+		_, err = ms.db.Pool.Exec(queryCtx,
+			`UPDATE mail_outbox SET next_attempt_at = CURRENT_TIMESTAMP + $2::interval, last_error = $3
+			 WHERE id = $1`,
+			e.id, fmt.Sprintf("%d seconds", int(mail.Backoff(e.attempts).Seconds())), err.Error())
+		return err
+	}
+}
+
+// sendResult records the run in the worker metrics and sends the result to
+// the results channel (non-blocking)
+func (ms *MailSender) sendResult(result MailSendResult) {
+	metrics.ObserveWorkerRun(ms.Name(), result.Timestamp, result.Error)
+
+	select {
+	case ms.resultsCh <- result:
+	default:
+		// Channel full, discard old result
+	}
+}
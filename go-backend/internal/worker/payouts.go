@@ -0,0 +1,240 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"go-backend/internal/database"
+	"go-backend/internal/ledger"
+	"go-backend/internal/metrics"
+	"go-backend/internal/payments"
+)
+
+// PayoutWorker releases host earnings in batches once a booking's check-in is
+// at least delayDays in the past. Each host gets one payout per run covering
+// all of their released bookings.
+type PayoutWorker struct {
+	db        *database.DB
+	delayDays int
+	currency  string
+	interval  time.Duration
+
+	// Channel for manual trigger (useful for testing)
+	triggerCh chan struct{}
+
+	// Channel for results (for monitoring)
+	resultsCh chan PayoutResult
+}
+
+// PayoutResult contains the result of a payout cycle
+type PayoutResult struct {
+	PayoutCount int
+	Amount      float64
+	Error       error
+	Timestamp   time.Time
+}
+
+// NewPayoutWorker creates a new payout worker
+func NewPayoutWorker(db *database.DB, delayDays int, currency string, interval time.Duration) *PayoutWorker {
+	return &PayoutWorker{
+		db:        db,
+		delayDays: delayDays,
+		currency:  currency,
+		interval:  interval,
+		triggerCh: make(chan struct{}, 1),
+		resultsCh: make(chan PayoutResult, 10),
+	}
+}
+
+// Name returns the worker name
+func (pw *PayoutWorker) Name() string {
+	return "PayoutWorker"
+}
+
+// TriggerPayouts allows manual triggering of a payout run
+func (pw *PayoutWorker) TriggerPayouts() {
+	select {
+	case pw.triggerCh <- struct{}{}:
+	default:
+		// Channel full, run already pending
+	}
+}
+
+// Results returns the results channel for monitoring
+func (pw *PayoutWorker) Results() <-chan PayoutResult {
+	return pw.resultsCh
+}
+
+// Start begins the payout loop
+func (pw *PayoutWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(pw.interval)
+	defer ticker.Stop()
+
+	// Run immediately on start
+	pw.runPayouts(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "context cancelled, stopping", "worker", pw.Name())
+			return
+
+		case <-ticker.C:
+			pw.runPayouts(ctx)
+
+		case <-pw.triggerCh:
+			slog.DebugContext(ctx, "manual trigger received", "worker", pw.Name())
+			pw.runPayouts(ctx)
+		}
+	}
+}
+
+// releasableQuery selects each booking's unpaid host balance once the payout
+// delay after check-in has passed
+const releasableQuery = `
+	SELECT e.booking_id, SUM(e.credit - e.debit)::bigint
+	FROM ledger_entries e
+	JOIN bookings b ON b.id = e.booking_id
+	WHERE e.account = $1 AND e.host_id = $2
+	  AND b.start_date + $3::int <= CURRENT_DATE
+	GROUP BY e.booking_id
+	HAVING SUM(e.credit - e.debit) > 0
+	ORDER BY e.booking_id
+`
+
+// runPayouts creates a payout for every host with released earnings
+func (pw *PayoutWorker) runPayouts(ctx context.Context) {
+	result := PayoutResult{
+		Timestamp: time.Now(),
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	rows, err := pw.db.Pool.Query(queryCtx,
+		`SELECT DISTINCT e.host_id
+		 FROM ledger_entries e
+		 JOIN bookings b ON b.id = e.booking_id
+		 WHERE e.account = $1 AND e.host_id IS NOT NULL
+		   AND b.start_date + $2::int <= CURRENT_DATE
+		 GROUP BY e.host_id, e.booking_id
+		 HAVING SUM(e.credit - e.debit) > 0`,
+		ledger.AccountHostPayable, pw.delayDays)
+	if err != nil {
+		slog.ErrorContext(ctx, "error loading hosts", "worker", pw.Name(), "error", err)
+		result.Error = err
+		pw.sendResult(result)
+		return
+	}
+	hostIDs, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		slog.ErrorContext(ctx, "error loading hosts", "worker", pw.Name(), "error", err)
+		result.Error = err
+		pw.sendResult(result)
+		return
+	}
+
+	for _, hostID := range hostIDs {
+		amount, err := pw.payoutHost(queryCtx, hostID)
+		if err != nil {
+			slog.ErrorContext(ctx, "error paying out host", "worker", pw.Name(), "host_id", hostID, "error", err)
+			result.Error = err
+			continue
+		}
+		if amount > 0 {
+			result.PayoutCount++
+			result.Amount += payments.FromCents(amount)
+		}
+	}
+
+	if result.PayoutCount > 0 {
+		slog.InfoContext(ctx, "payouts released",
+			"worker", pw.Name(), "payout_count", result.PayoutCount, "amount", result.Amount, "currency", pw.currency)
+	} else {
+		slog.DebugContext(ctx, "no earnings to release", "worker", pw.Name())
+	}
+
+	pw.sendResult(result)
+}
+
+// payoutHost releases one host's earnings in a single transaction and returns
+// the amount in cents. The host row is locked so a payout is never created
+// twice for the same balance.
+func (pw *PayoutWorker) payoutHost(ctx context.Context, hostID int) (int64, error) {
+	tx, err := pw.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, hostID); err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.Query(ctx, releasableQuery, ledger.AccountHostPayable, hostID, pw.delayDays)
+	if err != nil {
+		return 0, err
+	}
+	items, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (ledger.PayoutItem, error) {
+		var item ledger.PayoutItem
+		err := row.Scan(&item.BookingID, &item.Amount)
+		return item, err
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(items) == 0 {
+		return 0, nil
+	}
+
+	var total int64
+	for _, item := range items {
+		total += item.Amount
+	}
+
+	var payoutID int
+	err = tx.QueryRow(ctx,
+		`INSERT INTO host_payouts (host_id, amount, currency) VALUES ($1, $2, $3) RETURNING id`,
+		hostID, payments.FromCents(total), pw.currency,
+	).Scan(&payoutID)
+	if err != nil {
+		return 0, err
+	}
+	for _, item := range items {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO host_payout_items (payout_id, booking_id, amount) VALUES ($1, $2, $3)`,
+			payoutID, item.BookingID, payments.FromCents(item.Amount))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	ref := fmt.Sprintf("payout:%d", payoutID)
+	if _, err := ledger.Post(ctx, tx, ledger.Payout(ref, hostID, items)); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	slog.InfoContext(ctx, "payout created",
+		"worker", pw.Name(), "payout_id", payoutID, "amount", payments.FromCents(total), "currency", pw.currency,
+		"host_id", hostID, "bookings", len(items))
+	return total, nil
+}
+
+// sendResult records the run in the worker metrics and sends the result to
+// the results channel (non-blocking)
+func (pw *PayoutWorker) sendResult(result PayoutResult) {
+	metrics.ObserveWorkerRun(pw.Name(), result.Timestamp, result.Error)
+
+	select {
+	case pw.resultsCh <- result:
+	default:
+		// Channel full, discard old result
+	}
+}
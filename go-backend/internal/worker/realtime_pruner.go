@@ -0,0 +1,123 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go-backend/internal/database"
+	"go-backend/internal/metrics"
+	"go-backend/internal/realtime"
+)
+
+// RealtimePruner deletes stored realtime events once they can no longer be replayed
+type RealtimePruner struct {
+	db       *database.DB
+	interval time.Duration
+
+	// Channel for manual trigger (useful for testing)
+	triggerCh chan struct{}
+
+	// Channel for results (for monitoring)
+	resultsCh chan RealtimePruneResult
+}
+
+// RealtimePruneResult contains the result of a prune cycle
+type RealtimePruneResult struct {
+	DeletedCount int64
+	Error        error
+	Timestamp    time.Time
+}
+
+// NewRealtimePruner creates a worker removing events older than realtime.Retention
+func NewRealtimePruner(db *database.DB, interval time.Duration) *RealtimePruner {
+	return &RealtimePruner{
+		db:        db,
+		interval:  interval,
+		triggerCh: make(chan struct{}, 1),
+		resultsCh: make(chan RealtimePruneResult, 10),
+	}
+}
+
+// Name returns the worker name
+func (rp *RealtimePruner) Name() string {
+	return "RealtimePruner"
+}
+
+// TriggerPrune allows manual triggering of a prune
+func (rp *RealtimePruner) TriggerPrune() {
+	select {
+	case rp.triggerCh <- struct{}{}:
+	default:
+		// Channel full, prune already pending
+	}
+}
+
+// Results returns the results channel for monitoring
+func (rp *RealtimePruner) Results() <-chan RealtimePruneResult {
+	return rp.resultsCh
+}
+
+// Start begins the prune loop
+func (rp *RealtimePruner) Start(ctx context.Context) {
+	ticker := time.NewTicker(rp.interval)
+	defer ticker.Stop()
+
+	// Run immediately on start
+	rp.prune(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "context cancelled, stopping", "worker", rp.Name())
+			return
+
+		case <-ticker.C:
+			rp.prune(ctx)
+
+		case <-rp.triggerCh:
+			slog.DebugContext(ctx, "manual trigger received", "worker", rp.Name())
+			rp.prune(ctx)
+		}
+	}
+}
+
+// prune deletes events past the replay window
+func (rp *RealtimePruner) prune(ctx context.Context) {
+	result := RealtimePruneResult{
+		Timestamp: time.Now(),
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := rp.db.Pool.Exec(queryCtx,
+		`DELETE FROM realtime_events WHERE created_at < CURRENT_TIMESTAMP - $1::interval`,
+		fmt.Sprintf("%d seconds", int(realtime.Retention.Seconds())))
+	if err != nil {
+		slog.ErrorContext(ctx, "error deleting old events", "worker", rp.Name(), "error", err)
+		result.Error = err
+		rp.sendResult(result)
+		return
+	}
+
+	result.DeletedCount = tag.RowsAffected()
+	if result.DeletedCount > 0 {
+		slog.InfoContext(ctx, "realtime events deleted", "worker", rp.Name(), "deleted_count", result.DeletedCount)
+	}
+
+	rp.sendResult(result)
+}
+
+// sendResult records the run in the worker metrics and sends the result to
+// the results channel (non-blocking)
+func (rp *RealtimePruner) sendResult(result RealtimePruneResult) {
+	metrics.ObserveWorkerRun(rp.Name(), result.Timestamp, result.Error)
+
+	select {
+	case rp.resultsCh <- result:
+	default:
+		// Channel full, discard old result
+	}
+}
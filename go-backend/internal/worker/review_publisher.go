@@ -0,0 +1,174 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"go-backend/internal/database"
+	"go-backend/internal/metrics"
+	"go-backend/internal/review"
+)
+
+// reviewPublishBatchSize limits how many bookings are published per query
+const reviewPublishBatchSize = 500
+
+// ReviewPublisher publishes hidden reviews once the review window of their
+// stay has closed, even if only one side reviewed
+type ReviewPublisher struct {
+	db       *database.DB
+	interval time.Duration
+
+	// Channel for manual trigger (useful for testing)
+	triggerCh chan struct{}
+
+	// Channel for results (for monitoring)
+	resultsCh chan ReviewPublishResult
+}
+
+// ReviewPublishResult contains the result of a publish cycle
+type ReviewPublishResult struct {
+	PublishedCount int
+	Error          error
+	Timestamp      time.Time
+}
+
+// NewReviewPublisher creates a new review publisher worker
+func NewReviewPublisher(db *database.DB, interval time.Duration) *ReviewPublisher {
+	return &ReviewPublisher{
+		db:        db,
+		interval:  interval,
+		triggerCh: make(chan struct{}, 1),
+		resultsCh: make(chan ReviewPublishResult, 10),
+	}
+}
+
+// Name returns the worker name
+func (rp *ReviewPublisher) Name() string {
+	return "ReviewPublisher"
+}
+
+// TriggerPublish allows manual triggering of a publish cycle
+func (rp *ReviewPublisher) TriggerPublish() {
+	select {
+	case rp.triggerCh <- struct{}{}:
+	default:
+		// Channel full, publish already pending
+	}
+}
+
+// Results returns the results channel for monitoring
+func (rp *ReviewPublisher) Results() <-chan ReviewPublishResult {
+	return rp.resultsCh
+}
+
+// Start begins the publish loop
+func (rp *ReviewPublisher) Start(ctx context.Context) {
+	ticker := time.NewTicker(rp.interval)
+	defer ticker.Stop()
+
+	// Run immediately on start
+	rp.publish(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "context cancelled, stopping", "worker", rp.Name())
+			return
+
+		case <-ticker.C:
+			rp.publish(ctx)
+
+		case <-rp.triggerCh:
+			slog.DebugContext(ctx, "manual trigger received", "worker", rp.Name())
+			rp.publish(ctx)
+		}
+	}
+}
+
+// publish publishes the reviews of every booking whose review window has closed
+func (rp *ReviewPublisher) publish(ctx context.Context) {
+	result := ReviewPublishResult{
+		Timestamp: time.Now(),
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	for {
+		rows, err := rp.db.Pool.Query(queryCtx,
+			`SELECT b.id FROM bookings b
+			 WHERE b.end_date + $1::int <= CURRENT_DATE
+			   AND (EXISTS(SELECT 1 FROM reviews r WHERE r.booking_id = b.id AND r.published_at IS NULL)
+			     OR EXISTS(SELECT 1 FROM guest_reviews g WHERE g.booking_id = b.id AND g.published_at IS NULL))
+			 ORDER BY b.id
+			 LIMIT $2`,
+			review.WindowDays, reviewPublishBatchSize)
+		if err != nil {
+			slog.ErrorContext(ctx, "error loading bookings", "worker", rp.Name(), "error", err)
+			result.Error = err
+			break
+		}
+		bookingIDs, err := pgx.CollectRows(rows, pgx.RowTo[int])
+		if err != nil {
+			slog.ErrorContext(ctx, "error loading bookings", "worker", rp.Name(), "error", err)
+			result.Error = err
+			break
+		}
+
+		failed := 0
+		for _, id := range bookingIDs {
+			n, err := rp.publishBooking(queryCtx, id)
+			if err != nil {
+				slog.ErrorContext(ctx, "error publishing reviews", "worker", rp.Name(), "booking_id", id, "error", err)
+				result.Error = err
+				failed++
+				continue
+			}
+			result.PublishedCount += n
+		}
+
+		// Stop on a short batch, or if nothing in the batch could be published
+		if len(bookingIDs) < reviewPublishBatchSize || failed == len(bookingIDs) {
+			break
+		}
+	}
+
+	if result.PublishedCount > 0 {
+		slog.InfoContext(ctx, "reviews published", "worker", rp.Name(), "published_count", result.PublishedCount)
+	}
+
+	rp.sendResult(result)
+}
+
+// publishBooking publishes one booking's reviews in a transaction
+func (rp *ReviewPublisher) publishBooking(ctx context.Context, bookingID int) (int, error) {
+	tx, err := rp.db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := review.Lock(ctx, tx, bookingID); err != nil {
+		return 0, err
+	}
+	n, err := review.Publish(ctx, tx, bookingID)
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit(ctx)
+}
+
+// sendResult records the run in the worker metrics and sends the result to
+// the results channel (non-blocking)
+func (rp *ReviewPublisher) sendResult(result ReviewPublishResult) {
+	metrics.ObserveWorkerRun(rp.Name(), result.Timestamp, result.Error)
+
+	select {
+	case rp.resultsCh <- result:
+	default:
+		// Channel full, discard old result
+	}
+}
@@ -0,0 +1,230 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go-backend/internal/database"
+	"go-backend/internal/metrics"
+	"go-backend/internal/notification"
+	"go-backend/internal/support"
+)
+
+// SupportSLA enforces the SLA targets of support tickets. Active tickets that
+// miss their first response or resolution target are flagged as breached and
+// their assignee (or, if unassigned, every agent) is alerted once per target.
+// Resolved tickets are closed after support.AutoCloseAfter.
+type SupportSLA struct {
+	db       *database.DB
+	interval time.Duration
+
+	// Channel for manual trigger (useful for testing)
+	triggerCh chan struct{}
+
+	// Channel for results (for monitoring)
+	resultsCh chan SupportSLAResult
+}
+
+// SupportSLAResult contains the result of an SLA check
+type SupportSLAResult struct {
+	FirstResponseBreaches int
+	ResolutionBreaches    int
+	ClosedCount           int64
+	Error                 error
+	Timestamp             time.Time
+}
+
+// NewSupportSLA creates a new support SLA worker
+func NewSupportSLA(db *database.DB, interval time.Duration) *SupportSLA {
+	return &SupportSLA{
+		db:        db,
+		interval:  interval,
+		triggerCh: make(chan struct{}, 1),
+		resultsCh: make(chan SupportSLAResult, 10),
+	}
+}
+
+// Name returns the worker name
+func (ss *SupportSLA) Name() string {
+	return "SupportSLA"
+}
+
+// TriggerCheck allows manual triggering of an SLA check
+func (ss *SupportSLA) TriggerCheck() {
+	select {
+	case ss.triggerCh <- struct{}{}:
+	default:
+		// Channel full, check already pending
+	}
+}
+
+// Results returns the results channel for monitoring
+func (ss *SupportSLA) Results() <-chan SupportSLAResult {
+	return ss.resultsCh
+}
+
+// Start begins the check loop
+func (ss *SupportSLA) Start(ctx context.Context) {
+	ticker := time.NewTicker(ss.interval)
+	defer ticker.Stop()
+
+	// Run immediately on start
+	ss.check(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "context cancelled, stopping", "worker", ss.Name())
+			return
+
+		case <-ticker.C:
+			ss.check(ctx)
+
+		case <-ss.triggerCh:
+			slog.DebugContext(ctx, "manual trigger received", "worker", ss.Name())
+			ss.check(ctx)
+		}
+	}
+}
+
+// slaBreach describes how a target is checked and announced
+type slaBreach struct {
+	column string // breach timestamp column
+	due    string // condition for a missed target
+	title  string
+}
+
+var (
+	firstResponseBreach = slaBreach{
+		column: "first_response_breached_at",
+		due:    "first_responded_at IS NULL AND first_response_due_at <= CURRENT_TIMESTAMP",
+		title:  "First response overdue",
+	}
+	resolutionBreach = slaBreach{
+		column: "resolution_breached_at",
+		due:    "resolution_due_at <= CURRENT_TIMESTAMP",
+		title:  "Resolution overdue",
+	}
+)
+
+// check flags breaches and closes resolved tickets
+func (ss *SupportSLA) check(ctx context.Context) {
+	result := SupportSLAResult{
+		Timestamp: time.Now(),
+	}
+
+	var err error
+	if result.FirstResponseBreaches, err = ss.flag(ctx, firstResponseBreach); err != nil {
+		slog.ErrorContext(ctx, "error flagging first response breaches", "worker", ss.Name(), "error", err)
+		result.Error = err
+	}
+	if result.ResolutionBreaches, err = ss.flag(ctx, resolutionBreach); err != nil {
+		slog.ErrorContext(ctx, "error flagging resolution breaches", "worker", ss.Name(), "error", err)
+		result.Error = err
+	}
+	if result.ClosedCount, err = ss.closeResolved(ctx); err != nil {
+		slog.ErrorContext(ctx, "error closing resolved tickets", "worker", ss.Name(), "error", err)
+		result.Error = err
+	}
+
+	if result.FirstResponseBreaches+result.ResolutionBreaches > 0 || result.ClosedCount > 0 {
+		slog.InfoContext(ctx, "tickets checked",
+			"worker", ss.Name(), "first_response_breaches", result.FirstResponseBreaches,
+			"resolution_breaches", result.ResolutionBreaches, "closed_count", result.ClosedCount)
+	}
+
+	ss.sendResult(result)
+}
+
+// flag marks the active tickets that newly missed a target and alerts their
+// assignees, or every agent for unassigned tickets, in the same transaction
+func (ss *SupportSLA) flag(ctx context.Context, b slaBreach) (int, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := ss.db.Pool.Begin(queryCtx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(queryCtx)
+
+	rows, err := tx.Query(queryCtx,
+		`WITH breached AS (
+		   UPDATE support_tickets SET `+b.column+` = CURRENT_TIMESTAMP
+		   WHERE status IN ('open', 'pending') AND `+b.column+` IS NULL AND `+b.due+`
+		   RETURNING id, subject, priority, assignee_id
+		 )
+		 SELECT b.id, b.subject, b.priority, u.id
+		 FROM breached b
+		 LEFT JOIN users u ON u.id = b.assignee_id
+		     OR (b.assignee_id IS NULL AND u.role IN ('support', 'admin') AND u.suspended_at IS NULL)`)
+	if err != nil {
+		return 0, err
+	}
+
+	tickets := map[int]bool{}
+	var notes []notification.Notification
+	for rows.Next() {
+		var (
+			id                int
+			subject, priority string
+			userID            *int
+		)
+		if err := rows.Scan(&id, &subject, &priority, &userID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		tickets[id] = true
+		if userID == nil {
+			continue // no agent to alert
+		}
+		notes = append(notes, notification.Notification{
+			UserID:      *userID,
+			Type:        notification.TypeSystem,
+			Priority:    notification.PriorityHigh,
+			Title:       b.title,
+			Message:     fmt.Sprintf("%s (%s priority): %s", support.Reference(id), priority, subject),
+			ActionLabel: "View ticket",
+			ActionURL:   fmt.Sprintf("/support/tickets/%d", id),
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if err := notification.Publish(queryCtx, tx, notes...); err != nil {
+		return 0, err
+	}
+	return len(tickets), tx.Commit(queryCtx)
+}
+
+// closeResolved closes tickets resolved longer than support.AutoCloseAfter ago
+func (ss *SupportSLA) closeResolved(ctx context.Context) (int64, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := ss.db.Pool.Exec(queryCtx,
+		`UPDATE support_tickets
+		 SET status = 'closed', closed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		 WHERE status = 'resolved' AND resolved_at <= CURRENT_TIMESTAMP - $1::interval`,
+		fmt.Sprintf("%d seconds", int(support.AutoCloseAfter.Seconds())))
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// sendResult records the run in the worker metrics and sends the result to
+// the results channel (non-blocking)
+func (ss *SupportSLA) sendResult(result SupportSLAResult) {
+	metrics.ObserveWorkerRun(ss.Name(), result.Timestamp, result.Error)
+
+	select {
+	case ss.resultsCh <- result:
+	default:
+		// Channel full, discard old result
+	}
+}
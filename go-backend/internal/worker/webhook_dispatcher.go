@@ -0,0 +1,300 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go-backend/internal/database"
+	"go-backend/internal/metrics"
+	"go-backend/internal/webhook"
+)
+
+const (
+	// webhookBatch is how many deliveries are claimed at a time
+	webhookBatch = 20
+	// webhookLease is how long a claimed delivery is held before another
+	// worker may try it, in case this one stops mid-delivery
+	webhookLease = 5 * time.Minute
+	// webhookTimeout bounds one delivery attempt
+	webhookTimeout = 10 * time.Second
+	// webhookRetention is how long events are kept once none of their
+	// deliveries are pending, so they can be inspected and redelivered
+	webhookRetention = 30 * 24 * time.Hour
+	// webhookPruneInterval is how often old events are deleted
+	webhookPruneInterval = time.Hour
+)
+
+// WebhookDispatcher posts queued webhook deliveries to their subscriptions.
+// Every attempt is logged; failed deliveries are retried with exponential
+// backoff and dead-lettered after webhook.MaxAttempts, where they wait for a
+// manual redelivery. Deliveries to paused subscriptions are held until the
+// subscription is resumed.
+type WebhookDispatcher struct {
+	db       *database.DB
+	client   *webhook.Client
+	interval time.Duration
+
+	lastPrune time.Time
+
+	// Channel for manual trigger (useful for testing)
+	triggerCh chan struct{}
+
+	// Channel for results (for monitoring)
+	resultsCh chan WebhookDispatchResult
+}
+
+// WebhookDispatchResult contains the result of a delivery cycle
+type WebhookDispatchResult struct {
+	DeliveredCount int
+	RetryCount     int
+	DeadCount      int
+	PrunedCount    int64
+	Error          error
+	Timestamp      time.Time
+}
+
+// NewWebhookDispatcher creates a new webhook dispatcher worker
+func NewWebhookDispatcher(db *database.DB, interval time.Duration) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		db:        db,
+		client:    webhook.NewClient(webhookTimeout),
+		interval:  interval,
+		triggerCh: make(chan struct{}, 1),
+		resultsCh: make(chan WebhookDispatchResult, 10),
+	}
+}
+
+// Name returns the worker name
+func (wd *WebhookDispatcher) Name() string {
+	return "WebhookDispatcher"
+}
+
+// TriggerDispatch allows manual triggering of a delivery cycle
+func (wd *WebhookDispatcher) TriggerDispatch() {
+	select {
+	case wd.triggerCh <- struct{}{}:
+	default:
+		// Channel full, delivery already pending
+	}
+}
+
+// Results returns the results channel for monitoring
+func (wd *WebhookDispatcher) Results() <-chan WebhookDispatchResult {
+	return wd.resultsCh
+}
+
+// Start begins the delivery loop
+func (wd *WebhookDispatcher) Start(ctx context.Context) {
+	ticker := time.NewTicker(wd.interval)
+	defer ticker.Stop()
+
+	// Run immediately on start
+	wd.dispatch(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "context cancelled, stopping", "worker", wd.Name())
+			return
+
+		case <-ticker.C:
+			wd.dispatch(ctx)
+
+		case <-wd.triggerCh:
+			slog.DebugContext(ctx, "manual trigger received", "worker", wd.Name())
+			wd.dispatch(ctx)
+		}
+	}
+}
+
+// claimedDelivery is a claimed delivery with its subscription and event
+type claimedDelivery struct {
+	id       int
+	attempts int
+	url      string
+	secret   string
+	event    webhook.Envelope
+}
+
+// dispatch posts due deliveries in batches until none are left, then prunes
+// old events if it is time to
+func (wd *WebhookDispatcher) dispatch(ctx context.Context) {
+	result := WebhookDispatchResult{
+		Timestamp: time.Now(),
+	}
+
+	for ctx.Err() == nil {
+		deliveries, err := wd.claim(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "error claiming deliveries", "worker", wd.Name(), "error", err)
+			result.Error = err
+			break
+		}
+		for _, d := range deliveries {
+			if err := wd.deliver(ctx, d, &result); err != nil {
+				slog.ErrorContext(ctx, "error recording delivery", "worker", wd.Name(), "delivery_id", d.id, "error", err)
+				result.Error = err
+			}
+		}
+		if len(deliveries) < webhookBatch {
+			break
+		}
+	}
+
+	if time.Since(wd.lastPrune) >= webhookPruneInterval && ctx.Err() == nil {
+		pruned, err := wd.prune(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "error pruning events", "worker", wd.Name(), "error", err)
+			result.Error = err
+		} else {
+			wd.lastPrune = time.Now()
+			result.PrunedCount = pruned
+		}
+	}
+
+	if result.DeliveredCount+result.RetryCount+result.DeadCount > 0 {
+		slog.InfoContext(ctx, "webhooks delivered",
+			"worker", wd.Name(), "delivered_count", result.DeliveredCount, "retry_count", result.RetryCount, "dead_count", result.DeadCount)
+	}
+
+	wd.sendResult(result)
+}
+
+// claim leases a batch of due deliveries to active subscriptions by moving
+// their next attempt past the lease, so concurrent workers skip them and a
+// crashed worker's deliveries are retried once the lease runs out
+func (wd *WebhookDispatcher) claim(ctx context.Context) ([]claimedDelivery, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := wd.db.Pool.Query(queryCtx,
+		`WITH claimed AS (
+		   UPDATE webhook_deliveries SET attempts = attempts + 1,
+		   next_attempt_at = CURRENT_TIMESTAMP + $2::interval
+		   WHERE id IN (
+		     SELECT d.id FROM webhook_deliveries d
+		     JOIN webhook_subscriptions s ON s.id = d.subscription_id
+		     WHERE d.status = 'pending' AND d.next_attempt_at <= CURRENT_TIMESTAMP AND s.active
+		     ORDER BY d.next_attempt_at, d.id
+		     LIMIT $1
+		     FOR UPDATE OF d SKIP LOCKED
+		   )
+		   RETURNING id, subscription_id, event_id, attempts
+		 )
+		 SELECT c.id, c.attempts, s.url, s.secret, e.id, e.type, e.created_at, e.data
+		 FROM claimed c
+		 JOIN webhook_subscriptions s ON s.id = c.subscription_id
+		 JOIN webhook_events e ON e.id = c.event_id
+		 ORDER BY c.id`,
+		webhookBatch, fmt.Sprintf("%d seconds", int(webhookLease.Seconds())))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []claimedDelivery
+	for rows.Next() {
+		var d claimedDelivery
+		err := rows.Scan(&d.id, &d.attempts, &d.url, &d.secret,
+			&d.event.ID, &d.event.Type, &d.event.CreatedAt, &d.event.Data)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// deliver posts one delivery and records the attempt and its outcome
+func (wd *WebhookDispatcher) deliver(ctx context.Context, d claimedDelivery, result *WebhookDispatchResult) error {
+	body, err := json.Marshal(d.event)
+	if err != nil {
+		return err
+	}
+
+	res, sendErr := wd.client.Send(ctx, webhook.Request{
+		URL:        d.url,
+		Secret:     d.secret,
+		DeliveryID: d.id,
+		Event:      d.event.Type,
+		Body:       body,
+	})
+
+	var statusCode *int
+	if res.StatusCode != 0 {
+		statusCode = &res.StatusCode
+	}
+	var errText *string
+	if sendErr != nil {
+		msg := sendErr.Error()
+		errText = &msg
+	}
+
+	var update string
+	var args []any
+	switch {
+	case sendErr == nil:
+		result.DeliveredCount++
+		update = `status = 'delivered', delivered_at = CURRENT_TIMESTAMP`
+
+	case d.attempts >= webhook.MaxAttempts:
+		result.DeadCount++
+		slog.ErrorContext(ctx, "delivery dead",
+			"worker", wd.Name(), "delivery_id", d.id, "event", d.event.Type, "attempts", d.attempts, "error", sendErr)
+		update = `status = 'dead'`
+
+	default:
+		result.RetryCount++
+		args = append(args, fmt.Sprintf("%d seconds", int(webhook.Backoff(d.attempts).Seconds())))
+		update = `next_attempt_at = CURRENT_TIMESTAMP + $7::interval`
+	}
+
+	queryCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	args = append([]any{d.id, d.attempts, statusCode, errText, res.Body, res.Duration.Milliseconds()}, args...)
+	_, err = wd.db.Pool.Exec(queryCtx,
+		`WITH attempt AS (
+		   INSERT INTO webhook_delivery_attempts
+		   (delivery_id, attempt, status_code, error, response_body, duration_ms)
+		   VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+		 )
+		 UPDATE webhook_deliveries SET `+update+`, last_status_code = $3, last_error = $4
+		 WHERE id = $1`,
+		args...)
+	return err
+}
+
+// prune deletes events older than webhookRetention with no pending
+// deliveries, along with their deliveries and attempt logs
+func (wd *WebhookDispatcher) prune(ctx context.Context) (int64, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	tag, err := wd.db.Pool.Exec(queryCtx,
+		`DELETE FROM webhook_events e
+		 WHERE e.created_at < CURRENT_TIMESTAMP - $1::interval
+		   AND NOT EXISTS (
+		     SELECT 1 FROM webhook_deliveries d
+		     WHERE d.event_id = e.id AND d.status = 'pending')`,
+		fmt.Sprintf("%d seconds", int(webhookRetention.Seconds())))
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// sendResult records the run in the worker metrics and sends the result to
+// the results channel (non-blocking)
+func (wd *WebhookDispatcher) sendResult(result WebhookDispatchResult) {
+	metrics.ObserveWorkerRun(wd.Name(), result.Timestamp, result.Error)
+
+	select {
+	case wd.resultsCh <- result:
+	default:
+		// Channel full, discard old result
+	}
+}
@@ -2,11 +2,12 @@ package worker
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"sync"
 	"time"
 
 	"go-backend/internal/database"
+	"go-backend/internal/metrics"
 )
 
 // Worker interface for all background workers
@@ -46,17 +47,19 @@ func (m *Manager) Start() {
 		m.wg.Add(1)
 		go func(worker Worker) {
 			defer m.wg.Done()
-			log.Printf("[WorkerManager] Starting worker: %s", worker.Name())
+			slog.Info("starting worker", "component", "worker_manager", "worker", worker.Name())
+			metrics.WorkerUp.WithLabelValues(worker.Name()).Set(1)
 			worker.Start(m.ctx)
-			log.Printf("[WorkerManager] Worker stopped: %s", worker.Name())
+			metrics.WorkerUp.WithLabelValues(worker.Name()).Set(0)
+			slog.Info("worker stopped", "component", "worker_manager", "worker", worker.Name())
 		}(w)
 	}
-	log.Printf("[WorkerManager] Started %d workers", len(m.workers))
+	slog.Info("workers started", "component", "worker_manager", "workers", len(m.workers))
 }
 
 // Shutdown gracefully stops all workers
 func (m *Manager) Shutdown(timeout time.Duration) error {
-	log.Println("[WorkerManager] Initiating shutdown...")
+	slog.Info("initiating shutdown", "component", "worker_manager")
 
 	// Signal all workers to stop
 	m.cancel()
@@ -70,10 +73,10 @@ func (m *Manager) Shutdown(timeout time.Duration) error {
 
 	select {
 	case <-done:
-		log.Println("[WorkerManager] All workers stopped gracefully")
+		slog.Info("all workers stopped gracefully", "component", "worker_manager")
 		return nil
 	case <-time.After(timeout):
-		log.Println("[WorkerManager] Shutdown timed out, some workers may not have stopped")
+		slog.Warn("shutdown timed out, some workers may not have stopped", "component", "worker_manager")
 		return context.DeadlineExceeded
 	}
 }
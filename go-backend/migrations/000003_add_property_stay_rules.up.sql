@@ -0,0 +1,14 @@
+-- 000003_add_property_stay_rules.up.sql
+-- Per-property stay rules enforced on booking creation
+
+CREATE TABLE IF NOT EXISTS property_stay_rules (
+    property_id INTEGER PRIMARY KEY REFERENCES properties(id) ON DELETE CASCADE,
+    min_nights INTEGER NOT NULL DEFAULT 1,
+    max_nights INTEGER NOT NULL DEFAULT 0,
+    check_in_days INTEGER[] NOT NULL DEFAULT '{}',
+    check_out_days INTEGER[] NOT NULL DEFAULT '{}',
+    advance_notice_days INTEGER NOT NULL DEFAULT 0,
+    booking_window_days INTEGER NOT NULL DEFAULT 0,
+    preparation_days INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
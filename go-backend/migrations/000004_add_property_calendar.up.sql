@@ -0,0 +1,11 @@
+-- 000004_add_property_calendar.up.sql
+-- Nights blocked by hosts (personal use, maintenance, ...)
+
+CREATE TABLE IF NOT EXISTS property_calendar (
+    property_id INTEGER NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (property_id, date)
+);
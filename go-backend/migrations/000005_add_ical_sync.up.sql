@@ -0,0 +1,37 @@
+-- 000005_add_ical_sync.up.sql
+-- iCal export tokens and imported external calendars
+
+ALTER TABLE properties ADD COLUMN ical_token VARCHAR(64);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_properties_ical_token ON properties(ical_token);
+
+CREATE TABLE IF NOT EXISTS property_ical_feeds (
+    id SERIAL PRIMARY KEY,
+    property_id INTEGER NOT NULL REFERENCES properties(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    url VARCHAR(2000) NOT NULL,
+    last_synced_at TIMESTAMP,
+    last_error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_property_ical_feeds_property_id ON property_ical_feeds(property_id);
+
+-- Nights imported from a feed are replaced on every sync
+ALTER TABLE property_calendar ADD COLUMN feed_id INTEGER REFERENCES property_ical_feeds(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_property_calendar_feed_id ON property_calendar(feed_id);
+
+-- Imported events that overlap bookings made on this platform
+CREATE TABLE IF NOT EXISTS property_ical_conflicts (
+    id SERIAL PRIMARY KEY,
+    feed_id INTEGER NOT NULL REFERENCES property_ical_feeds(id) ON DELETE CASCADE,
+    booking_id INTEGER NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    event_uid VARCHAR(500) NOT NULL,
+    summary VARCHAR(500) NOT NULL DEFAULT '',
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    detected_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_property_ical_conflicts_feed_id ON property_ical_conflicts(feed_id);
@@ -0,0 +1,17 @@
+-- 000006_add_cancellation_policies.down.sql
+-- Remove cancellation policies and price breakdown
+
+ALTER TABLE bookings DROP COLUMN IF EXISTS refund_amount;
+ALTER TABLE bookings DROP COLUMN IF EXISTS cancellation_reason;
+ALTER TABLE bookings DROP COLUMN IF EXISTS cancelled_by;
+ALTER TABLE bookings DROP COLUMN IF EXISTS cancelled_at;
+ALTER TABLE bookings DROP COLUMN IF EXISTS cancellation_tiers;
+ALTER TABLE bookings DROP COLUMN IF EXISTS cancellation_policy;
+ALTER TABLE bookings DROP COLUMN IF EXISTS total_price;
+ALTER TABLE bookings DROP COLUMN IF EXISTS service_fee;
+ALTER TABLE bookings DROP COLUMN IF EXISTS accommodation_total;
+ALTER TABLE bookings DROP COLUMN IF EXISTS nights;
+ALTER TABLE bookings DROP COLUMN IF EXISTS nightly_rate;
+
+ALTER TABLE properties DROP COLUMN IF EXISTS cancellation_tiers;
+ALTER TABLE properties DROP COLUMN IF EXISTS cancellation_policy;
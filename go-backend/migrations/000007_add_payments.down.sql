@@ -0,0 +1,5 @@
+-- 000007_add_payments.down.sql
+-- Remove payments
+
+DROP TABLE IF EXISTS payment_events;
+DROP TABLE IF EXISTS payments;
@@ -0,0 +1,44 @@
+-- 000008_add_ledger.up.sql
+-- Double-entry ledger and host payouts
+
+CREATE TABLE IF NOT EXISTS ledger_journals (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL,
+    reference VARCHAR(255) NOT NULL UNIQUE,
+    payment_id INTEGER REFERENCES payments(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Amounts are in cents
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id SERIAL PRIMARY KEY,
+    journal_id INTEGER NOT NULL REFERENCES ledger_journals(id) ON DELETE CASCADE,
+    account VARCHAR(50) NOT NULL,
+    host_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    booking_id INTEGER REFERENCES bookings(id) ON DELETE SET NULL,
+    debit BIGINT NOT NULL DEFAULT 0 CHECK (debit >= 0),
+    credit BIGINT NOT NULL DEFAULT 0 CHECK (credit >= 0),
+    CHECK ((debit = 0) <> (credit = 0))
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_journal_id ON ledger_entries(journal_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_account ON ledger_entries(account, host_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_booking_id ON ledger_entries(booking_id);
+
+CREATE TABLE IF NOT EXISTS host_payouts (
+    id SERIAL PRIMARY KEY,
+    host_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    amount DECIMAL(10, 2) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'released',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_host_payouts_host_id ON host_payouts(host_id);
+
+CREATE TABLE IF NOT EXISTS host_payout_items (
+    payout_id INTEGER NOT NULL REFERENCES host_payouts(id) ON DELETE CASCADE,
+    booking_id INTEGER NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    amount DECIMAL(10, 2) NOT NULL,
+    PRIMARY KEY (payout_id, booking_id)
+);
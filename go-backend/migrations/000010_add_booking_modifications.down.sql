@@ -0,0 +1,4 @@
+-- 000010_add_booking_modifications.down.sql
+-- Remove booking modification requests
+
+DROP TABLE IF EXISTS booking_modifications;
@@ -0,0 +1,4 @@
+-- 000012_add_booking_holds.down.sql
+-- Remove booking holds
+
+DROP TABLE IF EXISTS booking_holds;
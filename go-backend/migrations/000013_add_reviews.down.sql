@@ -0,0 +1,4 @@
+-- 000013_add_reviews.down.sql
+-- Remove reviews (property ratings keep their last computed values)
+
+DROP TABLE IF EXISTS reviews;
@@ -0,0 +1,10 @@
+-- 000014_add_guest_reviews.down.sql
+-- Remove guest reviews and review publishing
+
+ALTER TABLE users DROP COLUMN IF EXISTS guest_reviews;
+ALTER TABLE users DROP COLUMN IF EXISTS guest_rating;
+
+DROP TABLE IF EXISTS guest_reviews;
+
+DROP INDEX IF EXISTS idx_reviews_unpublished;
+ALTER TABLE reviews DROP COLUMN IF EXISTS published_at;
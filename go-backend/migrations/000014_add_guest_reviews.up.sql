@@ -0,0 +1,28 @@
+-- 000014_add_guest_reviews.up.sql
+-- Host reviews of guests and blind publishing of both sides' reviews
+
+-- Reviews stay hidden until both sides have reviewed or the review window closes
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS published_at TIMESTAMP;
+UPDATE reviews SET published_at = created_at WHERE published_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_reviews_unpublished ON reviews(booking_id) WHERE published_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS guest_reviews (
+    id SERIAL PRIMARY KEY,
+    booking_id INTEGER NOT NULL UNIQUE REFERENCES bookings(id) ON DELETE CASCADE,
+    guest_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    cleanliness SMALLINT NOT NULL CHECK (cleanliness BETWEEN 1 AND 5),
+    communication SMALLINT NOT NULL CHECK (communication BETWEEN 1 AND 5),
+    house_rules SMALLINT NOT NULL CHECK (house_rules BETWEEN 1 AND 5),
+    comment TEXT NOT NULL DEFAULT '',
+    published_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_guest_reviews_guest_id ON guest_reviews(guest_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_guest_reviews_unpublished ON guest_reviews(booking_id) WHERE published_at IS NULL;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS guest_rating DECIMAL(2, 1) NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS guest_reviews INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,31 @@
+-- 000016_add_conversation_participants.down.sql
+-- Restore single-user conversations with a named contact
+
+DROP INDEX IF EXISTS idx_conversations_participants;
+DROP INDEX IF EXISTS idx_conversations_host_id;
+DROP INDEX IF EXISTS idx_conversations_guest_id;
+ALTER TABLE conversations DROP CONSTRAINT IF EXISTS conversations_distinct_participants;
+
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS user_id INTEGER REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS contact_name VARCHAR(255);
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS contact_type VARCHAR(50);
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS property_name VARCHAR(255);
+
+UPDATE conversations c SET
+    user_id = c.guest_id,
+    contact_name = COALESCE((SELECT name FROM users WHERE id = c.host_id), 'Host'),
+    contact_type = 'host',
+    property_name = (SELECT title FROM properties WHERE id = c.property_id);
+
+-- Conversations without a guest cannot be represented
+DELETE FROM conversations WHERE user_id IS NULL;
+
+ALTER TABLE conversations ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE conversations ALTER COLUMN contact_name SET NOT NULL;
+ALTER TABLE conversations ALTER COLUMN contact_type SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_conversations_user_id ON conversations(user_id);
+
+ALTER TABLE conversations DROP COLUMN IF EXISTS booking_id;
+ALTER TABLE conversations DROP COLUMN IF EXISTS property_id;
+ALTER TABLE conversations DROP COLUMN IF EXISTS host_id;
+ALTER TABLE conversations DROP COLUMN IF EXISTS guest_id;
@@ -0,0 +1,30 @@
+-- 000016_add_conversation_participants.up.sql
+-- Conversations between a guest and a host about a property and optionally a booking
+
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS guest_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS host_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS property_id INTEGER REFERENCES properties(id) ON DELETE SET NULL;
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS booking_id INTEGER REFERENCES bookings(id) ON DELETE SET NULL;
+
+-- The single user of existing conversations was the guest; the host is the
+-- owner of the property they were named after
+UPDATE conversations c SET
+    guest_id = c.user_id,
+    property_id = (SELECT p.id FROM properties p WHERE p.title = c.property_name ORDER BY p.id LIMIT 1);
+UPDATE conversations c SET host_id = p.owner_id
+FROM properties p
+WHERE p.id = c.property_id AND p.owner_id IS DISTINCT FROM c.guest_id;
+
+ALTER TABLE conversations DROP COLUMN IF EXISTS user_id;
+ALTER TABLE conversations DROP COLUMN IF EXISTS contact_name;
+ALTER TABLE conversations DROP COLUMN IF EXISTS contact_type;
+ALTER TABLE conversations DROP COLUMN IF EXISTS property_name;
+
+ALTER TABLE conversations ADD CONSTRAINT conversations_distinct_participants CHECK (guest_id <> host_id);
+
+CREATE INDEX IF NOT EXISTS idx_conversations_guest_id ON conversations(guest_id);
+CREATE INDEX IF NOT EXISTS idx_conversations_host_id ON conversations(host_id);
+
+-- One conversation per guest, host and property, and per booking
+CREATE UNIQUE INDEX IF NOT EXISTS idx_conversations_participants
+    ON conversations(guest_id, host_id, property_id, COALESCE(booking_id, 0));
@@ -0,0 +1,13 @@
+-- 000017_add_realtime_events.up.sql
+-- Events pushed to connected users, kept briefly so reconnecting clients can replay them
+
+CREATE TABLE IF NOT EXISTS realtime_events (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(50) NOT NULL,
+    user_ids INTEGER[] NOT NULL,
+    data JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_realtime_events_user_ids ON realtime_events USING GIN (user_ids);
+CREATE INDEX IF NOT EXISTS idx_realtime_events_created_at ON realtime_events(created_at);
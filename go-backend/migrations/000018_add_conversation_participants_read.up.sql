@@ -0,0 +1,24 @@
+-- 000018_add_conversation_participants_read.up.sql
+-- Per-participant read state of conversations, replacing messages.read
+
+CREATE TABLE IF NOT EXISTS conversation_participants (
+    conversation_id INTEGER NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    last_read_message_id INTEGER NOT NULL DEFAULT 0,
+    last_read_at TIMESTAMP,
+    PRIMARY KEY (conversation_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversation_participants_user_id ON conversation_participants(user_id);
+
+-- Existing messages were all stored as read
+INSERT INTO conversation_participants (conversation_id, user_id, last_read_message_id, last_read_at)
+SELECT c.id, u.user_id, COALESCE((SELECT MAX(m.id) FROM messages m WHERE m.conversation_id = c.id), 0), CURRENT_TIMESTAMP
+FROM conversations c
+CROSS JOIN LATERAL (VALUES (c.guest_id), (c.host_id)) AS u(user_id)
+WHERE u.user_id IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+CREATE INDEX IF NOT EXISTS idx_messages_conversation_id_id ON messages(conversation_id, id);
+
+ALTER TABLE messages DROP COLUMN IF EXISTS read;
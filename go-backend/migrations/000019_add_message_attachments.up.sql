@@ -0,0 +1,23 @@
+-- 000019_add_message_attachments.up.sql
+-- Files attached to messages. Uploads are pending until a message is sent
+-- with them; rows losing their conversation, message or uploader are
+-- orphans whose files the attachment cleaner deletes.
+
+CREATE TABLE IF NOT EXISTS message_attachments (
+    id SERIAL PRIMARY KEY,
+    conversation_id INTEGER REFERENCES conversations(id) ON DELETE SET NULL,
+    message_id INTEGER REFERENCES messages(id) ON DELETE SET NULL,
+    uploader_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL CHECK (size_bytes > 0),
+    storage_key VARCHAR(255) NOT NULL UNIQUE,
+    thumbnail_key VARCHAR(255) UNIQUE,
+    width INTEGER,
+    height INTEGER,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_attachments_message_id ON message_attachments(message_id);
+CREATE INDEX IF NOT EXISTS idx_message_attachments_orphaned ON message_attachments(created_at)
+    WHERE conversation_id IS NULL OR message_id IS NULL OR uploader_id IS NULL;
@@ -0,0 +1,33 @@
+-- 000021_create_notifications.up.sql
+-- In-app notifications and per-type notification preferences
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL
+        CHECK (type IN ('booking', 'payment', 'review', 'message', 'system', 'reminder')),
+    title VARCHAR(200) NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    priority VARCHAR(10) NOT NULL DEFAULT 'medium'
+        CHECK (priority IN ('low', 'medium', 'high')),
+    action_label VARCHAR(100),
+    action_url VARCHAR(500),
+    -- Repeated notifications with the same key replace the unread one
+    group_key VARCHAR(100),
+    read_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications(user_id) WHERE read_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_group_key
+    ON notifications(user_id, group_key) WHERE read_at IS NULL;
+
+-- Types without a row are delivered
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL,
+    in_app BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, type)
+);
@@ -0,0 +1,6 @@
+-- 000024_add_support_tickets.down.sql
+-- Remove support tickets and their replies
+
+DROP TABLE IF EXISTS support_replies;
+DROP TABLE IF EXISTS support_tickets;
+UPDATE users SET role = 'user' WHERE role = 'support';
@@ -8,14 +8,14 @@ INSERT INTO users (email, name, password_hash, role) VALUES
 ('admin@gorent.com', 'Admin User', '$2a$10$rPrF4cHvT6TvJxmKv8Y4.OX8K9K1Lq8VQ6Q1zfxQZT7sQzKK.6Vhe', 'admin')
 ON CONFLICT (email) DO NOTHING;
 
--- Demo properties
+-- Demo properties (rating and reviews are computed from the reviews table)
 INSERT INTO properties (title, location, price_per_night, rating, reviews, guests, bedrooms, bathrooms, type, amenities, image, owner_id) VALUES
 (
     'Modern Downtown Apartment',
     'San Francisco, CA',
     150.00,
-    4.8,
-    124,
+    0,
+    0,
     4,
     2,
     1,
@@ -28,8 +28,8 @@ INSERT INTO properties (title, location, price_per_night, rating, reviews, guest
     'Luxury Villa with Ocean View',
     'Malibu, CA',
     450.00,
-    4.9,
-    89,
+    0,
+    0,
     8,
     4,
     3,
@@ -42,8 +42,8 @@ INSERT INTO properties (title, location, price_per_night, rating, reviews, guest
     'Cozy Mountain Cabin',
     'Lake Tahoe, CA',
     200.00,
-    4.7,
-    56,
+    0,
+    0,
     6,
     3,
     2,
@@ -56,8 +56,8 @@ INSERT INTO properties (title, location, price_per_night, rating, reviews, guest
     'Beachfront Condo',
     'Santa Monica, CA',
     280.00,
-    4.6,
-    78,
+    0,
+    0,
     4,
     2,
     2,
@@ -75,11 +75,19 @@ INSERT INTO bookings (user_id, property_id, start_date, end_date, guests, status
 INSERT INTO bookings (user_id, property_id, start_date, end_date, guests, status) VALUES
 (1, 2, CURRENT_DATE - INTERVAL '5 days', CURRENT_DATE - INTERVAL '2 days', 4, 'pending');
 
--- Demo conversation
-INSERT INTO conversations (user_id, contact_name, contact_type, property_name) VALUES
-(1, 'John Host', 'host', 'Modern Downtown Apartment');
+-- Demo conversation between the demo user and the host of the Beachfront Condo
+INSERT INTO conversations (guest_id, host_id, property_id) VALUES
+(1, 2, 4);
+
+INSERT INTO conversation_participants (conversation_id, user_id) VALUES
+(1, 1),
+(1, 2);
 
 -- Demo messages
-INSERT INTO messages (conversation_id, sender_id, text, read) VALUES
-(1, NULL, 'Welcome! Let me know if you have any questions about the apartment.', TRUE),
-(1, 1, 'Thanks! What time is check-in?', TRUE);
+INSERT INTO messages (conversation_id, sender_id, text) VALUES
+(1, 2, 'Welcome! Let me know if you have any questions about the condo.'),
+(1, 1, 'Thanks! What time is check-in?');
+
+-- The demo user has read the host's welcome; the host has not read the reply yet
+UPDATE conversation_participants SET last_read_message_id = 2, last_read_at = CURRENT_TIMESTAMP
+WHERE conversation_id = 1 AND user_id = 1;